@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,11 +14,21 @@ import (
 	"walletpoint/internal/config"
 	"walletpoint/internal/database"
 	"walletpoint/internal/middleware"
+	"walletpoint/internal/modules/audit"
 	"walletpoint/internal/modules/auth"
+	"walletpoint/internal/modules/badge"
+	"walletpoint/internal/modules/group"
+	"walletpoint/internal/modules/leaderboard"
+	"walletpoint/internal/modules/lockout"
 	"walletpoint/internal/modules/mission"
+	"walletpoint/internal/modules/notification"
 	"walletpoint/internal/modules/product"
 	"walletpoint/internal/modules/qr"
+	"walletpoint/internal/modules/user"
+	"walletpoint/internal/modules/voucher"
 	"walletpoint/internal/modules/wallet"
+	"walletpoint/pkg/mailer"
+	"walletpoint/pkg/storage"
 )
 
 func main() {
@@ -35,8 +47,17 @@ func main() {
 
 	log.Println("Connected to database successfully")
 
-	// Initialize JWT Manager
-	jwtManager := middleware.NewJWTManager(cfg.JWT)
+	// Submission uploads are kept on local disk
+	fileStore, err := storage.NewLocal(cfg.Upload.Dir)
+	if err != nil {
+		log.Fatalf("Failed to prepare upload storage: %v", err)
+	}
+
+	// Account mail (password resets, verification links)
+	mail, err := newMailer(cfg.Mail)
+	if err != nil {
+		log.Fatalf("Failed to prepare mailer: %v", err)
+	}
 
 	// Initialize repositories
 	authRepo := auth.NewRepository(db)
@@ -44,13 +65,59 @@ func main() {
 	qrRepo := qr.NewRepository(db)
 	missionRepo := mission.NewRepository(db)
 	productRepo := product.NewRepository(db)
+	voucherRepo := voucher.NewRepository(db)
+	notificationRepo := notification.NewRepository(db)
+	groupRepo := group.NewRepository(db)
+	leaderboardRepo := leaderboard.NewRepository(db)
+	badgeRepo := badge.NewRepository(db)
+	userRepo := user.NewRepository(db)
+	auditRepo := audit.NewRepository(db)
+	lockoutRepo := lockout.NewRepository(db)
+
+	// Initialize JWT Manager; access tokens stop working once their session
+	// is revoked or their user deactivated
+	sessionCache := middleware.NewSessionCache(authRepo, cfg.JWT.SessionCacheTTL)
+	jwtManager := middleware.NewJWTManager(cfg.JWT)
+	jwtManager.UseSessionCache(sessionCache)
+
+	// Access tokens are signed with asymmetric keys when a key directory is
+	// configured, so other services can verify them through the JWKS
+	var keyring *middleware.Keyring
+	if cfg.JWT.KeysDir != "" {
+		keyring, err = middleware.LoadKeyring(cfg.JWT.KeysDir, cfg.JWT.ActiveKeyID)
+		if err != nil {
+			log.Fatalf("Failed to load JWT keys: %v", err)
+		}
+		jwtManager.UseKeyring(keyring)
+	} else {
+		log.Println("JWT_ALLOW_HS256 set, signing access tokens with HS256; do not use this in production")
+	}
 
 	// Initialize services
-	authService := auth.NewService(authRepo, jwtManager)
-	walletService := wallet.NewService(walletRepo, db)
+	lockoutService := lockout.NewService(lockoutRepo, auditRepo, cfg.Login)
+	authService := auth.NewService(authRepo, badgeRepo, jwtManager, mail, cfg.Account, cfg.TwoFactor, lockoutService, cfg.OIDC)
+	walletService := wallet.NewService(walletRepo, auditRepo, db, cfg.TwoFactor)
 	qrService := qr.NewService(qrRepo, walletRepo, db, cfg.QR)
-	missionService := mission.NewService(missionRepo, walletRepo, db)
-	productService := product.NewService(productRepo, walletRepo, db)
+	missionService := mission.NewService(missionRepo, walletRepo, notificationRepo, groupRepo, fileStore, db, cfg.Mission, cfg.Upload)
+	productService := product.NewService(productRepo, walletRepo, voucherRepo, db, cfg.Market)
+	voucherService := voucher.NewService(voucherRepo)
+	notificationService := notification.NewService(notificationRepo)
+	groupService := group.NewService(groupRepo)
+	leaderboardService := leaderboard.NewService(leaderboardRepo, groupRepo)
+	badgeService := badge.NewService(badgeRepo, notificationRepo, cfg.Badge)
+	userService := user.NewService(userRepo, authRepo, walletRepo, auditRepo, jwtManager, db, cfg.Account)
+
+	// Background jobs
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go productService.RunReservationSweeper(jobCtx)
+	go missionService.RunScheduler(jobCtx)
+	go badgeService.RunAwarder(jobCtx)
+	go sessionCache.RunSweeper(jobCtx)
+	go lockoutService.RunCleanup(jobCtx)
+	if keyring != nil {
+		go keyring.RunReloader(jobCtx, cfg.JWT.KeysReloadInterval)
+	}
 
 	// Initialize handlers
 	authHandler := auth.NewHandler(authService)
@@ -58,11 +125,20 @@ func main() {
 	qrHandler := qr.NewHandler(qrService)
 	missionHandler := mission.NewHandler(missionService)
 	productHandler := product.NewHandler(productService)
+	voucherHandler := voucher.NewHandler(voucherService)
+	notificationHandler := notification.NewHandler(notificationService)
+	groupHandler := group.NewHandler(groupService)
+	leaderboardHandler := leaderboard.NewHandler(leaderboardService)
+	badgeHandler := badge.NewHandler(badgeService)
+	userHandler := user.NewHandler(userService)
+	lockoutHandler := lockout.NewHandler(lockoutService)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
 		AppName:      cfg.App.Name,
 		ErrorHandler: customErrorHandler,
+		// Room for a full set of submission attachments plus form fields
+		BodyLimit: int(cfg.Upload.MaxFileSize)*cfg.Upload.MaxFiles + 1<<20,
 	})
 
 	// Global middlewares
@@ -86,6 +162,9 @@ func main() {
 		})
 	})
 
+	// Public keys for verifying access tokens
+	app.Get("/.well-known/jwks.json", middleware.JWKSHandler(jwtManager))
+
 	// API v1 routes
 	v1 := app.Group("/api/v1")
 
@@ -95,6 +174,13 @@ func main() {
 	qr.RegisterRoutes(v1, qrHandler, jwtManager)
 	mission.RegisterRoutes(v1, missionHandler, jwtManager)
 	product.RegisterRoutes(v1, productHandler, jwtManager)
+	voucher.RegisterRoutes(v1, voucherHandler, jwtManager)
+	notification.RegisterRoutes(v1, notificationHandler, jwtManager)
+	group.RegisterRoutes(v1, groupHandler, jwtManager)
+	leaderboard.RegisterRoutes(v1, leaderboardHandler, jwtManager)
+	badge.RegisterRoutes(v1, badgeHandler, jwtManager)
+	user.RegisterRoutes(v1, userHandler, jwtManager)
+	lockout.RegisterRoutes(v1, lockoutHandler, jwtManager)
 
 	// Start server
 	log.Printf("Starting %s on port %s", cfg.App.Name, cfg.App.Port)
@@ -118,3 +204,21 @@ func customErrorHandler(c *fiber.Ctx, err error) error {
 		},
 	})
 }
+
+func newMailer(cfg config.MailConfig) (mailer.Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return &mailer.SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}, nil
+	case "file":
+		return mailer.NewFile(cfg.Dir, cfg.From)
+	case "log", "":
+		return &mailer.LogMailer{}, nil
+	}
+	return nil, fmt.Errorf("unknown MAIL_DRIVER %q", cfg.Driver)
+}
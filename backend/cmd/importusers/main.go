@@ -0,0 +1,147 @@
+// Command importusers provisions accounts from a CSV roster, the same way as
+// POST /api/v1/admin/users/import. The roster needs a header naming at least
+// username, email and full_name; nim_nip, role and phone are optional.
+//
+// New accounts get a random initial password or, with -credentials invite, a
+// single-use invitation token. Both only appear in the report, which is
+// written with owner-only permissions:
+//
+//	go run ./cmd/importusers -file roster.csv -dry-run
+//	go run ./cmd/importusers -file roster.csv -credentials invite -report report.csv
+//	go run ./cmd/importusers -file roster.csv -on-existing update -report report.csv
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"walletpoint/internal/config"
+	"walletpoint/internal/database"
+	"walletpoint/internal/middleware"
+	"walletpoint/internal/modules/audit"
+	"walletpoint/internal/modules/auth"
+	"walletpoint/internal/modules/user"
+	"walletpoint/internal/modules/wallet"
+)
+
+func main() {
+	file := flag.String("file", "", "path of the CSV roster")
+	dryRun := flag.Bool("dry-run", false, "validate the roster without saving anything")
+	onExisting := flag.String("on-existing", "skip", "what to do with usernames that already exist: skip or update")
+	credentials := flag.String("credentials", "password", "how new accounts get in: password or invite")
+	defaultRole := flag.String("default-role", "mahasiswa", "role for rows without a role column")
+	report := flag.String("report", "", "write the per-row report, including credentials, to this CSV file")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts := user.ImportOptions{
+		DryRun:      *dryRun,
+		OnExisting:  *onExisting,
+		Credentials: *credentials,
+		DefaultRole: *defaultRole,
+	}
+	if errs := opts.Validate(); len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", e.Field, e.Message)
+		}
+		os.Exit(2)
+	}
+	if !opts.DryRun && *report == "" {
+		log.Fatal("Refusing to create accounts without -report: their credentials would be lost")
+	}
+
+	roster, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Failed to open roster: %v", err)
+	}
+	defer roster.Close()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	service := user.NewService(
+		user.NewRepository(db), auth.NewRepository(db), wallet.NewRepository(db), audit.NewRepository(db),
+		middleware.NewJWTManager(cfg.JWT), db, cfg.Account,
+	)
+
+	hostname, _ := os.Hostname()
+	result, err := service.ImportCSV(context.Background(), roster, opts, user.Actor{
+		IPAddress: "127.0.0.1",
+		UserAgent: "importusers@" + hostname,
+	})
+	if err != nil {
+		log.Fatalf("Import failed, nothing was saved: %v", err)
+	}
+
+	// Write the report first: it holds the only copy of new credentials
+	var reportErr error
+	if *report != "" {
+		if reportErr = writeReport(*report, result); reportErr != nil {
+			log.Printf("Failed to write report: %v", reportErr)
+		} else {
+			fmt.Println("Report written to", *report)
+		}
+	}
+
+	for _, row := range result.Rows {
+		if row.Status == user.ImportInvalid || row.Status == user.ImportFailed {
+			fmt.Printf("row %d %s: %s %s\n", row.Row, row.Username, row.Status, row.Message)
+		}
+	}
+
+	verb := "Imported"
+	if result.DryRun {
+		verb = "Dry run, nothing saved:"
+	}
+	fmt.Printf("%s %d created, %d updated, %d skipped, %d failed\n",
+		verb, result.Created, result.Updated, result.Skipped, result.Failed)
+	if reportErr != nil {
+		os.Exit(1)
+	}
+}
+
+func writeReport(path string, result *user.ImportResult) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{
+		"row", "username", "email", "full_name", "nim_nip", "role", "status", "message",
+		"user_id", "password", "invite_token", "invite_expires_at",
+	})
+	for _, row := range result.Rows {
+		userID := ""
+		if row.UserID != 0 {
+			userID = strconv.FormatUint(uint64(row.UserID), 10)
+		}
+		w.Write([]string{
+			strconv.Itoa(row.Row), row.Username, row.Email, row.FullName, row.NimNip, row.Role,
+			row.Status, row.Message, userID, row.Password, row.InviteToken, row.InviteExpiresAt,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
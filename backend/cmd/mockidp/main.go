@@ -0,0 +1,274 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"walletpoint/pkg/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/joho/godotenv"
+)
+
+// Mock OpenID provider for developing single sign-on locally. Anyone can sign
+// in as anyone: the login form simply asks which claims to put in the ID token
+
+const (
+	keyID   = "mock-1"
+	codeTTL = 2 * time.Minute
+	idTTL   = 5 * time.Minute
+)
+
+type authCode struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+	claims      jwt.MapClaims
+	expiresAt   time.Time
+}
+
+type server struct {
+	issuer string
+	key    ed25519.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]*authCode
+}
+
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Mock IdP</title></head>
+<body>
+<h1>Mock campus sign-in</h1>
+<form method="post" action="/authorize">
+{{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}
+<p><label>Subject <input name="sub" value="student-1" required></label></p>
+<p><label>Email <input name="email" value="student1@campus.ac.id"></label></p>
+<p><label>Name <input name="name" value="Student One"></label></p>
+<p><label>Username <input name="preferred_username" value="student1"></label></p>
+<p><label>NIM/NIP <input name="nim" value=""></label></p>
+<p><label><input type="checkbox" name="email_verified" value="true" checked> Email verified</label></p>
+<p><button type="submit">Sign in</button></p>
+</form>
+</body>
+</html>
+`))
+
+func main() {
+	// Load .env
+	godotenv.Load()
+
+	addr := getEnv("MOCK_IDP_ADDR", ":9000")
+	issuer := strings.TrimSuffix(getEnv("MOCK_IDP_ISSUER", "http://localhost:9000"), "/")
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatal("Failed to generate key:", err)
+	}
+
+	s := &server{issuer: issuer, key: key, codes: make(map[string]*authCode)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+
+	log.Printf("Mock IdP listening on %s with issuer %s", addr, issuer)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+func (s *server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"EdDSA"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *server) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.Public().(ed25519.PublicKey)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "EdDSA",
+			"x":   base64.RawURLEncoding.EncodeToString(pub),
+		}},
+	})
+}
+
+// authorize shows the login form on GET and issues a code on POST
+func (s *server) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	redirectURI := r.Form.Get("redirect_uri")
+	if r.Form.Get("response_type") != "code" || r.Form.Get("client_id") == "" || redirectURI == "" {
+		http.Error(w, "response_type=code, client_id and redirect_uri are required", http.StatusBadRequest)
+		return
+	}
+	if r.Form.Get("code_challenge") == "" || r.Form.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE with code_challenge_method=S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		params := map[string]string{}
+		for _, name := range []string{"response_type", "client_id", "redirect_uri", "state", "nonce", "code_challenge", "code_challenge_method"} {
+			params[name] = r.Form.Get(name)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		loginPage.Execute(w, map[string]interface{}{"Params": params})
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sub := strings.TrimSpace(r.Form.Get("sub"))
+	if sub == "" {
+		http.Error(w, "sub is required", http.StatusBadRequest)
+		return
+	}
+	claims := jwt.MapClaims{
+		"sub":            sub,
+		"email_verified": r.Form.Get("email_verified") == "true",
+	}
+	for _, name := range []string{"email", "name", "preferred_username", "nim"} {
+		if v := strings.TrimSpace(r.Form.Get(name)); v != "" {
+			claims[name] = v
+		}
+	}
+
+	code := randomString()
+	s.mu.Lock()
+	s.codes[code] = &authCode{
+		clientID:    r.Form.Get("client_id"),
+		redirectURI: redirectURI,
+		challenge:   r.Form.Get("code_challenge"),
+		nonce:       r.Form.Get("nonce"),
+		claims:      claims,
+		expiresAt:   time.Now().Add(codeTTL),
+	}
+	s.mu.Unlock()
+
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	q := target.Query()
+	q.Set("code", code)
+	if state := r.Form.Get("state"); state != "" {
+		q.Set("state", state)
+	}
+	target.RawQuery = q.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// token redeems a code once, checking the PKCE verifier
+func (s *server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	clientID := r.Form.Get("client_id")
+	if user, _, ok := r.BasicAuth(); ok {
+		clientID, _ = url.QueryUnescape(user)
+	}
+
+	s.mu.Lock()
+	code := s.codes[r.Form.Get("code")]
+	delete(s.codes, r.Form.Get("code"))
+	s.mu.Unlock()
+
+	if code == nil || time.Now().After(code.expiresAt) ||
+		code.clientID != clientID || code.redirectURI != r.Form.Get("redirect_uri") ||
+		oidc.Challenge(r.Form.Get("code_verifier")) != code.challenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": s.issuer,
+		"aud": code.clientID,
+		"iat": now.Unix(),
+		"exp": now.Add(idTTL).Unix(),
+	}
+	if code.nonce != "" {
+		claims["nonce"] = code.nonce
+	}
+	for name, value := range code.claims {
+		claims[name] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(s.key)
+	if err != nil {
+		tokenError(w, "server_error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+		"expires_in":   int64(idTTL.Seconds()),
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatal("Failed to read random bytes:", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"time"
@@ -9,10 +10,19 @@ import (
 )
 
 type Config struct {
-	App      AppConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	QR       QRConfig
+	App       AppConfig
+	Database  DatabaseConfig
+	JWT       JWTConfig
+	QR        QRConfig
+	Market    MarketConfig
+	Mission   MissionConfig
+	Upload    UploadConfig
+	Badge     BadgeConfig
+	Account   AccountConfig
+	Mail      MailConfig
+	TwoFactor TwoFactorConfig
+	Login     LoginConfig
+	OIDC      OIDCConfig
 }
 
 type AppConfig struct {
@@ -34,6 +44,30 @@ type JWTConfig struct {
 	RefreshSecret string
 	AccessExpiry  time.Duration
 	RefreshExpiry time.Duration
+	// How long the middleware trusts a looked-up session/user state
+	SessionCacheTTL time.Duration
+	Issuer          string
+	Audience        string
+	// Directory of PEM signing keys named <kid>.pem. Required unless
+	// AllowHS256 lets access tokens be signed with AccessSecret instead
+	KeysDir            string
+	ActiveKeyID        string // defaults to the last kid in sort order
+	KeysReloadInterval time.Duration
+	AllowHS256         bool // development only
+}
+
+// minSecretLen keeps signing and encryption secrets out of guessing range
+const minSecretLen = 32
+
+// publishedSecrets are the example and former default values that ship with
+// the repository; a server using one of them has no secret at all
+var publishedSecrets = map[string]bool{
+	"default-access-secret-key-32chars":          true,
+	"default-refresh-secret-key-32chars":         true,
+	"your-super-secret-access-key-min-32-chars":  true,
+	"your-super-secret-refresh-key-min-32-chars": true,
+	"default-two-factor-secret-key":              true,
+	"your-two-factor-secret-key":                 true,
 }
 
 type QRConfig struct {
@@ -41,15 +75,117 @@ type QRConfig struct {
 	ExpiryMinutes int
 }
 
+type MarketConfig struct {
+	ReservationTTL   time.Duration
+	ReservationSweep time.Duration
+}
+
+type MissionConfig struct {
+	SweepInterval  time.Duration
+	ReminderBefore time.Duration
+}
+
+type AccountConfig struct {
+	InviteTTL   time.Duration
+	ResetTTL    time.Duration
+	VerifyTTL   time.Duration
+	LinkBaseURL string // frontend address that reset and verification links point to
+}
+
+type MailConfig struct {
+	Driver       string // smtp, file or log
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	Dir          string
+}
+
+type TwoFactorConfig struct {
+	Issuer       string // shown next to the account in authenticator apps
+	SecretKey    string // encrypts stored TOTP secrets
+	ChallengeTTL time.Duration
+	StepUpTTL    time.Duration
+	// Transfers of more points than this need a fresh step-up
+	TransferThreshold int64
+}
+
+type LoginConfig struct {
+	MaxFailures   int // per username before a lockout
+	IPMaxFailures int // per client IP before a lockout; campus NATs share IPs
+	DelayAfter    int // failures allowed before retries are slowed down
+	BaseDelay     time.Duration
+	MaxDelay      time.Duration
+	// Failures older than this no longer count
+	FailureWindow time.Duration
+	// First lockout length; each repeat doubles it up to a day
+	LockoutDuration time.Duration
+}
+
+// OIDCConfig is single sign-on with the campus identity provider; it is off
+// while IssuerURL is empty
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // where the provider sends the user back to the app
+	// Claim holding the NIM/NIP. It is only matched against users.nim_nip
+	// and stored on new users when TrustNimClaim says the provider vouches
+	// for it; otherwise users are matched by verified email alone
+	NimClaim      string
+	TrustNimClaim bool
+	// Create accounts for campus users with no match, with DefaultRole
+	Provision   bool
+	DefaultRole string
+	StateTTL    time.Duration
+}
+
+type BadgeConfig struct {
+	EvalInterval time.Duration
+}
+
+type UploadConfig struct {
+	Dir         string
+	MaxFileSize int64 // bytes
+	MaxFiles    int
+}
+
 func Load() (*Config, error) {
 	// Load .env file
 	godotenv.Load()
 
 	accessExpiry, _ := time.ParseDuration(getEnv("JWT_ACCESS_EXPIRY", "15m"))
 	refreshExpiry, _ := time.ParseDuration(getEnv("JWT_REFRESH_EXPIRY", "168h"))
+	sessionCacheTTL, _ := time.ParseDuration(getEnv("JWT_SESSION_CACHE_TTL", "30s"))
+	keysReload, _ := time.ParseDuration(getEnv("JWT_KEYS_RELOAD_INTERVAL", "5m"))
+	allowHS256, _ := strconv.ParseBool(getEnv("JWT_ALLOW_HS256", "false"))
 	qrExpiry, _ := strconv.Atoi(getEnv("QR_EXPIRY_MINUTES", "10"))
+	reservationTTL, _ := time.ParseDuration(getEnv("RESERVATION_TTL", "15m"))
+	reservationSweep, _ := time.ParseDuration(getEnv("RESERVATION_SWEEP_INTERVAL", "1m"))
+	missionSweep, _ := time.ParseDuration(getEnv("MISSION_SWEEP_INTERVAL", "5m"))
+	reminderBefore, _ := time.ParseDuration(getEnv("MISSION_REMINDER_BEFORE", "24h"))
+	uploadMaxMB, _ := strconv.Atoi(getEnv("UPLOAD_MAX_FILE_MB", "10"))
+	uploadMaxFiles, _ := strconv.Atoi(getEnv("UPLOAD_MAX_FILES", "5"))
+	badgeInterval, _ := time.ParseDuration(getEnv("BADGE_EVAL_INTERVAL", "10m"))
+	inviteTTL, _ := time.ParseDuration(getEnv("ACCOUNT_INVITE_TTL", "336h"))
+	resetTTL, _ := time.ParseDuration(getEnv("ACCOUNT_RESET_TTL", "1h"))
+	verifyTTL, _ := time.ParseDuration(getEnv("ACCOUNT_VERIFY_TTL", "48h"))
+	challengeTTL, _ := time.ParseDuration(getEnv("TWO_FACTOR_CHALLENGE_TTL", "5m"))
+	stepUpTTL, _ := time.ParseDuration(getEnv("TWO_FACTOR_STEP_UP_TTL", "5m"))
+	transferThreshold, _ := strconv.ParseInt(getEnv("TWO_FACTOR_TRANSFER_THRESHOLD", "1000"), 10, 64)
+	loginMaxFailures, _ := strconv.Atoi(getEnv("LOGIN_MAX_FAILURES", "5"))
+	loginIPMaxFailures, _ := strconv.Atoi(getEnv("LOGIN_IP_MAX_FAILURES", "50"))
+	loginDelayAfter, _ := strconv.Atoi(getEnv("LOGIN_DELAY_AFTER", "3"))
+	loginBaseDelay, _ := time.ParseDuration(getEnv("LOGIN_DELAY_BASE", "2s"))
+	loginMaxDelay, _ := time.ParseDuration(getEnv("LOGIN_DELAY_MAX", "1m"))
+	loginWindow, _ := time.ParseDuration(getEnv("LOGIN_FAILURE_WINDOW", "15m"))
+	loginLockout, _ := time.ParseDuration(getEnv("LOGIN_LOCKOUT_DURATION", "15m"))
+	oidcTrustNim, _ := strconv.ParseBool(getEnv("OIDC_TRUST_NIM_CLAIM", "false"))
+	oidcProvision, _ := strconv.ParseBool(getEnv("OIDC_PROVISION_USERS", "false"))
+	oidcStateTTL, _ := time.ParseDuration(getEnv("OIDC_STATE_TTL", "10m"))
 
-	return &Config{
+	cfg := &Config{
 		App: AppConfig{
 			Name: getEnv("APP_NAME", "WalletPoint"),
 			Env:  getEnv("APP_ENV", "development"),
@@ -63,16 +199,122 @@ func Load() (*Config, error) {
 			Password: getEnv("DB_PASSWORD", ""),
 		},
 		JWT: JWTConfig{
-			AccessSecret:  getEnv("JWT_ACCESS_SECRET", "default-access-secret-key-32chars"),
-			RefreshSecret: getEnv("JWT_REFRESH_SECRET", "default-refresh-secret-key-32chars"),
-			AccessExpiry:  accessExpiry,
-			RefreshExpiry: refreshExpiry,
+			AccessSecret:       getEnv("JWT_ACCESS_SECRET", ""),
+			RefreshSecret:      getEnv("JWT_REFRESH_SECRET", ""),
+			AccessExpiry:       accessExpiry,
+			RefreshExpiry:      refreshExpiry,
+			SessionCacheTTL:    sessionCacheTTL,
+			Issuer:             getEnv("JWT_ISSUER", "walletpoint"),
+			Audience:           getEnv("JWT_AUDIENCE", "walletpoint-api"),
+			KeysDir:            getEnv("JWT_KEYS_DIR", ""),
+			AllowHS256:         allowHS256,
+			ActiveKeyID:        getEnv("JWT_ACTIVE_KID", ""),
+			KeysReloadInterval: keysReload,
 		},
 		QR: QRConfig{
 			SigningSecret: getEnv("QR_SIGNING_SECRET", "default-qr-secret"),
 			ExpiryMinutes: qrExpiry,
 		},
-	}, nil
+		Market: MarketConfig{
+			ReservationTTL:   reservationTTL,
+			ReservationSweep: reservationSweep,
+		},
+		Mission: MissionConfig{
+			SweepInterval:  missionSweep,
+			ReminderBefore: reminderBefore,
+		},
+		Upload: UploadConfig{
+			Dir:         getEnv("UPLOAD_DIR", "./storage/uploads"),
+			MaxFileSize: int64(uploadMaxMB) << 20,
+			MaxFiles:    uploadMaxFiles,
+		},
+		Badge: BadgeConfig{
+			EvalInterval: badgeInterval,
+		},
+		Account: AccountConfig{
+			InviteTTL:   inviteTTL,
+			ResetTTL:    resetTTL,
+			VerifyTTL:   verifyTTL,
+			LinkBaseURL: getEnv("ACCOUNT_LINK_BASE_URL", "http://localhost:5173"),
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "log"),
+			From:         getEnv("MAIL_FROM", "WalletPoint <no-reply@walletpoint.local>"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			Dir:          getEnv("MAIL_DIR", "./storage/mail"),
+		},
+		TwoFactor: TwoFactorConfig{
+			Issuer:            getEnv("TWO_FACTOR_ISSUER", "WalletPoint"),
+			SecretKey:         getEnv("TWO_FACTOR_SECRET_KEY", ""),
+			ChallengeTTL:      challengeTTL,
+			StepUpTTL:         stepUpTTL,
+			TransferThreshold: transferThreshold,
+		},
+		Login: LoginConfig{
+			MaxFailures:     loginMaxFailures,
+			IPMaxFailures:   loginIPMaxFailures,
+			DelayAfter:      loginDelayAfter,
+			BaseDelay:       loginBaseDelay,
+			MaxDelay:        loginMaxDelay,
+			FailureWindow:   loginWindow,
+			LockoutDuration: loginLockout,
+		},
+		OIDC: OIDCConfig{
+			IssuerURL:     getEnv("OIDC_ISSUER_URL", ""),
+			ClientID:      getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret:  getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:   getEnv("OIDC_REDIRECT_URL", ""),
+			NimClaim:      getEnv("OIDC_NIM_CLAIM", "nim"),
+			TrustNimClaim: oidcTrustNim,
+			Provision:     oidcProvision,
+			DefaultRole:   getEnv("OIDC_DEFAULT_ROLE", "mahasiswa"),
+			StateTTL:      oidcStateTTL,
+		},
+	}
+
+	if err := cfg.JWT.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.TwoFactor.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validate refuses to run with secrets anyone can look up: they would let
+// anyone mint refresh tokens, or access tokens in HS256 mode
+func (c JWTConfig) validate() error {
+	if err := checkSecret("JWT_REFRESH_SECRET", c.RefreshSecret); err != nil {
+		return err
+	}
+	if c.KeysDir != "" {
+		return nil
+	}
+	if !c.AllowHS256 {
+		return fmt.Errorf("JWT_KEYS_DIR is required; set JWT_ALLOW_HS256=true to sign access tokens with JWT_ACCESS_SECRET in development")
+	}
+	return checkSecret("JWT_ACCESS_SECRET", c.AccessSecret)
+}
+
+// validate refuses to run without a real key: with a known key, anyone who
+// reads the database could decrypt every TOTP secret
+func (c TwoFactorConfig) validate() error {
+	return checkSecret("TWO_FACTOR_SECRET_KEY", c.SecretKey)
+}
+
+func checkSecret(name, value string) error {
+	switch {
+	case value == "":
+		return fmt.Errorf("%s is required", name)
+	case publishedSecrets[value]:
+		return fmt.Errorf("%s is still the example value; generate a random one", name)
+	case len(value) < minSecretLen:
+		return fmt.Errorf("%s must be at least %d characters", name, minSecretLen)
+	}
+	return nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -1,17 +1,21 @@
 package middleware
 
 import (
+	"context"
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"walletpoint/internal/config"
 	"walletpoint/internal/shared/response"
+	"walletpoint/pkg/utils"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// Claims for JWT token
+// Claims for JWT token. Access tokens carry their session ID as the jti
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
@@ -19,6 +23,15 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// SessionID returns the session an access token was issued for
+func (c *Claims) SessionID() (uint, bool) {
+	id, err := strconv.ParseUint(c.ID, 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
 // TokenPair represents access and refresh tokens
 type TokenPair struct {
 	AccessToken  string `json:"access_token"`
@@ -29,63 +42,164 @@ type TokenPair struct {
 
 // JWTManager handles JWT operations
 type JWTManager struct {
-	config config.JWTConfig
+	config   config.JWTConfig
+	sessions *SessionCache
+	keys     *Keyring
 }
 
 func NewJWTManager(cfg config.JWTConfig) *JWTManager {
 	return &JWTManager{config: cfg}
 }
 
-// GenerateTokenPair creates access and refresh tokens
-func (m *JWTManager) GenerateTokenPair(userID uint, username, role string) (*TokenPair, error) {
+// UseSessionCache makes JWTMiddleware reject access tokens whose session was
+// revoked or whose user was deactivated
+func (m *JWTManager) UseSessionCache(sessions *SessionCache) {
+	m.sessions = sessions
+}
+
+// UseKeyring signs access tokens with the keyring's asymmetric keys instead of
+// the shared HS256 secret. Refresh tokens only ever come back to us and keep
+// using the refresh secret
+func (m *JWTManager) UseKeyring(keys *Keyring) {
+	m.keys = keys
+}
+
+// JWKS returns the public keys access tokens can be verified with
+func (m *JWTManager) JWKS() JWKSet {
+	if m.keys == nil {
+		return JWKSet{Keys: []JWK{}}
+	}
+	return m.keys.JWKS()
+}
+
+// ForgetSessions makes the next request of the user re-check their sessions;
+// call it after revoking sessions or changing the account's status
+func (m *JWTManager) ForgetSessions(userID uint) {
+	if m.sessions != nil {
+		m.sessions.ForgetUser(userID)
+	}
+}
+
+// RefreshExpiry is how long refresh tokens, and the sessions holding them, live
+func (m *JWTManager) RefreshExpiry() time.Duration {
+	return m.config.RefreshExpiry
+}
+
+// GenerateRefreshToken creates a refresh token. It comes first because the
+// session storing its hash must exist before an access token can name it
+func (m *JWTManager) GenerateRefreshToken(userID uint) (string, error) {
 	now := time.Now()
 
-	// Access Token
-	accessClaims := Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+	refreshClaims := Claims{
+		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(m.config.AccessExpiry)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.config.RefreshExpiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
-			Issuer:    "walletpoint",
+			Issuer:    m.config.Issuer,
+			// Keeps tokens issued to the same user in the same second distinct
+			ID: utils.GenerateUUID(),
 		},
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString([]byte(m.config.AccessSecret))
+	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
+	return refreshToken.SignedString([]byte(m.config.RefreshSecret))
+}
+
+// GenerateTokenPair creates an access token for sessionID and pairs it with
+// the session's refresh token
+func (m *JWTManager) GenerateTokenPair(userID uint, username, role string, sessionID uint, refreshToken string) (*TokenPair, error) {
+	pair, err := m.GenerateAccessToken(userID, username, role, sessionID)
 	if err != nil {
 		return nil, err
 	}
+	pair.RefreshToken = refreshToken
+	return pair, nil
+}
 
-	// Refresh Token
-	refreshClaims := Claims{
-		UserID: userID,
+// GenerateAccessToken creates an access token alone, for a session whose
+// refresh token the client already holds
+func (m *JWTManager) GenerateAccessToken(userID uint, username, role string, sessionID uint) (*TokenPair, error) {
+	now := time.Now()
+
+	accessClaims := Claims{
+		UserID:   userID,
+		Username: username,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(m.config.RefreshExpiry)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.config.AccessExpiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    m.config.Issuer,
+			Audience:  jwt.ClaimStrings{m.config.Audience},
+			ID:        strconv.FormatUint(uint64(sessionID), 10),
 		},
 	}
 
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte(m.config.RefreshSecret))
+	accessTokenString, err := m.signAccessToken(accessClaims)
 	if err != nil {
 		return nil, err
 	}
 
 	return &TokenPair{
-		AccessToken:  accessTokenString,
-		RefreshToken: refreshTokenString,
-		ExpiresIn:    int64(m.config.AccessExpiry.Seconds()),
-		TokenType:    "Bearer",
+		AccessToken: accessTokenString,
+		ExpiresIn:   int64(m.config.AccessExpiry.Seconds()),
+		TokenType:   "Bearer",
 	}, nil
 }
 
-// ValidateAccessToken validates access token and returns claims
-func (m *JWTManager) ValidateAccessToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+func (m *JWTManager) signAccessToken(claims Claims) (string, error) {
+	if m.keys == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(m.config.AccessSecret))
+	}
+
+	key := m.keys.signer()
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.private)
+}
+
+// accessKey finds the key an access token names in its kid header
+func (m *JWTManager) accessKey(token *jwt.Token) (interface{}, error) {
+	if m.keys == nil {
 		return []byte(m.config.AccessSecret), nil
-	})
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key := m.keys.lookup(kid)
+	if key == nil {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("key %q does not use %s", kid, token.Method.Alg())
+	}
+	return key.public, nil
+}
+
+// checkSession rejects tokens whose session, user or role is no longer valid
+func (m *JWTManager) checkSession(ctx context.Context, claims *Claims) (SessionState, error) {
+	if m.sessions == nil {
+		return SessionState{}, nil
+	}
+	sessionID, ok := claims.SessionID()
+	if !ok {
+		return SessionState{}, ErrSessionRevoked
+	}
+	return m.sessions.Check(ctx, sessionID, claims.UserID, claims.Role)
+}
+
+// ValidateAccessToken validates access token and returns claims. The
+// algorithm is pinned to the configured signing mode, so an HS256 token made
+// with some other secret is never accepted
+func (m *JWTManager) ValidateAccessToken(tokenString string) (*Claims, error) {
+	methods := []string{jwt.SigningMethodHS256.Alg()}
+	if m.keys != nil {
+		methods = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.accessKey,
+		jwt.WithValidMethods(methods),
+		jwt.WithIssuer(m.config.Issuer),
+		jwt.WithAudience(m.config.Audience),
+	)
 
 	if err != nil {
 		return nil, err
@@ -98,11 +212,15 @@ func (m *JWTManager) ValidateAccessToken(tokenString string) (*Claims, error) {
 	return nil, jwt.ErrSignatureInvalid
 }
 
-// ValidateRefreshToken validates refresh token
+// ValidateRefreshToken validates refresh token. Like access tokens, the
+// algorithm and issuer are pinned
 func (m *JWTManager) ValidateRefreshToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(m.config.RefreshSecret), nil
-	})
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(m.config.Issuer),
+	)
 
 	if err != nil {
 		return nil, err
@@ -133,11 +251,36 @@ func JWTMiddleware(jwtManager *JWTManager) fiber.Handler {
 			return response.Unauthorized(c, "Invalid or expired token")
 		}
 
+		state, err := jwtManager.checkSession(c.Context(), claims)
+		switch err {
+		case nil:
+		case ErrSessionRevoked:
+			return response.Unauthorized(c, "Session has been revoked")
+		case ErrUserInactive:
+			return response.Unauthorized(c, "Account is inactive")
+		case ErrRoleChanged:
+			return response.Unauthorized(c, "Active role has changed")
+		default:
+			return response.InternalError(c, "Failed to verify session")
+		}
+
 		// Set user info in context
+		sessionID, _ := claims.SessionID()
 		c.Locals("userID", claims.UserID)
+		c.Locals("sessionID", sessionID)
+		c.Locals("stepUpUntil", state.StepUpUntil)
 		c.Locals("username", claims.Username)
 		c.Locals("role", claims.Role)
 
 		return c.Next()
 	}
 }
+
+// JWKSHandler serves the public signing keys for services that verify our
+// access tokens
+func JWKSHandler(jwtManager *JWTManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+		return c.JSON(jwtManager.JWKS())
+	}
+}
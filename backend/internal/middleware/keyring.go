@@ -0,0 +1,250 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits is the smallest RSA key accepted for signing or verifying
+const minRSABits = 2048
+
+// signingKey is one key of the keyring. Retired keys may be kept as public
+// keys only: they still verify tokens but never sign
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+// Keyring holds the asymmetric keys access tokens are signed with. Keys are
+// PEM files named <kid>.pem in one directory; rotating means adding a file,
+// which Reload picks up without a restart
+type Keyring struct {
+	dir      string
+	activeID string
+
+	mu     sync.RWMutex
+	keys   map[string]*signingKey
+	active *signingKey
+}
+
+// LoadKeyring reads every key in dir. activeID picks the signing key; when
+// empty the last private key in kid order signs, so date-named kids rotate by
+// themselves
+func LoadKeyring(dir, activeID string) (*Keyring, error) {
+	k := &Keyring{dir: dir, activeID: activeID}
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Reload re-reads the key directory. On error the current keys stay in use
+func (k *Keyring) Reload() error {
+	paths, err := filepath.Glob(filepath.Join(k.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*signingKey, len(paths))
+	for _, path := range paths {
+		key, err := loadKey(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		if _, ok := keys[key.id]; ok {
+			return fmt.Errorf("duplicate key id %q", key.id)
+		}
+		keys[key.id] = key
+	}
+
+	active, err := pickActive(keys, k.activeID)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.active = active
+	k.mu.Unlock()
+	return nil
+}
+
+// RunReloader periodically reloads the key directory until ctx is cancelled
+func (k *Keyring) RunReloader(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Reload(); err != nil {
+				log.Printf("Failed to reload JWT keys: %v", err)
+			}
+		}
+	}
+}
+
+func (k *Keyring) signer() *signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active
+}
+
+func (k *Keyring) lookup(kid string) *signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.keys[kid]
+}
+
+// JWK is a public key in JSON Web Key form
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every key, so other services can verify
+// tokens signed with the current key and the ones still in circulation
+func (k *Keyring) JWKS() JWKSet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := JWKSet{Keys: make([]JWK, 0, len(ids))}
+	for _, id := range ids {
+		key := k.keys[id]
+		jwk := JWK{KeyID: key.id, Use: "sig", Algorithm: key.method.Alg()}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func pickActive(keys map[string]*signingKey, activeID string) (*signingKey, error) {
+	if activeID != "" {
+		key, ok := keys[activeID]
+		if !ok {
+			return nil, fmt.Errorf("active key %q not found", activeID)
+		}
+		if key.private == nil {
+			return nil, fmt.Errorf("active key %q has no private key", activeID)
+		}
+		return key, nil
+	}
+
+	var active *signingKey
+	for _, key := range keys {
+		if key.private != nil && (active == nil || key.id > active.id) {
+			active = key
+		}
+	}
+	if active == nil {
+		return nil, errors.New("no private key to sign with")
+	}
+	return active, nil
+}
+
+// loadKey parses a PEM private or public key. The kid is the file name
+// without .pem, and without .pub for public keys
+func loadKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data")
+	}
+
+	id := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".pem"), ".pub")
+	key := &signingKey{id: id}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.private, key.public = v, &v.PublicKey
+	case *rsa.PublicKey:
+		key.public = v
+	case ed25519.PrivateKey:
+		key.private, key.public = v, v.Public()
+	case ed25519.PublicKey:
+		key.public = v
+	default:
+		return nil, errors.New("only RSA and Ed25519 keys are supported")
+	}
+
+	switch pub := key.public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSABits)
+		}
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	}
+	return key, nil
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSessionRevoked = errors.New("session revoked")
+	ErrUserInactive   = errors.New("user inactive")
+	ErrRoleChanged    = errors.New("role changed")
+)
+
+// SessionState is what the middleware needs to know about the session an
+// access token was issued for
+type SessionState struct {
+	UserID     uint
+	Active     bool // the device is still signed in
+	UserActive bool
+	// Sensitive actions are allowed until then; zero when never stepped up
+	StepUpUntil time.Time
+	// Role the device acts as, and whether the user still holds it. Empty
+	// for sessions started before roles were tracked
+	Role       string
+	RoleActive bool
+}
+
+// SessionStore looks up session state; the auth repository implements it
+type SessionStore interface {
+	GetSessionState(ctx context.Context, sessionID uint) (*SessionState, error)
+}
+
+type sessionEntry struct {
+	state   SessionState
+	expires time.Time
+}
+
+// SessionCache keeps looked-up session states for a short TTL so the
+// middleware does not hit the database on every request. Services that
+// revoke sessions in this process call ForgetUser so the change applies
+// immediately; other instances see it once the TTL runs out
+type SessionCache struct {
+	store   SessionStore
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[uint]sessionEntry
+}
+
+func NewSessionCache(store SessionStore, ttl time.Duration) *SessionCache {
+	return &SessionCache{
+		store:   store,
+		ttl:     ttl,
+		entries: make(map[uint]sessionEntry),
+	}
+}
+
+// Check reports whether the session still authorizes requests for userID
+// acting as role
+func (c *SessionCache) Check(ctx context.Context, sessionID, userID uint, role string) (SessionState, error) {
+	state, err := c.get(ctx, sessionID)
+	if err != nil {
+		return state, err
+	}
+	if state.UserID != userID || !state.Active {
+		return state, ErrSessionRevoked
+	}
+	if !state.UserActive {
+		return state, ErrUserInactive
+	}
+	if state.Role != "" && (state.Role != role || !state.RoleActive) {
+		return state, ErrRoleChanged
+	}
+	return state, nil
+}
+
+func (c *SessionCache) get(ctx context.Context, sessionID uint) (SessionState, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[sessionID]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.state, nil
+	}
+
+	state, err := c.store.GetSessionState(ctx, sessionID)
+	if err != nil {
+		return SessionState{}, err
+	}
+	if state == nil {
+		// Unknown sessions are treated as revoked
+		state = &SessionState{}
+	}
+
+	if c.ttl > 0 {
+		c.mu.Lock()
+		c.entries[sessionID] = sessionEntry{state: *state, expires: now.Add(c.ttl)}
+		c.mu.Unlock()
+	}
+	return *state, nil
+}
+
+// ForgetUser drops every cached session of the user
+func (c *SessionCache) ForgetUser(userID uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, entry := range c.entries {
+		if entry.state.UserID == userID {
+			delete(c.entries, id)
+		}
+	}
+}
+
+// RunSweeper periodically drops expired entries until ctx is cancelled
+func (c *SessionCache) RunSweeper(ctx context.Context) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.sweep(time.Now())
+		}
+	}
+}
+
+func (c *SessionCache) sweep(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, id)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"time"
+
+	"walletpoint/internal/shared/response"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HasStepUp reports whether the request's session passed a step-up
+// verification recently enough to perform sensitive actions
+func HasStepUp(c *fiber.Ctx) bool {
+	until, ok := c.Locals("stepUpUntil").(time.Time)
+	return ok && time.Now().Before(until)
+}
+
+// StepUpRequired answers a request that needs a fresh step-up verification
+func StepUpRequired(c *fiber.Ctx) error {
+	return response.Error(c, fiber.StatusForbidden, "Confirm it's you with POST /auth/step-up before doing this", "STEP_UP_REQUIRED")
+}
+
+// RequireStepUp middleware guards sensitive routes with a step-up verification
+func RequireStepUp() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !HasStepUp(c) {
+			return StepUpRequired(c)
+		}
+		return c.Next()
+	}
+}
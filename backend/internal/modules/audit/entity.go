@@ -0,0 +1,23 @@
+package audit
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Log entity is one audit trail entry
+type Log struct {
+	ID             uint
+	UserID         sql.NullInt64 // who acted; empty for system or anonymous actions
+	TargetType     string
+	TargetID       uint
+	Action         string
+	ActionCategory string
+	OldValues      sql.NullString
+	NewValues      sql.NullString
+	IPAddress      sql.NullString
+	UserAgent      sql.NullString
+	Description    string
+	RiskLevel      string
+	CreatedAt      time.Time
+}
@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(ctx context.Context, l *Log) error {
+	query := `
+		INSERT INTO audit_logs (
+			user_id, target_type, target_id, action, action_category, old_values, new_values,
+			ip_address, user_agent, description, risk_level, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		l.UserID, l.TargetType, l.TargetID, l.Action, l.ActionCategory, l.OldValues, l.NewValues,
+		l.IPAddress, l.UserAgent, l.Description, l.RiskLevel,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	l.ID = uint(id)
+	return nil
+}
+
+// Values marshals v for the old_values and new_values columns
+func Values(v interface{}) sql.NullString {
+	if v == nil {
+		return sql.NullString{}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(data), Valid: true}
+}
@@ -1,9 +1,17 @@
 package auth
 
+import (
+	"strings"
+	"time"
+
+	"walletpoint/internal/modules/badge"
+)
+
 // LoginRequest for login endpoint
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Role     string `json:"role"` // optional on the unified route
 }
 
 func (r *LoginRequest) Validate() []ValidationError {
@@ -17,6 +25,41 @@ func (r *LoginRequest) Validate() []ValidationError {
 	return errors
 }
 
+// SwitchRoleRequest for switch role endpoint
+type SwitchRoleRequest struct {
+	Role string `json:"role"`
+}
+
+func (r *SwitchRoleRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Role == "" {
+		errors = append(errors, ValidationError{Field: "role", Message: "Role is required"})
+	}
+	return errors
+}
+
+// SSOStartRequest starts a single sign-on login, optionally as a given role
+type SSOStartRequest struct {
+	Role string `json:"role"`
+}
+
+// SSOCallbackRequest carries what the identity provider sent back to the app
+type SSOCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+func (r *SSOCallbackRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Code == "" {
+		errors = append(errors, ValidationError{Field: "code", Message: "Code is required"})
+	}
+	if r.State == "" {
+		errors = append(errors, ValidationError{Field: "state", Message: "State is required"})
+	}
+	return errors
+}
+
 // RefreshTokenRequest for refresh endpoint
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token"`
@@ -46,6 +89,155 @@ func (r *ChangePasswordRequest) Validate() []ValidationError {
 	return errors
 }
 
+// AcceptInvitationRequest sets the first password of an invited account
+type AcceptInvitationRequest struct {
+	Token           string `json:"token"`
+	NewPassword     string `json:"new_password"`
+	ConfirmPassword string `json:"confirm_password"`
+}
+
+func (r *AcceptInvitationRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Token == "" {
+		errors = append(errors, ValidationError{Field: "token", Message: "Token is required"})
+	}
+	if len(r.NewPassword) < 8 {
+		errors = append(errors, ValidationError{Field: "new_password", Message: "New password must be at least 8 characters"})
+	}
+	if r.NewPassword != r.ConfirmPassword {
+		errors = append(errors, ValidationError{Field: "confirm_password", Message: "Passwords do not match"})
+	}
+	return errors
+}
+
+// ForgotPasswordRequest asks for a reset link by username or email
+type ForgotPasswordRequest struct {
+	Identifier string `json:"identifier"`
+}
+
+func (r *ForgotPasswordRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	r.Identifier = strings.TrimSpace(r.Identifier)
+	if r.Identifier == "" {
+		errors = append(errors, ValidationError{Field: "identifier", Message: "Username or email is required"})
+	}
+	return errors
+}
+
+// ResetPasswordRequest sets a new password with a reset token
+type ResetPasswordRequest struct {
+	Token           string `json:"token"`
+	NewPassword     string `json:"new_password"`
+	ConfirmPassword string `json:"confirm_password"`
+}
+
+func (r *ResetPasswordRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Token == "" {
+		errors = append(errors, ValidationError{Field: "token", Message: "Token is required"})
+	}
+	if len(r.NewPassword) < 8 {
+		errors = append(errors, ValidationError{Field: "new_password", Message: "New password must be at least 8 characters"})
+	}
+	if r.NewPassword != r.ConfirmPassword {
+		errors = append(errors, ValidationError{Field: "confirm_password", Message: "Passwords do not match"})
+	}
+	return errors
+}
+
+// VerifyEmailRequest confirms an email address with a verification token
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+func (r *VerifyEmailRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Token == "" {
+		errors = append(errors, ValidationError{Field: "token", Message: "Token is required"})
+	}
+	return errors
+}
+
+// TwoFactorLoginRequest finishes a login that needs a second factor, either
+// an authenticator code or a recovery code
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+func (r *TwoFactorLoginRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.ChallengeToken == "" {
+		errors = append(errors, ValidationError{Field: "challenge_token", Message: "Challenge token is required"})
+	}
+	if r.Code == "" && r.RecoveryCode == "" {
+		errors = append(errors, ValidationError{Field: "code", Message: "Authenticator code or recovery code is required"})
+	}
+	return errors
+}
+
+// TwoFactorChallengeRequest starts enrollment during a login that requires it
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
+func (r *TwoFactorChallengeRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.ChallengeToken == "" {
+		errors = append(errors, ValidationError{Field: "challenge_token", Message: "Challenge token is required"})
+	}
+	return errors
+}
+
+// TwoFactorCodeRequest carries a code from the authenticator app
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+func (r *TwoFactorCodeRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Code == "" {
+		errors = append(errors, ValidationError{Field: "code", Message: "Authenticator code is required"})
+	}
+	return errors
+}
+
+// DisableTwoFactorRequest turns two-factor off; it needs the password and a
+// second factor
+type DisableTwoFactorRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (r *DisableTwoFactorRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Password == "" {
+		errors = append(errors, ValidationError{Field: "password", Message: "Password is required"})
+	}
+	if r.Code == "" && r.RecoveryCode == "" {
+		errors = append(errors, ValidationError{Field: "code", Message: "Authenticator code or recovery code is required"})
+	}
+	return errors
+}
+
+// StepUpRequest re-verifies the user before sensitive actions. Users with
+// two-factor give a code; others confirm their password
+type StepUpRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+	Password     string `json:"password"`
+}
+
+func (r *StepUpRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Code == "" && r.RecoveryCode == "" && r.Password == "" {
+		errors = append(errors, ValidationError{Field: "code", Message: "Authenticator code, recovery code or password is required"})
+	}
+	return errors
+}
+
 // RegisterRequest for registration
 type RegisterRequest struct {
 	Username string `json:"username"`
@@ -82,39 +274,78 @@ type ValidationError struct {
 	Message string `json:"message"`
 }
 
-// LoginResponse for login success
+// LoginResponse for login success. When a second factor is still needed it
+// carries a challenge token instead of tokens
 type LoginResponse struct {
-	AccessToken  string       `json:"access_token"`
-	RefreshToken string       `json:"refresh_token"`
-	ExpiresIn    int64        `json:"expires_in"`
-	TokenType    string       `json:"token_type"`
-	User         UserResponse `json:"user"`
+	AccessToken            string       `json:"access_token,omitempty"`
+	RefreshToken           string       `json:"refresh_token,omitempty"`
+	ExpiresIn              int64        `json:"expires_in,omitempty"`
+	TokenType              string       `json:"token_type,omitempty"`
+	TwoFactorRequired      bool         `json:"two_factor_required,omitempty"`
+	TwoFactorSetupRequired bool         `json:"two_factor_setup_required,omitempty"`
+	ChallengeToken         string       `json:"challenge_token,omitempty"`
+	RecoveryCodes          []string     `json:"recovery_codes,omitempty"`
+	User                   UserResponse `json:"user"`
+}
+
+// SSOStartResponse points the client at the identity provider
+type SSOStartResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+	ExpiresIn        int64  `json:"expires_in"`
+}
+
+// TwoFactorStatusResponse for the two-factor settings page
+type TwoFactorStatusResponse struct {
+	Enabled                bool `json:"enabled"`
+	Required               bool `json:"required"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorSetupResponse holds what the authenticator app needs to enroll
+type TwoFactorSetupResponse struct {
+	Secret        string `json:"secret"`
+	OtpauthURL    string `json:"otpauth_url"`
+	QRImageBase64 string `json:"qr_image_base64,omitempty"`
+}
+
+// RecoveryCodesResponse shows new recovery codes; they are not shown again
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// StepUpResponse tells until when sensitive actions are unlocked
+type StepUpResponse struct {
+	ExpiresAt string `json:"expires_at"`
 }
 
 // UserResponse for user data
 type UserResponse struct {
-	ID        uint    `json:"id"`
-	Username  string  `json:"username"`
-	Email     string  `json:"email"`
-	FullName  string  `json:"full_name"`
-	NimNip    *string `json:"nim_nip,omitempty"`
-	Role      string  `json:"role"`
-	AvatarURL *string `json:"avatar_url,omitempty"`
+	ID        uint     `json:"id"`
+	Username  string   `json:"username"`
+	Email     string   `json:"email"`
+	FullName  string   `json:"full_name"`
+	NimNip    *string  `json:"nim_nip,omitempty"`
+	Role      string   `json:"role"`
+	Roles     []string `json:"roles"`
+	AvatarURL *string  `json:"avatar_url,omitempty"`
 }
 
 // ProfileResponse for profile endpoint
 type ProfileResponse struct {
-	ID              uint    `json:"id"`
-	Username        string  `json:"username"`
-	Email           string  `json:"email"`
-	FullName        string  `json:"full_name"`
-	NimNip          *string `json:"nim_nip,omitempty"`
-	Phone           *string `json:"phone,omitempty"`
-	Role            string  `json:"role"`
-	AvatarURL       *string `json:"avatar_url,omitempty"`
-	EmailVerifiedAt *string `json:"email_verified_at,omitempty"`
-	LastLoginAt     *string `json:"last_login_at,omitempty"`
-	CreatedAt       string  `json:"created_at"`
+	ID              uint                       `json:"id"`
+	Username        string                     `json:"username"`
+	Email           string                     `json:"email"`
+	FullName        string                     `json:"full_name"`
+	NimNip          *string                    `json:"nim_nip,omitempty"`
+	Phone           *string                    `json:"phone,omitempty"`
+	Role            string                     `json:"role"`
+	Roles           []string                   `json:"roles"`
+	AvatarURL       *string                    `json:"avatar_url,omitempty"`
+	EmailVerifiedAt *string                    `json:"email_verified_at,omitempty"`
+	LastLoginAt     *string                    `json:"last_login_at,omitempty"`
+	CreatedAt       string                     `json:"created_at"`
+	Badges          []*badge.UserBadgeResponse `json:"badges"`
 }
 
 // ToUserResponse converts UserWithRole to UserResponse
@@ -125,6 +356,7 @@ func ToUserResponse(u *UserWithRole) UserResponse {
 		Email:    u.Email,
 		FullName: u.FullName,
 		Role:     u.RoleName,
+		Roles:    u.Roles,
 	}
 	if u.NimNip.Valid {
 		resp.NimNip = &u.NimNip.String
@@ -134,3 +366,31 @@ func ToUserResponse(u *UserWithRole) UserResponse {
 	}
 	return resp
 }
+
+// SessionResponse describes a signed-in device
+type SessionResponse struct {
+	ID             uint    `json:"id"`
+	Device         *string `json:"device,omitempty"`
+	IPAddress      *string `json:"ip_address,omitempty"`
+	SignedInAt     string  `json:"signed_in_at"`
+	LastActivityAt string  `json:"last_activity_at"`
+	ExpiresAt      string  `json:"expires_at"`
+	Current        bool    `json:"current"`
+}
+
+// ToSessionResponse converts Session to SessionResponse
+func ToSessionResponse(s *Session) *SessionResponse {
+	resp := &SessionResponse{
+		ID:             s.ID,
+		SignedInAt:     s.SignedInAt.Format(time.RFC3339),
+		LastActivityAt: s.LastActivityAt.Format(time.RFC3339),
+		ExpiresAt:      s.ExpiresAt.Format(time.RFC3339),
+	}
+	if s.DeviceInfo.Valid {
+		resp.Device = &s.DeviceInfo.String
+	}
+	if s.IPAddress.Valid {
+		resp.IPAddress = &s.IPAddress.String
+	}
+	return resp
+}
@@ -44,10 +44,12 @@ type UserRole struct {
 	ExpiresAt  sql.NullTime
 }
 
-// Session entity
+// Session entity is one refresh token. Sessions rotated from the same login
+// share a FamilyID
 type Session struct {
 	ID             uint
 	UserID         uint
+	FamilyID       string
 	TokenHash      string
 	DeviceInfo     sql.NullString
 	IPAddress      sql.NullString
@@ -57,10 +59,79 @@ type Session struct {
 	LastActivityAt time.Time
 	RevokedAt      sql.NullTime
 	RevokedReason  sql.NullString
+	ReplacedBy     sql.NullInt64
+	ActiveRole     sql.NullString // role the device acts as
+	SignedInAt     time.Time      // when the family's login happened
 }
 
-// UserWithRole combines user data with role
+// UserWithRole combines user data with roles. RoleName is the role being
+// signed in as; it starts out as the user's earliest assigned role
 type UserWithRole struct {
 	User
 	RoleName string
+	Roles    []string
+}
+
+// Session revocation reasons
+const (
+	SessionRevokeRotated = "token_refresh"
+	SessionRevokeReused  = "token_reuse"
+	SessionRevokeLogout  = "logout"
+	SessionRevokeByUser  = "revoked_by_user"
+)
+
+// Account token purposes
+const (
+	TokenPurposeInvite        = "INVITE"
+	TokenPurposePasswordReset = "PASSWORD_RESET"
+	TokenPurposeEmailVerify   = "EMAIL_VERIFY"
+	TokenPurposeTwoFactor     = "TWO_FACTOR_LOGIN"
+)
+
+// UserToken is a single-use account token; only its hash is stored
+type UserToken struct {
+	ID        uint
+	UserID    uint
+	Purpose   string
+	Role      sql.NullString // role a two-factor login signs in as
+	TokenHash string
+	CreatedBy sql.NullInt64
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+// TwoFactor is a user's TOTP enrollment. EnabledAt stays unset until the user
+// confirms a first code
+type TwoFactor struct {
+	UserID          uint
+	SecretEncrypted string
+	EnabledAt       sql.NullTime
+	LastUsedStep    sql.NullInt64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// SSOState is a started single sign-on login, waiting for the provider to
+// send the user back
+type SSOState struct {
+	ID           uint
+	StateHash    string
+	CodeVerifier string
+	Nonce        string
+	Role         sql.NullString // role the login asked to sign in as
+	ExpiresAt    time.Time
+	UsedAt       sql.NullTime
+	CreatedAt    time.Time
+}
+
+// Identity links a user to their account at an identity provider
+type Identity struct {
+	ID          uint
+	UserID      uint
+	Issuer      string
+	Subject     string
+	Email       sql.NullString
+	LinkedAt    time.Time
+	LastLoginAt sql.NullTime
 }
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"strconv"
+
 	apperrors "walletpoint/internal/shared/errors"
 	"walletpoint/internal/shared/response"
 
@@ -15,7 +17,8 @@ func NewHandler(service *Service) *Handler {
 	return &Handler{service: service}
 }
 
-// Login handles user login
+// Login handles user login. Role-specific routes pass their role; the unified
+// route takes an optional role from the body
 func (h *Handler) Login(role string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var req LoginRequest
@@ -32,11 +35,19 @@ func (h *Handler) Login(role string) fiber.Handler {
 		ipAddress := c.IP()
 		userAgent := c.Get("User-Agent")
 
+		activeRole := role
+		if activeRole == "" {
+			activeRole = req.Role
+		}
+
 		// Login
-		result, err := h.service.Login(c.Context(), req, role, ipAddress, userAgent)
+		result, err := h.service.Login(c.Context(), req, activeRole, ipAddress, userAgent)
 		if err != nil {
 			return handleError(c, err)
 		}
+		if result.TwoFactorRequired {
+			return response.Success(c, "Two-factor verification required", result)
+		}
 
 		return response.Success(c, "Login successful", result)
 	}
@@ -61,22 +72,89 @@ func (h *Handler) RefreshToken(c *fiber.Ctx) error {
 	return response.Success(c, "Token refreshed successfully", result)
 }
 
-// Logout handles user logout
+// Logout signs out the current device
 func (h *Handler) Logout(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uint)
+	sessionID := c.Locals("sessionID").(uint)
 
-	if err := h.service.Logout(c.Context(), userID); err != nil {
+	if err := h.service.Logout(c.Context(), userID, sessionID); err != nil {
 		return handleError(c, err)
 	}
 
 	return response.Success(c, "Logged out successfully", nil)
 }
 
+// SwitchRole makes the current device act as another of the user's roles
+func (h *Handler) SwitchRole(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	sessionID := c.Locals("sessionID").(uint)
+
+	var req SwitchRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.SwitchRole(c.Context(), userID, sessionID, req)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Role switched successfully", result)
+}
+
+// GetSessions lists the current user's signed-in devices
+func (h *Handler) GetSessions(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	sessionID := c.Locals("sessionID").(uint)
+
+	sessions, err := h.service.GetSessions(c.Context(), userID, sessionID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Sessions retrieved successfully", sessions)
+}
+
+// RevokeSession signs out one device
+func (h *Handler) RevokeSession(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid session ID")
+	}
+
+	if err := h.service.RevokeSession(c.Context(), userID, uint(id)); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Session revoked successfully", nil)
+}
+
+// RevokeOtherSessions signs out every device except the caller's
+func (h *Handler) RevokeOtherSessions(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	sessionID := c.Locals("sessionID").(uint)
+
+	revoked, err := h.service.RevokeOtherSessions(c.Context(), userID, sessionID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Other sessions revoked successfully", fiber.Map{"revoked": revoked})
+}
+
 // GetProfile returns current user profile
 func (h *Handler) GetProfile(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uint)
 
-	profile, err := h.service.GetProfile(c.Context(), userID)
+	role, _ := c.Locals("role").(string)
+
+	profile, err := h.service.GetProfile(c.Context(), userID, role)
 	if err != nil {
 		return handleError(c, err)
 	}
@@ -105,6 +183,89 @@ func (h *Handler) ChangePassword(c *fiber.Ctx) error {
 	return response.Success(c, "Password changed successfully", nil)
 }
 
+// AcceptInvitation sets the password of an invited account
+func (h *Handler) AcceptInvitation(c *fiber.Ctx) error {
+	var req AcceptInvitationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	if err := h.service.AcceptInvitation(c.Context(), req); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Password set successfully, you can now log in", nil)
+}
+
+// ForgotPassword mails a reset link; the response never says whether the account exists
+func (h *Handler) ForgotPassword(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	if err := h.service.ForgotPassword(c.Context(), req); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "If the account exists, a reset link has been sent to its email address", nil)
+}
+
+// ResetPassword sets a new password with a reset token
+func (h *Handler) ResetPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	if err := h.service.ResetPassword(c.Context(), req); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Password reset successfully, please log in again", nil)
+}
+
+// SendVerificationEmail mails a verification link to the caller
+func (h *Handler) SendVerificationEmail(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	if err := h.service.SendVerificationEmail(c.Context(), userID); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Verification email sent", nil)
+}
+
+// VerifyEmail confirms an email address
+func (h *Handler) VerifyEmail(c *fiber.Ctx) error {
+	var req VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	if err := h.service.VerifyEmail(c.Context(), req); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Email verified", nil)
+}
+
 // Register handles user registration (admin only)
 func (h *Handler) Register(c *fiber.Ctx) error {
 	var req RegisterRequest
@@ -125,17 +286,201 @@ func (h *Handler) Register(c *fiber.Ctx) error {
 	return response.Created(c, "User registered successfully", result)
 }
 
+// CompleteTwoFactorLogin exchanges a login challenge and a second factor for tokens
+func (h *Handler) CompleteTwoFactorLogin(c *fiber.Ctx) error {
+	var req TwoFactorLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.CompleteTwoFactorLogin(c.Context(), req, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Login successful", result)
+}
+
+// StartSSO returns the identity provider URL to sign in at
+func (h *Handler) StartSSO(c *fiber.Ctx) error {
+	req := SSOStartRequest{Role: c.Query("role")}
+
+	result, err := h.service.StartSSO(c.Context(), req)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Sign-in started", result)
+}
+
+// CompleteSSO signs in with the code the identity provider sent back
+func (h *Handler) CompleteSSO(c *fiber.Ctx) error {
+	var req SSOCallbackRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.CompleteSSO(c.Context(), req, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		return handleError(c, err)
+	}
+	if result.TwoFactorRequired {
+		return response.Success(c, "Two-factor verification required", result)
+	}
+
+	return response.Success(c, "Login successful", result)
+}
+
+// SetupTwoFactorLogin starts the mandatory enrollment of a pending login
+func (h *Handler) SetupTwoFactorLogin(c *fiber.Ctx) error {
+	var req TwoFactorChallengeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.SetupTwoFactorLogin(c.Context(), req)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Scan the QR code with your authenticator app", result)
+}
+
+// GetTwoFactorStatus returns the current user's two-factor settings
+func (h *Handler) GetTwoFactorStatus(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	status, err := h.service.GetTwoFactorStatus(c.Context(), userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Two-factor status retrieved successfully", status)
+}
+
+// SetupTwoFactor starts two-factor enrollment
+func (h *Handler) SetupTwoFactor(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	result, err := h.service.SetupTwoFactor(c.Context(), userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Scan the QR code with your authenticator app", result)
+}
+
+// EnableTwoFactor confirms enrollment with a first code
+func (h *Handler) EnableTwoFactor(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.EnableTwoFactor(c.Context(), userID, req)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Two-factor authentication enabled", result)
+}
+
+// DisableTwoFactor turns two-factor off
+func (h *Handler) DisableTwoFactor(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req DisableTwoFactorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	if err := h.service.DisableTwoFactor(c.Context(), userID, req); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Two-factor authentication disabled", nil)
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes
+func (h *Handler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.RegenerateRecoveryCodes(c.Context(), userID, req)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Recovery codes regenerated", result)
+}
+
+// StepUp re-verifies the user before sensitive actions
+func (h *Handler) StepUp(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	sessionID := c.Locals("sessionID").(uint)
+
+	var req StepUpRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.StepUp(c.Context(), userID, sessionID, req, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Verification successful", result)
+}
+
 func handleError(c *fiber.Ctx, err error) error {
 	if appErr, ok := err.(*apperrors.AppError); ok {
 		switch appErr.Code {
-		case "INVALID_CREDENTIALS", "INVALID_TOKEN", "SESSION_NOT_FOUND":
+		case "INVALID_CREDENTIALS", "INVALID_TOKEN", "SESSION_NOT_FOUND", "TOKEN_REUSED", "SSO_FAILED":
 			return response.Unauthorized(c, appErr.Message)
-		case "NOT_FOUND", "USER_NOT_FOUND":
+		case "NOT_FOUND", "USER_NOT_FOUND", "SSO_DISABLED":
 			return response.NotFound(c, appErr.Message)
-		case "USERNAME_EXISTS", "ACCOUNT_INACTIVE":
+		case "USERNAME_EXISTS", "ACCOUNT_INACTIVE", "EMAIL_ALREADY_VERIFIED", "TWO_FACTOR_ENABLED", "TWO_FACTOR_NOT_SETUP", "ACCOUNT_EXISTS":
 			return response.Conflict(c, appErr.Message)
-		case "INVALID_PASSWORD", "VALIDATION_ERROR", "INVALID_ROLE":
+		case "TWO_FACTOR_REQUIRED", "ROLE_NOT_ASSIGNED", "SSO_NO_ACCOUNT":
+			return response.Forbidden(c, appErr.Message)
+		case "INVALID_PASSWORD", "INVALID_CODE", "VALIDATION_ERROR", "INVALID_ROLE":
 			return response.BadRequest(c, appErr.Message)
+		case "LOGIN_LOCKED", "LOGIN_THROTTLED":
+			return response.Error(c, fiber.StatusTooManyRequests, appErr.Message, appErr.Code)
 		default:
 			return response.InternalError(c, appErr.Message)
 		}
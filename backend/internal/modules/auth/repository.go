@@ -3,20 +3,52 @@ package auth
 import (
 	"context"
 	"database/sql"
+	"strings"
+	"time"
+
+	"walletpoint/internal/middleware"
 )
 
 type RepositoryInterface interface {
 	GetUserByUsername(ctx context.Context, username string) (*UserWithRole, error)
 	GetUserByID(ctx context.Context, id uint) (*UserWithRole, error)
+	GetUserByEmail(ctx context.Context, email string) (*UserWithRole, error)
 	GetRoleByName(ctx context.Context, name string) (*Role, error)
-	CreateUser(ctx context.Context, user *User) error
-	AssignRole(ctx context.Context, userID, roleID uint) error
-	UpdatePassword(ctx context.Context, userID uint, passwordHash string) error
+	GetUserByNimNip(ctx context.Context, nimNip string) (*UserWithRole, error)
+	UsernameTaken(ctx context.Context, username string) (bool, error)
+	CreateUser(ctx context.Context, ex execer, user *User) error
+	AssignRole(ctx context.Context, ex execer, userID, roleID uint) error
+	UpdatePassword(ctx context.Context, ex execer, userID uint, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, userID uint) error
 	UpdateLastLogin(ctx context.Context, userID uint) error
 	CreateSession(ctx context.Context, session *Session) error
 	GetSessionByToken(ctx context.Context, tokenHash string) (*Session, error)
+	GetSessionByID(ctx context.Context, id uint) (*Session, error)
+	GetActiveSessions(ctx context.Context, userID uint) ([]*Session, error)
+	GetSessionState(ctx context.Context, sessionID uint) (*middleware.SessionState, error)
 	RevokeSession(ctx context.Context, sessionID uint, reason string) error
-	RevokeAllUserSessions(ctx context.Context, userID uint, reason string) error
+	RotateSession(ctx context.Context, sessionID, replacedBy uint) (bool, error)
+	RevokeFamily(ctx context.Context, familyID, reason string) error
+	RevokeOtherSessions(ctx context.Context, userID uint, keepFamilyID, reason string) (int64, error)
+	RevokeAllUserSessions(ctx context.Context, ex execer, userID uint, reason string) error
+	CreateUserToken(ctx context.Context, ex execer, t *UserToken) error
+	GetUserToken(ctx context.Context, tokenHash, purpose string) (*UserToken, error)
+	UseUserToken(ctx context.Context, id uint) (bool, error)
+	ExpireUserTokens(ctx context.Context, userID uint, purpose string) error
+	SetStepUp(ctx context.Context, familyID string, until time.Time) error
+	SetActiveRole(ctx context.Context, familyID, role string) error
+	GetTwoFactor(ctx context.Context, userID uint) (*TwoFactor, error)
+	SaveTwoFactorSecret(ctx context.Context, userID uint, secretEncrypted string) error
+	EnableTwoFactor(ctx context.Context, ex execer, userID uint) error
+	DeleteTwoFactor(ctx context.Context, tx *sql.Tx, userID uint) error
+	UseTwoFactorStep(ctx context.Context, userID uint, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID uint, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID uint) (int, error)
+	CreateSSOState(ctx context.Context, state *SSOState) error
+	UseSSOState(ctx context.Context, stateHash string) (*SSOState, error)
+	GetIdentity(ctx context.Context, issuer, subject string) (*Identity, error)
+	LinkIdentity(ctx context.Context, ex execer, identity *Identity) error
 }
 
 type Repository struct {
@@ -27,25 +59,32 @@ func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) GetUserByUsername(ctx context.Context, username string) (*UserWithRole, error) {
-	query := `
-		SELECT u.id, u.username, u.email, u.password_hash, u.full_name, 
-			   u.nim_nip, u.phone, u.avatar_url, u.is_active, 
-			   u.email_verified_at, u.last_login_at, u.created_at, u.updated_at,
-			   ro.name as role_name
-		FROM users u
-		INNER JOIN user_roles ur ON u.id = ur.user_id
-		INNER JOIN roles ro ON ur.role_id = ro.id
-		WHERE u.username = ? AND u.deleted_at IS NULL
-		LIMIT 1
+// userWithRoleQuery loads a user with their unexpired roles, earliest
+// assigned first; the first one is the role logins default to
+const userWithRoleQuery = `
+	SELECT u.id, u.username, u.email, u.password_hash, u.full_name,
+		   u.nim_nip, u.phone, u.avatar_url, u.is_active,
+		   u.email_verified_at, u.last_login_at, u.created_at, u.updated_at,
+		   GROUP_CONCAT(ro.name ORDER BY ur.assigned_at ASC, ur.id ASC) as role_names
+	FROM users u
+	INNER JOIN user_roles ur ON u.id = ur.user_id
+	INNER JOIN roles ro ON ur.role_id = ro.id
+`
+
+func (r *Repository) getUserWithRole(ctx context.Context, where string, arg interface{}) (*UserWithRole, error) {
+	query := userWithRoleQuery + `
+		WHERE ` + where + ` AND u.deleted_at IS NULL
+			AND (ur.expires_at IS NULL OR ur.expires_at > NOW())
+		GROUP BY u.id
 	`
 
 	var user UserWithRole
-	err := r.db.QueryRowContext(ctx, query, username).Scan(
+	var roleNames string
+	err := r.db.QueryRowContext(ctx, query, arg).Scan(
 		&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.FullName,
 		&user.NimNip, &user.Phone, &user.AvatarURL, &user.IsActive,
 		&user.EmailVerifiedAt, &user.LastLoginAt, &user.CreatedAt, &user.UpdatedAt,
-		&user.RoleName,
+		&roleNames,
 	)
 
 	if err == sql.ErrNoRows {
@@ -55,38 +94,32 @@ func (r *Repository) GetUserByUsername(ctx context.Context, username string) (*U
 		return nil, err
 	}
 
+	user.Roles = strings.Split(roleNames, ",")
+	user.RoleName = user.Roles[0]
 	return &user, nil
 }
 
+func (r *Repository) GetUserByUsername(ctx context.Context, username string) (*UserWithRole, error) {
+	return r.getUserWithRole(ctx, "u.username = ?", username)
+}
+
 func (r *Repository) GetUserByID(ctx context.Context, id uint) (*UserWithRole, error) {
-	query := `
-		SELECT u.id, u.username, u.email, u.password_hash, u.full_name, 
-			   u.nim_nip, u.phone, u.avatar_url, u.is_active, 
-			   u.email_verified_at, u.last_login_at, u.created_at, u.updated_at,
-			   ro.name as role_name
-		FROM users u
-		INNER JOIN user_roles ur ON u.id = ur.user_id
-		INNER JOIN roles ro ON ur.role_id = ro.id
-		WHERE u.id = ? AND u.deleted_at IS NULL
-		LIMIT 1
-	`
+	return r.getUserWithRole(ctx, "u.id = ?", id)
+}
 
-	var user UserWithRole
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.FullName,
-		&user.NimNip, &user.Phone, &user.AvatarURL, &user.IsActive,
-		&user.EmailVerifiedAt, &user.LastLoginAt, &user.CreatedAt, &user.UpdatedAt,
-		&user.RoleName,
-	)
+func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*UserWithRole, error) {
+	return r.getUserWithRole(ctx, "u.email = ?", email)
+}
 
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
-	}
+func (r *Repository) GetUserByNimNip(ctx context.Context, nimNip string) (*UserWithRole, error) {
+	return r.getUserWithRole(ctx, "u.nim_nip = ?", nimNip)
+}
 
-	return &user, nil
+// UsernameTaken also counts deleted users, whose usernames stay reserved
+func (r *Repository) UsernameTaken(ctx context.Context, username string) (bool, error) {
+	var taken bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE username = ?)`, username).Scan(&taken)
+	return taken, err
 }
 
 func (r *Repository) GetRoleByName(ctx context.Context, name string) (*Role, error) {
@@ -107,13 +140,13 @@ func (r *Repository) GetRoleByName(ctx context.Context, name string) (*Role, err
 	return &role, nil
 }
 
-func (r *Repository) CreateUser(ctx context.Context, user *User) error {
+func (r *Repository) CreateUser(ctx context.Context, ex execer, user *User) error {
 	query := `
 		INSERT INTO users (username, email, password_hash, full_name, nim_nip, phone, is_active, created_at, updated_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
 	`
 
-	result, err := r.db.ExecContext(ctx, query,
+	result, err := ex.ExecContext(ctx, query,
 		user.Username, user.Email, user.PasswordHash, user.FullName,
 		user.NimNip, user.Phone, user.IsActive,
 	)
@@ -130,15 +163,21 @@ func (r *Repository) CreateUser(ctx context.Context, user *User) error {
 	return nil
 }
 
-func (r *Repository) AssignRole(ctx context.Context, userID, roleID uint) error {
+func (r *Repository) AssignRole(ctx context.Context, ex execer, userID, roleID uint) error {
 	query := `INSERT INTO user_roles (user_id, role_id, assigned_at) VALUES (?, ?, NOW())`
-	_, err := r.db.ExecContext(ctx, query, userID, roleID)
+	_, err := ex.ExecContext(ctx, query, userID, roleID)
 	return err
 }
 
-func (r *Repository) UpdatePassword(ctx context.Context, userID uint, passwordHash string) error {
+func (r *Repository) UpdatePassword(ctx context.Context, ex execer, userID uint, passwordHash string) error {
 	query := `UPDATE users SET password_hash = ?, updated_at = NOW() WHERE id = ?`
-	_, err := r.db.ExecContext(ctx, query, passwordHash, userID)
+	_, err := ex.ExecContext(ctx, query, passwordHash, userID)
+	return err
+}
+
+func (r *Repository) MarkEmailVerified(ctx context.Context, userID uint) error {
+	query := `UPDATE users SET email_verified_at = NOW(), updated_at = NOW() WHERE id = ? AND email_verified_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, userID)
 	return err
 }
 
@@ -150,13 +189,13 @@ func (r *Repository) UpdateLastLogin(ctx context.Context, userID uint) error {
 
 func (r *Repository) CreateSession(ctx context.Context, session *Session) error {
 	query := `
-		INSERT INTO sessions (user_id, token_hash, device_info, ip_address, is_active, created_at, expires_at, last_activity_at)
-		VALUES (?, ?, ?, ?, ?, NOW(), ?, NOW())
+		INSERT INTO sessions (user_id, family_id, token_hash, device_info, ip_address, active_role, is_active, created_at, expires_at, last_activity_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NOW(), ?, NOW())
 	`
 
 	result, err := r.db.ExecContext(ctx, query,
-		session.UserID, session.TokenHash, session.DeviceInfo, session.IPAddress,
-		session.IsActive, session.ExpiresAt,
+		session.UserID, session.FamilyID, session.TokenHash, session.DeviceInfo, session.IPAddress,
+		session.ActiveRole, session.IsActive, session.ExpiresAt,
 	)
 	if err != nil {
 		return err
@@ -171,19 +210,24 @@ func (r *Repository) CreateSession(ctx context.Context, session *Session) error
 	return nil
 }
 
-func (r *Repository) GetSessionByToken(ctx context.Context, tokenHash string) (*Session, error) {
-	query := `
-		SELECT id, user_id, token_hash, device_info, ip_address, is_active, 
-			   created_at, expires_at, last_activity_at, revoked_at, revoked_reason
-		FROM sessions
-		WHERE token_hash = ? AND is_active = TRUE AND expires_at > NOW()
-	`
+const sessionColumns = `
+	s.id, s.user_id, s.family_id, s.token_hash, s.device_info, s.ip_address, s.is_active,
+	s.created_at, s.expires_at, s.last_activity_at, s.revoked_at, s.revoked_reason, s.replaced_by,
+	s.active_role,
+	(SELECT MIN(f.created_at) FROM sessions f WHERE f.family_id = s.family_id)
+`
 
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSession(row rowScanner) (*Session, error) {
 	var session Session
-	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
-		&session.ID, &session.UserID, &session.TokenHash, &session.DeviceInfo,
+	err := row.Scan(
+		&session.ID, &session.UserID, &session.FamilyID, &session.TokenHash, &session.DeviceInfo,
 		&session.IPAddress, &session.IsActive, &session.CreatedAt, &session.ExpiresAt,
-		&session.LastActivityAt, &session.RevokedAt, &session.RevokedReason,
+		&session.LastActivityAt, &session.RevokedAt, &session.RevokedReason, &session.ReplacedBy,
+		&session.ActiveRole, &session.SignedInAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -196,14 +240,392 @@ func (r *Repository) GetSessionByToken(ctx context.Context, tokenHash string) (*
 	return &session, nil
 }
 
+// GetSessionByToken finds an unexpired session by refresh token hash, whether
+// or not it is still active, so a replayed rotated token can be recognised
+func (r *Repository) GetSessionByToken(ctx context.Context, tokenHash string) (*Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions s WHERE s.token_hash = ? AND s.expires_at > NOW()`
+	return scanSession(r.db.QueryRowContext(ctx, query, tokenHash))
+}
+
+func (r *Repository) GetSessionByID(ctx context.Context, id uint) (*Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions s WHERE s.id = ?`
+	return scanSession(r.db.QueryRowContext(ctx, query, id))
+}
+
+// GetSessionState reports whether an access token issued for sessionID is
+// still good. Rotating a refresh token retires the session row, so it is the
+// family that has to stay signed in
+func (r *Repository) GetSessionState(ctx context.Context, sessionID uint) (*middleware.SessionState, error) {
+	query := `
+		SELECT s.user_id,
+			EXISTS (
+				SELECT 1 FROM sessions f
+				WHERE f.family_id = s.family_id AND f.is_active = TRUE AND f.expires_at > NOW()
+			),
+			u.is_active AND u.deleted_at IS NULL,
+			(SELECT MAX(f.step_up_until) FROM sessions f WHERE f.family_id = s.family_id),
+			s.active_role,
+			EXISTS (
+				SELECT 1 FROM user_roles ur
+				INNER JOIN roles ro ON ur.role_id = ro.id
+				WHERE ur.user_id = s.user_id AND ro.name = s.active_role
+					AND (ur.expires_at IS NULL OR ur.expires_at > NOW())
+			)
+		FROM sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.id = ?
+	`
+	state := &middleware.SessionState{}
+	var stepUpUntil sql.NullTime
+	var role sql.NullString
+	err := r.db.QueryRowContext(ctx, query, sessionID).Scan(
+		&state.UserID, &state.Active, &state.UserActive, &stepUpUntil, &role, &state.RoleActive,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state.StepUpUntil = stepUpUntil.Time
+	state.Role = role.String
+	return state, nil
+}
+
+// GetActiveSessions lists a user's signed-in devices, most recently used first
+func (r *Repository) GetActiveSessions(ctx context.Context, userID uint) ([]*Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions s
+		WHERE s.user_id = ? AND s.is_active = TRUE AND s.expires_at > NOW()
+		ORDER BY s.last_activity_at DESC, s.id DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
 func (r *Repository) RevokeSession(ctx context.Context, sessionID uint, reason string) error {
 	query := `UPDATE sessions SET is_active = FALSE, revoked_at = NOW(), revoked_reason = ? WHERE id = ?`
 	_, err := r.db.ExecContext(ctx, query, reason, sessionID)
 	return err
 }
 
-func (r *Repository) RevokeAllUserSessions(ctx context.Context, userID uint, reason string) error {
+// RotateSession retires a session in favour of its successor; it reports false
+// when the session was no longer active, meaning its token was already used
+func (r *Repository) RotateSession(ctx context.Context, sessionID, replacedBy uint) (bool, error) {
+	query := `
+		UPDATE sessions
+		SET is_active = FALSE, revoked_at = NOW(), revoked_reason = ?, replaced_by = ?
+		WHERE id = ? AND is_active = TRUE
+	`
+	result, err := r.db.ExecContext(ctx, query, SessionRevokeRotated, replacedBy, sessionID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// RevokeFamily signs out the device a family belongs to
+func (r *Repository) RevokeFamily(ctx context.Context, familyID, reason string) error {
+	query := `UPDATE sessions SET is_active = FALSE, revoked_at = NOW(), revoked_reason = ? WHERE family_id = ? AND is_active = TRUE`
+	_, err := r.db.ExecContext(ctx, query, reason, familyID)
+	return err
+}
+
+// RevokeOtherSessions signs the user out everywhere except the given family
+func (r *Repository) RevokeOtherSessions(ctx context.Context, userID uint, keepFamilyID, reason string) (int64, error) {
+	query := `
+		UPDATE sessions SET is_active = FALSE, revoked_at = NOW(), revoked_reason = ?
+		WHERE user_id = ? AND family_id <> ? AND is_active = TRUE
+	`
+	result, err := r.db.ExecContext(ctx, query, reason, userID, keepFamilyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RevokeAllUserSessions signs the user out everywhere, inside a transaction when ex is one
+func (r *Repository) RevokeAllUserSessions(ctx context.Context, ex execer, userID uint, reason string) error {
 	query := `UPDATE sessions SET is_active = FALSE, revoked_at = NOW(), revoked_reason = ? WHERE user_id = ? AND is_active = TRUE`
-	_, err := r.db.ExecContext(ctx, query, reason, userID)
+	_, err := ex.ExecContext(ctx, query, reason, userID)
+	return err
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// CreateUserToken stores a new account token, inside a transaction when ex is one
+func (r *Repository) CreateUserToken(ctx context.Context, ex execer, t *UserToken) error {
+	query := `
+		INSERT INTO user_tokens (user_id, purpose, role, token_hash, created_by, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW())
+	`
+
+	result, err := ex.ExecContext(ctx, query, t.UserID, t.Purpose, t.Role, t.TokenHash, t.CreatedBy, t.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	t.ID = uint(id)
+	return nil
+}
+
+// GetUserToken returns an unused, unexpired token of the given purpose
+func (r *Repository) GetUserToken(ctx context.Context, tokenHash, purpose string) (*UserToken, error) {
+	query := `
+		SELECT id, user_id, purpose, role, token_hash, created_by, expires_at, used_at, created_at
+		FROM user_tokens
+		WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > NOW()
+	`
+
+	var t UserToken
+	err := r.db.QueryRowContext(ctx, query, tokenHash, purpose).Scan(
+		&t.ID, &t.UserID, &t.Purpose, &t.Role, &t.TokenHash, &t.CreatedBy, &t.ExpiresAt, &t.UsedAt, &t.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// UseUserToken marks a token used; it reports false when another request
+// already used it, so each token works exactly once
+func (r *Repository) UseUserToken(ctx context.Context, id uint) (bool, error) {
+	query := `UPDATE user_tokens SET used_at = NOW() WHERE id = ? AND used_at IS NULL AND expires_at > NOW()`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ExpireUserTokens invalidates every outstanding token of a purpose for a user
+func (r *Repository) ExpireUserTokens(ctx context.Context, userID uint, purpose string) error {
+	query := `UPDATE user_tokens SET expires_at = NOW() WHERE user_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > NOW()`
+	_, err := r.db.ExecContext(ctx, query, userID, purpose)
+	return err
+}
+
+// SetStepUp unlocks sensitive actions for a session family until the given time
+func (r *Repository) SetStepUp(ctx context.Context, familyID string, until time.Time) error {
+	query := `UPDATE sessions SET step_up_until = ? WHERE family_id = ? AND is_active = TRUE`
+	_, err := r.db.ExecContext(ctx, query, until, familyID)
+	return err
+}
+
+// SetActiveRole switches the role a session family acts as. Rotated rows are
+// updated too, since access tokens issued for them are still in use
+func (r *Repository) SetActiveRole(ctx context.Context, familyID, role string) error {
+	query := `UPDATE sessions SET active_role = ? WHERE family_id = ?`
+	_, err := r.db.ExecContext(ctx, query, role, familyID)
+	return err
+}
+
+func (r *Repository) GetTwoFactor(ctx context.Context, userID uint) (*TwoFactor, error) {
+	query := `
+		SELECT user_id, secret_encrypted, enabled_at, last_used_step, created_at, updated_at
+		FROM user_two_factor WHERE user_id = ?
+	`
+
+	var t TwoFactor
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&t.UserID, &t.SecretEncrypted, &t.EnabledAt, &t.LastUsedStep, &t.CreatedAt, &t.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SaveTwoFactorSecret starts (or restarts) an enrollment with a new secret
+func (r *Repository) SaveTwoFactorSecret(ctx context.Context, userID uint, secretEncrypted string) error {
+	query := `
+		INSERT INTO user_two_factor (user_id, secret_encrypted, created_at, updated_at)
+		VALUES (?, ?, NOW(), NOW())
+		ON DUPLICATE KEY UPDATE secret_encrypted = VALUES(secret_encrypted), enabled_at = NULL, last_used_step = NULL
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, secretEncrypted)
+	return err
+}
+
+func (r *Repository) EnableTwoFactor(ctx context.Context, ex execer, userID uint) error {
+	query := `UPDATE user_two_factor SET enabled_at = NOW() WHERE user_id = ?`
+	_, err := ex.ExecContext(ctx, query, userID)
+	return err
+}
+
+func (r *Repository) DeleteTwoFactor(ctx context.Context, tx *sql.Tx, userID uint) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM two_factor_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM user_two_factor WHERE user_id = ?`, userID)
+	return err
+}
+
+// UseTwoFactorStep records an accepted code's time step. It reports false when
+// that step or a later one was already used, so a code cannot be replayed
+func (r *Repository) UseTwoFactorStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	query := `
+		UPDATE user_two_factor SET last_used_step = ?
+		WHERE user_id = ? AND (last_used_step IS NULL OR last_used_step < ?)
+	`
+	result, err := r.db.ExecContext(ctx, query, step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ReplaceRecoveryCodes discards the user's recovery codes in favour of new ones
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID uint, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM two_factor_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	query := `INSERT INTO two_factor_recovery_codes (user_id, code_hash, created_at) VALUES (?, ?, NOW())`
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, query, userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode spends a recovery code; false means no such unused code
+func (r *Repository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	query := `
+		UPDATE two_factor_recovery_codes SET used_at = NOW()
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *Repository) CountRecoveryCodes(ctx context.Context, userID uint) (int, error) {
+	query := `SELECT COUNT(*) FROM two_factor_recovery_codes WHERE user_id = ? AND used_at IS NULL`
+	var count int
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&count)
+	return count, err
+}
+
+func (r *Repository) CreateSSOState(ctx context.Context, state *SSOState) error {
+	query := `
+		INSERT INTO sso_states (state_hash, code_verifier, nonce, role, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, NOW())
+	`
+
+	result, err := r.db.ExecContext(ctx, query, state.StateHash, state.CodeVerifier, state.Nonce, state.Role, state.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	state.ID = uint(id)
+	return nil
+}
+
+// UseSSOState uses up an unexpired state. It returns nil when the state is
+// unknown, expired or was already used, so each state completes one login
+func (r *Repository) UseSSOState(ctx context.Context, stateHash string) (*SSOState, error) {
+	query := `
+		SELECT id, state_hash, code_verifier, nonce, role, expires_at, used_at, created_at
+		FROM sso_states
+		WHERE state_hash = ? AND used_at IS NULL AND expires_at > NOW()
+	`
+
+	var st SSOState
+	err := r.db.QueryRowContext(ctx, query, stateHash).Scan(
+		&st.ID, &st.StateHash, &st.CodeVerifier, &st.Nonce, &st.Role, &st.ExpiresAt, &st.UsedAt, &st.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.db.ExecContext(ctx, `UPDATE sso_states SET used_at = NOW() WHERE id = ? AND used_at IS NULL`, st.ID)
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func (r *Repository) GetIdentity(ctx context.Context, issuer, subject string) (*Identity, error) {
+	query := `
+		SELECT id, user_id, issuer, subject, email, linked_at, last_login_at
+		FROM user_identities
+		WHERE issuer = ? AND subject = ?
+	`
+
+	var i Identity
+	err := r.db.QueryRowContext(ctx, query, issuer, subject).Scan(
+		&i.ID, &i.UserID, &i.Issuer, &i.Subject, &i.Email, &i.LinkedAt, &i.LastLoginAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &i, nil
+}
+
+// LinkIdentity records a login through an identity provider, pointing the
+// identity at identity.UserID
+func (r *Repository) LinkIdentity(ctx context.Context, ex execer, identity *Identity) error {
+	query := `
+		INSERT INTO user_identities (user_id, issuer, subject, email, linked_at, last_login_at)
+		VALUES (?, ?, ?, ?, NOW(), NOW())
+		ON DUPLICATE KEY UPDATE
+			linked_at = IF(user_id = VALUES(user_id), linked_at, NOW()),
+			user_id = VALUES(user_id),
+			email = VALUES(email),
+			last_login_at = NOW()
+	`
+	_, err := ex.ExecContext(ctx, query, identity.UserID, identity.Issuer, identity.Subject, identity.Email)
 	return err
 }
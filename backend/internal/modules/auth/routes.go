@@ -10,16 +10,36 @@ func RegisterRoutes(app fiber.Router, handler *Handler, jwtManager *middleware.J
 	auth := app.Group("/auth")
 
 	// Public routes with rate limiting
+	auth.Post("/login", middleware.AuthRateLimiter(), handler.Login(""))
 	auth.Post("/admin/login", middleware.AuthRateLimiter(), handler.Login("admin"))
 	auth.Post("/dosen/login", middleware.AuthRateLimiter(), handler.Login("dosen"))
 	auth.Post("/mahasiswa/login", middleware.AuthRateLimiter(), handler.Login("mahasiswa"))
 	auth.Post("/refresh", middleware.AuthRateLimiter(), handler.RefreshToken)
+	auth.Post("/invitations/accept", middleware.AuthRateLimiter(), handler.AcceptInvitation)
+	auth.Post("/password/forgot", middleware.AuthRateLimiter(), handler.ForgotPassword)
+	auth.Post("/password/reset", middleware.AuthRateLimiter(), handler.ResetPassword)
+	auth.Post("/email/verify", middleware.AuthRateLimiter(), handler.VerifyEmail)
+	auth.Post("/2fa/login", middleware.AuthRateLimiter(), handler.CompleteTwoFactorLogin)
+	auth.Post("/2fa/login/setup", middleware.AuthRateLimiter(), handler.SetupTwoFactorLogin)
+	auth.Get("/sso/authorize", middleware.AuthRateLimiter(), handler.StartSSO)
+	auth.Post("/sso/callback", middleware.AuthRateLimiter(), handler.CompleteSSO)
 
 	// Protected routes
 	protected := auth.Group("", middleware.JWTMiddleware(jwtManager))
 	protected.Post("/logout", handler.Logout)
+	protected.Post("/switch-role", handler.SwitchRole)
+	protected.Get("/sessions", handler.GetSessions)
+	protected.Post("/sessions/revoke-others", handler.RevokeOtherSessions)
+	protected.Delete("/sessions/:id", handler.RevokeSession)
 	protected.Get("/me", handler.GetProfile)
 	protected.Put("/password", handler.ChangePassword)
+	protected.Post("/email/verification", handler.SendVerificationEmail)
+	protected.Get("/2fa", handler.GetTwoFactorStatus)
+	protected.Post("/2fa/setup", handler.SetupTwoFactor)
+	protected.Post("/2fa/enable", middleware.AuthRateLimiter(), handler.EnableTwoFactor)
+	protected.Post("/2fa/disable", middleware.AuthRateLimiter(), handler.DisableTwoFactor)
+	protected.Post("/2fa/recovery-codes", middleware.AuthRateLimiter(), handler.RegenerateRecoveryCodes)
+	protected.Post("/step-up", middleware.AuthRateLimiter(), handler.StepUp)
 
 	// Admin only routes
 	admin := auth.Group("", middleware.JWTMiddleware(jwtManager), middleware.RequireAdmin())
@@ -2,44 +2,104 @@ package auth
 
 import (
 	"context"
-	"crypto/sha256"
 	"database/sql"
-	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
 	"time"
 
+	"walletpoint/internal/config"
 	"walletpoint/internal/middleware"
+	"walletpoint/internal/modules/badge"
+	"walletpoint/internal/modules/lockout"
 	apperrors "walletpoint/internal/shared/errors"
+	"walletpoint/pkg/mailer"
+	"walletpoint/pkg/oidc"
 	"walletpoint/pkg/utils"
 )
 
+const mailTimeout = 30 * time.Second
+
 type ServiceInterface interface {
 	Login(ctx context.Context, req LoginRequest, role, ipAddress, userAgent string) (*LoginResponse, error)
 	RefreshToken(ctx context.Context, refreshToken string) (*LoginResponse, error)
-	Logout(ctx context.Context, userID uint) error
-	GetProfile(ctx context.Context, userID uint) (*ProfileResponse, error)
+	Logout(ctx context.Context, userID, sessionID uint) error
+	SwitchRole(ctx context.Context, userID, sessionID uint, req SwitchRoleRequest) (*LoginResponse, error)
+	GetSessions(ctx context.Context, userID, currentSessionID uint) ([]*SessionResponse, error)
+	RevokeSession(ctx context.Context, userID, sessionID uint) error
+	RevokeOtherSessions(ctx context.Context, userID, currentSessionID uint) (int64, error)
+	GetProfile(ctx context.Context, userID uint, activeRole string) (*ProfileResponse, error)
 	ChangePassword(ctx context.Context, userID uint, req ChangePasswordRequest) error
+	AcceptInvitation(ctx context.Context, req AcceptInvitationRequest) error
+	ForgotPassword(ctx context.Context, req ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, req ResetPasswordRequest) error
+	SendVerificationEmail(ctx context.Context, userID uint) error
+	VerifyEmail(ctx context.Context, req VerifyEmailRequest) error
 	Register(ctx context.Context, req RegisterRequest) (*UserResponse, error)
+	GetTwoFactorStatus(ctx context.Context, userID uint) (*TwoFactorStatusResponse, error)
+	SetupTwoFactor(ctx context.Context, userID uint) (*TwoFactorSetupResponse, error)
+	EnableTwoFactor(ctx context.Context, userID uint, req TwoFactorCodeRequest) (*RecoveryCodesResponse, error)
+	DisableTwoFactor(ctx context.Context, userID uint, req DisableTwoFactorRequest) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uint, req TwoFactorCodeRequest) (*RecoveryCodesResponse, error)
+	StepUp(ctx context.Context, userID, sessionID uint, req StepUpRequest, ipAddress, userAgent string) (*StepUpResponse, error)
+	SetupTwoFactorLogin(ctx context.Context, req TwoFactorChallengeRequest) (*TwoFactorSetupResponse, error)
+	CompleteTwoFactorLogin(ctx context.Context, req TwoFactorLoginRequest, ipAddress, userAgent string) (*LoginResponse, error)
+	StartSSO(ctx context.Context, req SSOStartRequest) (*SSOStartResponse, error)
+	CompleteSSO(ctx context.Context, req SSOCallbackRequest, ipAddress, userAgent string) (*LoginResponse, error)
 }
 
 type Service struct {
 	repo       *Repository
+	badgeRepo  *badge.Repository
 	jwtManager *middleware.JWTManager
+	mailer     mailer.Mailer
+	account    config.AccountConfig
+	twoFactor  config.TwoFactorConfig
+	guard      *lockout.Service
+	sso        *oidc.Provider // nil while single sign-on is off
+	ssoConfig  config.OIDCConfig
 }
 
-func NewService(repo *Repository, jwtManager *middleware.JWTManager) *Service {
+func NewService(repo *Repository, badgeRepo *badge.Repository, jwtManager *middleware.JWTManager, m mailer.Mailer, accountCfg config.AccountConfig, twoFactorCfg config.TwoFactorConfig, guard *lockout.Service, oidcCfg config.OIDCConfig) *Service {
+	var sso *oidc.Provider
+	if oidcCfg.IssuerURL != "" {
+		sso = oidc.NewProvider(oidc.Config{
+			IssuerURL:    oidcCfg.IssuerURL,
+			ClientID:     oidcCfg.ClientID,
+			ClientSecret: oidcCfg.ClientSecret,
+			RedirectURL:  oidcCfg.RedirectURL,
+			Scopes:       ssoScopes,
+		})
+	}
+
 	return &Service{
 		repo:       repo,
+		badgeRepo:  badgeRepo,
 		jwtManager: jwtManager,
+		mailer:     m,
+		account:    accountCfg,
+		twoFactor:  twoFactorCfg,
+		guard:      guard,
+		sso:        sso,
+		ssoConfig:  oidcCfg,
 	}
 }
 
 func (s *Service) Login(ctx context.Context, req LoginRequest, role, ipAddress, userAgent string) (*LoginResponse, error) {
+	// Refuse locked out or throttled usernames and IPs before looking at the
+	// password, so guessing gets no feedback while locked
+	if err := s.guard.Check(ctx, req.Username, ipAddress); err != nil {
+		return nil, err
+	}
+
 	// Get user by username
 	user, err := s.repo.GetUserByUsername(ctx, req.Username)
 	if err != nil {
 		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get user")
 	}
 	if user == nil {
+		s.guard.RecordFailure(ctx, req.Username, ipAddress, userAgent)
 		return nil, apperrors.ErrInvalidCredentials
 	}
 
@@ -48,27 +108,46 @@ func (s *Service) Login(ctx context.Context, req LoginRequest, role, ipAddress,
 		return nil, apperrors.New("ACCOUNT_INACTIVE", "Account is inactive")
 	}
 
-	// Check role matches
-	if user.RoleName != role {
-		return nil, apperrors.ErrInvalidCredentials
+	// A role-specific route or an explicit role signs in as that role;
+	// otherwise the user's earliest assigned role is active
+	if role != "" {
+		if !hasRole(user.Roles, role) {
+			s.guard.RecordFailure(ctx, req.Username, ipAddress, userAgent)
+			return nil, apperrors.ErrInvalidCredentials
+		}
+		user.RoleName = role
 	}
 
 	// Verify password
 	if !utils.CheckPassword(req.Password, user.PasswordHash) {
+		s.guard.RecordFailure(ctx, req.Username, ipAddress, userAgent)
 		return nil, apperrors.ErrInvalidCredentials
 	}
 
-	// Generate tokens
-	tokenPair, err := s.jwtManager.GenerateTokenPair(user.ID, user.Username, user.RoleName)
+	// Users with two-factor, and admins who must set it up, finish the login
+	// with a second factor
+	tf, err := s.repo.GetTwoFactor(ctx, user.ID)
 	if err != nil {
-		return nil, apperrors.Wrap(err, "TOKEN_ERROR", "Failed to generate tokens")
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get two-factor settings")
 	}
+	enrolled := tf != nil && tf.EnabledAt.Valid
+	if enrolled || twoFactorRequired(user) {
+		return s.twoFactorChallenge(ctx, user, !enrolled)
+	}
+
+	resp, err := s.signIn(ctx, user, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	s.guard.RecordSuccess(ctx, user.Username)
+	return resp, nil
+}
 
-	// Create session
-	tokenHash := hashToken(tokenPair.RefreshToken)
+// signIn starts a new session family for a fully authenticated user
+func (s *Service) signIn(ctx context.Context, user *UserWithRole, ipAddress, userAgent string) (*LoginResponse, error) {
 	session := &Session{
-		UserID:    user.ID,
-		TokenHash: tokenHash,
+		UserID:   user.ID,
+		FamilyID: utils.GenerateUUID(),
 		DeviceInfo: sql.NullString{
 			String: userAgent,
 			Valid:  userAgent != "",
@@ -77,12 +156,11 @@ func (s *Service) Login(ctx context.Context, req LoginRequest, role, ipAddress,
 			String: ipAddress,
 			Valid:  ipAddress != "",
 		},
-		IsActive:  true,
-		ExpiresAt: time.Now().Add(168 * time.Hour), // 7 days
 	}
 
-	if err := s.repo.CreateSession(ctx, session); err != nil {
-		return nil, apperrors.Wrap(err, "SESSION_ERROR", "Failed to create session")
+	tokenPair, err := s.startSession(ctx, user, session)
+	if err != nil {
+		return nil, err
 	}
 
 	// Update last login
@@ -97,6 +175,9 @@ func (s *Service) Login(ctx context.Context, req LoginRequest, role, ipAddress,
 	}, nil
 }
 
+// RefreshToken rotates a refresh token. Each token works once: presenting one
+// that was already rotated means it leaked, so the whole family is revoked and
+// the device has to log in again
 func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (*LoginResponse, error) {
 	// Validate refresh token
 	claims, err := s.jwtManager.ValidateRefreshToken(refreshToken)
@@ -105,9 +186,17 @@ func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (*Login
 	}
 
 	// Check session exists
-	tokenHash := hashToken(refreshToken)
-	session, err := s.repo.GetSessionByToken(ctx, tokenHash)
-	if err != nil || session == nil {
+	session, err := s.repo.GetSessionByToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, apperrors.Wrap(err, "SESSION_ERROR", "Failed to get session")
+	}
+	if session == nil || session.UserID != claims.UserID {
+		return nil, apperrors.New("SESSION_NOT_FOUND", "Session not found or expired")
+	}
+	if !session.IsActive {
+		if session.RevokedReason.String == SessionRevokeRotated {
+			return nil, s.revokeReusedFamily(ctx, session)
+		}
 		return nil, apperrors.New("SESSION_NOT_FOUND", "Session not found or expired")
 	}
 
@@ -116,29 +205,35 @@ func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (*Login
 	if err != nil || user == nil {
 		return nil, apperrors.New("USER_NOT_FOUND", "User not found")
 	}
-
-	// Revoke old session
-	s.repo.RevokeSession(ctx, session.ID, "token_refresh")
-
-	// Generate new tokens
-	tokenPair, err := s.jwtManager.GenerateTokenPair(user.ID, user.Username, user.RoleName)
-	if err != nil {
-		return nil, apperrors.Wrap(err, "TOKEN_ERROR", "Failed to generate tokens")
+	if !user.IsActive {
+		return nil, apperrors.New("ACCOUNT_INACTIVE", "Account is inactive")
+	}
+	// Keep acting as the device's role while the user still holds it
+	if session.ActiveRole.Valid && hasRole(user.Roles, session.ActiveRole.String) {
+		user.RoleName = session.ActiveRole.String
 	}
 
-	// Create new session
-	newTokenHash := hashToken(tokenPair.RefreshToken)
+	// Create the successor in the same family
 	newSession := &Session{
 		UserID:     user.ID,
-		TokenHash:  newTokenHash,
+		FamilyID:   session.FamilyID,
 		DeviceInfo: session.DeviceInfo,
 		IPAddress:  session.IPAddress,
-		IsActive:   true,
-		ExpiresAt:  time.Now().Add(168 * time.Hour),
 	}
 
-	if err := s.repo.CreateSession(ctx, newSession); err != nil {
-		return nil, apperrors.Wrap(err, "SESSION_ERROR", "Failed to create session")
+	tokenPair, err := s.startSession(ctx, user, newSession)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retire the old session. Losing this race means a concurrent request
+	// already used the same token
+	rotated, err := s.repo.RotateSession(ctx, session.ID, newSession.ID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "SESSION_ERROR", "Failed to rotate session")
+	}
+	if !rotated {
+		return nil, s.revokeReusedFamily(ctx, session)
 	}
 
 	return &LoginResponse{
@@ -150,11 +245,111 @@ func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (*Login
 	}, nil
 }
 
-func (s *Service) Logout(ctx context.Context, userID uint) error {
-	return s.repo.RevokeAllUserSessions(ctx, userID, "logout")
+// startSession stores session and issues the token pair bound to it
+func (s *Service) startSession(ctx context.Context, user *UserWithRole, session *Session) (*middleware.TokenPair, error) {
+	refreshToken, err := s.jwtManager.GenerateRefreshToken(user.ID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "TOKEN_ERROR", "Failed to generate tokens")
+	}
+
+	session.TokenHash = hashToken(refreshToken)
+	session.ActiveRole = sql.NullString{String: user.RoleName, Valid: true}
+	session.IsActive = true
+	session.ExpiresAt = time.Now().Add(s.jwtManager.RefreshExpiry())
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return nil, apperrors.Wrap(err, "SESSION_ERROR", "Failed to create session")
+	}
+
+	tokenPair, err := s.jwtManager.GenerateTokenPair(user.ID, user.Username, user.RoleName, session.ID, refreshToken)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "TOKEN_ERROR", "Failed to generate tokens")
+	}
+	return tokenPair, nil
+}
+
+func (s *Service) revokeReusedFamily(ctx context.Context, session *Session) error {
+	log.Printf("Refresh token reuse detected for user %d, revoking session family %s", session.UserID, session.FamilyID)
+	if err := s.repo.RevokeFamily(ctx, session.FamilyID, SessionRevokeReused); err != nil {
+		return apperrors.Wrap(err, "SESSION_ERROR", "Failed to revoke sessions")
+	}
+	s.jwtManager.ForgetSessions(session.UserID)
+	return apperrors.New("TOKEN_REUSED", "Refresh token was already used; please log in again")
 }
 
-func (s *Service) GetProfile(ctx context.Context, userID uint) (*ProfileResponse, error) {
+// Logout signs out the device the access token was issued to
+func (s *Service) Logout(ctx context.Context, userID, sessionID uint) error {
+	session, err := s.ownSession(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.RevokeFamily(ctx, session.FamilyID, SessionRevokeLogout); err != nil {
+		return apperrors.Wrap(err, "SESSION_ERROR", "Failed to revoke session")
+	}
+	s.jwtManager.ForgetSessions(userID)
+	return nil
+}
+
+// GetSessions lists the devices the user is signed in on, flagging the one
+// making the request
+func (s *Service) GetSessions(ctx context.Context, userID, currentSessionID uint) ([]*SessionResponse, error) {
+	current, err := s.ownSession(ctx, userID, currentSessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.repo.GetActiveSessions(ctx, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "SESSION_ERROR", "Failed to get sessions")
+	}
+
+	result := make([]*SessionResponse, len(sessions))
+	for i, session := range sessions {
+		result[i] = ToSessionResponse(session)
+		result[i].Current = session.FamilyID == current.FamilyID
+	}
+	return result, nil
+}
+
+// RevokeSession signs out one of the user's devices
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	session, err := s.repo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return apperrors.Wrap(err, "SESSION_ERROR", "Failed to get session")
+	}
+	if session == nil || !session.IsActive || session.UserID != userID {
+		return apperrors.New("NOT_FOUND", "Session not found")
+	}
+	if err := s.repo.RevokeFamily(ctx, session.FamilyID, SessionRevokeByUser); err != nil {
+		return apperrors.Wrap(err, "SESSION_ERROR", "Failed to revoke session")
+	}
+	s.jwtManager.ForgetSessions(userID)
+	return nil
+}
+
+// RevokeOtherSessions signs out every device except the one making the request
+func (s *Service) RevokeOtherSessions(ctx context.Context, userID, currentSessionID uint) (int64, error) {
+	current, err := s.ownSession(ctx, userID, currentSessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked, err := s.repo.RevokeOtherSessions(ctx, userID, current.FamilyID, SessionRevokeByUser)
+	if err != nil {
+		return 0, apperrors.Wrap(err, "SESSION_ERROR", "Failed to revoke sessions")
+	}
+	s.jwtManager.ForgetSessions(userID)
+	return revoked, nil
+}
+
+// SwitchRole makes the device act as another of the user's roles without
+// logging in again. Only a new access token is issued: the refresh token the
+// client holds keeps working, and refreshing keeps the new role
+func (s *Service) SwitchRole(ctx context.Context, userID, sessionID uint, req SwitchRoleRequest) (*LoginResponse, error) {
+	session, err := s.ownSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := s.repo.GetUserByID(ctx, userID)
 	if err != nil {
 		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get user")
@@ -162,6 +357,54 @@ func (s *Service) GetProfile(ctx context.Context, userID uint) (*ProfileResponse
 	if user == nil {
 		return nil, apperrors.ErrNotFound
 	}
+	if !hasRole(user.Roles, req.Role) {
+		return nil, apperrors.New("ROLE_NOT_ASSIGNED", "You do not have this role")
+	}
+
+	// Access tokens of the family still naming the old role stop working
+	if err := s.repo.SetActiveRole(ctx, session.FamilyID, req.Role); err != nil {
+		return nil, apperrors.Wrap(err, "SESSION_ERROR", "Failed to switch role")
+	}
+	s.jwtManager.ForgetSessions(userID)
+
+	user.RoleName = req.Role
+	token, err := s.jwtManager.GenerateAccessToken(user.ID, user.Username, user.RoleName, sessionID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "TOKEN_ERROR", "Failed to generate tokens")
+	}
+
+	return &LoginResponse{
+		AccessToken: token.AccessToken,
+		ExpiresIn:   token.ExpiresIn,
+		TokenType:   token.TokenType,
+		User:        ToUserResponse(user),
+	}, nil
+}
+
+// ownSession loads the session an access token names. The row itself may have
+// been rotated since; callers work with its family
+func (s *Service) ownSession(ctx context.Context, userID, sessionID uint) (*Session, error) {
+	session, err := s.repo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "SESSION_ERROR", "Failed to get session")
+	}
+	if session == nil || session.UserID != userID {
+		return nil, apperrors.New("SESSION_NOT_FOUND", "Session not found or expired")
+	}
+	return session, nil
+}
+
+func (s *Service) GetProfile(ctx context.Context, userID uint, activeRole string) (*ProfileResponse, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get user")
+	}
+	if user == nil {
+		return nil, apperrors.ErrNotFound
+	}
+	if hasRole(user.Roles, activeRole) {
+		user.RoleName = activeRole
+	}
 
 	profile := &ProfileResponse{
 		ID:        user.ID,
@@ -169,6 +412,7 @@ func (s *Service) GetProfile(ctx context.Context, userID uint) (*ProfileResponse
 		Email:     user.Email,
 		FullName:  user.FullName,
 		Role:      user.RoleName,
+		Roles:     user.Roles,
 		CreatedAt: user.CreatedAt.Format(time.RFC3339),
 	}
 
@@ -190,6 +434,15 @@ func (s *Service) GetProfile(ctx context.Context, userID uint) (*ProfileResponse
 		profile.LastLoginAt = &t
 	}
 
+	badges, err := s.badgeRepo.GetUserBadges(ctx, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get badges")
+	}
+	profile.Badges = make([]*badge.UserBadgeResponse, len(badges))
+	for i, ub := range badges {
+		profile.Badges[i] = badge.ToUserBadgeResponse(ub)
+	}
+
 	return profile, nil
 }
 
@@ -211,17 +464,223 @@ func (s *Service) ChangePassword(ctx context.Context, userID uint, req ChangePas
 		return apperrors.Wrap(err, "HASH_ERROR", "Failed to hash password")
 	}
 
-	// Update password
-	if err := s.repo.UpdatePassword(ctx, userID, newHash); err != nil {
-		return apperrors.Wrap(err, "UPDATE_ERROR", "Failed to update password")
+	// Update password and revoke all sessions
+	return s.replacePassword(ctx, userID, newHash, "password_changed")
+}
+
+// AcceptInvitation lets an invited user choose their password. Each invitation
+// works once and only until it expires
+func (s *Service) AcceptInvitation(ctx context.Context, req AcceptInvitationRequest) error {
+	user, err := s.redeemToken(ctx, req.Token, TokenPurposeInvite)
+	if err != nil {
+		return err
+	}
+	return s.setPassword(ctx, user.ID, req.NewPassword, TokenPurposeInvite, "invitation_accepted")
+}
+
+// ForgotPassword mails a reset link to the account matching the username or
+// email. It succeeds whether or not an account matched, so callers cannot use
+// it to discover accounts
+func (s *Service) ForgotPassword(ctx context.Context, req ForgotPasswordRequest) error {
+	var user *UserWithRole
+	var err error
+	if strings.Contains(req.Identifier, "@") {
+		user, err = s.repo.GetUserByEmail(ctx, strings.ToLower(req.Identifier))
+	} else {
+		user, err = s.repo.GetUserByUsername(ctx, req.Identifier)
+	}
+	if err != nil {
+		return apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get user")
+	}
+	if user == nil || !user.IsActive {
+		return nil
+	}
+
+	token, err := s.issueToken(ctx, user.ID, TokenPurposePasswordReset, s.account.ResetTTL)
+	if err != nil {
+		return err
+	}
+
+	s.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your WalletPoint password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone asked to reset the password of your WalletPoint account %s.\n"+
+			"Open this link within %s to choose a new password:\n\n%s\n\n"+
+			"If it was not you, ignore this email; your password stays the same.\n",
+			user.FullName, user.Username, s.account.ResetTTL, s.link("/reset-password", token)),
+	})
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token and signs the user out
+// everywhere. Receiving the link also proves the email address works
+func (s *Service) ResetPassword(ctx context.Context, req ResetPasswordRequest) error {
+	user, err := s.redeemToken(ctx, req.Token, TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+	if err := s.setPassword(ctx, user.ID, req.NewPassword, TokenPurposePasswordReset, "password_reset"); err != nil {
+		return err
+	}
+
+	if !user.EmailVerifiedAt.Valid {
+		s.repo.MarkEmailVerified(ctx, user.ID)
+	}
+	return nil
+}
+
+// SendVerificationEmail mails a verification link to the user's current address
+func (s *Service) SendVerificationEmail(ctx context.Context, userID uint) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get user")
+	}
+	if user == nil {
+		return apperrors.ErrNotFound
+	}
+	if user.EmailVerifiedAt.Valid {
+		return apperrors.New("EMAIL_ALREADY_VERIFIED", "Email address is already verified")
+	}
+
+	token, err := s.issueToken(ctx, user.ID, TokenPurposeEmailVerify, s.account.VerifyTTL)
+	if err != nil {
+		return err
+	}
+
+	s.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your WalletPoint email address",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Open this link within %s to confirm this is your email address:\n\n%s\n",
+			user.FullName, s.account.VerifyTTL, s.link("/verify-email", token)),
+	})
+	return nil
+}
+
+// VerifyEmail marks the user's email address verified
+func (s *Service) VerifyEmail(ctx context.Context, req VerifyEmailRequest) error {
+	user, err := s.redeemToken(ctx, req.Token, TokenPurposeEmailVerify)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.MarkEmailVerified(ctx, user.ID); err != nil {
+		return apperrors.Wrap(err, "UPDATE_ERROR", "Failed to verify email")
+	}
+	return nil
+}
+
+// issueToken replaces any outstanding token of the purpose with a new one and
+// returns its plaintext
+func (s *Service) issueToken(ctx context.Context, userID uint, purpose string, ttl time.Duration) (string, error) {
+	return s.storeToken(ctx, &UserToken{UserID: userID, Purpose: purpose}, ttl)
+}
+
+// storeToken replaces the user's outstanding tokens of the same purpose with
+// token and returns its plaintext
+func (s *Service) storeToken(ctx context.Context, token *UserToken, ttl time.Duration) (string, error) {
+	if err := s.repo.ExpireUserTokens(ctx, token.UserID, token.Purpose); err != nil {
+		return "", apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to expire old tokens")
+	}
+
+	plain, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", apperrors.Wrap(err, "TOKEN_ERROR", "Failed to generate token")
+	}
+
+	token.TokenHash = hashToken(plain)
+	token.ExpiresAt = time.Now().Add(ttl)
+	if err := s.repo.CreateUserToken(ctx, s.repo.db, token); err != nil {
+		return "", apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to store token")
+	}
+	return plain, nil
+}
+
+// redeemToken uses up a token and returns its active owner
+func (s *Service) redeemToken(ctx context.Context, plain, purpose string) (*UserWithRole, error) {
+	invalid := apperrors.New("INVALID_TOKEN", "Token is invalid, used or expired")
+
+	token, err := s.repo.GetUserToken(ctx, hashToken(plain), purpose)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get token")
+	}
+	if token == nil {
+		return nil, invalid
+	}
+
+	user, err := s.repo.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get user")
+	}
+	if user == nil {
+		return nil, invalid
+	}
+	if !user.IsActive {
+		return nil, apperrors.New("ACCOUNT_INACTIVE", "Account is inactive")
+	}
+
+	used, err := s.repo.UseUserToken(ctx, token.ID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to use token")
 	}
+	if !used {
+		return nil, invalid
+	}
+	return user, nil
+}
 
-	// Revoke all sessions
-	s.repo.RevokeAllUserSessions(ctx, userID, "password_changed")
+func (s *Service) setPassword(ctx context.Context, userID uint, password, purpose, revokeReason string) error {
+	newHash, err := utils.HashPassword(password)
+	if err != nil {
+		return apperrors.Wrap(err, "HASH_ERROR", "Failed to hash password")
+	}
+	if err := s.replacePassword(ctx, userID, newHash, revokeReason); err != nil {
+		return err
+	}
 
+	// Any other link of the same kind stops working
+	s.repo.ExpireUserTokens(ctx, userID, purpose)
 	return nil
 }
 
+// replacePassword stores the new hash and signs the user out everywhere in one
+// transaction, so a password is never changed while old sessions stay valid
+func (s *Service) replacePassword(ctx context.Context, userID uint, passwordHash, revokeReason string) error {
+	tx, err := s.repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if err := s.repo.UpdatePassword(ctx, tx, userID, passwordHash); err != nil {
+		return apperrors.Wrap(err, "UPDATE_ERROR", "Failed to update password")
+	}
+	if err := s.repo.RevokeAllUserSessions(ctx, tx, userID, revokeReason); err != nil {
+		return apperrors.Wrap(err, "SESSION_ERROR", "Failed to revoke sessions")
+	}
+	if err := tx.Commit(); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to commit transaction")
+	}
+	s.jwtManager.ForgetSessions(userID)
+	return nil
+}
+
+func (s *Service) link(path, token string) string {
+	return strings.TrimRight(s.account.LinkBaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendMail delivers in the background so response times do not reveal whether
+// an account exists, and a slow mail server does not hold up the request
+func (s *Service) sendMail(msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			log.Printf("Failed to send mail to %s: %v", msg.To, err)
+		}
+	}()
+}
+
 func (s *Service) Register(ctx context.Context, req RegisterRequest) (*UserResponse, error) {
 	// Check if username exists
 	existing, _ := s.repo.GetUserByUsername(ctx, req.Username)
@@ -251,12 +710,12 @@ func (s *Service) Register(ctx context.Context, req RegisterRequest) (*UserRespo
 		IsActive:     true,
 	}
 
-	if err := s.repo.CreateUser(ctx, user); err != nil {
+	if err := s.repo.CreateUser(ctx, s.repo.db, user); err != nil {
 		return nil, apperrors.Wrap(err, "CREATE_ERROR", "Failed to create user")
 	}
 
 	// Assign role
-	if err := s.repo.AssignRole(ctx, user.ID, role.ID); err != nil {
+	if err := s.repo.AssignRole(ctx, s.repo.db, user.ID, role.ID); err != nil {
 		return nil, apperrors.Wrap(err, "ROLE_ERROR", "Failed to assign role")
 	}
 
@@ -270,6 +729,14 @@ func (s *Service) Register(ctx context.Context, req RegisterRequest) (*UserRespo
 }
 
 func hashToken(token string) string {
-	hash := sha256.Sum256([]byte(token))
-	return hex.EncodeToString(hash[:])
+	return utils.HashToken(token)
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,322 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	apperrors "walletpoint/internal/shared/errors"
+	"walletpoint/pkg/oidc"
+	"walletpoint/pkg/utils"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ssoScopes asks for the claims users are matched on
+var ssoScopes = []string{"openid", "email", "profile"}
+
+// maxUsernameLen matches users.username
+const maxUsernameLen = 50
+
+var (
+	errSSODisabled = apperrors.New("SSO_DISABLED", "Single sign-on is not configured")
+	errSSOFailed   = apperrors.New("SSO_FAILED", "Single sign-on failed, please try again")
+)
+
+// StartSSO begins a login with the campus identity provider. The client sends
+// the user to the returned URL; the provider sends them back to the app with
+// a code and the state, which go to CompleteSSO
+func (s *Service) StartSSO(ctx context.Context, req SSOStartRequest) (*SSOStartResponse, error) {
+	if s.sso == nil {
+		return nil, errSSODisabled
+	}
+	if req.Role != "" {
+		role, err := s.repo.GetRoleByName(ctx, req.Role)
+		if err != nil || role == nil {
+			return nil, apperrors.New("INVALID_ROLE", "Invalid role specified")
+		}
+	}
+
+	state, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "TOKEN_ERROR", "Failed to generate state")
+	}
+	nonce, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "TOKEN_ERROR", "Failed to generate nonce")
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return nil, apperrors.Wrap(err, "TOKEN_ERROR", "Failed to generate code verifier")
+	}
+
+	authURL, err := s.sso.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		log.Printf("Failed to reach identity provider: %v", err)
+		return nil, errSSOFailed
+	}
+
+	err = s.repo.CreateSSOState(ctx, &SSOState{
+		StateHash:    hashToken(state),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		Role:         sql.NullString{String: req.Role, Valid: req.Role != ""},
+		ExpiresAt:    time.Now().Add(s.ssoConfig.StateTTL),
+	})
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to store sign-in request")
+	}
+
+	return &SSOStartResponse{
+		AuthorizationURL: authURL,
+		State:            state,
+		ExpiresIn:        int64(s.ssoConfig.StateTTL.Seconds()),
+	}, nil
+}
+
+// CompleteSSO exchanges the provider's code for a verified identity and signs
+// the matching user in. Users with two-factor still get a challenge: the
+// provider vouches for their campus password, not for our second factor
+func (s *Service) CompleteSSO(ctx context.Context, req SSOCallbackRequest, ipAddress, userAgent string) (*LoginResponse, error) {
+	if s.sso == nil {
+		return nil, errSSODisabled
+	}
+
+	state, err := s.repo.UseSSOState(ctx, hashToken(req.State))
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get sign-in request")
+	}
+	if state == nil {
+		return nil, apperrors.New("INVALID_TOKEN", "Sign-in request is invalid, used or expired")
+	}
+
+	token, err := s.sso.Exchange(ctx, req.Code, state.CodeVerifier)
+	if err != nil {
+		log.Printf("SSO code exchange failed: %v", err)
+		return nil, errSSOFailed
+	}
+	claims, err := s.sso.VerifyIDToken(ctx, token.IDToken, state.Nonce)
+	if err != nil {
+		log.Printf("SSO ID token rejected: %v", err)
+		return nil, errSSOFailed
+	}
+
+	user, err := s.ssoUser(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, apperrors.New("ACCOUNT_INACTIVE", "Account is inactive")
+	}
+	if state.Role.Valid {
+		if !hasRole(user.Roles, state.Role.String) {
+			return nil, apperrors.New("ROLE_NOT_ASSIGNED", "You do not have this role")
+		}
+		user.RoleName = state.Role.String
+	}
+
+	tf, err := s.repo.GetTwoFactor(ctx, user.ID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get two-factor settings")
+	}
+	enrolled := tf != nil && tf.EnabledAt.Valid
+	if enrolled || twoFactorRequired(user) {
+		return s.twoFactorChallenge(ctx, user, !enrolled)
+	}
+
+	return s.signIn(ctx, user, ipAddress, userAgent)
+}
+
+// ssoUser finds the user a campus identity belongs to: by an earlier link,
+// then by verified email, then by NIM/NIP when that claim is trusted. The
+// first match links the identity for good, so only claims the provider
+// vouches for may match. Without a match a new user is provisioned when that
+// is enabled
+func (s *Service) ssoUser(ctx context.Context, claims *oidc.Claims) (*UserWithRole, error) {
+	identity, err := s.repo.GetIdentity(ctx, claims.Issuer, claims.Subject)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get identity")
+	}
+
+	var user *UserWithRole
+	if identity != nil {
+		if user, err = s.repo.GetUserByID(ctx, identity.UserID); err != nil {
+			return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get user")
+		}
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if user == nil && email != "" && claims.EmailVerified {
+		if user, err = s.repo.GetUserByEmail(ctx, email); err != nil {
+			return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get user")
+		}
+	}
+
+	nimNip := ""
+	if s.ssoConfig.TrustNimClaim {
+		nimNip = strings.TrimSpace(claims.String(s.ssoConfig.NimClaim))
+	}
+	if user == nil && nimNip != "" {
+		if user, err = s.repo.GetUserByNimNip(ctx, nimNip); err != nil {
+			return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get user")
+		}
+	}
+
+	if user == nil {
+		if !s.ssoConfig.Provision {
+			return nil, apperrors.New("SSO_NO_ACCOUNT", "No account matches this campus identity")
+		}
+		return s.provisionSSOUser(ctx, claims, email, nimNip)
+	}
+
+	err = s.repo.LinkIdentity(ctx, s.repo.db, &Identity{
+		UserID:  user.ID,
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+		Email:   sql.NullString{String: email, Valid: email != ""},
+	})
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to link identity")
+	}
+	return user, nil
+}
+
+// provisionSSOUser creates an account for a campus user with the default
+// role. Its password is random; the user can set one through a reset
+func (s *Service) provisionSSOUser(ctx context.Context, claims *oidc.Claims, email, nimNip string) (*UserWithRole, error) {
+	if email == "" || !claims.EmailVerified {
+		return nil, apperrors.New("SSO_NO_ACCOUNT", "Campus identity has no verified email address to create an account with")
+	}
+
+	role, err := s.repo.GetRoleByName(ctx, s.ssoConfig.DefaultRole)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get role")
+	}
+	if role == nil {
+		return nil, apperrors.New("INTERNAL_ERROR", "Default role for new SSO users does not exist")
+	}
+
+	username, err := s.ssoUsername(ctx, claims, email, nimNip)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "TOKEN_ERROR", "Failed to generate credentials")
+	}
+	passwordHash, err := utils.HashPassword(secret)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "HASH_ERROR", "Failed to hash password")
+	}
+
+	fullName := strings.TrimSpace(claims.Name)
+	if fullName == "" {
+		fullName = username
+	}
+	user := &User{
+		Username:     username,
+		Email:        email,
+		PasswordHash: passwordHash,
+		FullName:     fullName,
+		NimNip:       sql.NullString{String: nimNip, Valid: nimNip != ""},
+		IsActive:     true,
+	}
+
+	tx, err := s.repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if err := s.repo.CreateUser(ctx, tx, user); err != nil {
+		if isDuplicate(err) {
+			return nil, apperrors.New("ACCOUNT_EXISTS", "An account with this email or NIM/NIP already exists; ask an administrator to link it")
+		}
+		return nil, apperrors.Wrap(err, "CREATE_ERROR", "Failed to create user")
+	}
+	if err := s.repo.AssignRole(ctx, tx, user.ID, role.ID); err != nil {
+		return nil, apperrors.Wrap(err, "ROLE_ERROR", "Failed to assign role")
+	}
+	err = s.repo.LinkIdentity(ctx, tx, &Identity{
+		UserID:  user.ID,
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+		Email:   sql.NullString{String: email, Valid: true},
+	})
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to link identity")
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to commit transaction")
+	}
+
+	// The provider has verified the address
+	if err := s.repo.MarkEmailVerified(ctx, user.ID); err != nil {
+		log.Printf("Failed to mark email of SSO user %d verified: %v", user.ID, err)
+	}
+	log.Printf("Provisioned user %s from single sign-on", username)
+
+	created, err := s.repo.GetUserByID(ctx, user.ID)
+	if err != nil || created == nil {
+		return nil, apperrors.New("USER_NOT_FOUND", "User not found")
+	}
+	return created, nil
+}
+
+// ssoUsername picks a free username from the provider's preferred username,
+// the email's local part or the NIM/NIP, adding a number when taken
+func (s *Service) ssoUsername(ctx context.Context, claims *oidc.Claims, email, nimNip string) (string, error) {
+	base := ""
+	for _, candidate := range []string{claims.PreferredUsername, strings.SplitN(email, "@", 2)[0], nimNip} {
+		if base = sanitizeUsername(candidate); base != "" {
+			break
+		}
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	for i := 1; i <= 20; i++ {
+		username := base
+		if i > 1 {
+			suffix := fmt.Sprintf("%d", i)
+			username = truncate(base, maxUsernameLen-len(suffix)) + suffix
+		}
+		taken, err := s.repo.UsernameTaken(ctx, username)
+		if err != nil {
+			return "", apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to check username")
+		}
+		if !taken {
+			return username, nil
+		}
+	}
+	return "", apperrors.New("ACCOUNT_EXISTS", "Could not find a free username for this campus identity")
+}
+
+// sanitizeUsername keeps the characters usernames are made of
+func sanitizeUsername(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '_' || r == '-' {
+			b.WriteRune(r)
+		}
+	}
+	return truncate(b.String(), maxUsernameLen)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+func isDuplicate(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
@@ -0,0 +1,450 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"walletpoint/internal/shared/constants"
+	apperrors "walletpoint/internal/shared/errors"
+	"walletpoint/pkg/totp"
+	"walletpoint/pkg/utils"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	recoveryCodeCount = 10
+	// Accept the codes either side of the current one for clock drift
+	totpSkew = 1
+)
+
+var errInvalidCode = apperrors.New("INVALID_CODE", "Verification code is invalid or was already used")
+
+// GetTwoFactorStatus reports whether the user has two-factor on
+func (s *Service) GetTwoFactorStatus(ctx context.Context, userID uint) (*TwoFactorStatusResponse, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get user")
+	}
+	if user == nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	tf, err := s.repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get two-factor settings")
+	}
+
+	status := &TwoFactorStatusResponse{
+		Enabled:  tf != nil && tf.EnabledAt.Valid,
+		Required: twoFactorRequired(user),
+	}
+	if status.Enabled {
+		status.RecoveryCodesRemaining, err = s.repo.CountRecoveryCodes(ctx, userID)
+		if err != nil {
+			return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to count recovery codes")
+		}
+	}
+	return status, nil
+}
+
+// SetupTwoFactor starts enrollment with a fresh secret. Two-factor is not on
+// until EnableTwoFactor confirms a code from the app
+func (s *Service) SetupTwoFactor(ctx context.Context, userID uint) (*TwoFactorSetupResponse, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get user")
+	}
+	if user == nil {
+		return nil, apperrors.ErrNotFound
+	}
+	return s.startEnrollment(ctx, user)
+}
+
+// EnableTwoFactor confirms enrollment with a first code and hands out the
+// recovery codes
+func (s *Service) EnableTwoFactor(ctx context.Context, userID uint, req TwoFactorCodeRequest) (*RecoveryCodesResponse, error) {
+	codes, err := s.completeEnrollment(ctx, userID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor turns two-factor off for users who are not required to
+// have it
+func (s *Service) DisableTwoFactor(ctx context.Context, userID uint, req DisableTwoFactorRequest) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil || user == nil {
+		return apperrors.ErrNotFound
+	}
+	if twoFactorRequired(user) {
+		return apperrors.New("TWO_FACTOR_REQUIRED", "Two-factor authentication is mandatory for your role")
+	}
+	if !utils.CheckPassword(req.Password, user.PasswordHash) {
+		return apperrors.New("INVALID_PASSWORD", "Password is incorrect")
+	}
+
+	tf, err := s.enabledTwoFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.verifySecondFactor(ctx, tf, req.Code, req.RecoveryCode); err != nil {
+		return err
+	}
+
+	tx, err := s.repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if err := s.repo.DeleteTwoFactor(ctx, tx, userID); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to disable two-factor")
+	}
+	if err := tx.Commit(); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to commit transaction")
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes; it needs a code from
+// the authenticator so a stolen recovery code cannot mint more
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID uint, req TwoFactorCodeRequest) (*RecoveryCodesResponse, error) {
+	tf, err := s.enabledTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySecondFactor(ctx, tf, req.Code, ""); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	codes, err := s.replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to commit transaction")
+	}
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// StepUp re-verifies the user and unlocks sensitive actions for the current
+// device for a few minutes. Users without two-factor confirm their password.
+// Wrong answers count towards the login lockout, or a stolen access token
+// would allow unlimited guessing
+func (s *Service) StepUp(ctx context.Context, userID, sessionID uint, req StepUpRequest, ipAddress, userAgent string) (*StepUpResponse, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, apperrors.ErrNotFound
+	}
+	if err := s.guard.Check(ctx, user.Username, ipAddress); err != nil {
+		return nil, err
+	}
+
+	tf, err := s.repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get two-factor settings")
+	}
+
+	switch {
+	case tf != nil && tf.EnabledAt.Valid:
+		if err := s.verifySecondFactor(ctx, tf, req.Code, req.RecoveryCode); err != nil {
+			if appErr, ok := err.(*apperrors.AppError); ok && appErr.Code == "INVALID_CODE" {
+				s.guard.RecordFailure(ctx, user.Username, ipAddress, userAgent)
+			}
+			return nil, err
+		}
+	case twoFactorRequired(user):
+		return nil, apperrors.New("TWO_FACTOR_REQUIRED", "Set up two-factor authentication first")
+	default:
+		if !utils.CheckPassword(req.Password, user.PasswordHash) {
+			s.guard.RecordFailure(ctx, user.Username, ipAddress, userAgent)
+			return nil, apperrors.New("INVALID_PASSWORD", "Password is incorrect")
+		}
+	}
+	s.guard.RecordSuccess(ctx, user.Username)
+
+	session, err := s.ownSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	until := time.Now().Add(s.twoFactor.StepUpTTL)
+	if err := s.repo.SetStepUp(ctx, session.FamilyID, until); err != nil {
+		return nil, apperrors.Wrap(err, "SESSION_ERROR", "Failed to record verification")
+	}
+	s.jwtManager.ForgetSessions(userID)
+
+	return &StepUpResponse{ExpiresAt: until.Format(time.RFC3339)}, nil
+}
+
+// SetupTwoFactorLogin starts enrollment for a user whose login is waiting on
+// a two-factor setup they are required to have
+func (s *Service) SetupTwoFactorLogin(ctx context.Context, req TwoFactorChallengeRequest) (*TwoFactorSetupResponse, error) {
+	user, _, err := s.challengeUser(ctx, req.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.startEnrollment(ctx, user)
+}
+
+// CompleteTwoFactorLogin checks the second factor of a login and signs the
+// user in. A user enrolling during login confirms their first code here and
+// gets their recovery codes with the tokens
+func (s *Service) CompleteTwoFactorLogin(ctx context.Context, req TwoFactorLoginRequest, ipAddress, userAgent string) (*LoginResponse, error) {
+	user, challenge, err := s.challengeUser(ctx, req.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.guard.Check(ctx, user.Username, ipAddress); err != nil {
+		return nil, err
+	}
+
+	tf, err := s.repo.GetTwoFactor(ctx, user.ID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get two-factor settings")
+	}
+
+	var recoveryCodes []string
+	if tf != nil && tf.EnabledAt.Valid {
+		err = s.verifySecondFactor(ctx, tf, req.Code, req.RecoveryCode)
+	} else {
+		recoveryCodes, err = s.completeEnrollment(ctx, user.ID, req.Code)
+	}
+	if err != nil {
+		// Wrong codes count like wrong passwords, or a stolen password would
+		// allow unlimited guessing of the second factor
+		if appErr, ok := err.(*apperrors.AppError); ok && appErr.Code == "INVALID_CODE" {
+			s.guard.RecordFailure(ctx, user.Username, ipAddress, userAgent)
+		}
+		return nil, err
+	}
+
+	used, err := s.repo.UseUserToken(ctx, challenge.ID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to use token")
+	}
+	if !used {
+		return nil, apperrors.New("INVALID_TOKEN", "Token is invalid, used or expired")
+	}
+
+	resp, err := s.signIn(ctx, user, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	s.guard.RecordSuccess(ctx, user.Username)
+	resp.RecoveryCodes = recoveryCodes
+	return resp, nil
+}
+
+// twoFactorChallenge answers a correct password with a challenge the client
+// exchanges for tokens once it has the second factor
+func (s *Service) twoFactorChallenge(ctx context.Context, user *UserWithRole, setupRequired bool) (*LoginResponse, error) {
+	token, err := s.storeToken(ctx, &UserToken{
+		UserID:  user.ID,
+		Purpose: TokenPurposeTwoFactor,
+		Role:    sql.NullString{String: user.RoleName, Valid: true},
+	}, s.twoFactor.ChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResponse{
+		TwoFactorRequired:      true,
+		TwoFactorSetupRequired: setupRequired,
+		ChallengeToken:         token,
+		User:                   ToUserResponse(user),
+	}, nil
+}
+
+// challengeUser checks a login challenge without using it up, so a mistyped
+// code can be retried until the challenge expires
+func (s *Service) challengeUser(ctx context.Context, plain string) (*UserWithRole, *UserToken, error) {
+	invalid := apperrors.New("INVALID_TOKEN", "Token is invalid, used or expired")
+
+	challenge, err := s.repo.GetUserToken(ctx, hashToken(plain), TokenPurposeTwoFactor)
+	if err != nil {
+		return nil, nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get token")
+	}
+	if challenge == nil {
+		return nil, nil, invalid
+	}
+
+	user, err := s.repo.GetUserByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get user")
+	}
+	if user == nil {
+		return nil, nil, invalid
+	}
+	if !user.IsActive {
+		return nil, nil, apperrors.New("ACCOUNT_INACTIVE", "Account is inactive")
+	}
+	// Sign in as the role the password login asked for, if still held
+	if challenge.Role.Valid && hasRole(user.Roles, challenge.Role.String) {
+		user.RoleName = challenge.Role.String
+	}
+	return user, challenge, nil
+}
+
+func (s *Service) startEnrollment(ctx context.Context, user *UserWithRole) (*TwoFactorSetupResponse, error) {
+	tf, err := s.repo.GetTwoFactor(ctx, user.ID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get two-factor settings")
+	}
+	if tf != nil && tf.EnabledAt.Valid {
+		return nil, apperrors.New("TWO_FACTOR_ENABLED", "Two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, apperrors.Wrap(err, "TOKEN_ERROR", "Failed to generate secret")
+	}
+	encrypted, err := utils.EncryptString(secret, s.twoFactor.SecretKey)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "TOKEN_ERROR", "Failed to encrypt secret")
+	}
+	if err := s.repo.SaveTwoFactorSecret(ctx, user.ID, encrypted); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to save two-factor secret")
+	}
+
+	uri := totp.URI(s.twoFactor.Issuer, user.Username, secret)
+	resp := &TwoFactorSetupResponse{Secret: secret, OtpauthURL: uri}
+	if png, err := qrcode.Encode(uri, qrcode.Medium, 256); err == nil {
+		resp.QRImageBase64 = "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	}
+	return resp, nil
+}
+
+// completeEnrollment turns two-factor on once the app produced a valid code
+// and returns the new recovery codes
+func (s *Service) completeEnrollment(ctx context.Context, userID uint, code string) ([]string, error) {
+	tf, err := s.repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get two-factor settings")
+	}
+	if tf == nil {
+		return nil, apperrors.New("TWO_FACTOR_NOT_SETUP", "Start two-factor setup first")
+	}
+	if tf.EnabledAt.Valid {
+		return nil, apperrors.New("TWO_FACTOR_ENABLED", "Two-factor authentication is already enabled")
+	}
+	if err := s.verifySecondFactor(ctx, tf, code, ""); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if err := s.repo.EnableTwoFactor(ctx, tx, userID); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to enable two-factor")
+	}
+	codes, err := s.replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to commit transaction")
+	}
+	return codes, nil
+}
+
+func (s *Service) enabledTwoFactor(ctx context.Context, userID uint) (*TwoFactor, error) {
+	tf, err := s.repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get two-factor settings")
+	}
+	if tf == nil || !tf.EnabledAt.Valid {
+		return nil, apperrors.New("TWO_FACTOR_NOT_SETUP", "Two-factor authentication is not enabled")
+	}
+	return tf, nil
+}
+
+// verifySecondFactor accepts an authenticator code, each at most once, or
+// else an unused recovery code
+func (s *Service) verifySecondFactor(ctx context.Context, tf *TwoFactor, code, recoveryCode string) error {
+	if code != "" {
+		secret, err := utils.DecryptString(tf.SecretEncrypted, s.twoFactor.SecretKey)
+		if err != nil {
+			return apperrors.Wrap(err, "TOKEN_ERROR", "Failed to read two-factor secret")
+		}
+		step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+		if !ok {
+			return errInvalidCode
+		}
+		fresh, err := s.repo.UseTwoFactorStep(ctx, tf.UserID, step)
+		if err != nil {
+			return apperrors.Wrap(err, "DB_ERROR", "Failed to record code")
+		}
+		if !fresh {
+			return errInvalidCode
+		}
+		return nil
+	}
+
+	if recoveryCode != "" {
+		used, err := s.repo.UseRecoveryCode(ctx, tf.UserID, hashToken(normalizeRecoveryCode(recoveryCode)))
+		if err != nil {
+			return apperrors.Wrap(err, "DB_ERROR", "Failed to use recovery code")
+		}
+		if !used {
+			return errInvalidCode
+		}
+		return nil
+	}
+
+	return errInvalidCode
+}
+
+func (s *Service) replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, apperrors.Wrap(err, "TOKEN_ERROR", "Failed to generate recovery codes")
+		}
+		codes[i] = code
+		hashes[i] = hashToken(normalizeRecoveryCode(code))
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(ctx, tx, userID, hashes); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to save recovery codes")
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns 40 random bits as "xxxxx-xxxxx"
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := hex.EncodeToString(b)
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode lets users type codes without the dash or in capitals
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// twoFactorRequired reports whether the user may not sign in without two-factor
+func twoFactorRequired(user *UserWithRole) bool {
+	// Any admin role counts, as switching to it needs no new login
+	return hasRole(user.Roles, constants.RoleAdmin)
+}
@@ -0,0 +1,187 @@
+package badge
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"walletpoint/internal/shared/constants"
+)
+
+// CreateBadgeRequest for defining a new achievement
+type CreateBadgeRequest struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	RuleType    string `json:"rule_type"`    // MISSIONS_COMPLETED, POINTS_EARNED or STREAK_DAYS
+	MissionType string `json:"mission_type"` // only for MISSIONS_COMPLETED
+	Threshold   int64  `json:"threshold"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+func (r *CreateBadgeRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	r.Code = strings.ToUpper(strings.TrimSpace(r.Code))
+	if r.Code == "" {
+		errors = append(errors, ValidationError{Field: "code", Message: "Code is required"})
+	}
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		errors = append(errors, ValidationError{Field: "name", Message: "Name is required"})
+	}
+	return append(errors, validateRule(r.RuleType, r.MissionType, r.Threshold)...)
+}
+
+// UpdateBadgeRequest for changing an achievement; omitted fields are kept
+type UpdateBadgeRequest struct {
+	Code        *string `json:"code"`
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Icon        *string `json:"icon"`
+	RuleType    *string `json:"rule_type"`
+	MissionType *string `json:"mission_type"`
+	Threshold   *int64  `json:"threshold"`
+	IsActive    *bool   `json:"is_active"`
+}
+
+func (r *UpdateBadgeRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Code != nil && strings.TrimSpace(*r.Code) == "" {
+		errors = append(errors, ValidationError{Field: "code", Message: "Code must not be empty"})
+	}
+	if r.Name != nil && strings.TrimSpace(*r.Name) == "" {
+		errors = append(errors, ValidationError{Field: "name", Message: "Name must not be empty"})
+	}
+	return errors
+}
+
+func (r *UpdateBadgeRequest) apply(b *Badge) {
+	if r.Code != nil {
+		b.Code = strings.ToUpper(strings.TrimSpace(*r.Code))
+	}
+	if r.Name != nil {
+		b.Name = strings.TrimSpace(*r.Name)
+	}
+	if r.Description != nil {
+		b.Description = nullString(*r.Description)
+	}
+	if r.Icon != nil {
+		b.Icon = nullString(*r.Icon)
+	}
+	if r.RuleType != nil {
+		b.RuleType = *r.RuleType
+	}
+	if r.MissionType != nil {
+		b.MissionType = nullString(strings.ToUpper(*r.MissionType))
+	}
+	if r.Threshold != nil {
+		b.Threshold = *r.Threshold
+	}
+	if r.IsActive != nil {
+		b.IsActive = *r.IsActive
+	}
+}
+
+func validateRule(ruleType, missionType string, threshold int64) []ValidationError {
+	var errors []ValidationError
+	if !validRules[ruleType] {
+		errors = append(errors, ValidationError{Field: "rule_type", Message: "Rule type must be MISSIONS_COMPLETED, POINTS_EARNED or STREAK_DAYS"})
+	}
+	if missionType != "" {
+		if ruleType != RuleMissionsCompleted {
+			errors = append(errors, ValidationError{Field: "mission_type", Message: "Mission type only applies to MISSIONS_COMPLETED"})
+		} else if !validMissionTypes[strings.ToUpper(missionType)] {
+			errors = append(errors, ValidationError{Field: "mission_type", Message: "Invalid mission type"})
+		}
+	}
+	if threshold <= 0 {
+		errors = append(errors, ValidationError{Field: "threshold", Message: "Threshold must be positive"})
+	}
+	return errors
+}
+
+var validMissionTypes = map[string]bool{
+	constants.MissionTypeQuiz:       true,
+	constants.MissionTypeAssignment: true,
+	constants.MissionTypeAttendance: true,
+	constants.MissionTypeProject:    true,
+	constants.MissionTypeOther:      true,
+}
+
+func nullString(s string) sql.NullString {
+	s = strings.TrimSpace(s)
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// ValidationError for validation
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// BadgeResponse for an achievement definition
+type BadgeResponse struct {
+	ID          uint   `json:"id"`
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Icon        string `json:"icon,omitempty"`
+	RuleType    string `json:"rule_type"`
+	MissionType string `json:"mission_type,omitempty"`
+	Threshold   int64  `json:"threshold"`
+	IsActive    bool   `json:"is_active"`
+}
+
+// UserBadgeResponse is an earned badge as shown on a profile
+type UserBadgeResponse struct {
+	BadgeResponse
+	AwardedAt string `json:"awarded_at"`
+}
+
+// BadgeProgressResponse is a badge with how close the user is to earning it
+type BadgeProgressResponse struct {
+	BadgeResponse
+	Progress  int64  `json:"progress"`
+	Earned    bool   `json:"earned"`
+	AwardedAt string `json:"awarded_at,omitempty"`
+}
+
+// MyBadgesResponse for the caller's achievements
+type MyBadgesResponse struct {
+	Earned        int                      `json:"earned"`
+	Total         int                      `json:"total"`
+	NewlyAwarded  []*BadgeResponse         `json:"newly_awarded,omitempty"`
+	Badges        []*BadgeProgressResponse `json:"badges"`
+	LongestStreak int64                    `json:"longest_streak"`
+}
+
+// ToBadgeResponse converts entity to response
+func ToBadgeResponse(b *Badge) *BadgeResponse {
+	resp := &BadgeResponse{
+		ID:        b.ID,
+		Code:      b.Code,
+		Name:      b.Name,
+		RuleType:  b.RuleType,
+		Threshold: b.Threshold,
+		IsActive:  b.IsActive,
+	}
+	if b.Description.Valid {
+		resp.Description = b.Description.String
+	}
+	if b.Icon.Valid {
+		resp.Icon = b.Icon.String
+	}
+	if b.MissionType.Valid {
+		resp.MissionType = b.MissionType.String
+	}
+	return resp
+}
+
+// ToUserBadgeResponse converts entity to response
+func ToUserBadgeResponse(ub *UserBadge) *UserBadgeResponse {
+	return &UserBadgeResponse{
+		BadgeResponse: *ToBadgeResponse(&ub.Badge),
+		AwardedAt:     ub.AwardedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,27 @@
+package badge
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Badge entity is an achievement awarded once a rule's threshold is reached
+type Badge struct {
+	ID          uint
+	Code        string
+	Name        string
+	Description sql.NullString
+	Icon        sql.NullString
+	RuleType    string
+	MissionType sql.NullString
+	Threshold   int64
+	IsActive    bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// UserBadge is a badge a user has earned
+type UserBadge struct {
+	Badge
+	AwardedAt time.Time
+}
@@ -0,0 +1,135 @@
+package badge
+
+import (
+	"strconv"
+
+	apperrors "walletpoint/internal/shared/errors"
+	"walletpoint/internal/shared/response"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetCatalog lists the badges that can be earned
+func (h *Handler) GetCatalog(c *fiber.Ctx) error {
+	result, err := h.service.GetCatalog(c.Context())
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Badges retrieved", result)
+}
+
+// GetMyBadges returns the caller's badges and progress, awarding any newly earned
+func (h *Handler) GetMyBadges(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	result, err := h.service.GetMyBadges(c.Context(), userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Badges retrieved", result)
+}
+
+// GetUserBadges returns the badges another user has earned
+func (h *Handler) GetUserBadges(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	result, err := h.service.GetUserBadges(c.Context(), uint(userID))
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Badges retrieved", result)
+}
+
+// GetAll lists every badge including inactive ones (admin)
+func (h *Handler) GetAll(c *fiber.Ctx) error {
+	result, err := h.service.GetAll(c.Context())
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Badges retrieved", result)
+}
+
+// Create defines a new badge (admin)
+func (h *Handler) Create(c *fiber.Ctx) error {
+	var req CreateBadgeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.Create(c.Context(), req)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Created(c, "Badge created successfully", result)
+}
+
+// Update changes a badge (admin)
+func (h *Handler) Update(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid badge ID")
+	}
+
+	var req UpdateBadgeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.Update(c.Context(), uint(id), req)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Badge updated successfully", result)
+}
+
+func handleError(c *fiber.Ctx, err error) error {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		switch appErr.Code {
+		case "NOT_FOUND", "BADGE_NOT_FOUND":
+			return response.NotFound(c, appErr.Message)
+		case "BADGE_EXISTS":
+			return response.Conflict(c, appErr.Message)
+		case "INVALID_RULE":
+			return response.BadRequest(c, appErr.Message)
+		default:
+			return response.InternalError(c, appErr.Message)
+		}
+	}
+	return response.InternalError(c, "Internal server error")
+}
+
+func toResponseErrors(errors []ValidationError) []response.ValidationError {
+	result := make([]response.ValidationError, len(errors))
+	for i, e := range errors {
+		result[i] = response.ValidationError{
+			Field:   e.Field,
+			Message: e.Message,
+		}
+	}
+	return result
+}
@@ -0,0 +1,245 @@
+package badge
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"walletpoint/internal/shared/constants"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const badgeColumns = `
+	b.id, b.code, b.name, b.description, b.icon, b.rule_type, b.mission_type,
+	b.threshold, b.is_active, b.created_at, b.updated_at
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBadge(row rowScanner, extra ...interface{}) (*Badge, error) {
+	var b Badge
+	dest := append([]interface{}{
+		&b.ID, &b.Code, &b.Name, &b.Description, &b.Icon, &b.RuleType, &b.MissionType,
+		&b.Threshold, &b.IsActive, &b.CreatedAt, &b.UpdatedAt,
+	}, extra...)
+	err := row.Scan(dest...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *Repository) Create(ctx context.Context, b *Badge) error {
+	query := `
+		INSERT INTO badges (code, name, description, icon, rule_type, mission_type, threshold, is_active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		b.Code, b.Name, b.Description, b.Icon, b.RuleType, b.MissionType, b.Threshold, b.IsActive,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	b.ID = uint(id)
+	return nil
+}
+
+func (r *Repository) Update(ctx context.Context, b *Badge) error {
+	query := `
+		UPDATE badges
+		SET code = ?, name = ?, description = ?, icon = ?, rule_type = ?, mission_type = ?,
+			threshold = ?, is_active = ?, updated_at = NOW()
+		WHERE id = ?
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		b.Code, b.Name, b.Description, b.Icon, b.RuleType, b.MissionType, b.Threshold, b.IsActive, b.ID,
+	)
+	return err
+}
+
+func (r *Repository) GetByID(ctx context.Context, id uint) (*Badge, error) {
+	query := `SELECT ` + badgeColumns + ` FROM badges b WHERE b.id = ?`
+	return scanBadge(r.db.QueryRowContext(ctx, query, id))
+}
+
+// GetAll lists badges; inactive ones are only included for admins
+func (r *Repository) GetAll(ctx context.Context, includeInactive bool) ([]*Badge, error) {
+	query := `SELECT ` + badgeColumns + ` FROM badges b`
+	if !includeInactive {
+		query += ` WHERE b.is_active = TRUE`
+	}
+	query += ` ORDER BY b.rule_type ASC, b.threshold ASC, b.id ASC`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var badges []*Badge
+	for rows.Next() {
+		b, err := scanBadge(rows)
+		if err != nil {
+			return nil, err
+		}
+		badges = append(badges, b)
+	}
+	return badges, rows.Err()
+}
+
+// GetUserBadges lists the badges a user has earned, newest first
+func (r *Repository) GetUserBadges(ctx context.Context, userID uint) ([]*UserBadge, error) {
+	query := `
+		SELECT ` + badgeColumns + `, ub.awarded_at
+		FROM user_badges ub
+		INNER JOIN badges b ON ub.badge_id = b.id
+		WHERE ub.user_id = ?
+		ORDER BY ub.awarded_at DESC, b.id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var badges []*UserBadge
+	for rows.Next() {
+		var awardedAt time.Time
+		b, err := scanBadge(rows, &awardedAt)
+		if err != nil {
+			return nil, err
+		}
+		badges = append(badges, &UserBadge{Badge: *b, AwardedAt: awardedAt})
+	}
+	return badges, rows.Err()
+}
+
+// Award records a badge for a user; it reports false when they already had it
+func (r *Repository) Award(ctx context.Context, userID, badgeID uint) (bool, error) {
+	query := `INSERT IGNORE INTO user_badges (user_id, badge_id, awarded_at) VALUES (?, ?, NOW())`
+	result, err := r.db.ExecContext(ctx, query, userID, badgeID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetStats gathers everything badge rules are evaluated against
+func (r *Repository) GetStats(ctx context.Context, userID uint) (*Stats, error) {
+	stats := &Stats{CompletedByType: map[string]int64{}}
+
+	query := `
+		SELECT m.mission_type, COUNT(*)
+		FROM mission_logs ml
+		INNER JOIN missions m ON ml.mission_id = m.id
+		WHERE ml.user_id = ? AND ml.status = 'COMPLETED'
+		GROUP BY m.mission_type
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var missionType string
+		var count int64
+		if err := rows.Scan(&missionType, &count); err != nil {
+			return nil, err
+		}
+		stats.CompletedByType[missionType] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	pointsQuery := `
+		SELECT COALESCE(SUM(wl.amount), 0)
+		FROM wallet_ledgers wl
+		INNER JOIN wallets w ON wl.wallet_id = w.id
+		WHERE w.user_id = ? AND wl.entry_type = ? AND wl.reference_type = ?
+	`
+	err = r.db.QueryRowContext(ctx, pointsQuery, userID, constants.LedgerCredit, constants.TxTypeMissionReward).
+		Scan(&stats.PointsEarned)
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := r.getCompletionDays(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	stats.LongestStreak = longestStreak(days)
+
+	return stats, nil
+}
+
+func (r *Repository) getCompletionDays(ctx context.Context, userID uint) ([]time.Time, error) {
+	query := `
+		SELECT DISTINCT DATE(completed_at) AS day
+		FROM mission_logs
+		WHERE user_id = ? AND status = 'COMPLETED' AND completed_at IS NOT NULL
+		ORDER BY day ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []time.Time
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
+
+// GetRecentAchievers lists users who completed a mission since the given
+// time; mission rewards are only paid on completion so this covers every rule
+func (r *Repository) GetRecentAchievers(ctx context.Context, since time.Time) ([]uint, error) {
+	query := `
+		SELECT DISTINCT user_id
+		FROM mission_logs
+		WHERE status = 'COMPLETED' AND completed_at >= ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, rows.Err()
+}
@@ -0,0 +1,20 @@
+package badge
+
+import (
+	"walletpoint/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterRoutes(app fiber.Router, handler *Handler, jwtManager *middleware.JWTManager) {
+	badges := app.Group("/badges", middleware.JWTMiddleware(jwtManager))
+	badges.Get("", handler.GetCatalog)
+	badges.Get("/my", handler.GetMyBadges)
+	badges.Get("/users/:userId", handler.GetUserBadges)
+
+	// Admin manages the badge catalogue
+	admin := app.Group("/admin/badges", middleware.JWTMiddleware(jwtManager), middleware.RequireAdmin())
+	admin.Get("", handler.GetAll)
+	admin.Post("", handler.Create)
+	admin.Put("/:id", handler.Update)
+}
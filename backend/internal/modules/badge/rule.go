@@ -0,0 +1,75 @@
+package badge
+
+import "time"
+
+// Badge rule types
+const (
+	RuleMissionsCompleted = "MISSIONS_COMPLETED" // optionally limited to one mission_type
+	RulePointsEarned      = "POINTS_EARNED"
+	RuleStreakDays        = "STREAK_DAYS"
+)
+
+var validRules = map[string]bool{RuleMissionsCompleted: true, RulePointsEarned: true, RuleStreakDays: true}
+
+// Stats is what badge rules are evaluated against
+type Stats struct {
+	CompletedByType map[string]int64
+	PointsEarned    int64
+	LongestStreak   int64
+}
+
+func (s *Stats) completed(missionType string) int64 {
+	if missionType != "" {
+		return s.CompletedByType[missionType]
+	}
+	var total int64
+	for _, n := range s.CompletedByType {
+		total += n
+	}
+	return total
+}
+
+// Progress returns how far the user is towards b, capped at its threshold
+func (s *Stats) Progress(b *Badge) int64 {
+	var value int64
+	switch b.RuleType {
+	case RuleMissionsCompleted:
+		value = s.completed(b.MissionType.String)
+	case RulePointsEarned:
+		value = s.PointsEarned
+	case RuleStreakDays:
+		value = s.LongestStreak
+	}
+	if value > b.Threshold {
+		return b.Threshold
+	}
+	return value
+}
+
+// Earned reports whether the user qualifies for b
+func (s *Stats) Earned(b *Badge) bool {
+	return s.Progress(b) >= b.Threshold
+}
+
+// longestStreak returns the longest run of consecutive calendar days in
+// days, which must be sorted ascending and distinct
+func longestStreak(days []time.Time) int64 {
+	var longest, current int64
+	for i, d := range days {
+		if i > 0 && sameDay(days[i-1].AddDate(0, 0, 1), d) {
+			current++
+		} else {
+			current = 1
+		}
+		if current > longest {
+			longest = current
+		}
+	}
+	return longest
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,294 @@
+package badge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"walletpoint/internal/config"
+	"walletpoint/internal/modules/notification"
+	"walletpoint/internal/shared/constants"
+	apperrors "walletpoint/internal/shared/errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type Service struct {
+	repo             *Repository
+	notificationRepo *notification.Repository
+	config           config.BadgeConfig
+
+	// lastRun is when the awarder last swept; zero forces a full sweep
+	lastRun time.Time
+	rescan  chan struct{}
+}
+
+func NewService(repo *Repository, notificationRepo *notification.Repository, cfg config.BadgeConfig) *Service {
+	return &Service{
+		repo:             repo,
+		notificationRepo: notificationRepo,
+		config:           cfg,
+		rescan:           make(chan struct{}, 1),
+	}
+}
+
+// GetCatalog lists the active badges anyone can earn
+func (s *Service) GetCatalog(ctx context.Context) ([]*BadgeResponse, error) {
+	badges, err := s.repo.GetAll(ctx, false)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get badges")
+	}
+
+	result := make([]*BadgeResponse, len(badges))
+	for i, b := range badges {
+		result[i] = ToBadgeResponse(b)
+	}
+	return result, nil
+}
+
+// GetAll lists every badge including inactive ones
+func (s *Service) GetAll(ctx context.Context) ([]*BadgeResponse, error) {
+	badges, err := s.repo.GetAll(ctx, true)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get badges")
+	}
+
+	result := make([]*BadgeResponse, len(badges))
+	for i, b := range badges {
+		result[i] = ToBadgeResponse(b)
+	}
+	return result, nil
+}
+
+// GetMyBadges evaluates the caller's badges and reports progress on each
+func (s *Service) GetMyBadges(ctx context.Context, userID uint) (*MyBadgesResponse, error) {
+	badges, err := s.repo.GetAll(ctx, false)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get badges")
+	}
+
+	stats, err := s.repo.GetStats(ctx, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get achievements")
+	}
+
+	awarded, err := s.award(ctx, userID, badges, stats)
+	if err != nil {
+		return nil, err
+	}
+
+	earned, err := s.repo.GetUserBadges(ctx, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get badges")
+	}
+	awardedAt := make(map[uint]time.Time, len(earned))
+	for _, ub := range earned {
+		awardedAt[ub.ID] = ub.AwardedAt
+	}
+
+	resp := &MyBadgesResponse{
+		Total:         len(badges),
+		Badges:        make([]*BadgeProgressResponse, len(badges)),
+		LongestStreak: stats.LongestStreak,
+	}
+	for i, b := range badges {
+		p := &BadgeProgressResponse{
+			BadgeResponse: *ToBadgeResponse(b),
+			Progress:      stats.Progress(b),
+		}
+		// A badge stays earned even if its threshold is raised later
+		if at, ok := awardedAt[b.ID]; ok {
+			p.Earned = true
+			p.Progress = b.Threshold
+			p.AwardedAt = at.Format(time.RFC3339)
+			resp.Earned++
+		}
+		resp.Badges[i] = p
+	}
+	for _, b := range awarded {
+		resp.NewlyAwarded = append(resp.NewlyAwarded, ToBadgeResponse(b))
+	}
+
+	return resp, nil
+}
+
+// GetUserBadges lists the badges a user has earned, for showing on profiles
+func (s *Service) GetUserBadges(ctx context.Context, userID uint) ([]*UserBadgeResponse, error) {
+	badges, err := s.repo.GetUserBadges(ctx, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get badges")
+	}
+
+	result := make([]*UserBadgeResponse, len(badges))
+	for i, ub := range badges {
+		result[i] = ToUserBadgeResponse(ub)
+	}
+	return result, nil
+}
+
+// Create defines a new badge
+func (s *Service) Create(ctx context.Context, req CreateBadgeRequest) (*BadgeResponse, error) {
+	b := &Badge{
+		Code:        req.Code,
+		Name:        req.Name,
+		Description: nullString(req.Description),
+		Icon:        nullString(req.Icon),
+		RuleType:    req.RuleType,
+		MissionType: nullString(strings.ToUpper(req.MissionType)),
+		Threshold:   req.Threshold,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+	}
+
+	if err := s.repo.Create(ctx, b); err != nil {
+		if isDuplicate(err) {
+			return nil, apperrors.New("BADGE_EXISTS", "A badge with this code already exists")
+		}
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to create badge")
+	}
+
+	s.requestRescan()
+	return ToBadgeResponse(b), nil
+}
+
+// Update changes a badge. Users who already earned it keep it
+func (s *Service) Update(ctx context.Context, id uint, req UpdateBadgeRequest) (*BadgeResponse, error) {
+	b, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get badge")
+	}
+	if b == nil {
+		return nil, apperrors.New("BADGE_NOT_FOUND", "Badge not found")
+	}
+
+	req.apply(b)
+	if errs := validateRule(b.RuleType, b.MissionType.String, b.Threshold); len(errs) > 0 {
+		return nil, apperrors.New("INVALID_RULE", errs[0].Message)
+	}
+
+	if err := s.repo.Update(ctx, b); err != nil {
+		if isDuplicate(err) {
+			return nil, apperrors.New("BADGE_EXISTS", "A badge with this code already exists")
+		}
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to update badge")
+	}
+
+	s.requestRescan()
+	return ToBadgeResponse(b), nil
+}
+
+// Evaluate awards every active badge the user now qualifies for
+func (s *Service) Evaluate(ctx context.Context, userID uint) ([]*Badge, error) {
+	badges, err := s.repo.GetAll(ctx, false)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get badges")
+	}
+
+	stats, err := s.repo.GetStats(ctx, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get achievements")
+	}
+
+	return s.award(ctx, userID, badges, stats)
+}
+
+func (s *Service) award(ctx context.Context, userID uint, badges []*Badge, stats *Stats) ([]*Badge, error) {
+	var awarded []*Badge
+	for _, b := range badges {
+		if !stats.Earned(b) {
+			continue
+		}
+
+		created, err := s.repo.Award(ctx, userID, b.ID)
+		if err != nil {
+			return awarded, apperrors.Wrap(err, "DB_ERROR", "Failed to award badge")
+		}
+		if !created {
+			continue
+		}
+
+		awarded = append(awarded, b)
+		if _, err := s.notificationRepo.Create(ctx, &notification.Notification{
+			UserID:        userID,
+			Type:          constants.NotificationBadgeAwarded,
+			Title:         "Badge earned: " + b.Name,
+			Body:          badgeBody(b),
+			ReferenceType: "BADGE",
+			ReferenceID:   fmt.Sprint(b.ID),
+		}); err != nil {
+			log.Printf("Failed to notify user %d: %v", userID, err)
+		}
+	}
+	return awarded, nil
+}
+
+func badgeBody(b *Badge) string {
+	if b.Description.Valid {
+		return fmt.Sprintf("You earned the \"%s\" badge: %s.", b.Name, b.Description.String)
+	}
+	return fmt.Sprintf("You earned the \"%s\" badge.", b.Name)
+}
+
+// RunAwarder periodically awards badges to users who completed missions since
+// the previous sweep, until ctx is cancelled. The first sweep, and the one after
+// a badge is created or changed, covers every user
+func (s *Service) RunAwarder(ctx context.Context) {
+	ticker := time.NewTicker(s.config.EvalInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.rescan:
+			s.lastRun = time.Time{}
+		case <-ticker.C:
+			s.sweep(ctx)
+		}
+	}
+}
+
+func (s *Service) sweep(ctx context.Context) {
+	// Overlap the window slightly so completions committed during the last
+	// sweep are not missed
+	started := time.Now()
+	since := s.lastRun
+	if !since.IsZero() {
+		since = since.Add(-time.Minute)
+	}
+
+	userIDs, err := s.repo.GetRecentAchievers(ctx, since)
+	if err != nil {
+		log.Printf("Badge awarder: %v", err)
+		return
+	}
+
+	total := 0
+	for _, userID := range userIDs {
+		awarded, err := s.Evaluate(ctx, userID)
+		if err != nil {
+			log.Printf("Badge awarder: user %d: %v", userID, err)
+			return
+		}
+		total += len(awarded)
+	}
+	if total > 0 {
+		log.Printf("Badge awarder: awarded %d badges", total)
+	}
+	s.lastRun = started
+}
+
+// requestRescan makes the next sweep re-evaluate every user
+func (s *Service) requestRescan() {
+	select {
+	case s.rescan <- struct{}{}:
+	default:
+	}
+}
+
+func isDuplicate(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
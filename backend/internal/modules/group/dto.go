@@ -0,0 +1,154 @@
+package group
+
+import (
+	"strings"
+	"time"
+
+	"walletpoint/internal/shared/constants"
+)
+
+// CreateGroupRequest for creating a class group
+type CreateGroupRequest struct {
+	Name        string `json:"name"`
+	Code        string `json:"code"`
+	Kind        string `json:"kind"` // CLASS (default), COHORT or LIST
+	Description string `json:"description"`
+}
+
+func (r *CreateGroupRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		errors = append(errors, ValidationError{Field: "name", Message: "Name is required"})
+	}
+	r.Code = strings.ToUpper(strings.TrimSpace(r.Code))
+	if r.Kind == "" {
+		r.Kind = constants.GroupKindClass
+	}
+	if !validKinds[r.Kind] {
+		errors = append(errors, ValidationError{Field: "kind", Message: "Kind must be CLASS, COHORT or LIST"})
+	}
+	return errors
+}
+
+// UpdateGroupRequest for renaming or describing a class group
+type UpdateGroupRequest struct {
+	Name        *string `json:"name"`
+	Code        *string `json:"code"`
+	Kind        *string `json:"kind"`
+	Description *string `json:"description"`
+}
+
+func (r *UpdateGroupRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Name != nil && strings.TrimSpace(*r.Name) == "" {
+		errors = append(errors, ValidationError{Field: "name", Message: "Name must not be empty"})
+	}
+	if r.Kind != nil && !validKinds[*r.Kind] {
+		errors = append(errors, ValidationError{Field: "kind", Message: "Kind must be CLASS, COHORT or LIST"})
+	}
+	return errors
+}
+
+var validKinds = map[string]bool{constants.GroupKindClass: true, constants.GroupKindCohort: true, constants.GroupKindList: true}
+
+// AddMembersRequest for enrolling students by NIM
+type AddMembersRequest struct {
+	NIMs []string `json:"nims"`
+}
+
+func (r *AddMembersRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if len(r.NIMs) == 0 {
+		errors = append(errors, ValidationError{Field: "nims", Message: "At least one NIM is required"})
+	}
+	if len(r.NIMs) > maxImportRows {
+		errors = append(errors, ValidationError{Field: "nims", Message: "Too many NIMs in one request"})
+	}
+	return errors
+}
+
+// ValidationError for validation
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// GroupResponse for class group details
+type GroupResponse struct {
+	ID          uint              `json:"id"`
+	Name        string            `json:"name"`
+	Code        string            `json:"code,omitempty"`
+	Kind        string            `json:"kind"`
+	Description string            `json:"description,omitempty"`
+	OwnerID     uint              `json:"owner_id"`
+	MemberCount int               `json:"member_count"`
+	Members     []*MemberResponse `json:"members,omitempty"`
+	CreatedAt   string            `json:"created_at"`
+}
+
+// MemberResponse for an enrolled student
+type MemberResponse struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+	NIM      string `json:"nim,omitempty"`
+	AddedAt  string `json:"added_at"`
+}
+
+// Enrollment row statuses
+const (
+	EnrollAdded         = "ADDED"
+	EnrollAlreadyMember = "ALREADY_MEMBER"
+	EnrollNotFound      = "NOT_FOUND"
+	EnrollInvalid       = "INVALID"
+)
+
+// EnrollmentResult reports what happened to each NIM of an add or import
+type EnrollmentResult struct {
+	Added         int              `json:"added"`
+	AlreadyMember int              `json:"already_member"`
+	Failed        int              `json:"failed"`
+	Rows          []*EnrollmentRow `json:"rows"`
+}
+
+// EnrollmentRow is the outcome for one NIM
+type EnrollmentRow struct {
+	Row     int    `json:"row"`
+	NIM     string `json:"nim"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// ToGroupResponse converts entity to response
+func ToGroupResponse(g *Group) GroupResponse {
+	resp := GroupResponse{
+		ID:          g.ID,
+		Name:        g.Name,
+		Kind:        g.Kind,
+		OwnerID:     g.OwnerID,
+		MemberCount: g.MemberCount,
+		CreatedAt:   g.CreatedAt.Format(time.RFC3339),
+	}
+	if g.Code.Valid {
+		resp.Code = g.Code.String
+	}
+	if g.Description.Valid {
+		resp.Description = g.Description.String
+	}
+	return resp
+}
+
+// ToMemberResponse converts entity to response
+func ToMemberResponse(m *Member) *MemberResponse {
+	resp := &MemberResponse{
+		UserID:   m.UserID,
+		Username: m.Username,
+		FullName: m.FullName,
+		AddedAt:  m.AddedAt.Format(time.RFC3339),
+	}
+	if m.NimNip.Valid {
+		resp.NIM = m.NimNip.String
+	}
+	return resp
+}
@@ -0,0 +1,36 @@
+package group
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Group entity: a class, cohort or enrollment list owned by a lecturer
+type Group struct {
+	ID          uint
+	Name        string
+	Code        sql.NullString
+	Kind        string // CLASS, COHORT, LIST
+	Description sql.NullString
+	OwnerID     uint
+	MemberCount int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   sql.NullTime
+}
+
+// Member is a student enrolled in a group
+type Member struct {
+	UserID   uint
+	Username string
+	FullName string
+	NimNip   sql.NullString
+	AddedAt  time.Time
+}
+
+// Student is a mahasiswa account looked up by NIM
+type Student struct {
+	ID       uint
+	FullName string
+	NimNip   string
+}
@@ -0,0 +1,237 @@
+package group
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+
+	apperrors "walletpoint/internal/shared/errors"
+	"walletpoint/internal/shared/response"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Create creates a new class group
+func (h *Handler) Create(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req CreateGroupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.Create(c.Context(), req, userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Created(c, "Group created successfully", result)
+}
+
+// GetMyGroups lists groups owned by the lecturer
+func (h *Handler) GetMyGroups(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	perPage, _ := strconv.Atoi(c.Query("per_page", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	groups, total, err := h.service.GetMyGroups(c.Context(), userID, page, perPage)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	totalPages := (total + perPage - 1) / perPage
+
+	return response.SuccessWithMeta(c, "Groups retrieved", groups, &response.Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// GetByID returns a group with its members
+func (h *Handler) GetByID(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	role := c.Locals("role").(string)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid group ID")
+	}
+
+	result, err := h.service.GetByID(c.Context(), uint(id), userID, role)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Group retrieved", result)
+}
+
+// Update updates a group
+func (h *Handler) Update(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	role := c.Locals("role").(string)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid group ID")
+	}
+
+	var req UpdateGroupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.Update(c.Context(), uint(id), req, userID, role)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Group updated successfully", result)
+}
+
+// Delete deletes a group
+func (h *Handler) Delete(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	role := c.Locals("role").(string)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid group ID")
+	}
+
+	if err := h.service.Delete(c.Context(), uint(id), userID, role); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Group deleted successfully", nil)
+}
+
+// AddMembers enrolls students by NIM
+func (h *Handler) AddMembers(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	role := c.Locals("role").(string)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid group ID")
+	}
+
+	var req AddMembersRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.AddMembers(c.Context(), uint(id), req, userID, role)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Members processed", result)
+}
+
+// ImportMembers enrolls students from a CSV file, sent either as the "file"
+// field of a multipart form or as a text/csv body
+func (h *Handler) ImportMembers(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	role := c.Locals("role").(string)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid group ID")
+	}
+
+	var body io.Reader
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return response.BadRequest(c, "CSV file is required")
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return response.BadRequest(c, "Failed to read CSV file")
+		}
+		defer f.Close()
+		body = f
+	} else {
+		body = bytes.NewReader(c.Body())
+	}
+
+	result, err := h.service.ImportMembersCSV(c.Context(), uint(id), body, userID, role)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Members imported", result)
+}
+
+// RemoveMember removes a student from a group
+func (h *Handler) RemoveMember(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	role := c.Locals("role").(string)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid group ID")
+	}
+	memberID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	if err := h.service.RemoveMember(c.Context(), uint(id), uint(memberID), userID, role); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Member removed", nil)
+}
+
+func handleError(c *fiber.Ctx, err error) error {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		switch appErr.Code {
+		case "NOT_FOUND", "GROUP_NOT_FOUND":
+			return response.NotFound(c, appErr.Message)
+		case "FORBIDDEN":
+			return response.Forbidden(c, appErr.Message)
+		case "INVALID_CSV":
+			return response.BadRequest(c, appErr.Message)
+		default:
+			return response.InternalError(c, appErr.Message)
+		}
+	}
+	return response.InternalError(c, "Internal server error")
+}
+
+func toResponseErrors(errors []ValidationError) []response.ValidationError {
+	result := make([]response.ValidationError, len(errors))
+	for i, e := range errors {
+		result[i] = response.ValidationError{
+			Field:   e.Field,
+			Message: e.Message,
+		}
+	}
+	return result
+}
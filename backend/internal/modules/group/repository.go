@@ -0,0 +1,219 @@
+package group
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const groupColumns = `
+	g.id, g.name, g.code, g.kind, g.description, g.owner_id,
+	(SELECT COUNT(*) FROM class_group_members gm WHERE gm.group_id = g.id),
+	g.created_at, g.updated_at
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanGroup(row rowScanner) (*Group, error) {
+	var g Group
+	err := row.Scan(
+		&g.ID, &g.Name, &g.Code, &g.Kind, &g.Description, &g.OwnerID,
+		&g.MemberCount, &g.CreatedAt, &g.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (r *Repository) Create(ctx context.Context, g *Group) error {
+	query := `
+		INSERT INTO class_groups (name, code, kind, description, owner_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, NOW(), NOW())
+	`
+
+	result, err := r.db.ExecContext(ctx, query, g.Name, g.Code, g.Kind, g.Description, g.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	g.ID = uint(id)
+	return nil
+}
+
+func (r *Repository) GetByID(ctx context.Context, id uint) (*Group, error) {
+	query := `SELECT ` + groupColumns + ` FROM class_groups g WHERE g.id = ? AND g.deleted_at IS NULL`
+	return scanGroup(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *Repository) GetByOwnerID(ctx context.Context, ownerID uint, limit, offset int) ([]*Group, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM class_groups WHERE owner_id = ? AND deleted_at IS NULL`
+	if err := r.db.QueryRowContext(ctx, countQuery, ownerID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT ` + groupColumns + `
+		FROM class_groups g
+		WHERE g.owner_id = ? AND g.deleted_at IS NULL
+		ORDER BY g.name ASC
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, ownerID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var groups []*Group
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		groups = append(groups, g)
+	}
+
+	return groups, total, nil
+}
+
+func (r *Repository) Update(ctx context.Context, g *Group) error {
+	query := `UPDATE class_groups SET name = ?, code = ?, kind = ?, description = ?, updated_at = NOW() WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, g.Name, g.Code, g.Kind, g.Description, g.ID)
+	return err
+}
+
+// Delete soft-deletes a group and drops it from mission targeting, so missions
+// aimed only at it become open to everyone rather than to no one
+func (r *Repository) Delete(ctx context.Context, id uint) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE class_groups SET deleted_at = NOW() WHERE id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mission_target_groups WHERE group_id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repository) GetMembers(ctx context.Context, groupID uint) ([]*Member, error) {
+	query := `
+		SELECT u.id, u.username, u.full_name, u.nim_nip, gm.created_at
+		FROM class_group_members gm
+		INNER JOIN users u ON gm.user_id = u.id
+		WHERE gm.group_id = ? AND u.deleted_at IS NULL
+		ORDER BY u.nim_nip ASC, u.full_name ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*Member
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.UserID, &m.Username, &m.FullName, &m.NimNip, &m.AddedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, &m)
+	}
+
+	return members, nil
+}
+
+// AddMember enrolls a student; it reports false when they were already a member
+func (r *Repository) AddMember(ctx context.Context, groupID, userID, addedBy uint) (bool, error) {
+	query := `
+		INSERT IGNORE INTO class_group_members (group_id, user_id, added_by, created_at)
+		VALUES (?, ?, ?, NOW())
+	`
+	result, err := r.db.ExecContext(ctx, query, groupID, userID, addedBy)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *Repository) RemoveMember(ctx context.Context, groupID, userID uint) (bool, error) {
+	query := `DELETE FROM class_group_members WHERE group_id = ? AND user_id = ?`
+	result, err := r.db.ExecContext(ctx, query, groupID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *Repository) IsMember(ctx context.Context, groupID, userID uint) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM class_group_members WHERE group_id = ? AND user_id = ?)`
+	err := r.db.QueryRowContext(ctx, query, groupID, userID).Scan(&exists)
+	return exists, err
+}
+
+// GetStudentsByNIM looks up active mahasiswa accounts, keyed by NIM
+func (r *Repository) GetStudentsByNIM(ctx context.Context, nims []string) (map[string]*Student, error) {
+	students := map[string]*Student{}
+	if len(nims) == 0 {
+		return students, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(nims)), ", ")
+	query := `
+		SELECT u.id, u.full_name, u.nim_nip
+		FROM users u
+		INNER JOIN user_roles ur ON u.id = ur.user_id
+		INNER JOIN roles ro ON ur.role_id = ro.id
+		WHERE u.nim_nip IN (` + placeholders + `)
+			AND ro.name = 'mahasiswa' AND u.is_active = TRUE AND u.deleted_at IS NULL
+	`
+
+	args := make([]interface{}, len(nims))
+	for i, nim := range nims {
+		args[i] = nim
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s Student
+		if err := rows.Scan(&s.ID, &s.FullName, &s.NimNip); err != nil {
+			return nil, err
+		}
+		students[s.NimNip] = &s
+	}
+
+	return students, nil
+}
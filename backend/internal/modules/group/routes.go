@@ -0,0 +1,20 @@
+package group
+
+import (
+	"walletpoint/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterRoutes(app fiber.Router, handler *Handler, jwtManager *middleware.JWTManager) {
+	// Lecturers manage their own classes; admins can manage any group
+	groups := app.Group("/groups", middleware.JWTMiddleware(jwtManager), middleware.RequireRole("dosen", "admin"))
+	groups.Get("", handler.GetMyGroups)
+	groups.Post("", handler.Create)
+	groups.Get("/:id", handler.GetByID)
+	groups.Put("/:id", handler.Update)
+	groups.Delete("/:id", handler.Delete)
+	groups.Post("/:id/members", handler.AddMembers)
+	groups.Post("/:id/members/import", handler.ImportMembers)
+	groups.Delete("/:id/members/:userId", handler.RemoveMember)
+}
@@ -0,0 +1,276 @@
+package group
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"walletpoint/internal/shared/constants"
+	apperrors "walletpoint/internal/shared/errors"
+)
+
+// maxImportRows bounds one add or CSV import
+const maxImportRows = 1000
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) Create(ctx context.Context, req CreateGroupRequest, ownerID uint) (*GroupResponse, error) {
+	g := &Group{
+		Name:        req.Name,
+		Code:        sql.NullString{String: req.Code, Valid: req.Code != ""},
+		Kind:        req.Kind,
+		Description: sql.NullString{String: req.Description, Valid: req.Description != ""},
+		OwnerID:     ownerID,
+	}
+
+	if err := s.repo.Create(ctx, g); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to create group")
+	}
+
+	created, err := s.repo.GetByID(ctx, g.ID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get group")
+	}
+	resp := ToGroupResponse(created)
+	return &resp, nil
+}
+
+func (s *Service) GetMyGroups(ctx context.Context, ownerID uint, page, perPage int) ([]*GroupResponse, int, error) {
+	offset := (page - 1) * perPage
+	groups, total, err := s.repo.GetByOwnerID(ctx, ownerID, perPage, offset)
+	if err != nil {
+		return nil, 0, apperrors.Wrap(err, "DB_ERROR", "Failed to get groups")
+	}
+
+	responses := []*GroupResponse{}
+	for _, g := range groups {
+		resp := ToGroupResponse(g)
+		responses = append(responses, &resp)
+	}
+	return responses, total, nil
+}
+
+// GetByID returns a group with its members
+func (s *Service) GetByID(ctx context.Context, id, userID uint, role string) (*GroupResponse, error) {
+	g, err := s.getOwned(ctx, id, userID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.repo.GetMembers(ctx, id)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get members")
+	}
+
+	resp := ToGroupResponse(g)
+	resp.Members = []*MemberResponse{}
+	for _, m := range members {
+		resp.Members = append(resp.Members, ToMemberResponse(m))
+	}
+	return &resp, nil
+}
+
+func (s *Service) Update(ctx context.Context, id uint, req UpdateGroupRequest, userID uint, role string) (*GroupResponse, error) {
+	g, err := s.getOwned(ctx, id, userID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		g.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Code != nil {
+		code := strings.ToUpper(strings.TrimSpace(*req.Code))
+		g.Code = sql.NullString{String: code, Valid: code != ""}
+	}
+	if req.Kind != nil {
+		g.Kind = *req.Kind
+	}
+	if req.Description != nil {
+		g.Description = sql.NullString{String: *req.Description, Valid: *req.Description != ""}
+	}
+
+	if err := s.repo.Update(ctx, g); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to update group")
+	}
+
+	resp := ToGroupResponse(g)
+	return &resp, nil
+}
+
+func (s *Service) Delete(ctx context.Context, id, userID uint, role string) error {
+	if _, err := s.getOwned(ctx, id, userID, role); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to delete group")
+	}
+	return nil
+}
+
+// AddMembers enrolls students by NIM
+func (s *Service) AddMembers(ctx context.Context, id uint, req AddMembersRequest, userID uint, role string) (*EnrollmentResult, error) {
+	if _, err := s.getOwned(ctx, id, userID, role); err != nil {
+		return nil, err
+	}
+
+	rows := make([]*EnrollmentRow, len(req.NIMs))
+	for i, nim := range req.NIMs {
+		rows[i] = &EnrollmentRow{Row: i + 1, NIM: strings.TrimSpace(nim)}
+	}
+	return s.enroll(ctx, id, userID, rows)
+}
+
+// ImportMembersCSV enrolls the students listed in a CSV file. The NIM is taken
+// from a "nim" column when the file has a header row, otherwise from the first column.
+func (s *Service) ImportMembersCSV(ctx context.Context, id uint, r io.Reader, userID uint, role string) (*EnrollmentResult, error) {
+	if _, err := s.getOwned(ctx, id, userID, role); err != nil {
+		return nil, err
+	}
+
+	rows, err := parseMemberCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.enroll(ctx, id, userID, rows)
+}
+
+func (s *Service) RemoveMember(ctx context.Context, id, memberID, userID uint, role string) error {
+	if _, err := s.getOwned(ctx, id, userID, role); err != nil {
+		return err
+	}
+
+	removed, err := s.repo.RemoveMember(ctx, id, memberID)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to remove member")
+	}
+	if !removed {
+		return apperrors.New("NOT_FOUND", "Student is not a member of this group")
+	}
+	return nil
+}
+
+// enroll resolves each row's NIM to a student and adds them to the group
+func (s *Service) enroll(ctx context.Context, groupID, addedBy uint, rows []*EnrollmentRow) (*EnrollmentResult, error) {
+	var nims []string
+	for _, row := range rows {
+		if row.NIM != "" {
+			nims = append(nims, row.NIM)
+		}
+	}
+
+	students, err := s.repo.GetStudentsByNIM(ctx, nims)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to look up students")
+	}
+
+	result := &EnrollmentResult{Rows: rows}
+	for _, row := range rows {
+		student := students[row.NIM]
+		switch {
+		case row.NIM == "":
+			row.Status, row.Message = EnrollInvalid, "NIM is empty"
+		case student == nil:
+			row.Status, row.Message = EnrollNotFound, "No active student with this NIM"
+		default:
+			added, err := s.repo.AddMember(ctx, groupID, student.ID, addedBy)
+			if err != nil {
+				return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to add member")
+			}
+			row.Status, row.Message = EnrollAlreadyMember, student.FullName
+			if added {
+				row.Status = EnrollAdded
+			}
+		}
+
+		switch row.Status {
+		case EnrollAdded:
+			result.Added++
+		case EnrollAlreadyMember:
+			result.AlreadyMember++
+		default:
+			result.Failed++
+		}
+	}
+
+	return result, nil
+}
+
+// parseMemberCSV reads one NIM per record
+func parseMemberCSV(r io.Reader) ([]*EnrollmentRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows []*EnrollmentRow
+	column := 0
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, apperrors.New("INVALID_CSV", fmt.Sprintf("Line %d: %v", line, err))
+		}
+
+		if line == 1 {
+			if idx := headerColumn(record); idx >= 0 {
+				column = idx
+				continue
+			}
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		nim := ""
+		if column < len(record) {
+			nim = strings.TrimSpace(record[column])
+		}
+		rows = append(rows, &EnrollmentRow{Row: line, NIM: nim})
+		if len(rows) > maxImportRows {
+			return nil, apperrors.New("INVALID_CSV", fmt.Sprintf("At most %d students can be imported at once", maxImportRows))
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil, apperrors.New("INVALID_CSV", "CSV file has no students")
+	}
+	return rows, nil
+}
+
+// headerColumn returns the NIM column of a header row, or -1 when the record is data
+func headerColumn(record []string) int {
+	for i, cell := range record {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(cell, "\ufeff"))) {
+		case "nim", "nim_nip", "nimnip":
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Service) getOwned(ctx context.Context, id, userID uint, role string) (*Group, error) {
+	g, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get group")
+	}
+	if g == nil {
+		return nil, apperrors.New("GROUP_NOT_FOUND", "Group not found")
+	}
+	if role != constants.RoleAdmin && g.OwnerID != userID {
+		return nil, apperrors.ErrForbidden
+	}
+	return g, nil
+}
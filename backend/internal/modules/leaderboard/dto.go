@@ -0,0 +1,118 @@
+package leaderboard
+
+import (
+	"strings"
+
+	"walletpoint/internal/shared/constants"
+)
+
+// Leaderboard metrics
+const (
+	MetricPoints   = "POINTS"   // mission reward points credited
+	MetricMissions = "MISSIONS" // missions completed
+)
+
+// Leaderboard periods
+const (
+	PeriodAll   = "ALL"
+	PeriodDay   = "DAY"
+	PeriodWeek  = "WEEK"
+	PeriodMonth = "MONTH"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 100
+)
+
+// LeaderboardQuery selects what is ranked and over which students
+type LeaderboardQuery struct {
+	Metric      string `query:"metric"`       // POINTS (default) or MISSIONS
+	Period      string `query:"period"`       // ALL (default), DAY, WEEK or MONTH
+	GroupID     uint   `query:"group_id"`     // rank only members of a class group
+	MissionType string `query:"mission_type"` // rank only one kind of mission
+	Limit       int    `query:"limit"`
+}
+
+func (q *LeaderboardQuery) Validate() []ValidationError {
+	var errors []ValidationError
+	q.Metric = strings.ToUpper(strings.TrimSpace(q.Metric))
+	if q.Metric == "" {
+		q.Metric = MetricPoints
+	}
+	if q.Metric != MetricPoints && q.Metric != MetricMissions {
+		errors = append(errors, ValidationError{Field: "metric", Message: "Metric must be POINTS or MISSIONS"})
+	}
+
+	q.Period = strings.ToUpper(strings.TrimSpace(q.Period))
+	if q.Period == "" {
+		q.Period = PeriodAll
+	}
+	if !validPeriods[q.Period] {
+		errors = append(errors, ValidationError{Field: "period", Message: "Period must be ALL, DAY, WEEK or MONTH"})
+	}
+
+	q.MissionType = strings.ToUpper(strings.TrimSpace(q.MissionType))
+	if q.MissionType != "" && !validMissionTypes[q.MissionType] {
+		errors = append(errors, ValidationError{Field: "mission_type", Message: "Invalid mission type"})
+	}
+
+	if q.Limit < 1 || q.Limit > maxLimit {
+		q.Limit = defaultLimit
+	}
+	return errors
+}
+
+var validPeriods = map[string]bool{PeriodAll: true, PeriodDay: true, PeriodWeek: true, PeriodMonth: true}
+
+var validMissionTypes = map[string]bool{
+	constants.MissionTypeQuiz:       true,
+	constants.MissionTypeAssignment: true,
+	constants.MissionTypeAttendance: true,
+	constants.MissionTypeProject:    true,
+	constants.MissionTypeOther:      true,
+}
+
+// ValidationError for validation
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// LeaderboardResponse is a ranked slice of students plus the caller's position
+type LeaderboardResponse struct {
+	Metric      string           `json:"metric"`
+	Period      string           `json:"period"`
+	Since       string           `json:"since,omitempty"`
+	GroupID     uint             `json:"group_id,omitempty"`
+	MissionType string           `json:"mission_type,omitempty"`
+	Total       int              `json:"total"`
+	Entries     []*EntryResponse `json:"entries"`
+	Me          *EntryResponse   `json:"me,omitempty"`
+}
+
+// EntryResponse is one ranked student. Equal scores share a rank and the
+// next rank is skipped (1, 2, 2, 4)
+type EntryResponse struct {
+	Rank     int    `json:"rank"`
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username,omitempty"`
+	FullName string `json:"full_name,omitempty"`
+	NIM      string `json:"nim,omitempty"`
+	Value    int64  `json:"value"`
+}
+
+// ToEntryResponse converts entity to response
+func ToEntryResponse(e *Entry, rank int) *EntryResponse {
+	resp := &EntryResponse{
+		Rank:     rank,
+		UserID:   e.UserID,
+		Username: e.Username,
+		FullName: e.FullName,
+		Value:    e.Value,
+	}
+	if e.NimNip.Valid {
+		resp.NIM = e.NimNip.String
+	}
+	return resp
+}
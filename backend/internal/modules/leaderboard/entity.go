@@ -0,0 +1,12 @@
+package leaderboard
+
+import "database/sql"
+
+// Entry is one student's aggregated score
+type Entry struct {
+	UserID   uint
+	Username string
+	FullName string
+	NimNip   sql.NullString
+	Value    int64
+}
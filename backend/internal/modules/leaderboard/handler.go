@@ -0,0 +1,63 @@
+package leaderboard
+
+import (
+	apperrors "walletpoint/internal/shared/errors"
+	"walletpoint/internal/shared/response"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetLeaderboard ranks students by points earned or missions completed
+func (h *Handler) GetLeaderboard(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	role := c.Locals("role").(string)
+
+	var q LeaderboardQuery
+	if err := c.QueryParser(&q); err != nil {
+		return response.BadRequest(c, "Invalid query parameters")
+	}
+
+	if errors := q.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.GetLeaderboard(c.Context(), q, userID, role)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Leaderboard retrieved", result)
+}
+
+func handleError(c *fiber.Ctx, err error) error {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		switch appErr.Code {
+		case "NOT_FOUND", "GROUP_NOT_FOUND":
+			return response.NotFound(c, appErr.Message)
+		case "FORBIDDEN":
+			return response.Forbidden(c, appErr.Message)
+		default:
+			return response.InternalError(c, appErr.Message)
+		}
+	}
+	return response.InternalError(c, "Internal server error")
+}
+
+func toResponseErrors(errors []ValidationError) []response.ValidationError {
+	result := make([]response.ValidationError, len(errors))
+	for i, e := range errors {
+		result[i] = response.ValidationError{
+			Field:   e.Field,
+			Message: e.Message,
+		}
+	}
+	return result
+}
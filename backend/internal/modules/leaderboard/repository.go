@@ -0,0 +1,149 @@
+package leaderboard
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"walletpoint/internal/shared/constants"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Filter narrows a leaderboard to a metric, period and scope
+type Filter struct {
+	Metric      string
+	Since       time.Time // zero for all time
+	GroupID     uint      // zero for every student
+	MissionType string
+}
+
+// studentFilter keeps lecturers and admins who also hold wallets off the board
+const studentFilter = `
+	AND u.deleted_at IS NULL
+	AND EXISTS (
+		SELECT 1 FROM user_roles ur
+		INNER JOIN roles ro ON ur.role_id = ro.id
+		WHERE ur.user_id = u.id AND ro.name = 'mahasiswa'
+	)
+`
+
+// aggregate builds the per-student totals for f, one row per user with a
+// non-zero value
+func aggregate(f Filter) (string, []interface{}) {
+	var query string
+	var args []interface{}
+
+	switch f.Metric {
+	case MetricMissions:
+		query = `
+			SELECT u.id, u.username, u.full_name, u.nim_nip, COUNT(*) AS value
+			FROM mission_logs ml
+			INNER JOIN users u ON ml.user_id = u.id
+			INNER JOIN missions m ON ml.mission_id = m.id
+		`
+	default:
+		// Only mission rewards count as earned points; transfers and
+		// top-ups would otherwise let students buy their way up
+		query = `
+			SELECT u.id, u.username, u.full_name, u.nim_nip, SUM(wl.amount) AS value
+			FROM wallet_ledgers wl
+			INNER JOIN wallets w ON wl.wallet_id = w.id
+			INNER JOIN users u ON w.user_id = u.id
+		`
+		if f.MissionType != "" {
+			query += `
+			INNER JOIN transactions t ON wl.transaction_id = t.id
+			INNER JOIN mission_logs ml ON t.mission_log_id = ml.id
+			INNER JOIN missions m ON ml.mission_id = m.id
+			`
+		}
+	}
+
+	if f.GroupID != 0 {
+		query += ` INNER JOIN class_group_members gm ON gm.user_id = u.id AND gm.group_id = ?`
+		args = append(args, f.GroupID)
+	}
+
+	switch f.Metric {
+	case MetricMissions:
+		query += ` WHERE ml.status = 'COMPLETED'`
+		if !f.Since.IsZero() {
+			query += ` AND ml.completed_at >= ?`
+			args = append(args, f.Since)
+		}
+	default:
+		query += ` WHERE wl.entry_type = ? AND wl.reference_type = ?`
+		args = append(args, constants.LedgerCredit, constants.TxTypeMissionReward)
+		if !f.Since.IsZero() {
+			query += ` AND wl.created_at >= ?`
+			args = append(args, f.Since)
+		}
+	}
+
+	if f.MissionType != "" {
+		query += ` AND m.mission_type = ?`
+		args = append(args, f.MissionType)
+	}
+
+	query += studentFilter + ` GROUP BY u.id, u.username, u.full_name, u.nim_nip`
+	return query, args
+}
+
+// GetTop returns the highest scoring students, ties broken by name
+func (r *Repository) GetTop(ctx context.Context, f Filter, limit int) ([]*Entry, error) {
+	agg, args := aggregate(f)
+	query := agg + ` ORDER BY value DESC, u.full_name ASC LIMIT ?`
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.UserID, &e.Username, &e.FullName, &e.NimNip, &e.Value); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
+
+// GetUserValue returns the user's score, zero when they have none
+func (r *Repository) GetUserValue(ctx context.Context, f Filter, userID uint) (int64, error) {
+	agg, args := aggregate(f)
+	query := `SELECT COALESCE(SUM(t.value), 0) FROM (` + agg + `) t WHERE t.id = ?`
+
+	var value int64
+	err := r.db.QueryRowContext(ctx, query, append(args, userID)...).Scan(&value)
+	return value, err
+}
+
+// CountAbove counts students scoring strictly more than value
+func (r *Repository) CountAbove(ctx context.Context, f Filter, value int64) (int, error) {
+	agg, args := aggregate(f)
+	query := `SELECT COUNT(*) FROM (` + agg + `) t WHERE t.value > ?`
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query, append(args, value)...).Scan(&count)
+	return count, err
+}
+
+// CountRanked counts students with a non-zero score
+func (r *Repository) CountRanked(ctx context.Context, f Filter) (int, error) {
+	agg, args := aggregate(f)
+	query := `SELECT COUNT(*) FROM (` + agg + `) t`
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	return count, err
+}
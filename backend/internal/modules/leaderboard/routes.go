@@ -0,0 +1,12 @@
+package leaderboard
+
+import (
+	"walletpoint/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterRoutes(app fiber.Router, handler *Handler, jwtManager *middleware.JWTManager) {
+	leaderboards := app.Group("/leaderboards", middleware.JWTMiddleware(jwtManager))
+	leaderboards.Get("", handler.GetLeaderboard)
+}
@@ -0,0 +1,135 @@
+package leaderboard
+
+import (
+	"context"
+	"time"
+
+	"walletpoint/internal/modules/group"
+	"walletpoint/internal/shared/constants"
+	apperrors "walletpoint/internal/shared/errors"
+)
+
+type Service struct {
+	repo      *Repository
+	groupRepo *group.Repository
+}
+
+func NewService(repo *Repository, groupRepo *group.Repository) *Service {
+	return &Service{repo: repo, groupRepo: groupRepo}
+}
+
+// GetLeaderboard ranks students by q. Class boards are visible to the class
+// owner, its members and admins
+func (s *Service) GetLeaderboard(ctx context.Context, q LeaderboardQuery, userID uint, role string) (*LeaderboardResponse, error) {
+	if q.GroupID != 0 {
+		if err := s.checkGroupAccess(ctx, q.GroupID, userID, role); err != nil {
+			return nil, err
+		}
+	}
+
+	f := Filter{
+		Metric:      q.Metric,
+		Since:       periodStart(q.Period, time.Now()),
+		GroupID:     q.GroupID,
+		MissionType: q.MissionType,
+	}
+
+	entries, err := s.repo.GetTop(ctx, f, q.Limit)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get leaderboard")
+	}
+
+	total, err := s.repo.CountRanked(ctx, f)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get leaderboard")
+	}
+
+	resp := &LeaderboardResponse{
+		Metric:      q.Metric,
+		Period:      q.Period,
+		GroupID:     q.GroupID,
+		MissionType: q.MissionType,
+		Total:       total,
+		Entries:     make([]*EntryResponse, 0, len(entries)),
+	}
+	if !f.Since.IsZero() {
+		resp.Since = f.Since.Format(time.RFC3339)
+	}
+
+	rank := 0
+	for i, e := range entries {
+		if i == 0 || e.Value != entries[i-1].Value {
+			rank = i + 1
+		}
+		entry := ToEntryResponse(e, rank)
+		resp.Entries = append(resp.Entries, entry)
+		if e.UserID == userID {
+			resp.Me = entry
+		}
+	}
+
+	if resp.Me == nil && role == constants.RoleMahasiswa {
+		me, err := s.rankUser(ctx, f, userID)
+		if err != nil {
+			return nil, err
+		}
+		resp.Me = me
+	}
+
+	return resp, nil
+}
+
+// rankUser places a student who is not in the top slice. Students without
+// a score are left unranked
+func (s *Service) rankUser(ctx context.Context, f Filter, userID uint) (*EntryResponse, error) {
+	value, err := s.repo.GetUserValue(ctx, f, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get rank")
+	}
+	if value == 0 {
+		return &EntryResponse{UserID: userID}, nil
+	}
+
+	above, err := s.repo.CountAbove(ctx, f, value)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get rank")
+	}
+	return &EntryResponse{Rank: above + 1, UserID: userID, Value: value}, nil
+}
+
+func (s *Service) checkGroupAccess(ctx context.Context, groupID, userID uint, role string) error {
+	g, err := s.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to get group")
+	}
+	if g == nil {
+		return apperrors.New("GROUP_NOT_FOUND", "Group not found")
+	}
+	if role == constants.RoleAdmin || g.OwnerID == userID {
+		return nil
+	}
+
+	member, err := s.groupRepo.IsMember(ctx, groupID, userID)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to check group membership")
+	}
+	if !member {
+		return apperrors.ErrForbidden
+	}
+	return nil
+}
+
+// periodStart returns the start of the current day, week (from Monday) or
+// month, or the zero time for all-time boards
+func periodStart(period string, now time.Time) time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch period {
+	case PeriodDay:
+		return day
+	case PeriodWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case PeriodMonth:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}
+	return time.Time{}
+}
@@ -0,0 +1,51 @@
+package lockout
+
+import "time"
+
+// Actor identifies the administrator clearing a lockout, for the audit trail
+type Actor struct {
+	UserID    uint
+	IPAddress string
+	UserAgent string
+}
+
+// FailureResponse for the admin lockout list
+type FailureResponse struct {
+	ID            uint    `json:"id"`
+	Scope         string  `json:"scope"`
+	Subject       string  `json:"subject"`
+	FailedCount   int     `json:"failed_count"`
+	Locked        bool    `json:"locked"`
+	LockedUntil   *string `json:"locked_until,omitempty"`
+	LockoutCount  int     `json:"lockout_count"`
+	LastFailedAt  *string `json:"last_failed_at,omitempty"`
+	LastIP        *string `json:"last_ip,omitempty"`
+	LastUserAgent *string `json:"last_user_agent,omitempty"`
+}
+
+// ToFailureResponse converts Failure to FailureResponse
+func ToFailureResponse(f *Failure, now time.Time) *FailureResponse {
+	resp := &FailureResponse{
+		ID:           f.ID,
+		Scope:        f.Scope,
+		Subject:      f.Subject,
+		FailedCount:  f.FailedCount,
+		Locked:       f.Locked(now),
+		LockoutCount: f.LockoutCount,
+	}
+	if f.LockedUntil.Valid {
+		t := f.LockedUntil.Time.Format(time.RFC3339)
+		resp.LockedUntil = &t
+	}
+	if f.LastFailedAt.Valid {
+		t := f.LastFailedAt.Time.Format(time.RFC3339)
+		resp.LastFailedAt = &t
+	}
+	if f.LastIP.Valid {
+		resp.LastIP = &f.LastIP.String
+	}
+	if f.LastUserAgent.Valid {
+		resp.LastUserAgent = &f.LastUserAgent.String
+	}
+	return resp
+}
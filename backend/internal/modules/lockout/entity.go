@@ -0,0 +1,33 @@
+package lockout
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Failure scopes
+const (
+	ScopeUsername = "USERNAME"
+	ScopeIP       = "IP"
+)
+
+// Failure entity counts recent failed logins for a username or client IP
+type Failure struct {
+	ID            uint
+	Scope         string
+	Subject       string
+	FailedCount   int
+	FirstFailedAt sql.NullTime
+	LastFailedAt  sql.NullTime
+	LockedUntil   sql.NullTime
+	LockoutCount  int
+	LastIP        sql.NullString
+	LastUserAgent sql.NullString
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Locked reports whether logins for the subject are refused at now
+func (f *Failure) Locked(now time.Time) bool {
+	return f.LockedUntil.Valid && now.Before(f.LockedUntil.Time)
+}
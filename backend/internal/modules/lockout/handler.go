@@ -0,0 +1,86 @@
+package lockout
+
+import (
+	"strconv"
+	"strings"
+
+	apperrors "walletpoint/internal/shared/errors"
+	"walletpoint/internal/shared/response"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// List shows tracked usernames and IPs with their failures and lockouts
+func (h *Handler) List(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	perPage, _ := strconv.Atoi(c.Query("per_page", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	filter := ListFilter{
+		Scope:      strings.ToUpper(c.Query("scope")),
+		Search:     strings.TrimSpace(c.Query("q")),
+		LockedOnly: c.QueryBool("locked"),
+	}
+	if filter.Scope != "" && filter.Scope != ScopeUsername && filter.Scope != ScopeIP {
+		return response.BadRequest(c, "Scope must be USERNAME or IP")
+	}
+
+	failures, total, err := h.service.List(c.Context(), filter, page, perPage)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	totalPages := (total + perPage - 1) / perPage
+
+	return response.SuccessWithMeta(c, "Login lockouts retrieved", failures, &response.Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// Clear lifts a lockout
+func (h *Handler) Clear(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid lockout ID")
+	}
+
+	actor := Actor{
+		UserID:    c.Locals("userID").(uint),
+		IPAddress: c.IP(),
+		UserAgent: c.Get("User-Agent"),
+	}
+	if err := h.service.Clear(c.Context(), uint(id), actor); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Lockout cleared", nil)
+}
+
+func handleError(c *fiber.Ctx, err error) error {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		switch appErr.Code {
+		case "NOT_FOUND":
+			return response.NotFound(c, appErr.Message)
+		default:
+			return response.InternalError(c, appErr.Message)
+		}
+	}
+	return response.InternalError(c, "Internal server error")
+}
@@ -0,0 +1,183 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type RepositoryInterface interface {
+	Get(ctx context.Context, scope, subject string) (*Failure, error)
+	GetByID(ctx context.Context, id uint) (*Failure, error)
+	RecordFailure(ctx context.Context, scope, subject, ip, userAgent string, window time.Duration) (*Failure, error)
+	Lock(ctx context.Context, id uint, until time.Time) (bool, error)
+	Delete(ctx context.Context, id uint) error
+	DeleteSubject(ctx context.Context, scope, subject string) error
+	DeleteStale(ctx context.Context, olderThan time.Time) (int64, error)
+	List(ctx context.Context, f ListFilter, limit, offset int) ([]*Failure, int, error)
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const failureColumns = `
+	id, scope, subject, failed_count, first_failed_at, last_failed_at, locked_until,
+	lockout_count, last_ip, last_user_agent, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanFailure(row rowScanner) (*Failure, error) {
+	var f Failure
+	err := row.Scan(
+		&f.ID, &f.Scope, &f.Subject, &f.FailedCount, &f.FirstFailedAt, &f.LastFailedAt, &f.LockedUntil,
+		&f.LockoutCount, &f.LastIP, &f.LastUserAgent, &f.CreatedAt, &f.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *Repository) Get(ctx context.Context, scope, subject string) (*Failure, error) {
+	query := `SELECT ` + failureColumns + ` FROM login_failures WHERE scope = ? AND subject = ?`
+	return scanFailure(r.db.QueryRowContext(ctx, query, scope, subject))
+}
+
+func (r *Repository) GetByID(ctx context.Context, id uint) (*Failure, error) {
+	query := `SELECT ` + failureColumns + ` FROM login_failures WHERE id = ?`
+	return scanFailure(r.db.QueryRowContext(ctx, query, id))
+}
+
+// RecordFailure counts one failed login. The count restarts when the previous
+// failure is older than window. Assignments run left to right, so the count
+// and first_failed_at still see the old last_failed_at
+func (r *Repository) RecordFailure(ctx context.Context, scope, subject, ip, userAgent string, window time.Duration) (*Failure, error) {
+	query := `
+		INSERT INTO login_failures (scope, subject, failed_count, first_failed_at, last_failed_at, last_ip, last_user_agent, created_at, updated_at)
+		VALUES (?, ?, 1, NOW(), NOW(), ?, ?, NOW(), NOW())
+		ON DUPLICATE KEY UPDATE
+			failed_count = IF(last_failed_at IS NULL OR last_failed_at < NOW() - INTERVAL ? SECOND, 1, failed_count + 1),
+			first_failed_at = IF(last_failed_at IS NULL OR last_failed_at < NOW() - INTERVAL ? SECOND, NOW(), first_failed_at),
+			last_failed_at = NOW(),
+			last_ip = VALUES(last_ip),
+			last_user_agent = VALUES(last_user_agent)
+	`
+	seconds := int64(window / time.Second)
+	if _, err := r.db.ExecContext(ctx, query, scope, subject, ip, truncate(userAgent, 255), seconds, seconds); err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, scope, subject)
+}
+
+// Lock refuses logins until the given time and restarts the failure count. It
+// reports false when the subject was already locked, so a burst of failures
+// produces a single lockout
+func (r *Repository) Lock(ctx context.Context, id uint, until time.Time) (bool, error) {
+	query := `
+		UPDATE login_failures
+		SET locked_until = ?, lockout_count = lockout_count + 1, failed_count = 0
+		WHERE id = ? AND (locked_until IS NULL OR locked_until <= NOW())
+	`
+	result, err := r.db.ExecContext(ctx, query, until, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *Repository) Delete(ctx context.Context, id uint) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM login_failures WHERE id = ?`, id)
+	return err
+}
+
+func (r *Repository) DeleteSubject(ctx context.Context, scope, subject string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM login_failures WHERE scope = ? AND subject = ?`, scope, subject)
+	return err
+}
+
+// DeleteStale drops rows with no recent failures and no lockout in force
+func (r *Repository) DeleteStale(ctx context.Context, olderThan time.Time) (int64, error) {
+	query := `
+		DELETE FROM login_failures
+		WHERE last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)
+	`
+	result, err := r.db.ExecContext(ctx, query, olderThan, olderThan)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+type ListFilter struct {
+	Scope      string
+	Search     string // matches the username or IP
+	LockedOnly bool
+}
+
+func (f ListFilter) where() (string, []interface{}) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+
+	if f.Scope != "" {
+		conditions = append(conditions, "scope = ?")
+		args = append(args, f.Scope)
+	}
+	if f.Search != "" {
+		conditions = append(conditions, "subject LIKE ?")
+		args = append(args, "%"+strings.ToLower(f.Search)+"%")
+	}
+	if f.LockedOnly {
+		conditions = append(conditions, "locked_until > NOW()")
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// List returns tracked subjects, current lockouts first
+func (r *Repository) List(ctx context.Context, f ListFilter, limit, offset int) ([]*Failure, int, error) {
+	where, args := f.where()
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM login_failures` + where
+	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + failureColumns + ` FROM login_failures` + where + `
+		ORDER BY (locked_until > NOW()) DESC, last_failed_at DESC, id DESC
+		LIMIT ? OFFSET ?`
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var failures []*Failure
+	for rows.Next() {
+		f, err := scanFailure(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		failures = append(failures, f)
+	}
+	return failures, total, rows.Err()
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
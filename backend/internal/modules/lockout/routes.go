@@ -0,0 +1,13 @@
+package lockout
+
+import (
+	"walletpoint/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterRoutes(app fiber.Router, handler *Handler, jwtManager *middleware.JWTManager) {
+	admin := app.Group("/admin/login-lockouts", middleware.JWTMiddleware(jwtManager), middleware.RequireAdmin())
+	admin.Get("", handler.List)
+	admin.Delete("/:id", handler.Clear)
+}
@@ -0,0 +1,257 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"walletpoint/internal/config"
+	"walletpoint/internal/modules/audit"
+	"walletpoint/internal/shared/constants"
+	apperrors "walletpoint/internal/shared/errors"
+)
+
+// maxLockout caps how long repeat lockouts grow
+const maxLockout = 24 * time.Hour
+
+type ServiceInterface interface {
+	Check(ctx context.Context, username, ip string) error
+	RecordFailure(ctx context.Context, username, ip, userAgent string)
+	RecordSuccess(ctx context.Context, username string)
+	List(ctx context.Context, f ListFilter, page, perPage int) ([]*FailureResponse, int, error)
+	Clear(ctx context.Context, id uint, actor Actor) error
+}
+
+type Service struct {
+	repo      *Repository
+	auditRepo *audit.Repository
+	config    config.LoginConfig
+}
+
+func NewService(repo *Repository, auditRepo *audit.Repository, cfg config.LoginConfig) *Service {
+	return &Service{
+		repo:      repo,
+		auditRepo: auditRepo,
+		config:    cfg,
+	}
+}
+
+// Check refuses a login attempt while the username or IP is locked out, or
+// while it has to wait out the delay after its last failure
+func (s *Service) Check(ctx context.Context, username, ip string) error {
+	now := time.Now()
+	for _, subject := range s.subjects(username, ip) {
+		f, err := s.repo.Get(ctx, subject.scope, subject.value)
+		if err != nil {
+			return apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to check login attempts")
+		}
+		if f == nil {
+			continue
+		}
+
+		if f.Locked(now) {
+			return apperrors.New("LOGIN_LOCKED", fmt.Sprintf(
+				"Too many failed login attempts. Try again in %s", waitText(f.LockedUntil.Time.Sub(now))))
+		}
+		if next := s.nextAttempt(f); now.Before(next) {
+			return apperrors.New("LOGIN_THROTTLED", fmt.Sprintf(
+				"Too many failed login attempts. Try again in %s", waitText(next.Sub(now))))
+		}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login against the username and the IP, and
+// locks out whichever crossed its limit
+func (s *Service) RecordFailure(ctx context.Context, username, ip, userAgent string) {
+	for _, subject := range s.subjects(username, ip) {
+		f, err := s.repo.RecordFailure(ctx, subject.scope, subject.value, ip, userAgent, s.config.FailureWindow)
+		if err != nil {
+			log.Printf("Failed to record login failure for %s %s: %v", subject.scope, subject.value, err)
+			continue
+		}
+		if f == nil || f.FailedCount < subject.limit {
+			continue
+		}
+
+		duration := s.lockoutDuration(f.LockoutCount)
+		until := time.Now().Add(duration)
+		locked, err := s.repo.Lock(ctx, f.ID, until)
+		if err != nil {
+			log.Printf("Failed to lock out %s %s: %v", subject.scope, subject.value, err)
+			continue
+		}
+		if locked {
+			s.auditLockout(ctx, f, until, duration, ip, userAgent)
+		}
+	}
+}
+
+// RecordSuccess forgets the username's failures after a complete login. IP
+// failures stay, since one good login says nothing about other accounts
+func (s *Service) RecordSuccess(ctx context.Context, username string) {
+	if err := s.repo.DeleteSubject(ctx, ScopeUsername, normalize(username)); err != nil {
+		log.Printf("Failed to reset login failures for %s: %v", username, err)
+	}
+}
+
+// List shows tracked usernames and IPs for the admin lockout page
+func (s *Service) List(ctx context.Context, f ListFilter, page, perPage int) ([]*FailureResponse, int, error) {
+	offset := (page - 1) * perPage
+	failures, total, err := s.repo.List(ctx, f, perPage, offset)
+	if err != nil {
+		return nil, 0, apperrors.Wrap(err, "DB_ERROR", "Failed to get login failures")
+	}
+
+	now := time.Now()
+	result := make([]*FailureResponse, len(failures))
+	for i, failure := range failures {
+		result[i] = ToFailureResponse(failure, now)
+	}
+	return result, total, nil
+}
+
+// Clear lifts a lockout and forgets the failures behind it
+func (s *Service) Clear(ctx context.Context, id uint, actor Actor) error {
+	f, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to get login failures")
+	}
+	if f == nil {
+		return apperrors.ErrNotFound
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to clear lockout")
+	}
+
+	s.audit(ctx, &audit.Log{
+		UserID:      sql.NullInt64{Int64: int64(actor.UserID), Valid: true},
+		TargetID:    f.ID,
+		Action:      "LOGIN_LOCKOUT_CLEAR",
+		OldValues:   audit.Values(failureValues(f)),
+		IPAddress:   nullString(actor.IPAddress),
+		UserAgent:   nullString(actor.UserAgent),
+		Description: fmt.Sprintf("Login failures of %s %s cleared", strings.ToLower(f.Scope), f.Subject),
+		RiskLevel:   constants.RiskLevelMedium,
+	})
+	return nil
+}
+
+// RunCleanup periodically drops rows whose failures have expired until ctx is
+// cancelled
+func (s *Service) RunCleanup(ctx context.Context) {
+	ticker := time.NewTicker(s.config.FailureWindow)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.repo.DeleteStale(ctx, time.Now().Add(-s.config.FailureWindow)); err != nil {
+				log.Printf("Failed to clean up login failures: %v", err)
+			}
+		}
+	}
+}
+
+type subject struct {
+	scope string
+	value string
+	limit int
+}
+
+func (s *Service) subjects(username, ip string) []subject {
+	subjects := []subject{{scope: ScopeIP, value: ip, limit: s.config.IPMaxFailures}}
+	if name := normalize(username); name != "" {
+		subjects = append(subjects, subject{scope: ScopeUsername, value: name, limit: s.config.MaxFailures})
+	}
+	return subjects
+}
+
+// nextAttempt is when the subject may try again: right away for the first
+// few failures, then after a delay that doubles with every further failure
+func (s *Service) nextAttempt(f *Failure) time.Time {
+	if !f.LastFailedAt.Valid || f.FailedCount < s.config.DelayAfter {
+		return time.Time{}
+	}
+	if time.Since(f.LastFailedAt.Time) > s.config.FailureWindow {
+		return time.Time{}
+	}
+
+	delay := s.config.BaseDelay
+	for i := s.config.DelayAfter; i < f.FailedCount && delay < s.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.config.MaxDelay {
+		delay = s.config.MaxDelay
+	}
+	return f.LastFailedAt.Time.Add(delay)
+}
+
+// lockoutDuration doubles with every earlier lockout of the subject
+func (s *Service) lockoutDuration(previous int) time.Duration {
+	duration := s.config.LockoutDuration
+	for i := 0; i < previous && duration < maxLockout; i++ {
+		duration *= 2
+	}
+	if duration > maxLockout {
+		duration = maxLockout
+	}
+	return duration
+}
+
+func (s *Service) auditLockout(ctx context.Context, f *Failure, until time.Time, duration time.Duration, ip, userAgent string) {
+	values := failureValues(f)
+	values["locked_until"] = until.Format(time.RFC3339)
+
+	s.audit(ctx, &audit.Log{
+		TargetID:  f.ID,
+		Action:    "LOGIN_LOCKOUT",
+		NewValues: audit.Values(values),
+		IPAddress: nullString(ip),
+		UserAgent: nullString(userAgent),
+		Description: fmt.Sprintf("Logins for %s %s locked for %s after %d failed attempts",
+			strings.ToLower(f.Scope), f.Subject, duration, f.FailedCount),
+		RiskLevel: constants.RiskLevelHigh,
+	})
+}
+
+func (s *Service) audit(ctx context.Context, entry *audit.Log) {
+	entry.TargetType = "login_failures"
+	entry.ActionCategory = constants.AuditCategoryAuth
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		log.Printf("Failed to write audit log for login failures %d: %v", entry.TargetID, err)
+	}
+}
+
+func failureValues(f *Failure) map[string]interface{} {
+	return map[string]interface{}{
+		"scope":         f.Scope,
+		"subject":       f.Subject,
+		"failed_count":  f.FailedCount,
+		"lockout_count": f.LockoutCount,
+	}
+}
+
+// waitText rounds a wait up to whole seconds or minutes for messages
+func waitText(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%d seconds", int((d+time.Second-1)/time.Second))
+	}
+	return fmt.Sprintf("%d minutes", int((d+time.Minute-1)/time.Minute))
+}
+
+// normalize folds usernames so case variants share one counter, cut to the
+// column size so oversized input cannot fail the insert
+func normalize(username string) string {
+	return truncate(strings.ToLower(strings.TrimSpace(username)), 100)
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
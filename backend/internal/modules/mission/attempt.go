@@ -0,0 +1,139 @@
+package mission
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"walletpoint/internal/shared/constants"
+	apperrors "walletpoint/internal/shared/errors"
+)
+
+// Reward cap periods for repeatable missions
+const (
+	RewardCapDay   = "DAY"
+	RewardCapWeek  = "WEEK"
+	RewardCapMonth = "MONTH"
+)
+
+var validRewardCapPeriods = map[string]bool{RewardCapDay: true, RewardCapWeek: true, RewardCapMonth: true}
+
+// attemptFinished reports whether a participant is done with an attempt, so a
+// new one may be started
+func attemptFinished(l *MissionLog) bool {
+	switch l.Status {
+	case constants.MissionStatusCompleted, constants.MissionStatusFailed, constants.MissionStatusExpired:
+		return true
+	}
+	return false
+}
+
+// attemptEndedAt is when the attempt was last acted on; the cooldown runs from here
+func attemptEndedAt(l *MissionLog) time.Time {
+	switch {
+	case l.GradedAt.Valid:
+		return l.GradedAt.Time
+	case l.CompletedAt.Valid:
+		return l.CompletedAt.Time
+	case l.SubmittedAt.Valid:
+		return l.SubmittedAt.Time
+	}
+	return l.StartedAt
+}
+
+// checkNewAttempt rejects starting another attempt when the last one is still
+// open, the attempt limit is used up or the cooldown has not elapsed
+func checkNewAttempt(m *Mission, latest *MissionLog, now time.Time) error {
+	if !m.IsRepeatable {
+		return apperrors.New("ALREADY_PARTICIPATED", "Already participated in this mission")
+	}
+	if !attemptFinished(latest) {
+		return apperrors.New("ATTEMPT_IN_PROGRESS", fmt.Sprintf("Attempt #%d is still in progress", latest.AttemptNumber))
+	}
+	if m.MaxAttempts.Valid && int64(latest.AttemptNumber) >= m.MaxAttempts.Int64 {
+		return apperrors.New("MAX_ATTEMPTS", fmt.Sprintf("All %d attempts have been used", m.MaxAttempts.Int64))
+	}
+	if m.AttemptCooldownMinutes > 0 {
+		next := attemptEndedAt(latest).Add(time.Duration(m.AttemptCooldownMinutes) * time.Minute)
+		if now.Before(next) {
+			return apperrors.New("ATTEMPT_COOLDOWN", "Next attempt is available from "+next.Format(scheduleTimeFormat))
+		}
+	}
+	return nil
+}
+
+// rewardPeriodStart is the beginning of the cap period containing now
+func rewardPeriodStart(period string, now time.Time) time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch period {
+	case RewardCapWeek:
+		// Weeks start on Monday
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case RewardCapMonth:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}
+	return day
+}
+
+// AttemptPolicy limits how often a repeatable mission can be retried and rewarded
+type AttemptPolicy struct {
+	MaxAttempts     *int   `json:"max_attempts,omitempty"`
+	CooldownMinutes int    `json:"cooldown_minutes,omitempty"`
+	RewardCap       *int64 `json:"reward_cap,omitempty"`        // most points paid per period
+	RewardCapPeriod string `json:"reward_cap_period,omitempty"` // DAY, WEEK or MONTH
+}
+
+// Validate checks the limits and defaults the cap period to a week
+func (p *AttemptPolicy) Validate() []ValidationError {
+	var errors []ValidationError
+	if p.MaxAttempts != nil && *p.MaxAttempts < 1 {
+		errors = append(errors, ValidationError{Field: "attempts.max_attempts", Message: "Max attempts must be at least 1"})
+	}
+	if p.CooldownMinutes < 0 {
+		errors = append(errors, ValidationError{Field: "attempts.cooldown_minutes", Message: "Cooldown must not be negative"})
+	}
+	if p.RewardCap != nil {
+		if *p.RewardCap <= 0 {
+			errors = append(errors, ValidationError{Field: "attempts.reward_cap", Message: "Reward cap must be positive"})
+		}
+		if p.RewardCapPeriod == "" {
+			p.RewardCapPeriod = RewardCapWeek
+		}
+	}
+	if p.RewardCapPeriod != "" && !validRewardCapPeriods[p.RewardCapPeriod] {
+		errors = append(errors, ValidationError{Field: "attempts.reward_cap_period", Message: "Period must be DAY, WEEK or MONTH"})
+	}
+	return errors
+}
+
+// apply stores the policy on the mission
+func (p *AttemptPolicy) apply(m *Mission) {
+	m.MaxAttempts = sql.NullInt64{}
+	if p.MaxAttempts != nil {
+		m.MaxAttempts = sql.NullInt64{Int64: int64(*p.MaxAttempts), Valid: true}
+	}
+	m.AttemptCooldownMinutes = p.CooldownMinutes
+	m.RewardCap = sql.NullInt64{}
+	m.RewardCapPeriod = sql.NullString{}
+	if p.RewardCap != nil {
+		m.RewardCap = sql.NullInt64{Int64: *p.RewardCap, Valid: true}
+		m.RewardCapPeriod = sql.NullString{String: p.RewardCapPeriod, Valid: true}
+	}
+}
+
+// missionAttemptPolicy returns the retry limits of a repeatable mission
+func missionAttemptPolicy(m *Mission) *AttemptPolicy {
+	if !m.IsRepeatable {
+		return nil
+	}
+	p := &AttemptPolicy{CooldownMinutes: m.AttemptCooldownMinutes}
+	if m.MaxAttempts.Valid {
+		max := int(m.MaxAttempts.Int64)
+		p.MaxAttempts = &max
+	}
+	if m.RewardCap.Valid {
+		p.RewardCap = &m.RewardCap.Int64
+		p.RewardCapPeriod = m.RewardCapPeriod.String
+	}
+	return p
+}
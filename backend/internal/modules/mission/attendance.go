@@ -0,0 +1,71 @@
+package mission
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"walletpoint/pkg/utils"
+)
+
+// attendanceTokenPrefix marks QR payloads that belong to attendance check-in
+const attendanceTokenPrefix = "ATT"
+
+// attendanceSignatureLength keeps the QR payload short enough to scan reliably
+const attendanceSignatureLength = 16
+
+// attendanceWindow returns the rotation window a moment falls in
+func attendanceWindow(t time.Time, rotationSeconds int) int64 {
+	return t.Unix() / int64(rotationSeconds)
+}
+
+// attendanceToken builds the QR payload of a session for one rotation window
+func attendanceToken(s *AttendanceSession, window int64) string {
+	return fmt.Sprintf("%s.%d.%d.%s", attendanceTokenPrefix, s.ID, window, attendanceSignature(s, window))
+}
+
+func attendanceSignature(s *AttendanceSession, window int64) string {
+	return utils.GenerateHMAC(fmt.Sprintf("%d|%d", s.ID, window), s.Secret)[:attendanceSignatureLength]
+}
+
+// parseAttendanceToken splits a scanned payload into session ID, window and signature
+func parseAttendanceToken(token string) (uint, int64, string, bool) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 4 || parts[0] != attendanceTokenPrefix {
+		return 0, 0, "", false
+	}
+	sessionID, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, "", false
+	}
+	window, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return 0, 0, "", false
+	}
+	return uint(sessionID), window, parts[3], true
+}
+
+// verifyAttendanceToken accepts the current window and the one before it, so a
+// code that rotates while a student is scanning still works
+func verifyAttendanceToken(s *AttendanceSession, window int64, signature string, now time.Time) bool {
+	current := attendanceWindow(now, s.RotationSeconds)
+	if window != current && window != current-1 {
+		return false
+	}
+	return hmac.Equal([]byte(attendanceSignature(s, window)), []byte(signature))
+}
+
+// distanceMeters is the great-circle distance between two coordinates
+func distanceMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadius = 6371000.0
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return earthRadius * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
@@ -2,7 +2,12 @@ package mission
 
 import (
 	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"strings"
 	"time"
+
+	"walletpoint/internal/shared/constants"
 )
 
 // CreateMissionRequest for creating mission
@@ -18,6 +23,13 @@ type CreateMissionRequest struct {
 	EndDate         *time.Time      `json:"end_date"`
 	Deadline        *time.Time      `json:"deadline"`
 	IsRepeatable    bool            `json:"is_repeatable"`
+	GroupIDs        []uint          `json:"group_ids"` // empty opens the mission to every student
+	Attempts        *AttemptPolicy  `json:"attempts"`  // repeatable missions only
+	RewardRules     *RewardRules    `json:"reward_rules"`
+	Rubric          *Rubric         `json:"rubric"`
+	BudgetSource    string          `json:"budget_source"` // WALLET (default) or POOL
+	RewardPoolID    *uint           `json:"reward_pool_id"`
+	Budget          *int64          `json:"budget"` // defaults to max reward x max participants
 }
 
 func (r *CreateMissionRequest) Validate() []ValidationError {
@@ -42,6 +54,71 @@ func (r *CreateMissionRequest) Validate() []ValidationError {
 	if !validDifficulty[r.Difficulty] {
 		errors = append(errors, ValidationError{Field: "difficulty", Message: "Invalid difficulty"})
 	}
+	if r.RewardRules != nil {
+		errors = append(errors, r.RewardRules.Validate()...)
+	}
+	if r.Attempts != nil {
+		if !r.IsRepeatable {
+			errors = append(errors, ValidationError{Field: "attempts", Message: "Only repeatable missions take an attempt policy"})
+		}
+		errors = append(errors, r.Attempts.Validate()...)
+	}
+	if r.Rubric != nil {
+		if r.MissionType == "QUIZ" || r.MissionType == "ATTENDANCE" {
+			errors = append(errors, ValidationError{Field: "rubric", Message: "Quiz and attendance missions are graded automatically"})
+		}
+		errors = append(errors, r.Rubric.Validate()...)
+	}
+	errors = append(errors, r.validateBudget()...)
+	if r.MissionType == "QUIZ" {
+		if len(r.Content) == 0 {
+			errors = append(errors, ValidationError{Field: "content", Message: "Quiz missions require questions"})
+		} else {
+			quiz, quizErrors := ParseQuiz(r.Content)
+			errors = append(errors, quizErrors...)
+			if len(quizErrors) == 0 {
+				r.Content, _ = json.Marshal(quiz)
+			}
+		}
+	}
+	return errors
+}
+
+// validateBudget defaults the funding source and the budget to cover every participant
+func (r *CreateMissionRequest) validateBudget() []ValidationError {
+	var errors []ValidationError
+
+	if r.BudgetSource == "" {
+		r.BudgetSource = constants.BudgetSourceWallet
+	}
+	switch r.BudgetSource {
+	case constants.BudgetSourceWallet:
+		r.RewardPoolID = nil
+	case constants.BudgetSourcePool:
+		if r.RewardPoolID == nil || *r.RewardPoolID == 0 {
+			errors = append(errors, ValidationError{Field: "reward_pool_id", Message: "Reward pool is required for pool-funded missions"})
+		}
+	default:
+		errors = append(errors, ValidationError{Field: "budget_source", Message: "Budget source must be WALLET or POOL"})
+	}
+
+	maxReward := r.RewardPoints
+	if r.RewardRules != nil {
+		maxReward = r.RewardRules.MaxReward(r.RewardPoints)
+	}
+
+	if r.Budget == nil {
+		if r.MaxParticipants == nil || *r.MaxParticipants <= 0 {
+			errors = append(errors, ValidationError{Field: "budget", Message: "Budget is required when max participants is not set"})
+			return errors
+		}
+		budget := maxReward * int64(*r.MaxParticipants)
+		r.Budget = &budget
+	}
+	if *r.Budget < maxReward {
+		errors = append(errors, ValidationError{Field: "budget", Message: fmt.Sprintf("Budget must cover at least one full reward (%d points)", maxReward)})
+	}
+
 	return errors
 }
 
@@ -57,18 +134,218 @@ type UpdateMissionRequest struct {
 	StartDate       *time.Time      `json:"start_date"`
 	EndDate         *time.Time      `json:"end_date"`
 	Deadline        *time.Time      `json:"deadline"`
+	GroupIDs        *[]uint         `json:"group_ids"` // replaces the target groups; [] clears them
+	Attempts        *AttemptPolicy  `json:"attempts"`
+	RewardRules     *RewardRules    `json:"reward_rules"`
+	Rubric          *Rubric         `json:"rubric"`
 }
 
-// SubmitMissionRequest for submitting mission answers
+func (r *UpdateMissionRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.RewardRules != nil {
+		errors = append(errors, r.RewardRules.Validate()...)
+	}
+	if r.Attempts != nil {
+		errors = append(errors, r.Attempts.Validate()...)
+	}
+	if r.Rubric != nil {
+		errors = append(errors, r.Rubric.Validate()...)
+	}
+	return errors
+}
+
+// SubmitMissionRequest for submitting mission answers; assignments and
+// projects may also attach links and, via multipart form, files
 type SubmitMissionRequest struct {
-	Answers json.RawMessage `json:"answers"`
+	Answers json.RawMessage         `json:"answers"`
+	Links   []string                `json:"links"`
+	Note    string                  `json:"note"`
+	Files   []*multipart.FileHeader `json:"-"`
 }
 
-// GradeMissionRequest for grading submission
+// GradeMissionRequest for grading submission; missions with a rubric are
+// scored from RubricScores instead of Score
 type GradeMissionRequest struct {
-	Score    float64 `json:"score"`
-	Notes    string  `json:"notes"`
-	Approved bool    `json:"approved"`
+	Score        float64       `json:"score"`
+	RubricScores []RubricScore `json:"rubric_scores"`
+	Notes        string        `json:"notes"`
+	Approved     bool          `json:"approved"`
+}
+
+func (r *GradeMissionRequest) Validate() []ValidationError {
+	return r.validate("")
+}
+
+func (r *GradeMissionRequest) validate(prefix string) []ValidationError {
+	var errors []ValidationError
+	if r.Score < 0 || r.Score > 100 {
+		errors = append(errors, ValidationError{Field: prefix + "score", Message: "Score must be between 0 and 100"})
+	}
+	return errors
+}
+
+const maxBulkGrades = 200
+
+// BulkGradeRequest for grading many participants of a mission at once
+type BulkGradeRequest struct {
+	Grades []BulkGradeItem `json:"grades"`
+}
+
+// BulkGradeItem is one participant's grade in a bulk request
+type BulkGradeItem struct {
+	UserID uint `json:"user_id"`
+	GradeMissionRequest
+}
+
+func (r *BulkGradeRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if len(r.Grades) == 0 {
+		errors = append(errors, ValidationError{Field: "grades", Message: "At least one grade is required"})
+	}
+	if len(r.Grades) > maxBulkGrades {
+		errors = append(errors, ValidationError{Field: "grades", Message: fmt.Sprintf("At most %d grades per request", maxBulkGrades)})
+	}
+
+	seen := map[uint]bool{}
+	for i, g := range r.Grades {
+		prefix := fmt.Sprintf("grades[%d].", i)
+		if g.UserID == 0 {
+			errors = append(errors, ValidationError{Field: prefix + "user_id", Message: "User ID is required"})
+		} else if seen[g.UserID] {
+			errors = append(errors, ValidationError{Field: prefix + "user_id", Message: "Participant is graded twice"})
+		}
+		seen[g.UserID] = true
+		errors = append(errors, g.validate(prefix)...)
+	}
+	return errors
+}
+
+// TopUpBudgetRequest for adding points to a mission budget
+type TopUpBudgetRequest struct {
+	Amount int64 `json:"amount"`
+}
+
+func (r *TopUpBudgetRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Amount <= 0 {
+		errors = append(errors, ValidationError{Field: "amount", Message: "Amount must be positive"})
+	}
+	return errors
+}
+
+// CreateRewardPoolRequest for creating a reward pool
+type CreateRewardPoolRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (r *CreateRewardPoolRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if strings.TrimSpace(r.Name) == "" {
+		errors = append(errors, ValidationError{Field: "name", Message: "Name is required"})
+	}
+	return errors
+}
+
+// UpdateRewardPoolRequest for renaming or retiring a reward pool
+type UpdateRewardPoolRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	IsActive    *bool   `json:"is_active"`
+}
+
+func (r *UpdateRewardPoolRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Name != nil && strings.TrimSpace(*r.Name) == "" {
+		errors = append(errors, ValidationError{Field: "name", Message: "Name cannot be empty"})
+	}
+	return errors
+}
+
+// FundRewardPoolRequest for adding points to a reward pool
+type FundRewardPoolRequest struct {
+	Amount         int64  `json:"amount"`
+	Reason         string `json:"reason"`
+	IdempotencyKey string `json:"idempotency_key"`
+}
+
+func (r *FundRewardPoolRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Amount <= 0 {
+		errors = append(errors, ValidationError{Field: "amount", Message: "Amount must be positive"})
+	}
+	if r.Reason == "" {
+		errors = append(errors, ValidationError{Field: "reason", Message: "Reason is required"})
+	}
+	if r.IdempotencyKey == "" {
+		errors = append(errors, ValidationError{Field: "idempotency_key", Message: "Idempotency key is required"})
+	}
+	return errors
+}
+
+// OpenAttendanceRequest for opening a QR check-in session
+type OpenAttendanceRequest struct {
+	DurationMinutes int      `json:"duration_minutes"`
+	RotationSeconds int      `json:"rotation_seconds"`
+	Latitude        *float64 `json:"latitude"`
+	Longitude       *float64 `json:"longitude"`
+	RadiusMeters    *int     `json:"radius_meters"`
+}
+
+func (r *OpenAttendanceRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.DurationMinutes == 0 {
+		r.DurationMinutes = 15
+	}
+	if r.DurationMinutes < 1 || r.DurationMinutes > 480 {
+		errors = append(errors, ValidationError{Field: "duration_minutes", Message: "Duration must be between 1 and 480 minutes"})
+	}
+	if r.RotationSeconds == 0 {
+		r.RotationSeconds = 30
+	}
+	if r.RotationSeconds < 10 || r.RotationSeconds > 300 {
+		errors = append(errors, ValidationError{Field: "rotation_seconds", Message: "Rotation must be between 10 and 300 seconds"})
+	}
+	if (r.Latitude == nil) != (r.Longitude == nil) {
+		errors = append(errors, ValidationError{Field: "latitude", Message: "Latitude and longitude must be provided together"})
+	}
+	if r.Latitude != nil && (*r.Latitude < -90 || *r.Latitude > 90 || *r.Longitude < -180 || *r.Longitude > 180) {
+		errors = append(errors, ValidationError{Field: "latitude", Message: "Invalid coordinates"})
+	}
+	if r.RadiusMeters != nil {
+		if r.Latitude == nil {
+			errors = append(errors, ValidationError{Field: "radius_meters", Message: "Geofence requires a location"})
+		} else if *r.RadiusMeters < 10 || *r.RadiusMeters > 5000 {
+			errors = append(errors, ValidationError{Field: "radius_meters", Message: "Radius must be between 10 and 5000 meters"})
+		}
+	}
+	return errors
+}
+
+// CheckInRequest for scanning an attendance QR
+type CheckInRequest struct {
+	Token     string   `json:"token"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+func (r *CheckInRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Token == "" {
+		errors = append(errors, ValidationError{Field: "token", Message: "Token is required"})
+	}
+	if (r.Latitude == nil) != (r.Longitude == nil) {
+		errors = append(errors, ValidationError{Field: "latitude", Message: "Latitude and longitude must be provided together"})
+	}
+	return errors
+}
+
+// CheckInDetails is stored as the answers of an attendance mission log
+type CheckInDetails struct {
+	SessionID      uint     `json:"session_id"`
+	Latitude       *float64 `json:"latitude,omitempty"`
+	Longitude      *float64 `json:"longitude,omitempty"`
+	DistanceMeters *float64 `json:"distance_meters,omitempty"`
 }
 
 // ValidationError for validation
@@ -79,50 +356,135 @@ type ValidationError struct {
 
 // MissionResponse for mission details
 type MissionResponse struct {
-	ID                  uint            `json:"id"`
-	Title               string          `json:"title"`
-	Description         string          `json:"description,omitempty"`
-	MissionType         string          `json:"mission_type"`
-	CreatorID           uint            `json:"creator_id"`
-	CreatorName         string          `json:"creator_name,omitempty"`
-	RewardPoints        int64           `json:"reward_points"`
-	MaxParticipants     *int            `json:"max_participants,omitempty"`
-	CurrentParticipants int             `json:"current_participants"`
-	Difficulty          string          `json:"difficulty"`
-	Content             json.RawMessage `json:"content,omitempty"`
-	IsActive            bool            `json:"is_active"`
-	IsRepeatable        bool            `json:"is_repeatable"`
-	StartDate           *string         `json:"start_date,omitempty"`
-	EndDate             *string         `json:"end_date,omitempty"`
-	Deadline            *string         `json:"deadline,omitempty"`
-	CreatedAt           string          `json:"created_at"`
-	UserStatus          string          `json:"user_status,omitempty"` // For participant view
+	ID                  uint                  `json:"id"`
+	Title               string                `json:"title"`
+	Description         string                `json:"description,omitempty"`
+	MissionType         string                `json:"mission_type"`
+	CreatorID           uint                  `json:"creator_id"`
+	CreatorName         string                `json:"creator_name,omitempty"`
+	RewardPoints        int64                 `json:"reward_points"`
+	RewardRules         *RewardRules          `json:"reward_rules,omitempty"`
+	Rubric              *Rubric               `json:"rubric,omitempty"`
+	MaxParticipants     *int                  `json:"max_participants,omitempty"`
+	CurrentParticipants int                   `json:"current_participants"`
+	Difficulty          string                `json:"difficulty"`
+	Content             json.RawMessage       `json:"content,omitempty"`
+	IsActive            bool                  `json:"is_active"`
+	IsRepeatable        bool                  `json:"is_repeatable"`
+	Attempts            *AttemptPolicy        `json:"attempts,omitempty"`
+	TargetGroups        []TargetGroupResponse `json:"target_groups,omitempty"`
+	StartDate           *string               `json:"start_date,omitempty"`
+	EndDate             *string               `json:"end_date,omitempty"`
+	Deadline            *string               `json:"deadline,omitempty"`
+	Budget              *BudgetResponse       `json:"budget,omitempty"`
+	CreatedAt           string                `json:"created_at"`
+	UserStatus          string                `json:"user_status,omitempty"` // For participant view
+}
+
+// TargetGroupResponse for a class group a mission is restricted to
+type TargetGroupResponse struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// BudgetResponse for a mission's reserved reward budget
+type BudgetResponse struct {
+	Source       string  `json:"source"`
+	RewardPoolID *int64  `json:"reward_pool_id,omitempty"`
+	Amount       int64   `json:"amount"`
+	Spent        int64   `json:"spent"`
+	Remaining    int64   `json:"remaining"`
+	ReleasedAt   *string `json:"released_at,omitempty"`
+}
+
+// RewardPoolResponse for reward pool details
+type RewardPoolResponse struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Balance     int64  `json:"balance"`
+	Reserved    int64  `json:"reserved"`
+	TotalFunded int64  `json:"total_funded"`
+	IsActive    bool   `json:"is_active"`
+	CreatedAt   string `json:"created_at"`
 }
 
 // MissionLogResponse for participation details
 type MissionLogResponse struct {
-	ID            uint            `json:"id"`
-	MissionID     uint            `json:"mission_id"`
-	MissionTitle  string          `json:"mission_title"`
-	Status        string          `json:"status"`
-	Score         *float64        `json:"score,omitempty"`
-	Answers       json.RawMessage `json:"answers,omitempty"`
-	RewardClaimed bool            `json:"reward_claimed"`
-	RewardPoints  *int64          `json:"reward_points,omitempty"`
-	StartedAt     string          `json:"started_at"`
-	SubmittedAt   *string         `json:"submitted_at,omitempty"`
-	CompletedAt   *string         `json:"completed_at,omitempty"`
-	Notes         string          `json:"notes,omitempty"`
+	ID            uint                `json:"id"`
+	MissionID     uint                `json:"mission_id"`
+	MissionTitle  string              `json:"mission_title"`
+	AttemptNumber int                 `json:"attempt_number"`
+	Status        string              `json:"status"`
+	Score         *float64            `json:"score,omitempty"`
+	RubricScores  []RubricScore       `json:"rubric_scores,omitempty"`
+	Answers       json.RawMessage     `json:"answers,omitempty"`
+	RewardClaimed bool                `json:"reward_claimed"`
+	RewardPoints  *int64              `json:"reward_points,omitempty"`
+	StartedAt     string              `json:"started_at"`
+	SubmittedAt   *string             `json:"submitted_at,omitempty"`
+	CompletedAt   *string             `json:"completed_at,omitempty"`
+	Notes         string              `json:"notes,omitempty"`
+	TimeLimitEnds *string             `json:"time_limit_ends_at,omitempty"`
+	QuizResult    *QuizResult         `json:"quiz_result,omitempty"`
+	Submission    *SubmissionResponse `json:"submission,omitempty"`
+}
+
+// SubmissionResponse for one version of a hand-in
+type SubmissionResponse struct {
+	ID          uint                 `json:"id"`
+	Version     int                  `json:"version"`
+	Answers     json.RawMessage      `json:"answers,omitempty"`
+	Note        string               `json:"note,omitempty"`
+	SubmittedAt string               `json:"submitted_at"`
+	Attachments []AttachmentResponse `json:"attachments"`
+}
+
+// AttachmentResponse for an uploaded file or link
+type AttachmentResponse struct {
+	ID          uint   `json:"id"`
+	Kind        string `json:"kind"`
+	FileName    string `json:"file_name,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	SizeBytes   int64  `json:"size_bytes,omitempty"`
+	URL         string `json:"url,omitempty"`
+	DownloadURL string `json:"download_url,omitempty"`
 }
 
 // ParticipantResponse for listing participants
 type ParticipantResponse struct {
-	UserID      uint     `json:"user_id"`
-	UserName    string   `json:"user_name"`
-	Status      string   `json:"status"`
-	Score       *float64 `json:"score,omitempty"`
-	SubmittedAt *string  `json:"submitted_at,omitempty"`
-	CompletedAt *string  `json:"completed_at,omitempty"`
+	UserID        uint     `json:"user_id"`
+	UserName      string   `json:"user_name"`
+	AttemptNumber int      `json:"attempt_number"`
+	Status        string   `json:"status"`
+	Score         *float64 `json:"score,omitempty"`
+	SubmittedAt   *string  `json:"submitted_at,omitempty"`
+	CompletedAt   *string  `json:"completed_at,omitempty"`
+
+	RubricScores []RubricScore `json:"rubric_scores,omitempty"`
+
+	SubmissionVersion int                  `json:"submission_version,omitempty"`
+	Attachments       []AttachmentResponse `json:"attachments,omitempty"`
+}
+
+// BulkGradeResult is the outcome of one row of a bulk grade
+type BulkGradeResult struct {
+	UserID       uint     `json:"user_id"`
+	Status       string   `json:"status"` // GRADED or FAILED
+	Score        *float64 `json:"score,omitempty"`
+	RewardPoints int64    `json:"reward_points"`
+	Code         string   `json:"code,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// BulkGradeResponse summarizes a bulk grade; every reward in it is paid in one
+// database transaction and tagged with BatchID
+type BulkGradeResponse struct {
+	BatchID       string             `json:"batch_id"`
+	Graded        int                `json:"graded"`
+	Failed        int                `json:"failed"`
+	TotalRewarded int64              `json:"total_rewarded"`
+	Results       []*BulkGradeResult `json:"results"`
 }
 
 // ToMissionResponse converts entity to response
@@ -144,6 +506,11 @@ func ToMissionResponse(m *Mission, creatorName string) MissionResponse {
 	if m.Description.Valid {
 		resp.Description = m.Description.String
 	}
+	if m.RewardRules.Valid {
+		resp.RewardRules = missionRewardRules(m)
+	}
+	resp.Rubric = missionRubric(m)
+	resp.Attempts = missionAttemptPolicy(m)
 	if m.MaxParticipants.Valid {
 		max := int(m.MaxParticipants.Int64)
 		resp.MaxParticipants = &max
@@ -163,16 +530,86 @@ func ToMissionResponse(m *Mission, creatorName string) MissionResponse {
 		t := m.Deadline.Time.Format(time.RFC3339)
 		resp.Deadline = &t
 	}
+	if m.BudgetSource != "" && m.BudgetSource != constants.BudgetSourceNone {
+		resp.Budget = &BudgetResponse{
+			Source:    m.BudgetSource,
+			Amount:    m.BudgetAmount,
+			Spent:     m.BudgetSpent,
+			Remaining: m.BudgetRemaining(),
+		}
+		if m.RewardPoolID.Valid {
+			resp.Budget.RewardPoolID = &m.RewardPoolID.Int64
+		}
+		if m.BudgetReleasedAt.Valid {
+			t := m.BudgetReleasedAt.Time.Format(time.RFC3339)
+			resp.Budget.ReleasedAt = &t
+		}
+	}
 
 	return resp
 }
 
+// ToSubmissionResponse converts entity to response
+func ToSubmissionResponse(sub *Submission, missionID uint) SubmissionResponse {
+	resp := SubmissionResponse{
+		ID:          sub.ID,
+		Version:     sub.Version,
+		SubmittedAt: sub.SubmittedAt.Format(time.RFC3339),
+		Attachments: []AttachmentResponse{},
+	}
+	if sub.Answers.Valid {
+		resp.Answers = json.RawMessage(sub.Answers.String)
+	}
+	if sub.Note.Valid {
+		resp.Note = sub.Note.String
+	}
+	for _, a := range sub.Attachments {
+		resp.Attachments = append(resp.Attachments, ToAttachmentResponse(a, missionID))
+	}
+	return resp
+}
+
+// ToAttachmentResponse converts entity to response; files get a download URL
+func ToAttachmentResponse(a *SubmissionAttachment, missionID uint) AttachmentResponse {
+	resp := AttachmentResponse{
+		ID:   a.ID,
+		Kind: a.Kind,
+	}
+	if a.Kind == AttachmentFile {
+		resp.FileName = a.FileName.String
+		resp.ContentType = a.ContentType.String
+		resp.SizeBytes = a.SizeBytes.Int64
+		resp.DownloadURL = fmt.Sprintf("/api/v1/missions/%d/attachments/%d", missionID, a.ID)
+	} else {
+		resp.URL = a.URL.String
+	}
+	return resp
+}
+
+// ToRewardPoolResponse converts entity to response
+func ToRewardPoolResponse(p *RewardPool) RewardPoolResponse {
+	resp := RewardPoolResponse{
+		ID:          p.ID,
+		Name:        p.Name,
+		Balance:     p.Balance,
+		Reserved:    p.Reserved,
+		TotalFunded: p.TotalFunded,
+		IsActive:    p.IsActive,
+		CreatedAt:   p.CreatedAt.Format(time.RFC3339),
+	}
+	if p.Description.Valid {
+		resp.Description = p.Description.String
+	}
+	return resp
+}
+
 // ToMissionLogResponse converts entity to response
 func ToMissionLogResponse(l *MissionLog, missionTitle string) MissionLogResponse {
 	resp := MissionLogResponse{
 		ID:            l.ID,
 		MissionID:     l.MissionID,
 		MissionTitle:  missionTitle,
+		AttemptNumber: l.AttemptNumber,
 		Status:        l.Status,
 		RewardClaimed: l.RewardClaimed,
 		StartedAt:     l.StartedAt.Format(time.RFC3339),
@@ -181,6 +618,7 @@ func ToMissionLogResponse(l *MissionLog, missionTitle string) MissionLogResponse
 	if l.Score.Valid {
 		resp.Score = &l.Score.Float64
 	}
+	resp.RubricScores = logRubricScores(l)
 	if l.Answers.Valid {
 		resp.Answers = json.RawMessage(l.Answers.String)
 	}
@@ -201,3 +639,85 @@ func ToMissionLogResponse(l *MissionLog, missionTitle string) MissionLogResponse
 
 	return resp
 }
+
+// AttendanceSessionResponse for session details
+type AttendanceSessionResponse struct {
+	ID              uint     `json:"id"`
+	MissionID       uint     `json:"mission_id"`
+	RotationSeconds int      `json:"rotation_seconds"`
+	Latitude        *float64 `json:"latitude,omitempty"`
+	Longitude       *float64 `json:"longitude,omitempty"`
+	RadiusMeters    *int64   `json:"radius_meters,omitempty"`
+	StartsAt        string   `json:"starts_at"`
+	EndsAt          string   `json:"ends_at"`
+	ClosedAt        *string  `json:"closed_at,omitempty"`
+	IsOpen          bool     `json:"is_open"`
+}
+
+// AttendanceQRResponse for the currently valid attendance QR
+type AttendanceQRResponse struct {
+	SessionID       uint   `json:"session_id"`
+	Token           string `json:"token"`
+	QRImage         string `json:"qr_image"`
+	RotationSeconds int    `json:"rotation_seconds"`
+	ExpiresAt       string `json:"expires_at"`
+}
+
+// AttendanceRecordResponse for the live attendance list
+type AttendanceRecordResponse struct {
+	UserID         uint     `json:"user_id"`
+	UserName       string   `json:"user_name"`
+	NIM            string   `json:"nim,omitempty"`
+	Status         string   `json:"status"`
+	SessionID      uint     `json:"session_id,omitempty"`
+	CheckedInAt    *string  `json:"checked_in_at,omitempty"`
+	DistanceMeters *float64 `json:"distance_meters,omitempty"`
+}
+
+// ToAttendanceSessionResponse converts entity to response
+func ToAttendanceSessionResponse(s *AttendanceSession) AttendanceSessionResponse {
+	resp := AttendanceSessionResponse{
+		ID:              s.ID,
+		MissionID:       s.MissionID,
+		RotationSeconds: s.RotationSeconds,
+		StartsAt:        s.StartsAt.Format(time.RFC3339),
+		EndsAt:          s.EndsAt.Format(time.RFC3339),
+		IsOpen:          s.IsOpen(time.Now()),
+	}
+	if s.Latitude.Valid && s.Longitude.Valid {
+		resp.Latitude = &s.Latitude.Float64
+		resp.Longitude = &s.Longitude.Float64
+	}
+	if s.RadiusMeters.Valid {
+		resp.RadiusMeters = &s.RadiusMeters.Int64
+	}
+	if s.ClosedAt.Valid {
+		t := s.ClosedAt.Time.Format(time.RFC3339)
+		resp.ClosedAt = &t
+	}
+	return resp
+}
+
+// ToAttendanceRecordResponse converts entity to response
+func ToAttendanceRecordResponse(r *AttendanceRecord) AttendanceRecordResponse {
+	resp := AttendanceRecordResponse{
+		UserID:   r.UserID,
+		UserName: r.UserName,
+		Status:   r.Status,
+	}
+	if r.NIM.Valid {
+		resp.NIM = r.NIM.String
+	}
+	if r.CompletedAt.Valid {
+		t := r.CompletedAt.Time.Format(time.RFC3339)
+		resp.CheckedInAt = &t
+	}
+	if r.Answers.Valid {
+		var details CheckInDetails
+		if json.Unmarshal([]byte(r.Answers.String), &details) == nil {
+			resp.SessionID = details.SessionID
+			resp.DistanceMeters = details.DistanceMeters
+		}
+	}
+	return resp
+}
@@ -7,25 +7,44 @@ import (
 
 // Mission entity
 type Mission struct {
-	ID                  uint
-	Title               string
-	Description         sql.NullString
-	MissionType         string
-	CreatorID           uint
-	RewardPoints        int64
-	MaxParticipants     sql.NullInt64
-	CurrentParticipants int
-	Difficulty          string
-	Requirements        sql.NullString // JSON
-	Content             sql.NullString // JSON
-	IsActive            bool
-	IsRepeatable        bool
-	StartDate           sql.NullTime
-	EndDate             sql.NullTime
-	Deadline            sql.NullTime
-	CreatedAt           time.Time
-	UpdatedAt           time.Time
-	DeletedAt           sql.NullTime
+	ID                     uint
+	Title                  string
+	Description            sql.NullString
+	MissionType            string
+	CreatorID              uint
+	RewardPoints           int64
+	RewardRules            sql.NullString // JSON
+	Rubric                 sql.NullString // JSON
+	BudgetSource           string         // NONE, WALLET, POOL
+	RewardPoolID           sql.NullInt64
+	BudgetAmount           int64
+	BudgetSpent            int64
+	BudgetReleasedAt       sql.NullTime
+	MaxParticipants        sql.NullInt64
+	CurrentParticipants    int
+	Difficulty             string
+	Requirements           sql.NullString // JSON
+	Content                sql.NullString // JSON
+	IsActive               bool
+	IsRepeatable           bool
+	MaxAttempts            sql.NullInt64
+	AttemptCooldownMinutes int
+	RewardCap              sql.NullInt64
+	RewardCapPeriod        sql.NullString // DAY, WEEK, MONTH
+	StartDate              sql.NullTime
+	EndDate                sql.NullTime
+	Deadline               sql.NullTime
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+	DeletedAt              sql.NullTime
+}
+
+// BudgetRemaining is the reserved budget not yet paid out or released
+func (m *Mission) BudgetRemaining() int64 {
+	if m.BudgetReleasedAt.Valid {
+		return 0
+	}
+	return m.BudgetAmount - m.BudgetSpent
 }
 
 // MissionLog entity
@@ -33,8 +52,10 @@ type MissionLog struct {
 	ID            uint
 	MissionID     uint
 	UserID        uint
+	AttemptNumber int
 	Status        string
 	Score         sql.NullFloat64
+	RubricScores  sql.NullString // JSON
 	Answers       sql.NullString // JSON
 	RewardClaimed bool
 	RewardPoints  sql.NullInt64
@@ -46,11 +67,17 @@ type MissionLog struct {
 	Notes         sql.NullString
 }
 
+// TargetGroup is a class group a mission is restricted to
+type TargetGroup struct {
+	ID   uint
+	Name string
+}
+
 // MissionWithCreator includes creator info
 type MissionWithCreator struct {
 	Mission
 	CreatorName string
-	CreatorRole string
+	CreatorRole string // all unexpired roles, comma separated, earliest first
 }
 
 // MissionLogWithDetails includes mission and user info
@@ -59,3 +86,89 @@ type MissionLogWithDetails struct {
 	MissionTitle string
 	UserName     string
 }
+
+// ScheduledLog is an unfinished attempt together with its mission's schedule
+type ScheduledLog struct {
+	LogID   uint
+	UserID  uint
+	Mission Mission // only ID, Title, RewardRules and the schedule dates are loaded
+}
+
+// AttendanceSession is a QR check-in window of an ATTENDANCE mission
+type AttendanceSession struct {
+	ID              uint
+	MissionID       uint
+	CreatedBy       uint
+	Secret          string
+	RotationSeconds int
+	Latitude        sql.NullFloat64
+	Longitude       sql.NullFloat64
+	RadiusMeters    sql.NullInt64
+	StartsAt        time.Time
+	EndsAt          time.Time
+	ClosedAt        sql.NullTime
+	CreatedAt       time.Time
+}
+
+// IsOpen reports whether students can still check in
+func (s *AttendanceSession) IsOpen(now time.Time) bool {
+	return !s.ClosedAt.Valid && !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+// AttendanceRecord is a participant row of an attendance mission
+type AttendanceRecord struct {
+	UserID      uint
+	UserName    string
+	NIM         sql.NullString
+	Status      string
+	Answers     sql.NullString // JSON check-in details
+	CompletedAt sql.NullTime
+}
+
+// RewardPool is an admin-funded source of mission budgets
+type RewardPool struct {
+	ID          uint
+	Name        string
+	Description sql.NullString
+	Balance     int64
+	Reserved    int64
+	TotalFunded int64
+	IsActive    bool
+	CreatedBy   sql.NullInt64
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Submission is one version of a participant's hand-in
+type Submission struct {
+	ID           uint
+	MissionLogID uint
+	Version      int
+	Answers      sql.NullString // JSON
+	Note         sql.NullString
+	SubmittedAt  time.Time
+	Attachments  []*SubmissionAttachment
+}
+
+// SubmissionAttachment is an uploaded file or a link attached to a submission
+type SubmissionAttachment struct {
+	ID           uint
+	SubmissionID uint
+	Kind         string // FILE or LINK
+	FileName     sql.NullString
+	ContentType  sql.NullString
+	SizeBytes    sql.NullInt64
+	StorageKey   sql.NullString
+	Checksum     sql.NullString
+	URL          sql.NullString
+	CreatedAt    time.Time
+}
+
+// AttachmentWithOwner includes the mission and participant an attachment belongs to
+type AttachmentWithOwner struct {
+	SubmissionAttachment
+	MissionLogID uint
+	MissionID    uint
+	UserID       uint
+	Version      int
+}
@@ -1,7 +1,10 @@
 package mission
 
 import (
+	"encoding/json"
+	"fmt"
 	"strconv"
+	"strings"
 
 	apperrors "walletpoint/internal/shared/errors"
 	"walletpoint/internal/shared/response"
@@ -45,7 +48,8 @@ func (h *Handler) GetByID(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid mission ID")
 	}
 
-	result, err := h.service.GetByID(c.Context(), uint(id))
+	userID := c.Locals("userID").(uint)
+	result, err := h.service.GetByID(c.Context(), uint(id), userID)
 	if err != nil {
 		return handleError(c, err)
 	}
@@ -55,6 +59,9 @@ func (h *Handler) GetByID(c *fiber.Ctx) error {
 
 // GetActiveList lists active missions
 func (h *Handler) GetActiveList(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	role := c.Locals("role").(string)
+
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	perPage, _ := strconv.Atoi(c.Query("per_page", "20"))
 
@@ -65,7 +72,7 @@ func (h *Handler) GetActiveList(c *fiber.Ctx) error {
 		perPage = 20
 	}
 
-	missions, total, err := h.service.GetActiveList(c.Context(), page, perPage)
+	missions, total, err := h.service.GetActiveList(c.Context(), userID, role, page, perPage)
 	if err != nil {
 		return handleError(c, err)
 	}
@@ -122,6 +129,10 @@ func (h *Handler) Update(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid request body")
 	}
 
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
 	if err := h.service.Update(c.Context(), uint(id), req, userID); err != nil {
 		return handleError(c, err)
 	}
@@ -144,6 +155,142 @@ func (h *Handler) Delete(c *fiber.Ctx) error {
 	return response.Success(c, "Mission deleted successfully", nil)
 }
 
+// CloseMission closes a mission and releases its unused budget
+func (h *Handler) CloseMission(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid mission ID")
+	}
+
+	result, err := h.service.CloseMission(c.Context(), uint(id), userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Mission closed", result)
+}
+
+// TopUpBudget adds points to a mission budget
+func (h *Handler) TopUpBudget(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid mission ID")
+	}
+
+	var req TopUpBudgetRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.TopUpBudget(c.Context(), uint(id), req, userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Mission budget topped up", result)
+}
+
+// GetActiveRewardPools lists pools lecturers can fund missions from
+func (h *Handler) GetActiveRewardPools(c *fiber.Ctx) error {
+	result, err := h.service.GetRewardPools(c.Context(), true)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Reward pools retrieved", result)
+}
+
+// GetAllRewardPools lists every reward pool (admin)
+func (h *Handler) GetAllRewardPools(c *fiber.Ctx) error {
+	result, err := h.service.GetRewardPools(c.Context(), false)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Reward pools retrieved", result)
+}
+
+// CreateRewardPool creates a reward pool (admin)
+func (h *Handler) CreateRewardPool(c *fiber.Ctx) error {
+	adminID := c.Locals("userID").(uint)
+
+	var req CreateRewardPoolRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.CreateRewardPool(c.Context(), req, adminID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Created(c, "Reward pool created", result)
+}
+
+// UpdateRewardPool renames or retires a reward pool (admin)
+func (h *Handler) UpdateRewardPool(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid reward pool ID")
+	}
+
+	var req UpdateRewardPoolRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.UpdateRewardPool(c.Context(), uint(id), req)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Reward pool updated", result)
+}
+
+// FundRewardPool adds points to a reward pool (admin)
+func (h *Handler) FundRewardPool(c *fiber.Ctx) error {
+	adminID := c.Locals("userID").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid reward pool ID")
+	}
+
+	var req FundRewardPoolRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	// Get idempotency key from header if not in body
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = c.Get("X-Idempotency-Key")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.FundRewardPool(c.Context(), uint(id), req, adminID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Reward pool funded", result)
+}
+
 // StartMission starts participating in a mission
 func (h *Handler) StartMission(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uint)
@@ -169,15 +316,115 @@ func (h *Handler) SubmitMission(c *fiber.Ctx) error {
 	}
 
 	var req SubmitMissionRequest
-	if err := c.BodyParser(&req); err != nil {
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		if err := parseSubmissionForm(c, &req); err != nil {
+			return response.BadRequest(c, "Invalid request body")
+		}
+	} else if err := c.BodyParser(&req); err != nil {
 		return response.BadRequest(c, "Invalid request body")
 	}
 
-	if err := h.service.SubmitMission(c.Context(), uint(id), userID, req); err != nil {
+	result, err := h.service.SubmitMission(c.Context(), uint(id), userID, req)
+	if err != nil {
 		return handleError(c, err)
 	}
 
-	return response.Success(c, "Mission submitted successfully", nil)
+	return response.Success(c, "Mission submitted successfully", result)
+}
+
+// parseSubmissionForm reads a multipart submission: "answers" holds the JSON
+// answers, "links" and "files" may repeat
+func parseSubmissionForm(c *fiber.Ctx, req *SubmitMissionRequest) error {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return err
+	}
+	if answers := form.Value["answers"]; len(answers) > 0 && answers[0] != "" {
+		if !json.Valid([]byte(answers[0])) {
+			return fmt.Errorf("answers is not valid JSON")
+		}
+		req.Answers = json.RawMessage(answers[0])
+	}
+	if note := form.Value["note"]; len(note) > 0 {
+		req.Note = note[0]
+	}
+	req.Links = form.Value["links"]
+	req.Files = form.File["files"]
+	return nil
+}
+
+// GetMyAttempts returns the caller's attempt history for a mission
+func (h *Handler) GetMyAttempts(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid mission ID")
+	}
+
+	result, err := h.service.GetMyAttempts(c.Context(), uint(id), userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Attempts retrieved", result)
+}
+
+// GetMySubmissions returns the caller's submission versions for a mission
+func (h *Handler) GetMySubmissions(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid mission ID")
+	}
+
+	result, err := h.service.GetMySubmissions(c.Context(), uint(id), userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Submissions retrieved", result)
+}
+
+// GetParticipantSubmissions returns a participant's submission versions (creator only)
+func (h *Handler) GetParticipantSubmissions(c *fiber.Ctx) error {
+	graderID := c.Locals("userID").(uint)
+	missionID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid mission ID")
+	}
+	participantID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	result, err := h.service.GetParticipantSubmissions(c.Context(), uint(missionID), uint(participantID), graderID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Submissions retrieved", result)
+}
+
+// DownloadAttachment streams an uploaded submission file
+func (h *Handler) DownloadAttachment(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	missionID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid mission ID")
+	}
+	attachmentID, err := strconv.ParseUint(c.Params("attachmentId"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid attachment ID")
+	}
+
+	attachment, file, err := h.service.OpenAttachment(c.Context(), uint(missionID), uint(attachmentID), userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	c.Attachment(attachment.FileName.String)
+	c.Set(fiber.HeaderContentType, attachment.ContentType.String)
+	return c.SendStream(file, int(attachment.SizeBytes.Int64))
 }
 
 // GradeMission grades a mission submission
@@ -197,6 +444,10 @@ func (h *Handler) GradeMission(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid request body")
 	}
 
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
 	if err := h.service.GradeMission(c.Context(), uint(missionID), uint(participantID), graderID, req); err != nil {
 		return handleError(c, err)
 	}
@@ -204,6 +455,31 @@ func (h *Handler) GradeMission(c *fiber.Ctx) error {
 	return response.Success(c, "Mission graded successfully", nil)
 }
 
+// BulkGrade grades many participants of a mission in one request
+func (h *Handler) BulkGrade(c *fiber.Ctx) error {
+	graderID := c.Locals("userID").(uint)
+	missionID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid mission ID")
+	}
+
+	var req BulkGradeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.BulkGrade(c.Context(), uint(missionID), graderID, req)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, fmt.Sprintf("%d graded, %d failed", result.Graded, result.Failed), result)
+}
+
 // GetMyParticipations lists user's mission participations
 func (h *Handler) GetMyParticipations(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uint)
@@ -242,15 +518,142 @@ func (h *Handler) GetParticipants(c *fiber.Ctx) error {
 	return response.Success(c, "Participants retrieved", participants)
 }
 
+// OpenAttendanceSession opens a QR check-in session for an attendance mission
+func (h *Handler) OpenAttendanceSession(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid mission ID")
+	}
+
+	var req OpenAttendanceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.OpenAttendanceSession(c.Context(), uint(id), req, userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Created(c, "Attendance session opened", result)
+}
+
+// GetAttendanceQR returns the current rotating attendance QR
+func (h *Handler) GetAttendanceQR(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid mission ID")
+	}
+	sessionID, err := strconv.ParseUint(c.Params("sessionId"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid session ID")
+	}
+
+	result, err := h.service.GetAttendanceQR(c.Context(), uint(id), uint(sessionID), userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Attendance QR generated", result)
+}
+
+// CloseAttendanceSession stops accepting check-ins
+func (h *Handler) CloseAttendanceSession(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid mission ID")
+	}
+	sessionID, err := strconv.ParseUint(c.Params("sessionId"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid session ID")
+	}
+
+	if err := h.service.CloseAttendanceSession(c.Context(), uint(id), uint(sessionID), userID); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Attendance session closed", nil)
+}
+
+// CheckIn records attendance from a scanned QR
+func (h *Handler) CheckIn(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req CheckInRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.CheckIn(c.Context(), req, userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Checked in successfully", result)
+}
+
+// GetAttendance lists attendance of a mission
+func (h *Handler) GetAttendance(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid mission ID")
+	}
+
+	result, err := h.service.GetAttendance(c.Context(), uint(id), userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Attendance retrieved", result)
+}
+
+// ExportAttendance downloads attendance as CSV
+func (h *Handler) ExportAttendance(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid mission ID")
+	}
+
+	data, err := h.service.ExportAttendanceCSV(c.Context(), uint(id), userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Attachment(fmt.Sprintf("attendance-mission-%d.csv", id))
+	return c.Send(data)
+}
+
 func handleError(c *fiber.Ctx, err error) error {
 	if appErr, ok := err.(*apperrors.AppError); ok {
 		switch appErr.Code {
-		case "NOT_FOUND":
+		case "NOT_FOUND", "POOL_NOT_FOUND", "WALLET_NOT_FOUND", "GROUP_NOT_FOUND":
 			return response.NotFound(c, appErr.Message)
-		case "FORBIDDEN":
+		case "FORBIDDEN", "OUTSIDE_GEOFENCE", "NOT_TARGETED":
 			return response.Forbidden(c, appErr.Message)
-		case "CANNOT_START_OWN", "ALREADY_PARTICIPATED", "MAX_PARTICIPANTS", "MISSION_INACTIVE",
-			"NOT_STARTED", "INVALID_STATUS", "NOT_SUBMITTED":
+		case "ALREADY_CHECKED_IN":
+			return response.Conflict(c, appErr.Message)
+		case "CANNOT_START_OWN", "ALREADY_PARTICIPATED", "MAX_PARTICIPANTS", "MISSION_INACTIVE", "MISSION_NOT_OPEN", "MISSION_ENDED", "DEADLINE_PASSED",
+			"ATTEMPT_IN_PROGRESS", "MAX_ATTEMPTS", "ATTEMPT_COOLDOWN", "NOT_REPEATABLE",
+			"NOT_STARTED", "INVALID_STATUS", "NOT_SUBMITTED", "INVALID_QUIZ", "INVALID_ANSWERS",
+			"TIME_LIMIT_EXCEEDED", "USE_CHECK_IN", "NOT_ATTENDANCE", "SESSION_CLOSED", "INVALID_TOKEN",
+			"LOCATION_REQUIRED", "INSUFFICIENT_BALANCE", "WALLET_FROZEN", "POOL_INACTIVE", "INSUFFICIENT_POOL_BALANCE",
+			"BUDGET_EXHAUSTED", "BUDGET_RELEASED", "NO_BUDGET", "PENDING_SUBMISSIONS",
+			"INVALID_ATTACHMENT", "ATTACHMENT_TOO_LARGE", "ATTACHMENTS_NOT_ALLOWED",
+			"INVALID_RUBRIC", "RUBRIC_REQUIRED", "NO_RUBRIC", "INVALID_RUBRIC_SCORES":
 			return response.BadRequest(c, appErr.Message)
 		default:
 			return response.InternalError(c, appErr.Message)
@@ -0,0 +1,320 @@
+package mission
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Quiz question types
+const (
+	QuestionSingleChoice   = "SINGLE_CHOICE"
+	QuestionMultipleChoice = "MULTIPLE_CHOICE"
+	QuestionTrueFalse      = "TRUE_FALSE"
+	QuestionShortAnswer    = "SHORT_ANSWER"
+)
+
+// DefaultPassScore is the passing percentage when a quiz does not set one
+const DefaultPassScore = 60.0
+
+// quizSubmitGrace tolerates network latency on submissions right at the time limit
+const quizSubmitGrace = 30 * time.Second
+
+// QuizContent is the typed schema of Mission.Content for QUIZ missions
+type QuizContent struct {
+	Instructions     string         `json:"instructions,omitempty"`
+	TimeLimitMinutes int            `json:"time_limit_minutes,omitempty"`
+	PassScore        *float64       `json:"pass_score,omitempty"` // percentage 0-100
+	Questions        []QuizQuestion `json:"questions"`
+}
+
+// QuizQuestion is one question; AnswerKey holds option IDs for choice questions,
+// "true"/"false" for TRUE_FALSE and accepted answers for SHORT_ANSWER
+type QuizQuestion struct {
+	ID            string       `json:"id"`
+	Type          string       `json:"type"`
+	Text          string       `json:"text"`
+	Options       []QuizOption `json:"options,omitempty"`
+	Points        float64      `json:"points,omitempty"`
+	AnswerKey     []string     `json:"answer_key,omitempty"`
+	CaseSensitive bool         `json:"case_sensitive,omitempty"`
+}
+
+// QuizOption is a selectable option of a choice question
+type QuizOption struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// QuizQuestionResult is the outcome of one question, without revealing the key
+type QuizQuestionResult struct {
+	QuestionID   string  `json:"question_id"`
+	Correct      bool    `json:"correct"`
+	PointsEarned float64 `json:"points_earned"`
+	Points       float64 `json:"points"`
+}
+
+// QuizResult is the outcome of scoring a submission
+type QuizResult struct {
+	Score        float64              `json:"score"` // percentage 0-100
+	PassScore    float64              `json:"pass_score"`
+	Passed       bool                 `json:"passed"`
+	PointsEarned float64              `json:"points_earned"`
+	TotalPoints  float64              `json:"total_points"`
+	Questions    []QuizQuestionResult `json:"questions"`
+}
+
+// ParseQuiz decodes and validates quiz content
+func ParseQuiz(raw []byte) (*QuizContent, []ValidationError) {
+	var quiz QuizContent
+	if err := json.Unmarshal(raw, &quiz); err != nil {
+		return nil, []ValidationError{{Field: "content", Message: "Quiz content must be a valid quiz object"}}
+	}
+
+	var errors []ValidationError
+	if len(quiz.Questions) == 0 {
+		errors = append(errors, ValidationError{Field: "content.questions", Message: "Quiz must have at least one question"})
+	}
+	if quiz.TimeLimitMinutes < 0 {
+		errors = append(errors, ValidationError{Field: "content.time_limit_minutes", Message: "Time limit must not be negative"})
+	}
+	if quiz.PassScore != nil && (*quiz.PassScore < 0 || *quiz.PassScore > 100) {
+		errors = append(errors, ValidationError{Field: "content.pass_score", Message: "Pass score must be between 0 and 100"})
+	}
+
+	seen := map[string]bool{}
+	for i := range quiz.Questions {
+		q := &quiz.Questions[i]
+		field := fmt.Sprintf("content.questions[%d]", i)
+
+		if q.ID == "" {
+			q.ID = fmt.Sprintf("q%d", i+1)
+		}
+		if seen[q.ID] {
+			errors = append(errors, ValidationError{Field: field + ".id", Message: "Question ID must be unique"})
+		}
+		seen[q.ID] = true
+
+		if strings.TrimSpace(q.Text) == "" {
+			errors = append(errors, ValidationError{Field: field + ".text", Message: "Question text is required"})
+		}
+		if q.Points == 0 {
+			q.Points = 1
+		}
+		if q.Points < 0 {
+			errors = append(errors, ValidationError{Field: field + ".points", Message: "Points must be positive"})
+		}
+
+		errors = append(errors, validateAnswerKey(q, field)...)
+	}
+
+	return &quiz, errors
+}
+
+func validateAnswerKey(q *QuizQuestion, field string) []ValidationError {
+	var errors []ValidationError
+
+	switch q.Type {
+	case QuestionSingleChoice, QuestionMultipleChoice:
+		if len(q.Options) < 2 {
+			errors = append(errors, ValidationError{Field: field + ".options", Message: "Choice questions need at least two options"})
+		}
+		options := map[string]bool{}
+		for _, o := range q.Options {
+			if o.ID == "" || options[o.ID] {
+				errors = append(errors, ValidationError{Field: field + ".options", Message: "Option IDs must be present and unique"})
+				break
+			}
+			options[o.ID] = true
+		}
+		for _, key := range q.AnswerKey {
+			if !options[key] {
+				errors = append(errors, ValidationError{Field: field + ".answer_key", Message: "Answer key must reference existing options"})
+				break
+			}
+		}
+		if q.Type == QuestionSingleChoice && len(q.AnswerKey) != 1 {
+			errors = append(errors, ValidationError{Field: field + ".answer_key", Message: "Single-choice questions need exactly one correct option"})
+		}
+		if q.Type == QuestionMultipleChoice && len(q.AnswerKey) == 0 {
+			errors = append(errors, ValidationError{Field: field + ".answer_key", Message: "Multiple-choice questions need at least one correct option"})
+		}
+	case QuestionTrueFalse:
+		if len(q.AnswerKey) != 1 || (q.AnswerKey[0] != "true" && q.AnswerKey[0] != "false") {
+			errors = append(errors, ValidationError{Field: field + ".answer_key", Message: "True/false questions need an answer key of \"true\" or \"false\""})
+		}
+		q.Options = nil
+	case QuestionShortAnswer:
+		if len(q.AnswerKey) == 0 {
+			errors = append(errors, ValidationError{Field: field + ".answer_key", Message: "Short-answer questions need at least one accepted answer"})
+		}
+		q.Options = nil
+	default:
+		errors = append(errors, ValidationError{Field: field + ".type", Message: "Invalid question type"})
+	}
+
+	return errors
+}
+
+// PassThreshold returns the configured pass score or the default
+func (q *QuizContent) PassThreshold() float64 {
+	if q.PassScore != nil {
+		return *q.PassScore
+	}
+	return DefaultPassScore
+}
+
+// TimeLimit returns the time allowed after starting, zero when unlimited
+func (q *QuizContent) TimeLimit() time.Duration {
+	return time.Duration(q.TimeLimitMinutes) * time.Minute
+}
+
+// Public returns a copy safe to show participants, with answer keys removed
+func (q *QuizContent) Public() *QuizContent {
+	public := *q
+	public.Questions = make([]QuizQuestion, len(q.Questions))
+	for i, question := range q.Questions {
+		question.AnswerKey = nil
+		question.CaseSensitive = false
+		public.Questions[i] = question
+	}
+	return &public
+}
+
+// answerKeyFields are the QuizQuestion fields participants must never see
+var answerKeyFields = []string{"answer_key", "case_sensitive"}
+
+// StripAnswerKeys removes answer key fields at any depth from content that
+// does not parse as a quiz, so a malformed quiz cannot leak its keys. Content
+// that is not JSON at all is withheld
+func StripAnswerKeys(raw []byte) json.RawMessage {
+	var content interface{}
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return nil
+	}
+	stripped, err := json.Marshal(stripFields(content))
+	if err != nil {
+		return nil
+	}
+	return stripped
+}
+
+func stripFields(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for _, field := range answerKeyFields {
+			delete(value, field)
+		}
+		for k, child := range value {
+			value[k] = stripFields(child)
+		}
+	case []interface{}:
+		for i, child := range value {
+			value[i] = stripFields(child)
+		}
+	}
+	return v
+}
+
+// Score grades answers keyed by question ID; each answer is a string or list of strings
+func (q *QuizContent) Score(raw json.RawMessage) (*QuizResult, error) {
+	answers := map[string]json.RawMessage{}
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &answers); err != nil {
+			return nil, fmt.Errorf("answers must be an object keyed by question ID")
+		}
+	}
+
+	result := &QuizResult{PassScore: q.PassThreshold()}
+	for _, question := range q.Questions {
+		given, err := decodeAnswer(answers[question.ID])
+		if err != nil {
+			return nil, fmt.Errorf("invalid answer for question %s", question.ID)
+		}
+
+		correct := question.isCorrect(given)
+		qr := QuizQuestionResult{QuestionID: question.ID, Correct: correct, Points: question.Points}
+		if correct {
+			qr.PointsEarned = question.Points
+		}
+
+		result.TotalPoints += question.Points
+		result.PointsEarned += qr.PointsEarned
+		result.Questions = append(result.Questions, qr)
+	}
+
+	if result.TotalPoints > 0 {
+		result.Score = math.Round(result.PointsEarned/result.TotalPoints*10000) / 100
+	}
+	result.Passed = result.Score >= result.PassScore
+
+	return result, nil
+}
+
+func (q *QuizQuestion) isCorrect(given []string) bool {
+	switch q.Type {
+	case QuestionSingleChoice, QuestionMultipleChoice:
+		return sameSet(given, q.AnswerKey)
+	case QuestionTrueFalse:
+		return len(given) == 1 && strings.EqualFold(strings.TrimSpace(given[0]), q.AnswerKey[0])
+	case QuestionShortAnswer:
+		if len(given) != 1 {
+			return false
+		}
+		answer := normalizeShortAnswer(given[0], q.CaseSensitive)
+		for _, accepted := range q.AnswerKey {
+			if answer == normalizeShortAnswer(accepted, q.CaseSensitive) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeAnswer accepts a string, boolean or list of strings
+func decodeAnswer(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list, nil
+	}
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}, nil
+	}
+	var flag bool
+	if err := json.Unmarshal(raw, &flag); err == nil {
+		return []string{fmt.Sprint(flag)}, nil
+	}
+	return nil, fmt.Errorf("unsupported answer format")
+}
+
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x := append([]string(nil), a...)
+	y := append([]string(nil), b...)
+	sort.Strings(x)
+	sort.Strings(y)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeShortAnswer(s string, caseSensitive bool) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if !caseSensitive {
+		s = strings.ToLower(s)
+	}
+	return s
+}
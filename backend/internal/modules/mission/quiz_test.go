@@ -0,0 +1,38 @@
+package mission
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripAnswerKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{
+			"invalid quiz",
+			`{"questions":[{"id":"q1","type":"SHORT_ANSWER","text":"Capital?","answer_key":["Jakarta"],"case_sensitive":true}],"pass_score":150}`,
+			`{"pass_score":150,"questions":[{"id":"q1","text":"Capital?","type":"SHORT_ANSWER"}]}`,
+		},
+		{
+			"nested layout",
+			`{"sections":[{"items":[{"text":"2+2","answer_key":"4"}]}]}`,
+			`{"sections":[{"items":[{"text":"2+2"}]}]}`,
+		},
+		{"legacy text", `"Read chapter 3"`, `"Read chapter 3"`},
+		{"not JSON", `answer_key: Jakarta`, ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(StripAnswerKeys([]byte(tt.raw)))
+			if got != tt.want {
+				t.Errorf("StripAnswerKeys = %s, want %s", got, tt.want)
+			}
+			if strings.Contains(got, "answer_key") || strings.Contains(got, "case_sensitive") {
+				t.Errorf("answer key leaked: %s", got)
+			}
+		})
+	}
+}
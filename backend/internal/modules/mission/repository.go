@@ -3,6 +3,7 @@ package mission
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 type Repository struct {
@@ -13,18 +14,50 @@ func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) Create(ctx context.Context, m *Mission) error {
+const missionColumns = `
+	id, title, description, mission_type, creator_id, reward_points, reward_rules, rubric,
+	budget_source, reward_pool_id, budget_amount, budget_spent, budget_released_at,
+	max_participants, current_participants, difficulty, requirements, content,
+	is_active, is_repeatable, max_attempts, attempt_cooldown_minutes, reward_cap, reward_cap_period,
+	start_date, end_date, deadline, created_at, updated_at
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMission(row rowScanner) (*Mission, error) {
+	var m Mission
+	err := row.Scan(
+		&m.ID, &m.Title, &m.Description, &m.MissionType, &m.CreatorID, &m.RewardPoints, &m.RewardRules, &m.Rubric,
+		&m.BudgetSource, &m.RewardPoolID, &m.BudgetAmount, &m.BudgetSpent, &m.BudgetReleasedAt,
+		&m.MaxParticipants, &m.CurrentParticipants, &m.Difficulty, &m.Requirements, &m.Content,
+		&m.IsActive, &m.IsRepeatable, &m.MaxAttempts, &m.AttemptCooldownMinutes, &m.RewardCap, &m.RewardCapPeriod,
+		&m.StartDate, &m.EndDate, &m.Deadline, &m.CreatedAt, &m.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Create inserts a mission inside tx so its budget is reserved atomically
+func (r *Repository) Create(ctx context.Context, tx *sql.Tx, m *Mission) error {
 	query := `
-		INSERT INTO missions (title, description, mission_type, creator_id, reward_points,
+		INSERT INTO missions (title, description, mission_type, creator_id, reward_points, reward_rules, rubric,
+			budget_source, reward_pool_id, budget_amount, budget_spent,
 			max_participants, current_participants, difficulty, requirements, content,
-			is_active, is_repeatable, start_date, end_date, deadline, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
+			is_active, is_repeatable, max_attempts, attempt_cooldown_minutes, reward_cap, reward_cap_period,
+			start_date, end_date, deadline, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, 0, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
 	`
 
-	result, err := r.db.ExecContext(ctx, query,
-		m.Title, m.Description, m.MissionType, m.CreatorID, m.RewardPoints,
+	result, err := tx.ExecContext(ctx, query,
+		m.Title, m.Description, m.MissionType, m.CreatorID, m.RewardPoints, m.RewardRules, m.Rubric,
+		m.BudgetSource, m.RewardPoolID, m.BudgetAmount,
 		m.MaxParticipants, m.Difficulty, m.Requirements, m.Content,
-		m.IsActive, m.IsRepeatable, m.StartDate, m.EndDate, m.Deadline,
+		m.IsActive, m.IsRepeatable, m.MaxAttempts, m.AttemptCooldownMinutes, m.RewardCap, m.RewardCapPeriod,
+		m.StartDate, m.EndDate, m.Deadline,
 	)
 	if err != nil {
 		return err
@@ -40,20 +73,24 @@ func (r *Repository) Create(ctx context.Context, m *Mission) error {
 }
 
 func (r *Repository) GetByID(ctx context.Context, id uint) (*Mission, error) {
-	query := `
-		SELECT id, title, description, mission_type, creator_id, reward_points,
-			max_participants, current_participants, difficulty, requirements, content,
-			is_active, is_repeatable, start_date, end_date, deadline, created_at, updated_at
-		FROM missions WHERE id = ? AND deleted_at IS NULL
-	`
+	query := `SELECT ` + missionColumns + ` FROM missions WHERE id = ? AND deleted_at IS NULL`
 
-	var m Mission
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&m.ID, &m.Title, &m.Description, &m.MissionType, &m.CreatorID, &m.RewardPoints,
-		&m.MaxParticipants, &m.CurrentParticipants, &m.Difficulty, &m.Requirements, &m.Content,
-		&m.IsActive, &m.IsRepeatable, &m.StartDate, &m.EndDate, &m.Deadline, &m.CreatedAt, &m.UpdatedAt,
-	)
+	m, err := scanMission(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// GetByIDForUpdate locks a mission row while its budget is spent or released
+func (r *Repository) GetByIDForUpdate(ctx context.Context, tx *sql.Tx, id uint) (*Mission, error) {
+	query := `SELECT ` + missionColumns + ` FROM missions WHERE id = ? AND deleted_at IS NULL FOR UPDATE`
 
+	m, err := scanMission(tx.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -61,31 +98,56 @@ func (r *Repository) GetByID(ctx context.Context, id uint) (*Mission, error) {
 		return nil, err
 	}
 
-	return &m, nil
+	return m, nil
 }
 
-func (r *Repository) GetActiveList(ctx context.Context, limit, offset int) ([]*MissionWithCreator, int, error) {
+// openMissionFilter matches active missions inside their schedule window
+const openMissionFilter = `m.is_active = TRUE AND m.deleted_at IS NULL
+	AND (m.start_date IS NULL OR m.start_date <= NOW())
+	AND (m.end_date IS NULL OR m.end_date > NOW())`
+
+// targetedMissionFilter matches missions the viewer (bound twice) may see:
+// their own, untargeted ones, and ones aimed at a group they belong to
+const targetedMissionFilter = `(m.creator_id = ?
+	OR NOT EXISTS (SELECT 1 FROM mission_target_groups mtg WHERE mtg.mission_id = m.id)
+	OR EXISTS (
+		SELECT 1 FROM mission_target_groups mtg
+		INNER JOIN class_group_members gm ON gm.group_id = mtg.group_id
+		WHERE mtg.mission_id = m.id AND gm.user_id = ?
+	))`
+
+// GetActiveList lists open missions visible to viewerID; 0 skips the group targeting filter
+func (r *Repository) GetActiveList(ctx context.Context, viewerID uint, limit, offset int) ([]*MissionWithCreator, int, error) {
+	filter := openMissionFilter
+	var args []interface{}
+	if viewerID != 0 {
+		filter += ` AND ` + targetedMissionFilter
+		args = append(args, viewerID, viewerID)
+	}
+
 	var total int
-	countQuery := `SELECT COUNT(*) FROM missions WHERE is_active = TRUE AND deleted_at IS NULL`
-	if err := r.db.QueryRowContext(ctx, countQuery).Scan(&total); err != nil {
+	countQuery := `SELECT COUNT(*) FROM missions m WHERE ` + filter
+	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
 	query := `
-		SELECT m.id, m.title, m.description, m.mission_type, m.creator_id, m.reward_points,
+		SELECT m.id, m.title, m.description, m.mission_type, m.creator_id, m.reward_points, m.reward_rules,
 			m.max_participants, m.current_participants, m.difficulty, m.content,
 			m.is_active, m.is_repeatable, m.start_date, m.end_date, m.deadline, m.created_at,
-			u.full_name as creator_name, ro.name as creator_role
+			u.full_name as creator_name,
+			COALESCE((SELECT GROUP_CONCAT(ro.name ORDER BY ur.assigned_at ASC, ur.id ASC)
+				FROM user_roles ur
+				INNER JOIN roles ro ON ur.role_id = ro.id
+				WHERE ur.user_id = u.id AND (ur.expires_at IS NULL OR ur.expires_at > NOW())), '') as creator_roles
 		FROM missions m
 		INNER JOIN users u ON m.creator_id = u.id
-		INNER JOIN user_roles ur ON u.id = ur.user_id
-		INNER JOIN roles ro ON ur.role_id = ro.id
-		WHERE m.is_active = TRUE AND m.deleted_at IS NULL
+		WHERE ` + filter + `
 		ORDER BY m.created_at DESC
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit, offset)...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -95,7 +157,7 @@ func (r *Repository) GetActiveList(ctx context.Context, limit, offset int) ([]*M
 	for rows.Next() {
 		var m MissionWithCreator
 		if err := rows.Scan(
-			&m.ID, &m.Title, &m.Description, &m.MissionType, &m.CreatorID, &m.RewardPoints,
+			&m.ID, &m.Title, &m.Description, &m.MissionType, &m.CreatorID, &m.RewardPoints, &m.RewardRules,
 			&m.MaxParticipants, &m.CurrentParticipants, &m.Difficulty, &m.Content,
 			&m.IsActive, &m.IsRepeatable, &m.StartDate, &m.EndDate, &m.Deadline, &m.CreatedAt,
 			&m.CreatorName, &m.CreatorRole,
@@ -108,6 +170,55 @@ func (r *Repository) GetActiveList(ctx context.Context, limit, offset int) ([]*M
 	return missions, total, nil
 }
 
+// SetTargetGroups replaces the groups a mission is restricted to
+func (r *Repository) SetTargetGroups(ctx context.Context, tx *sql.Tx, missionID uint, groupIDs []uint) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mission_target_groups WHERE mission_id = ?`, missionID); err != nil {
+		return err
+	}
+	for _, groupID := range groupIDs {
+		query := `INSERT INTO mission_target_groups (mission_id, group_id) VALUES (?, ?)`
+		if _, err := tx.ExecContext(ctx, query, missionID, groupID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) GetTargetGroups(ctx context.Context, missionID uint) ([]*TargetGroup, error) {
+	query := `
+		SELECT g.id, g.name
+		FROM mission_target_groups mtg
+		INNER JOIN class_groups g ON mtg.group_id = g.id
+		WHERE mtg.mission_id = ? AND g.deleted_at IS NULL
+		ORDER BY g.name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, missionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []*TargetGroup
+	for rows.Next() {
+		var g TargetGroup
+		if err := rows.Scan(&g.ID, &g.Name); err != nil {
+			return nil, err
+		}
+		groups = append(groups, &g)
+	}
+
+	return groups, nil
+}
+
+// IsTargetedUser reports whether a mission is open to the user under its group targeting
+func (r *Repository) IsTargetedUser(ctx context.Context, missionID, userID uint) (bool, error) {
+	query := `SELECT COUNT(*) FROM missions m WHERE m.id = ? AND ` + targetedMissionFilter
+	var count int
+	err := r.db.QueryRowContext(ctx, query, missionID, userID, userID).Scan(&count)
+	return count > 0, err
+}
+
 func (r *Repository) GetByCreatorID(ctx context.Context, creatorID uint, limit, offset int) ([]*Mission, int, error) {
 	var total int
 	countQuery := `SELECT COUNT(*) FROM missions WHERE creator_id = ? AND deleted_at IS NULL`
@@ -116,9 +227,7 @@ func (r *Repository) GetByCreatorID(ctx context.Context, creatorID uint, limit,
 	}
 
 	query := `
-		SELECT id, title, description, mission_type, creator_id, reward_points,
-			max_participants, current_participants, difficulty, content,
-			is_active, is_repeatable, start_date, end_date, deadline, created_at, updated_at
+		SELECT ` + missionColumns + `
 		FROM missions 
 		WHERE creator_id = ? AND deleted_at IS NULL
 		ORDER BY created_at DESC
@@ -133,40 +242,74 @@ func (r *Repository) GetByCreatorID(ctx context.Context, creatorID uint, limit,
 
 	var missions []*Mission
 	for rows.Next() {
-		var m Mission
-		if err := rows.Scan(
-			&m.ID, &m.Title, &m.Description, &m.MissionType, &m.CreatorID, &m.RewardPoints,
-			&m.MaxParticipants, &m.CurrentParticipants, &m.Difficulty, &m.Content,
-			&m.IsActive, &m.IsRepeatable, &m.StartDate, &m.EndDate, &m.Deadline, &m.CreatedAt, &m.UpdatedAt,
-		); err != nil {
+		m, err := scanMission(rows)
+		if err != nil {
 			return nil, 0, err
 		}
-		missions = append(missions, &m)
+		missions = append(missions, m)
 	}
 
 	return missions, total, nil
 }
 
-func (r *Repository) Update(ctx context.Context, m *Mission) error {
+func (r *Repository) Update(ctx context.Context, tx *sql.Tx, m *Mission) error {
 	query := `
-		UPDATE missions SET title = ?, description = ?, reward_points = ?,
+		UPDATE missions SET title = ?, description = ?, reward_points = ?, reward_rules = ?, rubric = ?,
 			max_participants = ?, difficulty = ?, content = ?, is_active = ?,
+			max_attempts = ?, attempt_cooldown_minutes = ?, reward_cap = ?, reward_cap_period = ?,
 			start_date = ?, end_date = ?, deadline = ?, updated_at = NOW()
 		WHERE id = ?
 	`
-	_, err := r.db.ExecContext(ctx, query,
-		m.Title, m.Description, m.RewardPoints, m.MaxParticipants, m.Difficulty,
-		m.Content, m.IsActive, m.StartDate, m.EndDate, m.Deadline, m.ID,
+	_, err := tx.ExecContext(ctx, query,
+		m.Title, m.Description, m.RewardPoints, m.RewardRules, m.Rubric, m.MaxParticipants, m.Difficulty,
+		m.Content, m.IsActive, m.MaxAttempts, m.AttemptCooldownMinutes, m.RewardCap, m.RewardCapPeriod,
+		m.StartDate, m.EndDate, m.Deadline, m.ID,
 	)
 	return err
 }
 
-func (r *Repository) Delete(ctx context.Context, id uint) error {
+func (r *Repository) Delete(ctx context.Context, tx *sql.Tx, id uint) error {
 	query := `UPDATE missions SET deleted_at = NOW() WHERE id = ?`
-	_, err := r.db.ExecContext(ctx, query, id)
+	_, err := tx.ExecContext(ctx, query, id)
+	return err
+}
+
+// Deactivate closes a mission to new participants
+func (r *Repository) Deactivate(ctx context.Context, tx *sql.Tx, id uint) error {
+	query := `UPDATE missions SET is_active = FALSE, updated_at = NOW() WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, id)
+	return err
+}
+
+// AddBudgetAmount grows the reserved budget after a top-up
+func (r *Repository) AddBudgetAmount(ctx context.Context, tx *sql.Tx, id uint, amount int64) error {
+	query := `UPDATE missions SET budget_amount = budget_amount + ? WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, amount, id)
+	return err
+}
+
+// AddBudgetSpent records a reward paid out of the mission budget
+func (r *Repository) AddBudgetSpent(ctx context.Context, tx *sql.Tx, id uint, amount int64) error {
+	query := `UPDATE missions SET budget_spent = budget_spent + ? WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, amount, id)
 	return err
 }
 
+// MarkBudgetReleased stops further payouts once the unused budget is returned
+func (r *Repository) MarkBudgetReleased(ctx context.Context, tx *sql.Tx, id uint) error {
+	query := `UPDATE missions SET budget_released_at = NOW() WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, id)
+	return err
+}
+
+// CountPendingSubmissions counts submissions still waiting to be graded
+func (r *Repository) CountPendingSubmissions(ctx context.Context, tx *sql.Tx, missionID uint) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM mission_logs WHERE mission_id = ? AND status = 'SUBMITTED'`
+	err := tx.QueryRowContext(ctx, query, missionID).Scan(&count)
+	return count, err
+}
+
 func (r *Repository) IncrementParticipants(ctx context.Context, tx *sql.Tx, id uint) error {
 	query := `UPDATE missions SET current_participants = current_participants + 1 WHERE id = ?`
 	_, err := tx.ExecContext(ctx, query, id)
@@ -176,16 +319,20 @@ func (r *Repository) IncrementParticipants(ctx context.Context, tx *sql.Tx, id u
 // MissionLog operations
 func (r *Repository) CreateLog(ctx context.Context, tx *sql.Tx, log *MissionLog) error {
 	query := `
-		INSERT INTO mission_logs (mission_id, user_id, status, started_at)
-		VALUES (?, ?, ?, NOW())
+		INSERT INTO mission_logs (mission_id, user_id, attempt_number, status, started_at)
+		VALUES (?, ?, ?, ?, NOW())
 	`
 
+	if log.AttemptNumber == 0 {
+		log.AttemptNumber = 1
+	}
+
 	var result sql.Result
 	var err error
 	if tx != nil {
-		result, err = tx.ExecContext(ctx, query, log.MissionID, log.UserID, log.Status)
+		result, err = tx.ExecContext(ctx, query, log.MissionID, log.UserID, log.AttemptNumber, log.Status)
 	} else {
-		result, err = r.db.ExecContext(ctx, query, log.MissionID, log.UserID, log.Status)
+		result, err = r.db.ExecContext(ctx, query, log.MissionID, log.UserID, log.AttemptNumber, log.Status)
 	}
 	if err != nil {
 		return err
@@ -200,30 +347,82 @@ func (r *Repository) CreateLog(ctx context.Context, tx *sql.Tx, log *MissionLog)
 	return nil
 }
 
-func (r *Repository) GetLogByMissionAndUser(ctx context.Context, missionID, userID uint) (*MissionLog, error) {
-	query := `
-		SELECT id, mission_id, user_id, status, score, answers, reward_claimed, reward_points,
-			started_at, submitted_at, completed_at, graded_at, graded_by, notes
-		FROM mission_logs WHERE mission_id = ? AND user_id = ?
-	`
+const logColumns = `
+	id, mission_id, user_id, attempt_number, status, score, rubric_scores, answers, reward_claimed, reward_points,
+	started_at, submitted_at, completed_at, graded_at, graded_by, notes
+`
 
+func scanLog(row rowScanner) (*MissionLog, error) {
 	var l MissionLog
-	err := r.db.QueryRowContext(ctx, query, missionID, userID).Scan(
-		&l.ID, &l.MissionID, &l.UserID, &l.Status, &l.Score, &l.Answers,
+	err := row.Scan(
+		&l.ID, &l.MissionID, &l.UserID, &l.AttemptNumber, &l.Status, &l.Score, &l.RubricScores, &l.Answers,
 		&l.RewardClaimed, &l.RewardPoints, &l.StartedAt, &l.SubmittedAt,
 		&l.CompletedAt, &l.GradedAt, &l.GradedBy, &l.Notes,
 	)
-
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-
 	return &l, nil
 }
 
+// GetLogByMissionAndUser returns the participant's latest attempt
+func (r *Repository) GetLogByMissionAndUser(ctx context.Context, missionID, userID uint) (*MissionLog, error) {
+	query := `SELECT ` + logColumns + ` FROM mission_logs WHERE mission_id = ? AND user_id = ?
+		ORDER BY attempt_number DESC LIMIT 1`
+	return scanLog(r.db.QueryRowContext(ctx, query, missionID, userID))
+}
+
+// GetLogByMissionAndUserForUpdate locks a participant's latest attempt while it is graded
+func (r *Repository) GetLogByMissionAndUserForUpdate(ctx context.Context, tx *sql.Tx, missionID, userID uint) (*MissionLog, error) {
+	query := `SELECT ` + logColumns + ` FROM mission_logs WHERE mission_id = ? AND user_id = ?
+		ORDER BY attempt_number DESC LIMIT 1 FOR UPDATE`
+	return scanLog(tx.QueryRowContext(ctx, query, missionID, userID))
+}
+
+// GetAttempts lists a participant's attempts at a mission, newest first
+func (r *Repository) GetAttempts(ctx context.Context, missionID, userID uint) ([]*MissionLog, error) {
+	query := `SELECT ` + logColumns + ` FROM mission_logs WHERE mission_id = ? AND user_id = ?
+		ORDER BY attempt_number DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, missionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []*MissionLog
+	for rows.Next() {
+		l, err := scanLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+
+	return logs, rows.Err()
+}
+
+// SumRewardsSince totals the points a participant has been paid by a mission
+// for attempts completed at or after since
+func (r *Repository) SumRewardsSince(ctx context.Context, tx *sql.Tx, missionID, userID uint, since time.Time) (int64, error) {
+	query := `
+		SELECT COALESCE(SUM(reward_points), 0) FROM mission_logs
+		WHERE mission_id = ? AND user_id = ? AND reward_claimed = TRUE AND completed_at >= ?
+	`
+	var total int64
+	err := tx.QueryRowContext(ctx, query, missionID, userID, since).Scan(&total)
+	return total, err
+}
+
+// GetLogByIDForUpdate locks a mission log so a submission is graded only once
+func (r *Repository) GetLogByIDForUpdate(ctx context.Context, tx *sql.Tx, id uint) (*MissionLog, error) {
+	query := `SELECT ` + logColumns + ` FROM mission_logs WHERE id = ? FOR UPDATE`
+	return scanLog(tx.QueryRowContext(ctx, query, id))
+}
+
 func (r *Repository) UpdateLogStatus(ctx context.Context, tx *sql.Tx, id uint, status string) error {
 	query := `UPDATE mission_logs SET status = ? WHERE id = ?`
 	if tx != nil {
@@ -234,13 +433,13 @@ func (r *Repository) UpdateLogStatus(ctx context.Context, tx *sql.Tx, id uint, s
 	return err
 }
 
-func (r *Repository) SubmitLog(ctx context.Context, id uint, answers string) error {
+func (r *Repository) SubmitLog(ctx context.Context, tx *sql.Tx, id uint, answers string) error {
 	query := `UPDATE mission_logs SET answers = ?, status = 'SUBMITTED', submitted_at = NOW() WHERE id = ?`
-	_, err := r.db.ExecContext(ctx, query, answers, id)
+	_, err := tx.ExecContext(ctx, query, answers, id)
 	return err
 }
 
-func (r *Repository) GradeLog(ctx context.Context, tx *sql.Tx, id uint, score float64, notes string, approved bool, graderID uint, rewardPoints int64) error {
+func (r *Repository) GradeLog(ctx context.Context, tx *sql.Tx, id uint, score float64, rubricScores sql.NullString, notes string, approved bool, graderID uint, rewardPoints int64) error {
 	status := "FAILED"
 	if approved {
 		status = "COMPLETED"
@@ -248,11 +447,28 @@ func (r *Repository) GradeLog(ctx context.Context, tx *sql.Tx, id uint, score fl
 
 	query := `
 		UPDATE mission_logs 
-		SET status = ?, score = ?, notes = ?, graded_at = NOW(), graded_by = ?, 
+		SET status = ?, score = ?, rubric_scores = ?, notes = ?, graded_at = NOW(), graded_by = ?, 
 			completed_at = IF(? = TRUE, NOW(), NULL), reward_points = ?, reward_claimed = ?
 		WHERE id = ?
 	`
-	_, err := tx.ExecContext(ctx, query, status, score, notes, graderID, approved, rewardPoints, approved, id)
+	_, err := tx.ExecContext(ctx, query, status, score, rubricScores, notes, graderID, approved, rewardPoints, rewardPoints > 0, id)
+	return err
+}
+
+// AutoGradeLog stores answers and the computed grade of an automatically scored submission
+func (r *Repository) AutoGradeLog(ctx context.Context, tx *sql.Tx, id uint, answers string, score float64, passed bool, rewardPoints int64, notes string) error {
+	status := "FAILED"
+	if passed {
+		status = "COMPLETED"
+	}
+
+	query := `
+		UPDATE mission_logs
+		SET status = ?, answers = ?, score = ?, notes = ?, submitted_at = NOW(), graded_at = NOW(), graded_by = NULL,
+			completed_at = IF(? = TRUE, NOW(), NULL), reward_points = ?, reward_claimed = ?
+		WHERE id = ?
+	`
+	_, err := tx.ExecContext(ctx, query, status, answers, score, notes, passed, rewardPoints, rewardPoints > 0, id)
 	return err
 }
 
@@ -264,7 +480,7 @@ func (r *Repository) GetLogsByUserID(ctx context.Context, userID uint, limit, of
 	}
 
 	query := `
-		SELECT ml.id, ml.mission_id, ml.user_id, ml.status, ml.score, ml.answers, 
+		SELECT ml.id, ml.mission_id, ml.user_id, ml.attempt_number, ml.status, ml.score, ml.rubric_scores, ml.answers, 
 			ml.reward_claimed, ml.reward_points, ml.started_at, ml.submitted_at, 
 			ml.completed_at, ml.graded_at, ml.graded_by, ml.notes,
 			m.title as mission_title, u.full_name as user_name
@@ -286,7 +502,7 @@ func (r *Repository) GetLogsByUserID(ctx context.Context, userID uint, limit, of
 	for rows.Next() {
 		var l MissionLogWithDetails
 		if err := rows.Scan(
-			&l.ID, &l.MissionID, &l.UserID, &l.Status, &l.Score, &l.Answers,
+			&l.ID, &l.MissionID, &l.UserID, &l.AttemptNumber, &l.Status, &l.Score, &l.RubricScores, &l.Answers,
 			&l.RewardClaimed, &l.RewardPoints, &l.StartedAt, &l.SubmittedAt,
 			&l.CompletedAt, &l.GradedAt, &l.GradedBy, &l.Notes,
 			&l.MissionTitle, &l.UserName,
@@ -301,7 +517,7 @@ func (r *Repository) GetLogsByUserID(ctx context.Context, userID uint, limit, of
 
 func (r *Repository) GetLogsByMissionID(ctx context.Context, missionID uint) ([]*MissionLogWithDetails, error) {
 	query := `
-		SELECT ml.id, ml.mission_id, ml.user_id, ml.status, ml.score, ml.answers, 
+		SELECT ml.id, ml.mission_id, ml.user_id, ml.attempt_number, ml.status, ml.score, ml.rubric_scores, ml.answers, 
 			ml.reward_claimed, ml.reward_points, ml.started_at, ml.submitted_at, 
 			ml.completed_at, ml.graded_at, ml.graded_by, ml.notes,
 			m.title as mission_title, u.full_name as user_name
@@ -322,7 +538,7 @@ func (r *Repository) GetLogsByMissionID(ctx context.Context, missionID uint) ([]
 	for rows.Next() {
 		var l MissionLogWithDetails
 		if err := rows.Scan(
-			&l.ID, &l.MissionID, &l.UserID, &l.Status, &l.Score, &l.Answers,
+			&l.ID, &l.MissionID, &l.UserID, &l.AttemptNumber, &l.Status, &l.Score, &l.RubricScores, &l.Answers,
 			&l.RewardClaimed, &l.RewardPoints, &l.StartedAt, &l.SubmittedAt,
 			&l.CompletedAt, &l.GradedAt, &l.GradedBy, &l.Notes,
 			&l.MissionTitle, &l.UserName,
@@ -334,3 +550,491 @@ func (r *Repository) GetLogsByMissionID(ctx context.Context, missionID uint) ([]
 
 	return logs, nil
 }
+
+// Attendance session operations
+
+func (r *Repository) CreateAttendanceSession(ctx context.Context, s *AttendanceSession) error {
+	query := `
+		INSERT INTO attendance_sessions (mission_id, created_by, secret, rotation_seconds,
+			latitude, longitude, radius_meters, starts_at, ends_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		s.MissionID, s.CreatedBy, s.Secret, s.RotationSeconds,
+		s.Latitude, s.Longitude, s.RadiusMeters, s.StartsAt, s.EndsAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	s.ID = uint(id)
+	return nil
+}
+
+func (r *Repository) GetAttendanceSessionByID(ctx context.Context, id uint) (*AttendanceSession, error) {
+	query := `
+		SELECT id, mission_id, created_by, secret, rotation_seconds, latitude, longitude,
+			radius_meters, starts_at, ends_at, closed_at, created_at
+		FROM attendance_sessions WHERE id = ?
+	`
+
+	var s AttendanceSession
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&s.ID, &s.MissionID, &s.CreatedBy, &s.Secret, &s.RotationSeconds, &s.Latitude, &s.Longitude,
+		&s.RadiusMeters, &s.StartsAt, &s.EndsAt, &s.ClosedAt, &s.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (r *Repository) CloseAttendanceSession(ctx context.Context, id uint) error {
+	query := `UPDATE attendance_sessions SET closed_at = NOW() WHERE id = ? AND closed_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// GetAttendanceRecords lists everyone who joined an attendance mission, earliest check-in first
+func (r *Repository) GetAttendanceRecords(ctx context.Context, missionID uint) ([]*AttendanceRecord, error) {
+	query := `
+		SELECT ml.user_id, u.full_name, u.nim_nip, ml.status, ml.answers, ml.completed_at
+		FROM mission_logs ml
+		INNER JOIN users u ON ml.user_id = u.id
+		WHERE ml.mission_id = ?
+		ORDER BY ml.completed_at IS NULL, ml.completed_at ASC, u.full_name ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, missionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*AttendanceRecord
+	for rows.Next() {
+		var a AttendanceRecord
+		if err := rows.Scan(&a.UserID, &a.UserName, &a.NIM, &a.Status, &a.Answers, &a.CompletedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, &a)
+	}
+
+	return records, nil
+}
+
+// Submission operations
+
+const attachmentColumns = `a.id, a.submission_id, a.kind, a.file_name, a.content_type, a.size_bytes,
+	a.storage_key, a.checksum, a.url, a.created_at`
+
+func scanAttachment(row rowScanner, extra ...interface{}) (*SubmissionAttachment, error) {
+	var a SubmissionAttachment
+	dest := append([]interface{}{
+		&a.ID, &a.SubmissionID, &a.Kind, &a.FileName, &a.ContentType, &a.SizeBytes,
+		&a.StorageKey, &a.Checksum, &a.URL, &a.CreatedAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// GetLatestSubmissionVersion returns the newest version number of a log, 0 when none
+func (r *Repository) GetLatestSubmissionVersion(ctx context.Context, tx *sql.Tx, logID uint) (int, error) {
+	var version int
+	query := `SELECT COALESCE(MAX(version), 0) FROM mission_submissions WHERE mission_log_id = ?`
+	err := tx.QueryRowContext(ctx, query, logID).Scan(&version)
+	return version, err
+}
+
+func (r *Repository) CreateSubmission(ctx context.Context, tx *sql.Tx, sub *Submission) error {
+	query := `
+		INSERT INTO mission_submissions (mission_log_id, version, answers, note, submitted_at)
+		VALUES (?, ?, ?, ?, NOW())
+	`
+
+	result, err := tx.ExecContext(ctx, query, sub.MissionLogID, sub.Version, sub.Answers, sub.Note)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	sub.ID = uint(id)
+	return nil
+}
+
+func (r *Repository) CreateAttachment(ctx context.Context, tx *sql.Tx, a *SubmissionAttachment) error {
+	query := `
+		INSERT INTO submission_attachments (submission_id, kind, file_name, content_type, size_bytes,
+			storage_key, checksum, url, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW())
+	`
+
+	result, err := tx.ExecContext(ctx, query,
+		a.SubmissionID, a.Kind, a.FileName, a.ContentType, a.SizeBytes, a.StorageKey, a.Checksum, a.URL,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	a.ID = uint(id)
+	return nil
+}
+
+// GetSubmissionsByLogID returns every version of a hand-in, newest first, with attachments
+func (r *Repository) GetSubmissionsByLogID(ctx context.Context, logID uint) ([]*Submission, error) {
+	query := `
+		SELECT id, mission_log_id, version, answers, note, submitted_at
+		FROM mission_submissions
+		WHERE mission_log_id = ?
+		ORDER BY version DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, logID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var submissions []*Submission
+	byID := map[uint]*Submission{}
+	for rows.Next() {
+		var sub Submission
+		if err := rows.Scan(&sub.ID, &sub.MissionLogID, &sub.Version, &sub.Answers, &sub.Note, &sub.SubmittedAt); err != nil {
+			return nil, err
+		}
+		submissions = append(submissions, &sub)
+		byID[sub.ID] = &sub
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	attachmentQuery := `
+		SELECT ` + attachmentColumns + `
+		FROM submission_attachments a
+		INNER JOIN mission_submissions s ON a.submission_id = s.id
+		WHERE s.mission_log_id = ?
+		ORDER BY a.id
+	`
+
+	attachmentRows, err := r.db.QueryContext(ctx, attachmentQuery, logID)
+	if err != nil {
+		return nil, err
+	}
+	defer attachmentRows.Close()
+
+	for attachmentRows.Next() {
+		a, err := scanAttachment(attachmentRows)
+		if err != nil {
+			return nil, err
+		}
+		if sub := byID[a.SubmissionID]; sub != nil {
+			sub.Attachments = append(sub.Attachments, a)
+		}
+	}
+
+	return submissions, nil
+}
+
+// GetLatestAttachmentsByMission returns the attachments of each participant's newest version
+func (r *Repository) GetLatestAttachmentsByMission(ctx context.Context, missionID uint) ([]*AttachmentWithOwner, error) {
+	query := `
+		SELECT ` + attachmentColumns + `, ml.id, ml.mission_id, ml.user_id, s.version
+		FROM submission_attachments a
+		INNER JOIN mission_submissions s ON a.submission_id = s.id
+		INNER JOIN mission_logs ml ON s.mission_log_id = ml.id
+		WHERE ml.mission_id = ?
+			AND s.version = (SELECT MAX(version) FROM mission_submissions WHERE mission_log_id = s.mission_log_id)
+		ORDER BY a.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, missionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*AttachmentWithOwner
+	for rows.Next() {
+		var a AttachmentWithOwner
+		base, err := scanAttachment(rows, &a.MissionLogID, &a.MissionID, &a.UserID, &a.Version)
+		if err != nil {
+			return nil, err
+		}
+		a.SubmissionAttachment = *base
+		attachments = append(attachments, &a)
+	}
+
+	return attachments, nil
+}
+
+func (r *Repository) GetAttachmentByID(ctx context.Context, id uint) (*AttachmentWithOwner, error) {
+	query := `
+		SELECT ` + attachmentColumns + `, ml.id, ml.mission_id, ml.user_id, s.version
+		FROM submission_attachments a
+		INNER JOIN mission_submissions s ON a.submission_id = s.id
+		INNER JOIN mission_logs ml ON s.mission_log_id = ml.id
+		WHERE a.id = ?
+	`
+
+	var a AttachmentWithOwner
+	base, err := scanAttachment(r.db.QueryRowContext(ctx, query, id), &a.MissionLogID, &a.MissionID, &a.UserID, &a.Version)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	a.SubmissionAttachment = *base
+	return &a, nil
+}
+
+// Scheduler operations
+
+// GetOverdueLogs returns unfinished attempts after afterID of missions whose
+// deadline or end date has passed; callers decide per mission whether late
+// work is still allowed, so they page through with the last ID they saw
+func (r *Repository) GetOverdueLogs(ctx context.Context, afterID uint, limit int) ([]*ScheduledLog, error) {
+	return r.getScheduledLogs(ctx, `
+		ml.id > ? AND ((m.deadline IS NOT NULL AND m.deadline < NOW()) OR (m.end_date IS NOT NULL AND m.end_date <= NOW()))
+		ORDER BY ml.id LIMIT ?`, afterID, limit)
+}
+
+// GetLogsDueBefore returns unfinished attempts whose deadline (or end date when
+// there is no deadline) falls between now and before, leaving out those that
+// already got a notificationType notification
+func (r *Repository) GetLogsDueBefore(ctx context.Context, before time.Time, notificationType string, limit int) ([]*ScheduledLog, error) {
+	return r.getScheduledLogs(ctx, `
+		COALESCE(m.deadline, m.end_date) > NOW() AND COALESCE(m.deadline, m.end_date) <= ?
+		AND NOT EXISTS (
+			SELECT 1 FROM notifications n
+			WHERE n.user_id = ml.user_id AND n.type = ?
+				AND n.reference_type = 'MISSION_LOG' AND CAST(n.reference_id AS UNSIGNED) = ml.id
+		)
+		ORDER BY ml.id LIMIT ?`, before, notificationType, limit)
+}
+
+func (r *Repository) getScheduledLogs(ctx context.Context, condition string, args ...interface{}) ([]*ScheduledLog, error) {
+	query := `
+		SELECT ml.id, ml.user_id, m.id, m.title, m.reward_rules, m.start_date, m.end_date, m.deadline
+		FROM mission_logs ml
+		INNER JOIN missions m ON ml.mission_id = m.id
+		WHERE ml.status IN ('STARTED', 'IN_PROGRESS') AND m.deleted_at IS NULL AND ` + condition
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []*ScheduledLog
+	for rows.Next() {
+		var l ScheduledLog
+		if err := rows.Scan(
+			&l.LogID, &l.UserID, &l.Mission.ID, &l.Mission.Title, &l.Mission.RewardRules,
+			&l.Mission.StartDate, &l.Mission.EndDate, &l.Mission.Deadline,
+		); err != nil {
+			return nil, err
+		}
+		logs = append(logs, &l)
+	}
+
+	return logs, nil
+}
+
+// ExpireLog marks an attempt EXPIRED unless it was submitted in the meantime
+func (r *Repository) ExpireLog(ctx context.Context, id uint) (bool, error) {
+	query := `UPDATE mission_logs SET status = 'EXPIRED' WHERE id = ? AND status IN ('STARTED', 'IN_PROGRESS')`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// DeactivateEnded closes every active mission whose end date has passed
+func (r *Repository) DeactivateEnded(ctx context.Context) (int64, error) {
+	query := `
+		UPDATE missions SET is_active = FALSE, updated_at = NOW()
+		WHERE is_active = TRUE AND deleted_at IS NULL AND end_date IS NOT NULL AND end_date <= NOW()
+	`
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetEndedUnreleasedBudgets lists ended missions still holding a budget
+func (r *Repository) GetEndedUnreleasedBudgets(ctx context.Context, limit int) ([]uint, error) {
+	query := `
+		SELECT id FROM missions
+		WHERE budget_source <> 'NONE' AND budget_released_at IS NULL AND deleted_at IS NULL
+			AND end_date IS NOT NULL AND end_date <= NOW()
+		ORDER BY id LIMIT ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// Reward pool operations
+const rewardPoolColumns = `id, name, description, balance, reserved, total_funded, is_active, created_by, created_at, updated_at`
+
+func scanRewardPool(row rowScanner) (*RewardPool, error) {
+	var p RewardPool
+	err := row.Scan(
+		&p.ID, &p.Name, &p.Description, &p.Balance, &p.Reserved, &p.TotalFunded,
+		&p.IsActive, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *Repository) CreateRewardPool(ctx context.Context, p *RewardPool) error {
+	query := `
+		INSERT INTO reward_pools (name, description, balance, reserved, total_funded, is_active, created_by, created_at, updated_at)
+		VALUES (?, ?, 0, 0, 0, ?, ?, NOW(), NOW())
+	`
+
+	result, err := r.db.ExecContext(ctx, query, p.Name, p.Description, p.IsActive, p.CreatedBy)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	p.ID = uint(id)
+	return nil
+}
+
+func (r *Repository) GetRewardPoolByID(ctx context.Context, id uint) (*RewardPool, error) {
+	query := `SELECT ` + rewardPoolColumns + ` FROM reward_pools WHERE id = ?`
+
+	p, err := scanRewardPool(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (r *Repository) GetRewardPoolByIDForUpdate(ctx context.Context, tx *sql.Tx, id uint) (*RewardPool, error) {
+	query := `SELECT ` + rewardPoolColumns + ` FROM reward_pools WHERE id = ? FOR UPDATE`
+
+	p, err := scanRewardPool(tx.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// GetRewardPools lists pools; activeOnly hides retired pools from lecturers
+func (r *Repository) GetRewardPools(ctx context.Context, activeOnly bool) ([]*RewardPool, error) {
+	query := `SELECT ` + rewardPoolColumns + ` FROM reward_pools`
+	if activeOnly {
+		query += ` WHERE is_active = TRUE`
+	}
+	query += ` ORDER BY name`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pools []*RewardPool
+	for rows.Next() {
+		p, err := scanRewardPool(rows)
+		if err != nil {
+			return nil, err
+		}
+		pools = append(pools, p)
+	}
+
+	return pools, nil
+}
+
+func (r *Repository) UpdateRewardPool(ctx context.Context, p *RewardPool) error {
+	query := `UPDATE reward_pools SET name = ?, description = ?, is_active = ?, updated_at = NOW() WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, p.Name, p.Description, p.IsActive, p.ID)
+	return err
+}
+
+// FundRewardPool adds admin-issued points to a pool
+func (r *Repository) FundRewardPool(ctx context.Context, tx *sql.Tx, id uint, amount int64) error {
+	query := `UPDATE reward_pools SET balance = balance + ?, total_funded = total_funded + ?, updated_at = NOW() WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, amount, amount, id)
+	return err
+}
+
+// ReservePoolBudget moves pool balance into reserved for a mission
+func (r *Repository) ReservePoolBudget(ctx context.Context, tx *sql.Tx, id uint, amount int64) error {
+	query := `UPDATE reward_pools SET balance = balance - ?, reserved = reserved + ?, updated_at = NOW() WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, amount, amount, id)
+	return err
+}
+
+// ReleasePoolBudget returns unused reserved points to the pool balance
+func (r *Repository) ReleasePoolBudget(ctx context.Context, tx *sql.Tx, id uint, amount int64) error {
+	query := `UPDATE reward_pools SET balance = balance + ?, reserved = reserved - ?, updated_at = NOW() WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, amount, amount, id)
+	return err
+}
+
+// SpendPoolBudget pays a reward out of the pool's reserved points
+func (r *Repository) SpendPoolBudget(ctx context.Context, tx *sql.Tx, id uint, amount int64) error {
+	query := `UPDATE reward_pools SET reserved = reserved - ?, updated_at = NOW() WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, amount, id)
+	return err
+}
@@ -0,0 +1,130 @@
+package mission
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Reward modes
+const (
+	RewardModeFixed        = "FIXED"
+	RewardModeProportional = "PROPORTIONAL"
+	RewardModeTiered       = "TIERED"
+)
+
+// RewardRules decide how much of Mission.RewardPoints an approved submission earns
+type RewardRules struct {
+	Mode               string       `json:"mode"`
+	MinScore           float64      `json:"min_score,omitempty"` // PROPORTIONAL: nothing below this score
+	Tiers              []RewardTier `json:"tiers,omitempty"`     // TIERED: highest matching band wins
+	EarlyBonusPercent  float64      `json:"early_bonus_percent,omitempty"`
+	EarlyBonusHours    float64      `json:"early_bonus_hours,omitempty"` // submitted at least this long before Deadline
+	LatePenaltyPercent float64      `json:"late_penalty_percent,omitempty"`
+}
+
+// RewardTier pays Percent of the reward for scores at or above MinScore
+type RewardTier struct {
+	MinScore float64 `json:"min_score"`
+	Percent  float64 `json:"percent"`
+}
+
+// Validate checks the rules and normalizes the mode and tier order
+func (r *RewardRules) Validate() []ValidationError {
+	var errors []ValidationError
+
+	if r.Mode == "" {
+		r.Mode = RewardModeFixed
+	}
+	switch r.Mode {
+	case RewardModeFixed:
+		r.Tiers = nil
+	case RewardModeProportional:
+		if r.MinScore < 0 || r.MinScore > 100 {
+			errors = append(errors, ValidationError{Field: "reward_rules.min_score", Message: "Minimum score must be between 0 and 100"})
+		}
+		r.Tiers = nil
+	case RewardModeTiered:
+		if len(r.Tiers) == 0 {
+			errors = append(errors, ValidationError{Field: "reward_rules.tiers", Message: "Tiered rewards need at least one tier"})
+		}
+		for i, t := range r.Tiers {
+			if t.MinScore < 0 || t.MinScore > 100 || t.Percent < 0 || t.Percent > 100 {
+				errors = append(errors, ValidationError{
+					Field:   fmt.Sprintf("reward_rules.tiers[%d]", i),
+					Message: "Tier score and percent must be between 0 and 100",
+				})
+			}
+		}
+		sort.Slice(r.Tiers, func(i, j int) bool { return r.Tiers[i].MinScore > r.Tiers[j].MinScore })
+	default:
+		errors = append(errors, ValidationError{Field: "reward_rules.mode", Message: "Mode must be FIXED, PROPORTIONAL or TIERED"})
+	}
+
+	if r.EarlyBonusPercent < 0 || r.EarlyBonusPercent > 100 {
+		errors = append(errors, ValidationError{Field: "reward_rules.early_bonus_percent", Message: "Early bonus must be between 0 and 100"})
+	}
+	if r.EarlyBonusHours < 0 {
+		errors = append(errors, ValidationError{Field: "reward_rules.early_bonus_hours", Message: "Early bonus hours must not be negative"})
+	}
+	if r.LatePenaltyPercent < 0 || r.LatePenaltyPercent > 100 {
+		errors = append(errors, ValidationError{Field: "reward_rules.late_penalty_percent", Message: "Late penalty must be between 0 and 100"})
+	}
+
+	return errors
+}
+
+// missionRewardRules returns the mission's rules, falling back to a fixed reward
+func missionRewardRules(m *Mission) *RewardRules {
+	rules := &RewardRules{Mode: RewardModeFixed}
+	if m.RewardRules.Valid {
+		if err := json.Unmarshal([]byte(m.RewardRules.String), rules); err != nil {
+			return &RewardRules{Mode: RewardModeFixed}
+		}
+	}
+	return rules
+}
+
+// Compute returns the reward for an approved submission with the given score
+// (0-100), adjusted for when it was submitted relative to the deadline
+func (r *RewardRules) Compute(base int64, score float64, submittedAt time.Time, deadline sql.NullTime) int64 {
+	score = math.Max(0, math.Min(100, score))
+
+	var factor float64
+	switch r.Mode {
+	case RewardModeProportional:
+		if score >= r.MinScore {
+			factor = score / 100
+		}
+	case RewardModeTiered:
+		for _, t := range r.Tiers {
+			if score >= t.MinScore {
+				factor = t.Percent / 100
+				break
+			}
+		}
+	default:
+		factor = 1
+	}
+	if factor == 0 {
+		return 0
+	}
+
+	if deadline.Valid {
+		if submittedAt.After(deadline.Time) {
+			factor *= 1 - r.LatePenaltyPercent/100
+		} else if r.EarlyBonusPercent > 0 && deadline.Time.Sub(submittedAt) >= time.Duration(r.EarlyBonusHours*float64(time.Hour)) {
+			factor *= 1 + r.EarlyBonusPercent/100
+		}
+	}
+
+	return int64(math.Round(float64(base) * factor))
+}
+
+// MaxReward is the most a single participant can earn under these rules
+func (r *RewardRules) MaxReward(base int64) int64 {
+	return int64(math.Round(float64(base) * (1 + r.EarlyBonusPercent/100)))
+}
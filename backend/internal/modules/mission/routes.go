@@ -17,6 +17,12 @@ func RegisterRoutes(app fiber.Router, handler *Handler, jwtManager *middleware.J
 	// Get my created missions - dosen only
 	missions.Get("/my/created", middleware.RequireDosen(), handler.GetMyMissions)
 
+	// Attendance check-in - mahasiswa scan the lecturer's rotating QR
+	missions.Post("/attendance/check-in", middleware.RequireMahasiswa(), handler.CheckIn)
+
+	// Reward pools a lecturer can fund a mission from
+	missions.Get("/reward-pools", middleware.RequireDosen(), handler.GetActiveRewardPools)
+
 	// List active missions - all users
 	missions.Get("", handler.GetActiveList)
 
@@ -35,10 +41,35 @@ func RegisterRoutes(app fiber.Router, handler *Handler, jwtManager *middleware.J
 		handler.SubmitMission,
 	)
 
+	// Attempt and submission history - mahasiswa only
+	missions.Get("/:id/attempts", middleware.RequireMahasiswa(), handler.GetMyAttempts)
+	missions.Get("/:id/submissions", middleware.RequireMahasiswa(), handler.GetMySubmissions)
+
+	// Attachment download - the uploader or the mission creator
+	missions.Get("/:id/attachments/:attachmentId", handler.DownloadAttachment)
+
 	// Dosen routes
 	missions.Post("", middleware.RequireDosen(), handler.Create)
 	missions.Put("/:id", middleware.RequireDosen(), handler.Update)
 	missions.Delete("/:id", middleware.RequireDosen(), handler.Delete)
+	missions.Post("/:id/close", middleware.RequireDosen(), handler.CloseMission)
+	missions.Post("/:id/budget/top-up", middleware.RequireDosen(), handler.TopUpBudget)
 	missions.Get("/:id/participants", middleware.RequireDosen(), handler.GetParticipants)
+	missions.Get("/:id/participants/:userId/submissions", middleware.RequireDosen(), handler.GetParticipantSubmissions)
+	missions.Post("/:id/grade", middleware.RequireDosen(), handler.BulkGrade)
 	missions.Post("/:id/grade/:userId", middleware.RequireDosen(), handler.GradeMission)
+
+	// Attendance sessions - dosen only
+	missions.Post("/:id/attendance/sessions", middleware.RequireDosen(), handler.OpenAttendanceSession)
+	missions.Get("/:id/attendance/sessions/:sessionId/qr", middleware.RequireDosen(), handler.GetAttendanceQR)
+	missions.Post("/:id/attendance/sessions/:sessionId/close", middleware.RequireDosen(), handler.CloseAttendanceSession)
+	missions.Get("/:id/attendance", middleware.RequireDosen(), handler.GetAttendance)
+	missions.Get("/:id/attendance/export", middleware.RequireDosen(), handler.ExportAttendance)
+
+	// Reward pools - admin only
+	admin := app.Group("/admin/reward-pools", middleware.JWTMiddleware(jwtManager), middleware.RequireAdmin())
+	admin.Get("", handler.GetAllRewardPools)
+	admin.Post("", handler.CreateRewardPool)
+	admin.Put("/:id", handler.UpdateRewardPool)
+	admin.Post("/:id/fund", handler.FundRewardPool)
 }
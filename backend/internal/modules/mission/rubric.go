@@ -0,0 +1,129 @@
+package mission
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	apperrors "walletpoint/internal/shared/errors"
+)
+
+const defaultCriterionPoints = 100
+
+// Rubric grades a submission on weighted criteria; the final score is the
+// weighted average of each criterion's points as a percentage of its maximum
+type Rubric struct {
+	Criteria []RubricCriterion `json:"criteria"`
+}
+
+// RubricCriterion is one graded aspect of a submission
+type RubricCriterion struct {
+	Key         string  `json:"key"`
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Weight      float64 `json:"weight"`
+	MaxPoints   float64 `json:"max_points"`
+}
+
+// RubricScore is the points a grader awarded for one criterion
+type RubricScore struct {
+	Key     string  `json:"key"`
+	Points  float64 `json:"points"`
+	Comment string  `json:"comment,omitempty"`
+}
+
+// Validate checks the criteria and fills in default maximum points
+func (r *Rubric) Validate() []ValidationError {
+	var errors []ValidationError
+
+	if len(r.Criteria) == 0 {
+		errors = append(errors, ValidationError{Field: "rubric.criteria", Message: "Rubric needs at least one criterion"})
+	}
+
+	seen := map[string]bool{}
+	for i := range r.Criteria {
+		c := &r.Criteria[i]
+		field := fmt.Sprintf("rubric.criteria[%d]", i)
+
+		c.Key = strings.TrimSpace(c.Key)
+		if c.Key == "" {
+			errors = append(errors, ValidationError{Field: field + ".key", Message: "Key is required"})
+		} else if seen[c.Key] {
+			errors = append(errors, ValidationError{Field: field + ".key", Message: "Key must be unique"})
+		}
+		seen[c.Key] = true
+
+		if strings.TrimSpace(c.Name) == "" {
+			c.Name = c.Key
+		}
+		if c.Weight <= 0 {
+			errors = append(errors, ValidationError{Field: field + ".weight", Message: "Weight must be positive"})
+		}
+		if c.MaxPoints == 0 {
+			c.MaxPoints = defaultCriterionPoints
+		}
+		if c.MaxPoints < 0 {
+			errors = append(errors, ValidationError{Field: field + ".max_points", Message: "Max points must be positive"})
+		}
+	}
+
+	return errors
+}
+
+// missionRubric returns the mission's rubric, or nil when it is graded with a plain score
+func missionRubric(m *Mission) *Rubric {
+	if !m.Rubric.Valid {
+		return nil
+	}
+	var rubric Rubric
+	if err := json.Unmarshal([]byte(m.Rubric.String), &rubric); err != nil || len(rubric.Criteria) == 0 {
+		return nil
+	}
+	return &rubric
+}
+
+// logRubricScores decodes the per-criterion points stored on a graded log
+func logRubricScores(l *MissionLog) []RubricScore {
+	if !l.RubricScores.Valid {
+		return nil
+	}
+	var scores []RubricScore
+	if err := json.Unmarshal([]byte(l.RubricScores.String), &scores); err != nil {
+		return nil
+	}
+	return scores
+}
+
+// Score checks that every criterion is scored exactly once and returns the
+// weighted score (0-100) with the scores in criterion order
+func (r *Rubric) Score(scores []RubricScore) (float64, []RubricScore, error) {
+	byKey := map[string]RubricScore{}
+	for _, s := range scores {
+		if _, dup := byKey[s.Key]; dup {
+			return 0, nil, apperrors.New("INVALID_RUBRIC_SCORES", fmt.Sprintf("Criterion %q is scored twice", s.Key))
+		}
+		byKey[s.Key] = s
+	}
+
+	var weighted, totalWeight float64
+	ordered := make([]RubricScore, 0, len(r.Criteria))
+	for _, c := range r.Criteria {
+		s, ok := byKey[c.Key]
+		if !ok {
+			return 0, nil, apperrors.New("INVALID_RUBRIC_SCORES", fmt.Sprintf("Criterion %q is not scored", c.Key))
+		}
+		if s.Points < 0 || s.Points > c.MaxPoints {
+			return 0, nil, apperrors.New("INVALID_RUBRIC_SCORES", fmt.Sprintf("Points for %q must be between 0 and %g", c.Key, c.MaxPoints))
+		}
+		weighted += s.Points / c.MaxPoints * c.Weight
+		totalWeight += c.Weight
+		ordered = append(ordered, s)
+		delete(byKey, c.Key)
+	}
+	for key := range byKey {
+		return 0, nil, apperrors.New("INVALID_RUBRIC_SCORES", fmt.Sprintf("Unknown criterion %q", key))
+	}
+
+	return math.Round(weighted/totalWeight*10000) / 100, ordered, nil
+}
@@ -0,0 +1,62 @@
+package mission
+
+import (
+	"time"
+
+	apperrors "walletpoint/internal/shared/errors"
+)
+
+// Missions run inside [StartDate, EndDate). Deadline is the submission cutoff;
+// when the reward rules set a late penalty, late work is still accepted (and
+// penalized) until EndDate.
+
+const scheduleTimeFormat = "02 Jan 2006 15:04"
+
+// acceptsLateWork reports whether submissions after Deadline are still taken
+func (m *Mission) acceptsLateWork() bool {
+	return missionRewardRules(m).LatePenaltyPercent > 0
+}
+
+// SubmissionCutoff is the moment after which an unfinished attempt expires
+func (m *Mission) SubmissionCutoff() (time.Time, bool) {
+	var cutoff time.Time
+	found := false
+	if m.Deadline.Valid && !m.acceptsLateWork() {
+		cutoff, found = m.Deadline.Time, true
+	}
+	if m.EndDate.Valid && (!found || m.EndDate.Time.Before(cutoff)) {
+		cutoff, found = m.EndDate.Time, true
+	}
+	return cutoff, found
+}
+
+// DueAt is the time participants are reminded about: the deadline, or the end
+// of the mission when it has none
+func (m *Mission) DueAt() (time.Time, bool) {
+	if m.Deadline.Valid {
+		return m.Deadline.Time, true
+	}
+	if m.EndDate.Valid {
+		return m.EndDate.Time, true
+	}
+	return time.Time{}, false
+}
+
+// checkStartWindow rejects starting a mission outside its schedule
+func checkStartWindow(m *Mission, now time.Time) error {
+	if m.StartDate.Valid && now.Before(m.StartDate.Time) {
+		return apperrors.New("MISSION_NOT_OPEN", "Mission opens on "+m.StartDate.Time.Format(scheduleTimeFormat))
+	}
+	return checkSubmitWindow(m, now)
+}
+
+// checkSubmitWindow rejects work handed in after the mission's cutoff
+func checkSubmitWindow(m *Mission, now time.Time) error {
+	if m.EndDate.Valid && !now.Before(m.EndDate.Time) {
+		return apperrors.New("MISSION_ENDED", "Mission has ended")
+	}
+	if m.Deadline.Valid && now.After(m.Deadline.Time) && !m.acceptsLateWork() {
+		return apperrors.New("DEADLINE_PASSED", "Mission deadline has passed")
+	}
+	return nil
+}
@@ -1,28 +1,56 @@
 package mission
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"walletpoint/internal/config"
+	"walletpoint/internal/modules/group"
+	"walletpoint/internal/modules/notification"
 	"walletpoint/internal/modules/wallet"
 	"walletpoint/internal/shared/constants"
 	apperrors "walletpoint/internal/shared/errors"
+	"walletpoint/pkg/storage"
 	"walletpoint/pkg/utils"
+
+	"github.com/go-sql-driver/mysql"
+	qrcode "github.com/skip2/go-qrcode"
 )
 
 type Service struct {
-	repo       *Repository
-	walletRepo *wallet.Repository
-	db         *sql.DB
+	repo             *Repository
+	walletRepo       *wallet.Repository
+	notificationRepo *notification.Repository
+	groupRepo        *group.Repository
+	files            *storage.Local
+	db               *sql.DB
+	config           config.MissionConfig
+	upload           config.UploadConfig
 }
 
-func NewService(repo *Repository, walletRepo *wallet.Repository, db *sql.DB) *Service {
+func NewService(repo *Repository, walletRepo *wallet.Repository, notificationRepo *notification.Repository, groupRepo *group.Repository, files *storage.Local, db *sql.DB, cfg config.MissionConfig, uploadCfg config.UploadConfig) *Service {
 	return &Service{
-		repo:       repo,
-		walletRepo: walletRepo,
-		db:         db,
+		repo:             repo,
+		walletRepo:       walletRepo,
+		notificationRepo: notificationRepo,
+		groupRepo:        groupRepo,
+		files:            files,
+		db:               db,
+		config:           cfg,
+		upload:           uploadCfg,
 	}
 }
 
@@ -41,6 +69,17 @@ func (s *Service) Create(ctx context.Context, req CreateMissionRequest, creatorI
 	if req.MaxParticipants != nil {
 		m.MaxParticipants = sql.NullInt64{Int64: int64(*req.MaxParticipants), Valid: true}
 	}
+	if req.RewardRules != nil {
+		rules, _ := json.Marshal(req.RewardRules)
+		m.RewardRules = sql.NullString{String: string(rules), Valid: true}
+	}
+	if req.Rubric != nil {
+		rubric, _ := json.Marshal(req.Rubric)
+		m.Rubric = sql.NullString{String: string(rubric), Valid: true}
+	}
+	if req.Attempts != nil {
+		req.Attempts.apply(m)
+	}
 	if req.Content != nil {
 		m.Content = sql.NullString{String: string(req.Content), Valid: true}
 	}
@@ -54,15 +93,48 @@ func (s *Service) Create(ctx context.Context, req CreateMissionRequest, creatorI
 		m.Deadline = sql.NullTime{Time: *req.Deadline, Valid: true}
 	}
 
-	if err := s.repo.Create(ctx, m); err != nil {
+	groupIDs, err := s.resolveTargetGroups(ctx, req.GroupIDs, creatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.BudgetSource = req.BudgetSource
+	m.BudgetAmount = *req.Budget
+	if req.RewardPoolID != nil {
+		m.RewardPoolID = sql.NullInt64{Int64: int64(*req.RewardPoolID), Valid: true}
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if err := s.repo.Create(ctx, tx, m); err != nil {
 		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to create mission")
 	}
 
+	if err := s.repo.SetTargetGroups(ctx, tx, m.ID, groupIDs); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to save target groups")
+	}
+
+	// Reserve the whole budget up front so every approved grade can be paid
+	if err := s.reserveBudget(ctx, tx, m, m.BudgetAmount); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to commit")
+	}
+
 	resp := ToMissionResponse(m, "")
+	if err := s.attachTargetGroups(ctx, m, &resp); err != nil {
+		return nil, err
+	}
 	return &resp, nil
 }
 
-func (s *Service) GetByID(ctx context.Context, id uint) (*MissionResponse, error) {
+func (s *Service) GetByID(ctx context.Context, id, viewerID uint) (*MissionResponse, error) {
 	m, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission")
@@ -72,12 +144,71 @@ func (s *Service) GetByID(ctx context.Context, id uint) (*MissionResponse, error
 	}
 
 	resp := ToMissionResponse(m, "")
+	if m.CreatorID != viewerID {
+		hideAnswerKeys(m, &resp)
+		resp.Budget = nil
+	} else if err := s.attachTargetGroups(ctx, m, &resp); err != nil {
+		return nil, err
+	}
 	return &resp, nil
 }
 
-func (s *Service) GetActiveList(ctx context.Context, page, perPage int) ([]*MissionResponse, int, error) {
+// resolveTargetGroups checks that the creator owns every group a mission is aimed at
+func (s *Service) resolveTargetGroups(ctx context.Context, ids []uint, creatorID uint) ([]uint, error) {
+	seen := map[uint]bool{}
+	var groupIDs []uint
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		g, err := s.groupRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get group")
+		}
+		if g == nil {
+			return nil, apperrors.New("GROUP_NOT_FOUND", fmt.Sprintf("Group %d not found", id))
+		}
+		if g.OwnerID != creatorID {
+			return nil, apperrors.New("FORBIDDEN", "Missions can only target your own groups")
+		}
+		groupIDs = append(groupIDs, id)
+	}
+	return groupIDs, nil
+}
+
+func (s *Service) attachTargetGroups(ctx context.Context, m *Mission, resp *MissionResponse) error {
+	groups, err := s.repo.GetTargetGroups(ctx, m.ID)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to get target groups")
+	}
+	for _, g := range groups {
+		resp.TargetGroups = append(resp.TargetGroups, TargetGroupResponse{ID: g.ID, Name: g.Name})
+	}
+	return nil
+}
+
+// checkTargeted rejects students outside the groups a mission is restricted to
+func (s *Service) checkTargeted(ctx context.Context, m *Mission, userID uint) error {
+	ok, err := s.repo.IsTargetedUser(ctx, m.ID, userID)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to check mission targeting")
+	}
+	if !ok {
+		return apperrors.New("NOT_TARGETED", "This mission is only open to selected classes")
+	}
+	return nil
+}
+
+// GetActiveList lists open missions; students only see missions aimed at their groups
+func (s *Service) GetActiveList(ctx context.Context, viewerID uint, role string, page, perPage int) ([]*MissionResponse, int, error) {
+	if role == constants.RoleAdmin {
+		viewerID = 0
+	}
+
 	offset := (page - 1) * perPage
-	missions, total, err := s.repo.GetActiveList(ctx, perPage, offset)
+	missions, total, err := s.repo.GetActiveList(ctx, viewerID, perPage, offset)
 	if err != nil {
 		return nil, 0, apperrors.Wrap(err, "DB_ERROR", "Failed to get missions")
 	}
@@ -85,6 +216,7 @@ func (s *Service) GetActiveList(ctx context.Context, page, perPage int) ([]*Miss
 	var responses []*MissionResponse
 	for _, m := range missions {
 		resp := ToMissionResponse(&m.Mission, m.CreatorName)
+		hideAnswerKeys(&m.Mission, &resp)
 		responses = append(responses, &resp)
 	}
 
@@ -128,6 +260,29 @@ func (s *Service) Update(ctx context.Context, id uint, req UpdateMissionRequest,
 	if req.RewardPoints != nil {
 		m.RewardPoints = *req.RewardPoints
 	}
+	if req.RewardRules != nil {
+		rules, _ := json.Marshal(req.RewardRules)
+		m.RewardRules = sql.NullString{String: string(rules), Valid: true}
+	}
+	var groupIDs []uint
+	if req.GroupIDs != nil {
+		if groupIDs, err = s.resolveTargetGroups(ctx, *req.GroupIDs, m.CreatorID); err != nil {
+			return err
+		}
+	}
+	if req.Attempts != nil {
+		if !m.IsRepeatable {
+			return apperrors.New("NOT_REPEATABLE", "Only repeatable missions take an attempt policy")
+		}
+		req.Attempts.apply(m)
+	}
+	if req.Rubric != nil {
+		if m.MissionType == constants.MissionTypeQuiz || m.MissionType == constants.MissionTypeAttendance {
+			return apperrors.New("INVALID_RUBRIC", "Quiz and attendance missions are graded automatically")
+		}
+		rubric, _ := json.Marshal(req.Rubric)
+		m.Rubric = sql.NullString{String: string(rubric), Valid: true}
+	}
 	if req.MaxParticipants != nil {
 		m.MaxParticipants = sql.NullInt64{Int64: int64(*req.MaxParticipants), Valid: true}
 	}
@@ -135,9 +290,19 @@ func (s *Service) Update(ctx context.Context, id uint, req UpdateMissionRequest,
 		m.Difficulty = *req.Difficulty
 	}
 	if req.Content != nil {
+		if m.MissionType == constants.MissionTypeQuiz {
+			quiz, quizErrors := ParseQuiz(req.Content)
+			if len(quizErrors) > 0 {
+				return apperrors.New("INVALID_QUIZ", quizErrors[0].Field+": "+quizErrors[0].Message)
+			}
+			req.Content, _ = json.Marshal(quiz)
+		}
 		m.Content = sql.NullString{String: string(req.Content), Valid: true}
 	}
 	if req.IsActive != nil {
+		if *req.IsActive && m.BudgetReleasedAt.Valid {
+			return apperrors.New("BUDGET_RELEASED", "Closed missions cannot be reopened")
+		}
 		m.IsActive = *req.IsActive
 	}
 	if req.StartDate != nil {
@@ -150,22 +315,131 @@ func (s *Service) Update(ctx context.Context, id uint, req UpdateMissionRequest,
 		m.Deadline = sql.NullTime{Time: *req.Deadline, Valid: true}
 	}
 
-	return s.repo.Update(ctx, m)
+	// Everything is validated; save the mission and its audience together so
+	// a rejected update changes neither
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if req.GroupIDs != nil {
+		if err := s.repo.SetTargetGroups(ctx, tx, m.ID, groupIDs); err != nil {
+			return apperrors.Wrap(err, "DB_ERROR", "Failed to save target groups")
+		}
+	}
+	if err := s.repo.Update(ctx, tx, m); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to update mission")
+	}
+	if err := tx.Commit(); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to commit")
+	}
+	return nil
 }
 
 func (s *Service) Delete(ctx context.Context, id uint, userID uint) error {
-	m, err := s.repo.GetByID(ctx, id)
+	_, err := s.closeMission(ctx, id, userID, true)
+	return err
+}
+
+// CloseMission stops a mission for good and returns its unused budget
+func (s *Service) CloseMission(ctx context.Context, id uint, userID uint) (*MissionResponse, error) {
+	m, err := s.closeMission(ctx, id, userID, false)
 	if err != nil {
-		return apperrors.Wrap(err, "DB_ERROR", "Failed to get mission")
+		return nil, err
+	}
+
+	resp := ToMissionResponse(m, "")
+	return &resp, nil
+}
+
+func (s *Service) closeMission(ctx context.Context, id uint, userID uint, remove bool) (*Mission, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	m, err := s.repo.GetByIDForUpdate(ctx, tx, id)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission")
 	}
 	if m == nil {
-		return apperrors.ErrNotFound
+		return nil, apperrors.ErrNotFound
 	}
 	if m.CreatorID != userID {
-		return apperrors.ErrForbidden
+		return nil, apperrors.ErrForbidden
+	}
+
+	// Releasing the budget would leave waiting submissions unpayable
+	pending, err := s.repo.CountPendingSubmissions(ctx, tx, id)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to count submissions")
+	}
+	if pending > 0 {
+		return nil, apperrors.New("PENDING_SUBMISSIONS", fmt.Sprintf("Grade the %d pending submission(s) before closing this mission", pending))
+	}
+
+	if remove {
+		err = s.repo.Delete(ctx, tx, id)
+	} else {
+		err = s.repo.Deactivate(ctx, tx, id)
+	}
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to close mission")
+	}
+
+	if err := s.releaseBudget(ctx, tx, m); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to commit")
+	}
+
+	m.IsActive = false
+	return m, nil
+}
+
+// TopUpBudget adds points to an open mission's budget from its original source
+func (s *Service) TopUpBudget(ctx context.Context, id uint, req TopUpBudgetRequest, userID uint) (*MissionResponse, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	m, err := s.repo.GetByIDForUpdate(ctx, tx, id)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission")
+	}
+	if m == nil {
+		return nil, apperrors.ErrNotFound
+	}
+	if m.CreatorID != userID {
+		return nil, apperrors.ErrForbidden
+	}
+	if m.BudgetSource == constants.BudgetSourceNone {
+		return nil, apperrors.New("NO_BUDGET", "Mission was created without a budget")
+	}
+	if m.BudgetReleasedAt.Valid {
+		return nil, apperrors.New("BUDGET_RELEASED", "Mission is closed and its budget was released")
+	}
+
+	if err := s.reserveBudget(ctx, tx, m, req.Amount); err != nil {
+		return nil, err
+	}
+	if err := s.repo.AddBudgetAmount(ctx, tx, m.ID, req.Amount); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to update mission budget")
+	}
+	m.BudgetAmount += req.Amount
+
+	if err := tx.Commit(); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to commit")
 	}
 
-	return s.repo.Delete(ctx, id)
+	resp := ToMissionResponse(m, "")
+	return &resp, nil
 }
 
 func (s *Service) StartMission(ctx context.Context, missionID, userID uint) (*MissionLogResponse, error) {
@@ -186,14 +460,31 @@ func (s *Service) StartMission(ctx context.Context, missionID, userID uint) (*Mi
 		return nil, apperrors.New("MISSION_INACTIVE", "Mission is not active")
 	}
 
-	// Check if already participated
-	existingLog, _ := s.repo.GetLogByMissionAndUser(ctx, missionID, userID)
-	if existingLog != nil && !m.IsRepeatable {
-		return nil, apperrors.New("ALREADY_PARTICIPATED", "Already participated in this mission")
+	if err := checkStartWindow(m, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkTargeted(ctx, m, userID); err != nil {
+		return nil, err
+	}
+
+	// Attendance is recorded by scanning the session QR instead
+	if m.MissionType == constants.MissionTypeAttendance {
+		return nil, apperrors.New("USE_CHECK_IN", "Attendance missions are completed by scanning the attendance QR")
 	}
 
-	// Check max participants
-	if m.MaxParticipants.Valid && m.CurrentParticipants >= int(m.MaxParticipants.Int64) {
+	// A returning participant starts a new attempt if the mission's policy allows it
+	latest, err := s.repo.GetLogByMissionAndUser(ctx, missionID, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission log")
+	}
+	attempt := 1
+	if latest != nil {
+		if err := checkNewAttempt(m, latest, time.Now()); err != nil {
+			return nil, err
+		}
+		attempt = latest.AttemptNumber + 1
+	} else if m.MaxParticipants.Valid && m.CurrentParticipants >= int(m.MaxParticipants.Int64) {
 		return nil, apperrors.New("MAX_PARTICIPANTS", "Maximum participants reached")
 	}
 
@@ -206,18 +497,25 @@ func (s *Service) StartMission(ctx context.Context, missionID, userID uint) (*Mi
 
 	// Create log
 	log := &MissionLog{
-		MissionID: missionID,
-		UserID:    userID,
-		Status:    constants.MissionStatusStarted,
+		MissionID:     missionID,
+		UserID:        userID,
+		AttemptNumber: attempt,
+		Status:        constants.MissionStatusStarted,
 	}
 
 	if err := s.repo.CreateLog(ctx, tx, log); err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return nil, apperrors.New("ATTEMPT_IN_PROGRESS", "This attempt has already been started")
+		}
 		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to create mission log")
 	}
 
-	// Increment participants
-	if err := s.repo.IncrementParticipants(ctx, tx, missionID); err != nil {
-		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to increment participants")
+	// Only a participant's first attempt counts towards the participant limit
+	if attempt == 1 {
+		if err := s.repo.IncrementParticipants(ctx, tx, missionID); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to increment participants")
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -225,119 +523,231 @@ func (s *Service) StartMission(ctx context.Context, missionID, userID uint) (*Mi
 	}
 
 	resp := ToMissionLogResponse(log, m.Title)
+	if quiz := parseMissionQuiz(m); quiz != nil && quiz.TimeLimit() > 0 {
+		t := time.Now().Add(quiz.TimeLimit()).Format(time.RFC3339)
+		resp.TimeLimitEnds = &t
+	}
 	return &resp, nil
 }
 
-func (s *Service) SubmitMission(ctx context.Context, missionID, userID uint, req SubmitMissionRequest) error {
+func (s *Service) SubmitMission(ctx context.Context, missionID, userID uint, req SubmitMissionRequest) (*MissionLogResponse, error) {
+	m, err := s.repo.GetByID(ctx, missionID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission")
+	}
+	if m == nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	// Students removed from the mission's groups can no longer hand in
+	if err := s.checkTargeted(ctx, m, userID); err != nil {
+		return nil, err
+	}
+
 	log, err := s.repo.GetLogByMissionAndUser(ctx, missionID, userID)
 	if err != nil {
-		return apperrors.Wrap(err, "DB_ERROR", "Failed to get mission log")
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission log")
 	}
 	if log == nil {
-		return apperrors.New("NOT_STARTED", "Mission not started")
+		return nil, apperrors.New("NOT_STARTED", "Mission not started")
 	}
 
-	if log.Status != constants.MissionStatusStarted && log.Status != constants.MissionStatusInProgress {
-		return apperrors.New("INVALID_STATUS", "Cannot submit in current status")
+	quiz := parseMissionQuiz(m)
+	if !canSubmit(log, quiz == nil) {
+		return nil, apperrors.New("INVALID_STATUS", "Cannot submit in current status")
+	}
+
+	// An attempt past the cutoff can no longer be completed
+	if err := checkSubmitWindow(m, time.Now()); err != nil {
+		if _, expireErr := s.repo.ExpireLog(ctx, log.ID); expireErr != nil {
+			return nil, apperrors.Wrap(expireErr, "DB_ERROR", "Failed to expire mission log")
+		}
+		return nil, err
 	}
 
 	answersJSON, _ := json.Marshal(req.Answers)
-	return s.repo.SubmitLog(ctx, log.ID, string(answersJSON))
+
+	// Quizzes are scored immediately; other missions wait for the creator to grade
+	if quiz != nil {
+		return s.submitQuiz(ctx, m, quiz, log.ID, userID, req.Answers, string(answersJSON))
+	}
+
+	return s.submitForGrading(ctx, m, log.ID, req, string(answersJSON))
 }
 
-func (s *Service) GradeMission(ctx context.Context, missionID, participantUserID, graderID uint, req GradeMissionRequest) error {
-	m, err := s.repo.GetByID(ctx, missionID)
-	if err != nil {
-		return apperrors.Wrap(err, "DB_ERROR", "Failed to get mission")
+// canSubmit reports whether a log takes a hand-in; manually graded work may be
+// replaced by a new version until it is graded
+func canSubmit(log *MissionLog, manual bool) bool {
+	switch log.Status {
+	case constants.MissionStatusStarted, constants.MissionStatusInProgress:
+		return true
+	case constants.MissionStatusSubmitted:
+		return manual
 	}
-	if m == nil {
-		return apperrors.ErrNotFound
+	return false
+}
+
+// submitForGrading stores a new submission version with its attachments and
+// marks the log SUBMITTED
+func (s *Service) submitForGrading(ctx context.Context, m *Mission, logID uint, req SubmitMissionRequest, answersJSON string) (*MissionLogResponse, error) {
+	if (len(req.Files) > 0 || len(req.Links) > 0) && !acceptsAttachments(m) {
+		return nil, apperrors.New("ATTACHMENTS_NOT_ALLOWED", "Only assignment and project missions accept files and links")
+	}
+	if len(req.Files) > s.upload.MaxFiles {
+		return nil, apperrors.New("INVALID_ATTACHMENT", fmt.Sprintf("At most %d files can be attached", s.upload.MaxFiles))
+	}
+	links, err := validateLinks(req.Links)
+	if err != nil {
+		return nil, err
 	}
 
-	// Only creator can grade
-	if m.CreatorID != graderID {
-		return apperrors.ErrForbidden
+	contentTypes := make([]string, len(req.Files))
+	for i, fh := range req.Files {
+		if contentTypes[i], err = inspectUpload(fh, s.upload.MaxFileSize); err != nil {
+			return nil, err
+		}
 	}
 
-	log, err := s.repo.GetLogByMissionAndUser(ctx, missionID, participantUserID)
+	// Files go to disk first; they are removed again unless the submission commits
+	attachments, err := s.storeUploads(m.ID, logID, req.Files, contentTypes)
 	if err != nil {
-		return apperrors.Wrap(err, "DB_ERROR", "Failed to get mission log")
+		return nil, err
 	}
-	if log == nil {
-		return apperrors.New("NOT_FOUND", "Submission not found")
+	committed := false
+	defer func() {
+		if !committed {
+			s.deleteUploads(attachments)
+		}
+	}()
+	for _, link := range links {
+		attachments = append(attachments, &SubmissionAttachment{
+			Kind: AttachmentLink,
+			URL:  sql.NullString{String: link, Valid: true},
+		})
 	}
 
-	if log.Status != constants.MissionStatusSubmitted {
-		return apperrors.New("NOT_SUBMITTED", "No submission to grade")
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
 	}
+	defer tx.Rollback()
 
-	// Start transaction
-	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	// Lock the log so concurrent hand-ins get distinct versions and none lands after grading
+	log, err := s.repo.GetLogByIDForUpdate(ctx, tx, logID)
 	if err != nil {
-		return apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission log")
+	}
+	if log == nil || !canSubmit(log, true) {
+		return nil, apperrors.New("INVALID_STATUS", "Cannot submit in current status")
 	}
-	defer tx.Rollback()
 
-	rewardPoints := int64(0)
-	if req.Approved {
-		rewardPoints = m.RewardPoints
+	latest, err := s.repo.GetLatestSubmissionVersion(ctx, tx, logID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get submission history")
 	}
 
-	// Grade the log
-	if err := s.repo.GradeLog(ctx, tx, log.ID, req.Score, req.Notes, req.Approved, graderID, rewardPoints); err != nil {
-		return apperrors.Wrap(err, "DB_ERROR", "Failed to grade")
+	sub := &Submission{
+		MissionLogID: logID,
+		Version:      latest + 1,
+		Answers:      sql.NullString{String: answersJSON, Valid: len(req.Answers) > 0},
+		Note:         sql.NullString{String: req.Note, Valid: req.Note != ""},
+		SubmittedAt:  time.Now(),
+		Attachments:  attachments,
+	}
+	if err := s.repo.CreateSubmission(ctx, tx, sub); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to save submission")
+	}
+	for _, a := range attachments {
+		a.SubmissionID = sub.ID
+		if err := s.repo.CreateAttachment(ctx, tx, a); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to save attachment")
+		}
 	}
 
-	// If approved, credit wallet
-	if req.Approved {
-		userWallet, err := s.walletRepo.GetByUserIDForUpdate(ctx, tx, participantUserID)
+	if err := s.repo.SubmitLog(ctx, tx, logID, answersJSON); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to submit mission")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to commit")
+	}
+	committed = true
+
+	log.Status = constants.MissionStatusSubmitted
+	log.Answers = sql.NullString{String: answersJSON, Valid: true}
+	log.SubmittedAt = sql.NullTime{Time: sub.SubmittedAt, Valid: true}
+	resp := ToMissionLogResponse(log, m.Title)
+	submission := ToSubmissionResponse(sub, m.ID)
+	resp.Submission = &submission
+	return &resp, nil
+}
+
+// storeUploads saves uploaded files under the mission log's folder
+func (s *Service) storeUploads(missionID, logID uint, files []*multipart.FileHeader, contentTypes []string) ([]*SubmissionAttachment, error) {
+	var attachments []*SubmissionAttachment
+	for i, fh := range files {
+		key := fmt.Sprintf("missions/%d/logs/%d/%s%s", missionID, logID, utils.GenerateUUID(), strings.ToLower(filepath.Ext(fh.Filename)))
+
+		f, err := fh.Open()
 		if err != nil {
-			return apperrors.Wrap(err, "DB_ERROR", "Failed to get wallet")
+			s.deleteUploads(attachments)
+			return nil, apperrors.Wrap(err, "UPLOAD_ERROR", "Failed to read upload")
 		}
-
-		// Credit wallet
-		if err := s.walletRepo.UpdateBalanceWithStats(ctx, tx, userWallet.ID, rewardPoints, true); err != nil {
-			return apperrors.Wrap(err, "DB_ERROR", "Failed to credit wallet")
+		size, checksum, err := s.files.Save(key, f)
+		f.Close()
+		if err != nil {
+			s.deleteUploads(attachments)
+			return nil, apperrors.Wrap(err, "UPLOAD_ERROR", "Failed to store upload")
 		}
 
-		// Create ledger entry
-		txCode := utils.GenerateTransactionCode("MIS")
-		entry := &wallet.WalletLedger{
-			WalletID:      userWallet.ID,
-			EntryType:     constants.LedgerCredit,
-			Amount:        rewardPoints,
-			BalanceBefore: userWallet.Balance,
-			BalanceAfter:  userWallet.Balance + rewardPoints,
-			Description:   "Mission reward: " + m.Title,
-			ReferenceType: constants.TxTypeMissionReward,
-			ReferenceID:   txCode,
-		}
+		attachments = append(attachments, &SubmissionAttachment{
+			Kind:        AttachmentFile,
+			FileName:    sql.NullString{String: sanitizeFileName(fh.Filename), Valid: true},
+			ContentType: sql.NullString{String: contentTypes[i], Valid: true},
+			SizeBytes:   sql.NullInt64{Int64: size, Valid: true},
+			StorageKey:  sql.NullString{String: key, Valid: true},
+			Checksum:    sql.NullString{String: checksum, Valid: true},
+		})
+	}
+	return attachments, nil
+}
 
-		if err := s.walletRepo.CreateLedgerEntry(ctx, tx, entry); err != nil {
-			return apperrors.Wrap(err, "DB_ERROR", "Failed to create ledger")
+func (s *Service) deleteUploads(attachments []*SubmissionAttachment) {
+	for _, a := range attachments {
+		if a.Kind != AttachmentFile {
+			continue
+		}
+		if err := s.files.Delete(a.StorageKey.String); err != nil {
+			log.Printf("Failed to remove upload %s: %v", a.StorageKey.String, err)
 		}
 	}
-
-	return tx.Commit()
 }
 
-func (s *Service) GetMyParticipations(ctx context.Context, userID uint, page, perPage int) ([]*MissionLogResponse, int, error) {
-	offset := (page - 1) * perPage
-	logs, total, err := s.repo.GetLogsByUserID(ctx, userID, perPage, offset)
+// GetMyAttempts returns the caller's attempts at a mission, newest first
+func (s *Service) GetMyAttempts(ctx context.Context, missionID, userID uint) ([]*MissionLogResponse, error) {
+	m, err := s.repo.GetByID(ctx, missionID)
 	if err != nil {
-		return nil, 0, apperrors.Wrap(err, "DB_ERROR", "Failed to get participations")
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission")
+	}
+	if m == nil {
+		return nil, apperrors.ErrNotFound
 	}
 
-	var responses []*MissionLogResponse
+	logs, err := s.repo.GetAttempts(ctx, missionID, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get attempts")
+	}
+
+	responses := []*MissionLogResponse{}
 	for _, l := range logs {
-		resp := ToMissionLogResponse(&l.MissionLog, l.MissionTitle)
+		resp := ToMissionLogResponse(l, m.Title)
 		responses = append(responses, &resp)
 	}
-
-	return responses, total, nil
+	return responses, nil
 }
 
-func (s *Service) GetParticipants(ctx context.Context, missionID, userID uint) ([]*ParticipantResponse, error) {
+// GetMySubmissions returns the caller's submission history for a mission
+func (s *Service) GetMySubmissions(ctx context.Context, missionID, userID uint) ([]*SubmissionResponse, error) {
 	m, err := s.repo.GetByID(ctx, missionID)
 	if err != nil {
 		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission")
@@ -345,37 +755,1193 @@ func (s *Service) GetParticipants(ctx context.Context, missionID, userID uint) (
 	if m == nil {
 		return nil, apperrors.ErrNotFound
 	}
+	return s.getSubmissionHistory(ctx, missionID, userID)
+}
 
-	// Only creator can view participants
-	if m.CreatorID != userID {
-		return nil, apperrors.ErrForbidden
+// GetParticipantSubmissions returns a participant's submission history for the mission creator
+func (s *Service) GetParticipantSubmissions(ctx context.Context, missionID, participantID, graderID uint) ([]*SubmissionResponse, error) {
+	if _, err := s.getOwnedMission(ctx, missionID, graderID); err != nil {
+		return nil, err
 	}
+	return s.getSubmissionHistory(ctx, missionID, participantID)
+}
 
-	logs, err := s.repo.GetLogsByMissionID(ctx, missionID)
+func (s *Service) getSubmissionHistory(ctx context.Context, missionID, userID uint) ([]*SubmissionResponse, error) {
+	log, err := s.repo.GetLogByMissionAndUser(ctx, missionID, userID)
 	if err != nil {
-		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get participants")
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission log")
+	}
+	if log == nil {
+		return nil, apperrors.New("NOT_FOUND", "Submission not found")
 	}
 
-	var responses []*ParticipantResponse
-	for _, l := range logs {
-		resp := &ParticipantResponse{
-			UserID:   l.UserID,
-			UserName: l.UserName,
-			Status:   l.Status,
-		}
-		if l.Score.Valid {
-			resp.Score = &l.Score.Float64
-		}
-		if l.SubmittedAt.Valid {
-			t := l.SubmittedAt.Time.Format(time.RFC3339)
-			resp.SubmittedAt = &t
-		}
-		if l.CompletedAt.Valid {
-			t := l.CompletedAt.Time.Format(time.RFC3339)
+	submissions, err := s.repo.GetSubmissionsByLogID(ctx, log.ID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get submissions")
+	}
+
+	responses := []*SubmissionResponse{}
+	for _, sub := range submissions {
+		resp := ToSubmissionResponse(sub, missionID)
+		responses = append(responses, &resp)
+	}
+	return responses, nil
+}
+
+// OpenAttachment opens an uploaded file for its uploader or the mission creator
+func (s *Service) OpenAttachment(ctx context.Context, missionID, attachmentID, userID uint) (*AttachmentWithOwner, *os.File, error) {
+	a, err := s.repo.GetAttachmentByID(ctx, attachmentID)
+	if err != nil {
+		return nil, nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get attachment")
+	}
+	if a == nil || a.MissionID != missionID || a.Kind != AttachmentFile {
+		return nil, nil, apperrors.New("NOT_FOUND", "Attachment not found")
+	}
+
+	if a.UserID != userID {
+		if _, err := s.getOwnedMission(ctx, missionID, userID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	f, err := s.files.Open(a.StorageKey.String)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, apperrors.New("NOT_FOUND", "Attachment file is missing")
+		}
+		return nil, nil, apperrors.Wrap(err, "STORAGE_ERROR", "Failed to open attachment")
+	}
+	return a, f, nil
+}
+
+func (s *Service) submitQuiz(ctx context.Context, m *Mission, quiz *QuizContent, logID, userID uint, answers json.RawMessage, answersJSON string) (*MissionLogResponse, error) {
+	result, err := quiz.Score(answers)
+	if err != nil {
+		return nil, apperrors.New("INVALID_ANSWERS", err.Error())
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	// Re-read under lock so concurrent submissions cannot both be rewarded
+	log, err := s.repo.GetLogByIDForUpdate(ctx, tx, logID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission log")
+	}
+	if log == nil {
+		return nil, apperrors.New("NOT_STARTED", "Mission not started")
+	}
+	if log.Status != constants.MissionStatusStarted && log.Status != constants.MissionStatusInProgress {
+		return nil, apperrors.New("INVALID_STATUS", "Cannot submit in current status")
+	}
+
+	if limit := quiz.TimeLimit(); limit > 0 && time.Since(log.StartedAt) > limit+quizSubmitGrace {
+		if err := s.repo.UpdateLogStatus(ctx, tx, log.ID, constants.MissionStatusExpired); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to expire mission log")
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to commit")
+		}
+		return nil, apperrors.New("TIME_LIMIT_EXCEEDED", "Quiz time limit has passed")
+	}
+
+	rewardPoints := int64(0)
+	if result.Passed {
+		rewardPoints = missionRewardRules(m).Compute(m.RewardPoints, result.Score, time.Now(), m.Deadline)
+		if rewardPoints, err = s.capReward(ctx, tx, m, userID, rewardPoints); err != nil {
+			return nil, err
+		}
+	}
+
+	notes := fmt.Sprintf("Auto-graded: %g/%g points", result.PointsEarned, result.TotalPoints)
+	if err := s.repo.AutoGradeLog(ctx, tx, log.ID, answersJSON, result.Score, result.Passed, rewardPoints, notes); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to grade")
+	}
+
+	if rewardPoints > 0 {
+		if err := s.creditReward(ctx, tx, m, log.ID, userID, rewardPoints, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to commit")
+	}
+
+	now := time.Now()
+	log.Status = constants.MissionStatusFailed
+	if result.Passed {
+		log.Status = constants.MissionStatusCompleted
+		log.CompletedAt = sql.NullTime{Time: now, Valid: true}
+	}
+	log.Answers = sql.NullString{String: answersJSON, Valid: true}
+	log.Score = sql.NullFloat64{Float64: result.Score, Valid: true}
+	log.RewardClaimed = rewardPoints > 0
+	log.RewardPoints = sql.NullInt64{Int64: rewardPoints, Valid: true}
+	log.SubmittedAt = sql.NullTime{Time: now, Valid: true}
+	log.Notes = sql.NullString{String: notes, Valid: true}
+
+	resp := ToMissionLogResponse(log, m.Title)
+	resp.QuizResult = result
+	return &resp, nil
+}
+
+func (s *Service) GradeMission(ctx context.Context, missionID, participantUserID, graderID uint, req GradeMissionRequest) error {
+	m, err := s.getOwnedMission(ctx, missionID, graderID)
+	if err != nil {
+		return err
+	}
+
+	// Start transaction
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if _, _, err := s.gradeParticipant(ctx, tx, m, participantUserID, graderID, req, ""); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// BulkGrade grades many participants in one database transaction. Each row is
+// applied under its own savepoint, so a row that fails (not submitted, budget
+// exhausted, bad rubric scores) is reported without undoing the others.
+func (s *Service) BulkGrade(ctx context.Context, missionID, graderID uint, req BulkGradeRequest) (*BulkGradeResponse, error) {
+	m, err := s.getOwnedMission(ctx, missionID, graderID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	resp := &BulkGradeResponse{
+		BatchID: utils.GenerateUUID(),
+		Results: make([]*BulkGradeResult, 0, len(req.Grades)),
+	}
+
+	for _, item := range req.Grades {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT grade_row"); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to grade")
+		}
+
+		result := &BulkGradeResult{UserID: item.UserID}
+		score, rewardPoints, err := s.gradeParticipant(ctx, tx, m, item.UserID, graderID, item.GradeMissionRequest, resp.BatchID)
+		if err != nil {
+			appErr, ok := err.(*apperrors.AppError)
+			// Database failures may have ended the transaction; give up on the whole batch
+			if !ok || appErr.Code == "DB_ERROR" {
+				return nil, err
+			}
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT grade_row"); err != nil {
+				return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to grade")
+			}
+			result.Status = "FAILED"
+			result.Code = appErr.Code
+			result.Error = appErr.Message
+			resp.Failed++
+		} else {
+			result.Status = "GRADED"
+			result.Score = &score
+			result.RewardPoints = rewardPoints
+			resp.Graded++
+			resp.TotalRewarded += rewardPoints
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to commit")
+	}
+
+	return resp, nil
+}
+
+// gradeParticipant grades a submitted log inside tx and pays the reward;
+// it returns the final score and the points credited
+func (s *Service) gradeParticipant(ctx context.Context, tx *sql.Tx, m *Mission, participantUserID, graderID uint, req GradeMissionRequest, batchID string) (float64, int64, error) {
+	log, err := s.repo.GetLogByMissionAndUserForUpdate(ctx, tx, m.ID, participantUserID)
+	if err != nil {
+		return 0, 0, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission log")
+	}
+	if log == nil {
+		return 0, 0, apperrors.New("NOT_FOUND", "Submission not found")
+	}
+
+	if log.Status != constants.MissionStatusSubmitted {
+		return 0, 0, apperrors.New("NOT_SUBMITTED", "No submission to grade")
+	}
+
+	// Missions with a rubric are scored per criterion
+	score := req.Score
+	var rubricScores sql.NullString
+	if rubric := missionRubric(m); rubric != nil {
+		if len(req.RubricScores) == 0 {
+			return 0, 0, apperrors.New("RUBRIC_REQUIRED", "This mission is graded with its rubric; score every criterion")
+		}
+		var scores []RubricScore
+		score, scores, err = rubric.Score(req.RubricScores)
+		if err != nil {
+			return 0, 0, err
+		}
+		data, _ := json.Marshal(scores)
+		rubricScores = sql.NullString{String: string(data), Valid: true}
+	} else if len(req.RubricScores) > 0 {
+		return 0, 0, apperrors.New("NO_RUBRIC", "This mission has no rubric; grade it with a score")
+	}
+
+	// Reward follows the mission's rules, using when the work was handed in
+	rewardPoints := int64(0)
+	if req.Approved {
+		submittedAt := time.Now()
+		if log.SubmittedAt.Valid {
+			submittedAt = log.SubmittedAt.Time
+		}
+		rewardPoints = missionRewardRules(m).Compute(m.RewardPoints, score, submittedAt, m.Deadline)
+		if rewardPoints, err = s.capReward(ctx, tx, m, participantUserID, rewardPoints); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	// Grade the log
+	if err := s.repo.GradeLog(ctx, tx, log.ID, score, rubricScores, req.Notes, req.Approved, graderID, rewardPoints); err != nil {
+		return 0, 0, apperrors.Wrap(err, "DB_ERROR", "Failed to grade")
+	}
+
+	// If approved, credit wallet
+	if rewardPoints > 0 {
+		if err := s.creditReward(ctx, tx, m, log.ID, participantUserID, rewardPoints, batchID); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return score, rewardPoints, nil
+}
+
+// capReward trims a reward so a participant's earnings from a repeatable
+// mission stay within its per-period cap
+func (s *Service) capReward(ctx context.Context, tx *sql.Tx, m *Mission, userID uint, rewardPoints int64) (int64, error) {
+	if !m.IsRepeatable || !m.RewardCap.Valid || rewardPoints <= 0 {
+		return rewardPoints, nil
+	}
+
+	earned, err := s.repo.SumRewardsSince(ctx, tx, m.ID, userID, rewardPeriodStart(m.RewardCapPeriod.String, time.Now()))
+	if err != nil {
+		return 0, apperrors.Wrap(err, "DB_ERROR", "Failed to check reward cap")
+	}
+
+	remaining := m.RewardCap.Int64 - earned
+	if remaining < 0 {
+		remaining = 0
+	}
+	if rewardPoints > remaining {
+		return remaining, nil
+	}
+	return rewardPoints, nil
+}
+
+// creditReward pays a mission reward into the participant's wallet inside tx,
+// drawing it from the mission budget and recording a MISSION_REWARD transaction;
+// batchID tags rewards paid together by a bulk grade
+func (s *Service) creditReward(ctx context.Context, tx *sql.Tx, m *Mission, logID, userID uint, rewardPoints int64, batchID string) error {
+	// Lock the mission so concurrent grades cannot overspend its budget
+	budget, err := s.repo.GetByIDForUpdate(ctx, tx, m.ID)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to get mission")
+	}
+	if budget == nil {
+		return apperrors.ErrNotFound
+	}
+
+	funded := budget.BudgetSource != constants.BudgetSourceNone
+	if funded && budget.BudgetRemaining() < rewardPoints {
+		return apperrors.New("BUDGET_EXHAUSTED", fmt.Sprintf(
+			"Mission budget has %d points left but this reward is %d; top up the budget first",
+			budget.BudgetRemaining(), rewardPoints))
+	}
+
+	transaction := &wallet.Transaction{
+		TransactionCode: utils.GenerateTransactionCode("MIS"),
+		IdempotencyKey:  fmt.Sprintf("mission-log-%d", logID),
+		TransactionType: constants.TxTypeMissionReward,
+		Status:          constants.TxStatusCompleted,
+		Amount:          rewardPoints,
+		FeeAmount:       0,
+		NetAmount:       rewardPoints,
+		Description:     sql.NullString{String: "Mission reward: " + m.Title, Valid: true},
+		MissionLogID:    sql.NullInt64{Int64: int64(logID), Valid: true},
+		ProcessedAt:     sql.NullTime{Time: time.Now(), Valid: true},
+	}
+
+	metadata := map[string]interface{}{}
+	if batchID != "" {
+		metadata["grading_batch"] = batchID
+	}
+
+	switch budget.BudgetSource {
+	case constants.BudgetSourceWallet:
+		creatorWallet, err := s.walletRepo.GetByUserIDForUpdate(ctx, tx, budget.CreatorID)
+		if err != nil {
+			return apperrors.Wrap(err, "DB_ERROR", "Failed to get wallet")
+		}
+		if creatorWallet == nil {
+			return apperrors.ErrWalletNotFound
+		}
+		if err := s.walletRepo.SpendLockedBalance(ctx, tx, creatorWallet.ID, rewardPoints); err != nil {
+			return apperrors.Wrap(err, "DB_ERROR", "Failed to debit mission budget")
+		}
+		transaction.FromWalletID = sql.NullInt64{Int64: int64(creatorWallet.ID), Valid: true}
+	case constants.BudgetSourcePool:
+		if err := s.repo.SpendPoolBudget(ctx, tx, uint(budget.RewardPoolID.Int64), rewardPoints); err != nil {
+			return apperrors.Wrap(err, "DB_ERROR", "Failed to debit reward pool")
+		}
+		metadata["reward_pool_id"] = budget.RewardPoolID.Int64
+	}
+	if len(metadata) > 0 {
+		data, _ := json.Marshal(metadata)
+		transaction.Metadata = sql.NullString{String: string(data), Valid: true}
+	}
+
+	userWallet, err := s.walletRepo.GetByUserIDForUpdate(ctx, tx, userID)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to get wallet")
+	}
+	if userWallet == nil {
+		return apperrors.ErrWalletNotFound
+	}
+	transaction.ToWalletID = sql.NullInt64{Int64: int64(userWallet.ID), Valid: true}
+
+	if err := s.walletRepo.CreateTransaction(ctx, tx, transaction); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to create transaction")
+	}
+
+	// Credit wallet
+	if err := s.walletRepo.UpdateBalanceWithStats(ctx, tx, userWallet.ID, rewardPoints, true); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to credit wallet")
+	}
+
+	// Create ledger entry
+	entry := &wallet.WalletLedger{
+		WalletID:      userWallet.ID,
+		TransactionID: sql.NullInt64{Int64: int64(transaction.ID), Valid: true},
+		EntryType:     constants.LedgerCredit,
+		Amount:        rewardPoints,
+		BalanceBefore: userWallet.Balance,
+		BalanceAfter:  userWallet.Balance + rewardPoints,
+		Description:   "Mission reward: " + m.Title,
+		ReferenceType: constants.TxTypeMissionReward,
+		ReferenceID:   transaction.TransactionCode,
+	}
+
+	if err := s.walletRepo.CreateLedgerEntry(ctx, tx, entry); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to create ledger")
+	}
+
+	if funded {
+		if err := s.repo.AddBudgetSpent(ctx, tx, m.ID, rewardPoints); err != nil {
+			return apperrors.Wrap(err, "DB_ERROR", "Failed to update mission budget")
+		}
+	}
+
+	return nil
+}
+
+// reserveBudget sets amount aside for m's rewards: in the creator's locked
+// balance for WALLET budgets, or in the pool's reserved points for POOL budgets
+func (s *Service) reserveBudget(ctx context.Context, tx *sql.Tx, m *Mission, amount int64) error {
+	switch m.BudgetSource {
+	case constants.BudgetSourceWallet:
+		creatorWallet, err := s.walletRepo.GetByUserIDForUpdate(ctx, tx, m.CreatorID)
+		if err != nil {
+			return apperrors.Wrap(err, "DB_ERROR", "Failed to lock wallet")
+		}
+		if creatorWallet == nil {
+			return apperrors.ErrWalletNotFound
+		}
+		if creatorWallet.IsFrozen {
+			return apperrors.ErrWalletFrozen
+		}
+		if creatorWallet.Balance < amount {
+			return apperrors.ErrInsufficientBalance
+		}
+
+		if err := s.walletRepo.LockBalance(ctx, tx, creatorWallet.ID, amount); err != nil {
+			return apperrors.Wrap(err, "DB_ERROR", "Failed to reserve budget")
+		}
+
+		entry := &wallet.WalletLedger{
+			WalletID:      creatorWallet.ID,
+			EntryType:     constants.LedgerDebit,
+			Amount:        amount,
+			BalanceBefore: creatorWallet.Balance,
+			BalanceAfter:  creatorWallet.Balance - amount,
+			Description:   "Mission budget reserved: " + m.Title,
+			ReferenceType: constants.LedgerRefMissionBudget,
+			ReferenceID:   fmt.Sprint(m.ID),
+		}
+		if err := s.walletRepo.CreateLedgerEntry(ctx, tx, entry); err != nil {
+			return apperrors.Wrap(err, "DB_ERROR", "Failed to create ledger")
+		}
+	case constants.BudgetSourcePool:
+		pool, err := s.repo.GetRewardPoolByIDForUpdate(ctx, tx, uint(m.RewardPoolID.Int64))
+		if err != nil {
+			return apperrors.Wrap(err, "DB_ERROR", "Failed to lock reward pool")
+		}
+		if pool == nil {
+			return apperrors.New("POOL_NOT_FOUND", "Reward pool not found")
+		}
+		if !pool.IsActive {
+			return apperrors.New("POOL_INACTIVE", "Reward pool is not active")
+		}
+		if pool.Balance < amount {
+			return apperrors.New("INSUFFICIENT_POOL_BALANCE", fmt.Sprintf("Reward pool has only %d points available", pool.Balance))
+		}
+
+		if err := s.repo.ReservePoolBudget(ctx, tx, pool.ID, amount); err != nil {
+			return apperrors.Wrap(err, "DB_ERROR", "Failed to reserve budget")
+		}
+	default:
+		return apperrors.New("NO_BUDGET", "Mission was created without a budget")
+	}
+
+	return nil
+}
+
+// releaseBudget returns what is left of a locked mission's budget to its source
+func (s *Service) releaseBudget(ctx context.Context, tx *sql.Tx, m *Mission) error {
+	if m.BudgetSource == constants.BudgetSourceNone || m.BudgetReleasedAt.Valid {
+		return nil
+	}
+
+	remaining := m.BudgetRemaining()
+	if remaining > 0 {
+		switch m.BudgetSource {
+		case constants.BudgetSourceWallet:
+			creatorWallet, err := s.walletRepo.GetByUserIDForUpdate(ctx, tx, m.CreatorID)
+			if err != nil {
+				return apperrors.Wrap(err, "DB_ERROR", "Failed to lock wallet")
+			}
+			if creatorWallet == nil {
+				return apperrors.ErrWalletNotFound
+			}
+
+			if err := s.walletRepo.UnlockBalance(ctx, tx, creatorWallet.ID, remaining); err != nil {
+				return apperrors.Wrap(err, "DB_ERROR", "Failed to release budget")
+			}
+
+			entry := &wallet.WalletLedger{
+				WalletID:      creatorWallet.ID,
+				EntryType:     constants.LedgerCredit,
+				Amount:        remaining,
+				BalanceBefore: creatorWallet.Balance,
+				BalanceAfter:  creatorWallet.Balance + remaining,
+				Description:   "Unused mission budget released: " + m.Title,
+				ReferenceType: constants.LedgerRefMissionBudget,
+				ReferenceID:   fmt.Sprint(m.ID),
+			}
+			if err := s.walletRepo.CreateLedgerEntry(ctx, tx, entry); err != nil {
+				return apperrors.Wrap(err, "DB_ERROR", "Failed to create ledger")
+			}
+		case constants.BudgetSourcePool:
+			if err := s.repo.ReleasePoolBudget(ctx, tx, uint(m.RewardPoolID.Int64), remaining); err != nil {
+				return apperrors.Wrap(err, "DB_ERROR", "Failed to release budget")
+			}
+		}
+	}
+
+	if err := s.repo.MarkBudgetReleased(ctx, tx, m.ID); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to release budget")
+	}
+
+	m.BudgetReleasedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (s *Service) GetMyParticipations(ctx context.Context, userID uint, page, perPage int) ([]*MissionLogResponse, int, error) {
+	offset := (page - 1) * perPage
+	logs, total, err := s.repo.GetLogsByUserID(ctx, userID, perPage, offset)
+	if err != nil {
+		return nil, 0, apperrors.Wrap(err, "DB_ERROR", "Failed to get participations")
+	}
+
+	var responses []*MissionLogResponse
+	for _, l := range logs {
+		resp := ToMissionLogResponse(&l.MissionLog, l.MissionTitle)
+		responses = append(responses, &resp)
+	}
+
+	return responses, total, nil
+}
+
+func (s *Service) GetParticipants(ctx context.Context, missionID, userID uint) ([]*ParticipantResponse, error) {
+	m, err := s.repo.GetByID(ctx, missionID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission")
+	}
+	if m == nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	// Only creator can view participants
+	if m.CreatorID != userID {
+		return nil, apperrors.ErrForbidden
+	}
+
+	logs, err := s.repo.GetLogsByMissionID(ctx, missionID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get participants")
+	}
+
+	attachments, err := s.repo.GetLatestAttachmentsByMission(ctx, missionID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get attachments")
+	}
+	latestByLog := map[uint][]*AttachmentWithOwner{}
+	for _, a := range attachments {
+		latestByLog[a.MissionLogID] = append(latestByLog[a.MissionLogID], a)
+	}
+
+	var responses []*ParticipantResponse
+	for _, l := range logs {
+		resp := &ParticipantResponse{
+			UserID:        l.UserID,
+			UserName:      l.UserName,
+			AttemptNumber: l.AttemptNumber,
+			Status:        l.Status,
+		}
+		if l.Score.Valid {
+			resp.Score = &l.Score.Float64
+		}
+		resp.RubricScores = logRubricScores(&l.MissionLog)
+		if l.SubmittedAt.Valid {
+			t := l.SubmittedAt.Time.Format(time.RFC3339)
+			resp.SubmittedAt = &t
+		}
+		if l.CompletedAt.Valid {
+			t := l.CompletedAt.Time.Format(time.RFC3339)
 			resp.CompletedAt = &t
 		}
-		responses = append(responses, resp)
+		for _, a := range latestByLog[l.ID] {
+			resp.SubmissionVersion = a.Version
+			resp.Attachments = append(resp.Attachments, ToAttachmentResponse(&a.SubmissionAttachment, missionID))
+		}
+		responses = append(responses, resp)
+	}
+
+	return responses, nil
+}
+
+// Scheduler
+
+// schedulerBatch bounds how many rows one scheduler pass touches per job
+const schedulerBatch = 500
+
+// RunScheduler expires overdue attempts, closes ended missions and sends
+// deadline reminders until ctx is cancelled
+func (s *Service) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(s.config.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runScheduledJobs(ctx)
+		}
+	}
+}
+
+func (s *Service) runScheduledJobs(ctx context.Context) {
+	if expired, err := s.ExpireOverdueLogs(ctx); err != nil {
+		log.Printf("Mission scheduler: %v", err)
+	} else if expired > 0 {
+		log.Printf("Mission scheduler: expired %d overdue attempts", expired)
+	}
+
+	if closed, err := s.CloseEndedMissions(ctx); err != nil {
+		log.Printf("Mission scheduler: %v", err)
+	} else if closed > 0 {
+		log.Printf("Mission scheduler: deactivated %d ended missions", closed)
+	}
+
+	if sent, err := s.SendDeadlineReminders(ctx); err != nil {
+		log.Printf("Mission scheduler: %v", err)
+	} else if sent > 0 {
+		log.Printf("Mission scheduler: sent %d deadline reminders", sent)
+	}
+}
+
+// ExpireOverdueLogs marks unfinished attempts past their mission's cutoff as
+// EXPIRED. Attempts whose mission still takes late work are skipped, so the
+// scan pages on past them instead of refetching them every run
+func (s *Service) ExpireOverdueLogs(ctx context.Context) (int, error) {
+	now := time.Now()
+	expired := 0
+	for afterID := uint(0); ; {
+		logs, err := s.repo.GetOverdueLogs(ctx, afterID, schedulerBatch)
+		if err != nil {
+			return expired, apperrors.Wrap(err, "DB_ERROR", "Failed to get overdue attempts")
+		}
+
+		for _, l := range logs {
+			afterID = l.LogID
+			if cutoff, ok := l.Mission.SubmissionCutoff(); !ok || now.Before(cutoff) {
+				// Late work is still accepted until the mission ends
+				continue
+			}
+
+			ok, err := s.repo.ExpireLog(ctx, l.LogID)
+			if err != nil {
+				return expired, apperrors.Wrap(err, "DB_ERROR", "Failed to expire attempt")
+			}
+			if !ok {
+				continue
+			}
+			expired++
+
+			s.notify(ctx, &notification.Notification{
+				UserID:        l.UserID,
+				Type:          constants.NotificationMissionExpired,
+				Title:         "Mission expired",
+				Body:          fmt.Sprintf("Your attempt at \"%s\" expired because the deadline passed before it was submitted.", l.Mission.Title),
+				ReferenceType: "MISSION_LOG",
+				ReferenceID:   fmt.Sprint(l.LogID),
+			})
+		}
+
+		if len(logs) < schedulerBatch {
+			return expired, nil
+		}
+	}
+}
+
+// CloseEndedMissions deactivates missions past their end date and returns the
+// unused budget of those with no submissions left to grade
+func (s *Service) CloseEndedMissions(ctx context.Context) (int64, error) {
+	closed, err := s.repo.DeactivateEnded(ctx)
+	if err != nil {
+		return 0, apperrors.Wrap(err, "DB_ERROR", "Failed to deactivate ended missions")
+	}
+
+	ids, err := s.repo.GetEndedUnreleasedBudgets(ctx, schedulerBatch)
+	if err != nil {
+		return closed, apperrors.Wrap(err, "DB_ERROR", "Failed to get ended missions")
+	}
+	for _, id := range ids {
+		if err := s.releaseEndedBudget(ctx, id); err != nil {
+			return closed, err
+		}
+	}
+
+	return closed, nil
+}
+
+func (s *Service) releaseEndedBudget(ctx context.Context, id uint) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	m, err := s.repo.GetByIDForUpdate(ctx, tx, id)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to get mission")
+	}
+	if m == nil {
+		return nil
+	}
+
+	// Keep the budget until the creator has graded what was handed in
+	pending, err := s.repo.CountPendingSubmissions(ctx, tx, id)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to count submissions")
+	}
+	if pending > 0 {
+		return nil
+	}
+
+	if err := s.releaseBudget(ctx, tx, m); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SendDeadlineReminders notifies participants whose unfinished attempt is due
+// within the configured lead time; each attempt is reminded once
+func (s *Service) SendDeadlineReminders(ctx context.Context) (int, error) {
+	logs, err := s.repo.GetLogsDueBefore(ctx, time.Now().Add(s.config.ReminderBefore), constants.NotificationMissionDeadline, schedulerBatch)
+	if err != nil {
+		return 0, apperrors.Wrap(err, "DB_ERROR", "Failed to get attempts due soon")
+	}
+
+	sent := 0
+	for _, l := range logs {
+		due, ok := l.Mission.DueAt()
+		if !ok {
+			continue
+		}
+
+		if s.notify(ctx, &notification.Notification{
+			UserID:        l.UserID,
+			Type:          constants.NotificationMissionDeadline,
+			Title:         "Mission deadline approaching",
+			Body:          fmt.Sprintf("\"%s\" is due %s. Submit your work before then.", l.Mission.Title, due.Format(scheduleTimeFormat)),
+			ReferenceType: "MISSION_LOG",
+			ReferenceID:   fmt.Sprint(l.LogID),
+		}) {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// notify stores a notification, reporting whether a new one was created;
+// failures are logged so a notification never blocks the job that sends it
+func (s *Service) notify(ctx context.Context, n *notification.Notification) bool {
+	created, err := s.notificationRepo.Create(ctx, n)
+	if err != nil {
+		log.Printf("Failed to notify user %d: %v", n.UserID, err)
+		return false
+	}
+	return created
+}
+
+// parseMissionQuiz returns the quiz definition of a QUIZ mission, or nil when the
+// mission is not a quiz or predates the typed schema
+func parseMissionQuiz(m *Mission) *QuizContent {
+	if m.MissionType != constants.MissionTypeQuiz || !m.Content.Valid {
+		return nil
+	}
+	quiz, quizErrors := ParseQuiz([]byte(m.Content.String))
+	if len(quizErrors) > 0 {
+		return nil
+	}
+	return quiz
+}
+
+// hideAnswerKeys strips answer keys from a quiz response shown to participants
+func hideAnswerKeys(m *Mission, resp *MissionResponse) {
+	if m.MissionType != constants.MissionTypeQuiz || !m.Content.Valid {
+		return
+	}
+	quiz, quizErrors := ParseQuiz([]byte(m.Content.String))
+	if len(quizErrors) > 0 {
+		// Legacy or malformed content may still hold keys in its own layout
+		resp.Content = StripAnswerKeys([]byte(m.Content.String))
+		return
+	}
+	resp.Content, _ = json.Marshal(quiz.Public())
+}
+
+// Reward pools
+
+func (s *Service) CreateRewardPool(ctx context.Context, req CreateRewardPoolRequest, adminID uint) (*RewardPoolResponse, error) {
+	pool := &RewardPool{
+		Name:        req.Name,
+		Description: sql.NullString{String: req.Description, Valid: req.Description != ""},
+		IsActive:    true,
+		CreatedBy:   sql.NullInt64{Int64: int64(adminID), Valid: true},
+	}
+
+	if err := s.repo.CreateRewardPool(ctx, pool); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to create reward pool")
+	}
+
+	pool.CreatedAt = time.Now()
+	resp := ToRewardPoolResponse(pool)
+	return &resp, nil
+}
+
+func (s *Service) GetRewardPools(ctx context.Context, activeOnly bool) ([]*RewardPoolResponse, error) {
+	pools, err := s.repo.GetRewardPools(ctx, activeOnly)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get reward pools")
+	}
+
+	responses := []*RewardPoolResponse{}
+	for _, p := range pools {
+		resp := ToRewardPoolResponse(p)
+		responses = append(responses, &resp)
+	}
+
+	return responses, nil
+}
+
+func (s *Service) UpdateRewardPool(ctx context.Context, id uint, req UpdateRewardPoolRequest) (*RewardPoolResponse, error) {
+	pool, err := s.repo.GetRewardPoolByID(ctx, id)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get reward pool")
+	}
+	if pool == nil {
+		return nil, apperrors.New("POOL_NOT_FOUND", "Reward pool not found")
+	}
+
+	if req.Name != nil {
+		pool.Name = *req.Name
+	}
+	if req.Description != nil {
+		pool.Description = sql.NullString{String: *req.Description, Valid: *req.Description != ""}
+	}
+	if req.IsActive != nil {
+		pool.IsActive = *req.IsActive
+	}
+
+	if err := s.repo.UpdateRewardPool(ctx, pool); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to update reward pool")
+	}
+
+	resp := ToRewardPoolResponse(pool)
+	return &resp, nil
+}
+
+// FundRewardPool issues points into a pool, recorded as an ADJUSTMENT transaction
+func (s *Service) FundRewardPool(ctx context.Context, id uint, req FundRewardPoolRequest, adminID uint) (*RewardPoolResponse, error) {
+	// Check idempotency
+	existing, _ := s.walletRepo.GetTransactionByIdempotencyKey(ctx, req.IdempotencyKey)
+	if existing == nil {
+		tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+		if err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+		}
+		defer tx.Rollback()
+
+		pool, err := s.repo.GetRewardPoolByIDForUpdate(ctx, tx, id)
+		if err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to lock reward pool")
+		}
+		if pool == nil {
+			return nil, apperrors.New("POOL_NOT_FOUND", "Reward pool not found")
+		}
+
+		if err := s.repo.FundRewardPool(ctx, tx, id, req.Amount); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to fund reward pool")
+		}
+
+		metadata, _ := json.Marshal(map[string]interface{}{"reward_pool_id": id, "admin_id": adminID})
+		transaction := &wallet.Transaction{
+			TransactionCode: utils.GenerateTransactionCode("POOL"),
+			IdempotencyKey:  req.IdempotencyKey,
+			TransactionType: constants.TxTypeAdjustment,
+			Status:          constants.TxStatusCompleted,
+			Amount:          req.Amount,
+			FeeAmount:       0,
+			NetAmount:       req.Amount,
+			Description:     sql.NullString{String: "Reward pool funding: " + req.Reason, Valid: true},
+			Metadata:        sql.NullString{String: string(metadata), Valid: true},
+			ProcessedAt:     sql.NullTime{Time: time.Now(), Valid: true},
+		}
+		if err := s.walletRepo.CreateTransaction(ctx, tx, transaction); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to create transaction")
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to commit")
+		}
+	}
+
+	pool, err := s.repo.GetRewardPoolByID(ctx, id)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get reward pool")
+	}
+	if pool == nil {
+		return nil, apperrors.New("POOL_NOT_FOUND", "Reward pool not found")
+	}
+
+	resp := ToRewardPoolResponse(pool)
+	return &resp, nil
+}
+
+// Attendance
+
+func (s *Service) OpenAttendanceSession(ctx context.Context, missionID uint, req OpenAttendanceRequest, userID uint) (*AttendanceSessionResponse, error) {
+	m, err := s.getOwnedMission(ctx, missionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if m.MissionType != constants.MissionTypeAttendance {
+		return nil, apperrors.New("NOT_ATTENDANCE", "Mission is not an attendance mission")
+	}
+	if !m.IsActive {
+		return nil, apperrors.New("MISSION_INACTIVE", "Mission is not active")
+	}
+
+	now := time.Now()
+	session := &AttendanceSession{
+		MissionID:       missionID,
+		CreatedBy:       userID,
+		Secret:          utils.GenerateUUID(),
+		RotationSeconds: req.RotationSeconds,
+		StartsAt:        now,
+		EndsAt:          now.Add(time.Duration(req.DurationMinutes) * time.Minute),
+	}
+	if req.Latitude != nil {
+		session.Latitude = sql.NullFloat64{Float64: *req.Latitude, Valid: true}
+		session.Longitude = sql.NullFloat64{Float64: *req.Longitude, Valid: true}
+	}
+	if req.RadiusMeters != nil {
+		session.RadiusMeters = sql.NullInt64{Int64: int64(*req.RadiusMeters), Valid: true}
+	}
+
+	if err := s.repo.CreateAttendanceSession(ctx, session); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to create attendance session")
+	}
+
+	resp := ToAttendanceSessionResponse(session)
+	return &resp, nil
+}
+
+// GetAttendanceQR returns the code for the current rotation window; the lecturer's screen polls it
+func (s *Service) GetAttendanceQR(ctx context.Context, missionID, sessionID, userID uint) (*AttendanceQRResponse, error) {
+	session, err := s.getOwnedSession(ctx, missionID, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !session.IsOpen(now) {
+		return nil, apperrors.New("SESSION_CLOSED", "Attendance session is closed")
+	}
+
+	window := attendanceWindow(now, session.RotationSeconds)
+	token := attendanceToken(session, window)
+
+	png, err := qrcode.Encode(token, qrcode.Medium, 256)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "QR_ERROR", "Failed to generate QR image")
+	}
+
+	expiresAt := time.Unix((window+1)*int64(session.RotationSeconds), 0)
+	if expiresAt.After(session.EndsAt) {
+		expiresAt = session.EndsAt
+	}
+
+	return &AttendanceQRResponse{
+		SessionID:       session.ID,
+		Token:           token,
+		QRImage:         "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+		RotationSeconds: session.RotationSeconds,
+		ExpiresAt:       expiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+func (s *Service) CloseAttendanceSession(ctx context.Context, missionID, sessionID, userID uint) error {
+	if _, err := s.getOwnedSession(ctx, missionID, sessionID, userID); err != nil {
+		return err
+	}
+
+	if err := s.repo.CloseAttendanceSession(ctx, sessionID); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to close attendance session")
+	}
+	return nil
+}
+
+// CheckIn completes an attendance mission from a scanned QR and pays the reward
+func (s *Service) CheckIn(ctx context.Context, req CheckInRequest, userID uint) (*MissionLogResponse, error) {
+	sessionID, window, signature, ok := parseAttendanceToken(req.Token)
+	if !ok {
+		return nil, apperrors.New("INVALID_TOKEN", "Invalid attendance code")
+	}
+
+	session, err := s.repo.GetAttendanceSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get attendance session")
+	}
+	if session == nil {
+		return nil, apperrors.New("INVALID_TOKEN", "Invalid attendance code")
+	}
+
+	now := time.Now()
+	if !session.IsOpen(now) {
+		return nil, apperrors.New("SESSION_CLOSED", "Attendance session is closed")
+	}
+	if !verifyAttendanceToken(session, window, signature, now) {
+		return nil, apperrors.New("INVALID_TOKEN", "Attendance code is invalid or has expired")
+	}
+
+	m, err := s.repo.GetByID(ctx, session.MissionID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission")
+	}
+	if m == nil {
+		return nil, apperrors.ErrNotFound
+	}
+	if !m.IsActive {
+		return nil, apperrors.New("MISSION_INACTIVE", "Mission is not active")
+	}
+	if m.CreatorID == userID {
+		return nil, apperrors.New("CANNOT_START_OWN", "Cannot check in to your own mission")
+	}
+	if err := checkSubmitWindow(m, now); err != nil {
+		return nil, err
+	}
+	if err := s.checkTargeted(ctx, m, userID); err != nil {
+		return nil, err
+	}
+
+	details := CheckInDetails{SessionID: session.ID, Latitude: req.Latitude, Longitude: req.Longitude}
+	if session.RadiusMeters.Valid {
+		if req.Latitude == nil {
+			return nil, apperrors.New("LOCATION_REQUIRED", "Location is required to check in to this session")
+		}
+		distance := distanceMeters(session.Latitude.Float64, session.Longitude.Float64, *req.Latitude, *req.Longitude)
+		if distance > float64(session.RadiusMeters.Int64) {
+			return nil, apperrors.New("OUTSIDE_GEOFENCE", fmt.Sprintf("You are %.0f m away from the class location", distance))
+		}
+		rounded := math.Round(distance*10) / 10
+		details.DistanceMeters = &rounded
+	}
+	detailsJSON, _ := json.Marshal(details)
+
+	existing, err := s.repo.GetLogByMissionAndUser(ctx, m.ID, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission log")
+	}
+	if existing == nil && m.MaxParticipants.Valid && m.CurrentParticipants >= int(m.MaxParticipants.Int64) {
+		return nil, apperrors.New("MAX_PARTICIPANTS", "Maximum participants reached")
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	log := existing
+	if log == nil {
+		log = &MissionLog{MissionID: m.ID, UserID: userID, Status: constants.MissionStatusStarted}
+		if err := s.repo.CreateLog(ctx, tx, log); err != nil {
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+				return nil, apperrors.New("ALREADY_CHECKED_IN", "You have already checked in")
+			}
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to create mission log")
+		}
+		if err := s.repo.IncrementParticipants(ctx, tx, m.ID); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to increment participants")
+		}
+	} else {
+		log, err = s.repo.GetLogByIDForUpdate(ctx, tx, existing.ID)
+		if err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission log")
+		}
+		if log.Status == constants.MissionStatusCompleted {
+			return nil, apperrors.New("ALREADY_CHECKED_IN", "You have already checked in")
+		}
+	}
+
+	notes := fmt.Sprintf("Checked in via attendance session #%d", session.ID)
+	if err := s.repo.AutoGradeLog(ctx, tx, log.ID, string(detailsJSON), 100, true, m.RewardPoints, notes); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to record check-in")
+	}
+	if err := s.creditReward(ctx, tx, m, log.ID, userID, m.RewardPoints, ""); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to commit")
+	}
+
+	log.Status = constants.MissionStatusCompleted
+	log.Answers = sql.NullString{String: string(detailsJSON), Valid: true}
+	log.Score = sql.NullFloat64{Float64: 100, Valid: true}
+	log.RewardClaimed = true
+	log.RewardPoints = sql.NullInt64{Int64: m.RewardPoints, Valid: true}
+	log.SubmittedAt = sql.NullTime{Time: now, Valid: true}
+	log.CompletedAt = sql.NullTime{Time: now, Valid: true}
+	log.Notes = sql.NullString{String: notes, Valid: true}
+
+	resp := ToMissionLogResponse(log, m.Title)
+	return &resp, nil
+}
+
+// GetAttendance returns the live attendance list of a mission
+func (s *Service) GetAttendance(ctx context.Context, missionID, userID uint) ([]*AttendanceRecordResponse, error) {
+	if _, err := s.getOwnedMission(ctx, missionID, userID); err != nil {
+		return nil, err
+	}
+
+	records, err := s.repo.GetAttendanceRecords(ctx, missionID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get attendance")
+	}
+
+	responses := []*AttendanceRecordResponse{}
+	for _, r := range records {
+		resp := ToAttendanceRecordResponse(r)
+		responses = append(responses, &resp)
 	}
 
 	return responses, nil
 }
+
+// ExportAttendanceCSV renders the attendance list as CSV
+func (s *Service) ExportAttendanceCSV(ctx context.Context, missionID, userID uint) ([]byte, error) {
+	records, err := s.GetAttendance(ctx, missionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"No", "NIM", "Name", "Status", "Checked In At", "Session", "Distance (m)"})
+	for i, r := range records {
+		checkedIn, session, distance := "", "", ""
+		if r.CheckedInAt != nil {
+			checkedIn = *r.CheckedInAt
+		}
+		if r.SessionID != 0 {
+			session = fmt.Sprint(r.SessionID)
+		}
+		if r.DistanceMeters != nil {
+			distance = fmt.Sprintf("%.1f", *r.DistanceMeters)
+		}
+		w.Write([]string{fmt.Sprint(i + 1), r.NIM, r.UserName, r.Status, checkedIn, session, distance})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, apperrors.Wrap(err, "EXPORT_ERROR", "Failed to export attendance")
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *Service) getOwnedMission(ctx context.Context, missionID, userID uint) (*Mission, error) {
+	m, err := s.repo.GetByID(ctx, missionID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get mission")
+	}
+	if m == nil {
+		return nil, apperrors.ErrNotFound
+	}
+	if m.CreatorID != userID {
+		return nil, apperrors.ErrForbidden
+	}
+	return m, nil
+}
+
+func (s *Service) getOwnedSession(ctx context.Context, missionID, sessionID, userID uint) (*AttendanceSession, error) {
+	if _, err := s.getOwnedMission(ctx, missionID, userID); err != nil {
+		return nil, err
+	}
+
+	session, err := s.repo.GetAttendanceSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get attendance session")
+	}
+	if session == nil || session.MissionID != missionID {
+		return nil, apperrors.New("NOT_FOUND", "Attendance session not found")
+	}
+	return session, nil
+}
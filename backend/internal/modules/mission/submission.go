@@ -0,0 +1,112 @@
+package mission
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"walletpoint/internal/shared/constants"
+	apperrors "walletpoint/internal/shared/errors"
+)
+
+// Attachment kinds
+const (
+	AttachmentFile = "FILE"
+	AttachmentLink = "LINK"
+)
+
+// maxSubmissionLinks caps the links attached to one submission
+const maxSubmissionLinks = 10
+
+// allowedAttachmentTypes maps accepted extensions to the content types their
+// bytes must sniff as, so a renamed executable is not accepted as a PDF
+var allowedAttachmentTypes = map[string][]string{
+	".pdf":  {"application/pdf"},
+	".png":  {"image/png"},
+	".jpg":  {"image/jpeg"},
+	".jpeg": {"image/jpeg"},
+	".webp": {"image/webp"},
+	".zip":  {"application/zip", "application/x-zip-compressed"},
+}
+
+// acceptsAttachments reports whether a mission type takes files and links
+func acceptsAttachments(m *Mission) bool {
+	return m.MissionType == constants.MissionTypeAssignment || m.MissionType == constants.MissionTypeProject
+}
+
+// inspectUpload checks an uploaded file against the size and type limits and
+// returns its sniffed content type
+func inspectUpload(fh *multipart.FileHeader, maxSize int64) (string, error) {
+	name := filepath.Base(fh.Filename)
+	if fh.Size == 0 {
+		return "", apperrors.New("INVALID_ATTACHMENT", fmt.Sprintf("%s is empty", name))
+	}
+	if fh.Size > maxSize {
+		return "", apperrors.New("ATTACHMENT_TOO_LARGE", fmt.Sprintf("%s exceeds the %d MB limit", name, maxSize>>20))
+	}
+
+	accepted, ok := allowedAttachmentTypes[strings.ToLower(filepath.Ext(name))]
+	if !ok {
+		return "", apperrors.New("INVALID_ATTACHMENT", fmt.Sprintf("%s: only PDF, PNG, JPEG, WebP and ZIP files are accepted", name))
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return "", apperrors.Wrap(err, "UPLOAD_ERROR", "Failed to read upload")
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := f.Read(head)
+	sniffed := http.DetectContentType(head[:n])
+	for _, t := range accepted {
+		if sniffed == t {
+			return t, nil
+		}
+	}
+
+	return "", apperrors.New("INVALID_ATTACHMENT", fmt.Sprintf("%s does not look like a %s file", name, strings.TrimPrefix(filepath.Ext(name), ".")))
+}
+
+// validateLinks accepts absolute http(s) URLs only
+func validateLinks(links []string) ([]string, error) {
+	if len(links) > maxSubmissionLinks {
+		return nil, apperrors.New("INVALID_ATTACHMENT", fmt.Sprintf("At most %d links can be attached", maxSubmissionLinks))
+	}
+
+	var cleaned []string
+	for _, link := range links {
+		link = strings.TrimSpace(link)
+		if link == "" {
+			continue
+		}
+		u, err := url.Parse(link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(link) > 2048 {
+			return nil, apperrors.New("INVALID_ATTACHMENT", fmt.Sprintf("%q is not a valid http(s) link", link))
+		}
+		cleaned = append(cleaned, link)
+	}
+	return cleaned, nil
+}
+
+// sanitizeFileName keeps a display name safe for Content-Disposition headers
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == '"' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if len(name) > 255 {
+		ext := filepath.Ext(name)
+		name = name[:255-len(ext)] + ext
+	}
+	if name == "" || name == "." || name == "/" {
+		name = "attachment"
+	}
+	return name
+}
@@ -0,0 +1,40 @@
+package notification
+
+import "time"
+
+// NotificationResponse for notification details
+type NotificationResponse struct {
+	ID            uint    `json:"id"`
+	Type          string  `json:"type"`
+	Title         string  `json:"title"`
+	Body          string  `json:"body"`
+	ReferenceType string  `json:"reference_type"`
+	ReferenceID   string  `json:"reference_id"`
+	IsRead        bool    `json:"is_read"`
+	ReadAt        *string `json:"read_at,omitempty"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+// UnreadCountResponse for the notification badge
+type UnreadCountResponse struct {
+	Unread int `json:"unread"`
+}
+
+// ToNotificationResponse converts entity to response
+func ToNotificationResponse(n *Notification) NotificationResponse {
+	resp := NotificationResponse{
+		ID:            n.ID,
+		Type:          n.Type,
+		Title:         n.Title,
+		Body:          n.Body,
+		ReferenceType: n.ReferenceType,
+		ReferenceID:   n.ReferenceID,
+		IsRead:        n.ReadAt.Valid,
+		CreatedAt:     n.CreatedAt.Format(time.RFC3339),
+	}
+	if n.ReadAt.Valid {
+		t := n.ReadAt.Time.Format(time.RFC3339)
+		resp.ReadAt = &t
+	}
+	return resp
+}
@@ -0,0 +1,19 @@
+package notification
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Notification entity
+type Notification struct {
+	ID            uint
+	UserID        uint
+	Type          string
+	Title         string
+	Body          string
+	ReferenceType string
+	ReferenceID   string
+	ReadAt        sql.NullTime
+	CreatedAt     time.Time
+}
@@ -0,0 +1,98 @@
+package notification
+
+import (
+	"strconv"
+
+	apperrors "walletpoint/internal/shared/errors"
+	"walletpoint/internal/shared/response"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetMyNotifications lists the user's notifications, newest first
+func (h *Handler) GetMyNotifications(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	perPage, _ := strconv.Atoi(c.Query("per_page", "20"))
+	unreadOnly := c.QueryBool("unread", false)
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	notifications, total, err := h.service.GetMyNotifications(c.Context(), userID, unreadOnly, page, perPage)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	totalPages := (total + perPage - 1) / perPage
+
+	return response.SuccessWithMeta(c, "Notifications retrieved", notifications, &response.Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// GetUnreadCount returns the number of unread notifications
+func (h *Handler) GetUnreadCount(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	result, err := h.service.GetUnreadCount(c.Context(), userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Unread count retrieved", result)
+}
+
+// MarkRead marks a notification as read
+func (h *Handler) MarkRead(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid notification ID")
+	}
+
+	if err := h.service.MarkRead(c.Context(), uint(id), userID); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Notification marked as read", nil)
+}
+
+// MarkAllRead marks every notification of the user as read
+func (h *Handler) MarkAllRead(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	if err := h.service.MarkAllRead(c.Context(), userID); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Notifications marked as read", nil)
+}
+
+func handleError(c *fiber.Ctx, err error) error {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		switch appErr.Code {
+		case "NOT_FOUND":
+			return response.NotFound(c, appErr.Message)
+		default:
+			return response.InternalError(c, appErr.Message)
+		}
+	}
+	return response.InternalError(c, "Internal server error")
+}
@@ -0,0 +1,125 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Create stores a notification; it reports false when the user already has one
+// of the same type for the same reference, so jobs can safely retry
+func (r *Repository) Create(ctx context.Context, n *Notification) (bool, error) {
+	query := `
+		INSERT IGNORE INTO notifications (user_id, type, title, body, reference_type, reference_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW())
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		n.UserID, n.Type, n.Title, n.Body, n.ReferenceType, n.ReferenceID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+
+	n.ID = uint(id)
+	return true, nil
+}
+
+func (r *Repository) GetByUserID(ctx context.Context, userID uint, unreadOnly bool, limit, offset int) ([]*Notification, int, error) {
+	where := `WHERE user_id = ?`
+	if unreadOnly {
+		where += ` AND read_at IS NULL`
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM notifications ` + where
+	if err := r.db.QueryRowContext(ctx, countQuery, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, user_id, type, title, body, reference_type, reference_id, read_at, created_at
+		FROM notifications ` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var notifications []*Notification
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(
+			&n.ID, &n.UserID, &n.Type, &n.Title, &n.Body, &n.ReferenceType, &n.ReferenceID, &n.ReadAt, &n.CreatedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		notifications = append(notifications, &n)
+	}
+
+	return notifications, total, nil
+}
+
+func (r *Repository) CountUnread(ctx context.Context, userID uint) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL`
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&count)
+	return count, err
+}
+
+func (r *Repository) GetByID(ctx context.Context, id uint) (*Notification, error) {
+	query := `
+		SELECT id, user_id, type, title, body, reference_type, reference_id, read_at, created_at
+		FROM notifications WHERE id = ?
+	`
+
+	var n Notification
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&n.ID, &n.UserID, &n.Type, &n.Title, &n.Body, &n.ReferenceType, &n.ReferenceID, &n.ReadAt, &n.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &n, nil
+}
+
+func (r *Repository) MarkRead(ctx context.Context, id uint) error {
+	query := `UPDATE notifications SET read_at = NOW() WHERE id = ? AND read_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *Repository) MarkAllRead(ctx context.Context, userID uint) (int64, error) {
+	query := `UPDATE notifications SET read_at = NOW() WHERE user_id = ? AND read_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
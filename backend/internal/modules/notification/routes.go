@@ -0,0 +1,16 @@
+package notification
+
+import (
+	"walletpoint/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterRoutes(app fiber.Router, handler *Handler, jwtManager *middleware.JWTManager) {
+	notifications := app.Group("/notifications", middleware.JWTMiddleware(jwtManager))
+
+	notifications.Get("", handler.GetMyNotifications)
+	notifications.Get("/unread-count", handler.GetUnreadCount)
+	notifications.Put("/read-all", handler.MarkAllRead)
+	notifications.Put("/:id/read", handler.MarkRead)
+}
@@ -0,0 +1,61 @@
+package notification
+
+import (
+	"context"
+
+	apperrors "walletpoint/internal/shared/errors"
+)
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) GetMyNotifications(ctx context.Context, userID uint, unreadOnly bool, page, perPage int) ([]*NotificationResponse, int, error) {
+	offset := (page - 1) * perPage
+	notifications, total, err := s.repo.GetByUserID(ctx, userID, unreadOnly, perPage, offset)
+	if err != nil {
+		return nil, 0, apperrors.Wrap(err, "DB_ERROR", "Failed to get notifications")
+	}
+
+	responses := []*NotificationResponse{}
+	for _, n := range notifications {
+		resp := ToNotificationResponse(n)
+		responses = append(responses, &resp)
+	}
+
+	return responses, total, nil
+}
+
+func (s *Service) GetUnreadCount(ctx context.Context, userID uint) (*UnreadCountResponse, error) {
+	count, err := s.repo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to count notifications")
+	}
+	return &UnreadCountResponse{Unread: count}, nil
+}
+
+func (s *Service) MarkRead(ctx context.Context, id, userID uint) error {
+	n, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to get notification")
+	}
+	if n == nil || n.UserID != userID {
+		return apperrors.New("NOT_FOUND", "Notification not found")
+	}
+
+	if err := s.repo.MarkRead(ctx, id); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to update notification")
+	}
+	return nil
+}
+
+func (s *Service) MarkAllRead(ctx context.Context, userID uint) error {
+	if _, err := s.repo.MarkAllRead(ctx, userID); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to update notifications")
+	}
+	return nil
+}
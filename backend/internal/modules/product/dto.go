@@ -1,12 +1,16 @@
 package product
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // CreateProductRequest for creating product
 type CreateProductRequest struct {
 	Name         string `json:"name"`
 	Description  string `json:"description"`
 	ProductType  string `json:"product_type"` // EBOOK, ECOURSE, MATERIAL, OTHER
+	CategoryID   *uint  `json:"category_id"`
 	Price        int64  `json:"price"`
 	Stock        *int   `json:"stock"`
 	IsUnlimited  bool   `json:"is_unlimited"`
@@ -36,6 +40,7 @@ func (r *CreateProductRequest) Validate() []ValidationError {
 type UpdateProductRequest struct {
 	Name         *string `json:"name"`
 	Description  *string `json:"description"`
+	CategoryID   *uint   `json:"category_id"`
 	Price        *int64  `json:"price"`
 	Stock        *int    `json:"stock"`
 	IsUnlimited  *bool   `json:"is_unlimited"`
@@ -44,12 +49,149 @@ type UpdateProductRequest struct {
 	IsActive     *bool   `json:"is_active"`
 }
 
+// Product sort options
+const (
+	SortNewest     = "newest"
+	SortPriceAsc   = "price_asc"
+	SortPriceDesc  = "price_desc"
+	SortBestSeller = "best_selling"
+	SortRating     = "rating"
+)
+
+// ProductFilter for catalog search and listing
+type ProductFilter struct {
+	Search      string
+	ProductType string
+	SellerID    uint
+	CategoryID  uint
+	MinPrice    *int64
+	MaxPrice    *int64
+	InStock     bool
+	Featured    bool
+	Sort        string
+}
+
+func (f *ProductFilter) Validate() []ValidationError {
+	var errors []ValidationError
+	validTypes := map[string]bool{"EBOOK": true, "ECOURSE": true, "MATERIAL": true, "OTHER": true}
+	if f.ProductType != "" && !validTypes[f.ProductType] {
+		errors = append(errors, ValidationError{Field: "type", Message: "Invalid product type"})
+	}
+	if f.MinPrice != nil && *f.MinPrice < 0 {
+		errors = append(errors, ValidationError{Field: "min_price", Message: "Minimum price cannot be negative"})
+	}
+	if f.MinPrice != nil && f.MaxPrice != nil && *f.MinPrice > *f.MaxPrice {
+		errors = append(errors, ValidationError{Field: "max_price", Message: "Maximum price must be greater than minimum price"})
+	}
+	validSorts := map[string]bool{"": true, SortNewest: true, SortPriceAsc: true, SortPriceDesc: true, SortBestSeller: true, SortRating: true}
+	if !validSorts[f.Sort] {
+		errors = append(errors, ValidationError{Field: "sort", Message: "Sort must be newest, price_asc, price_desc, best_selling or rating"})
+	}
+	return errors
+}
+
+// CreateCategoryRequest for creating category (admin)
+type CreateCategoryRequest struct {
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	ParentID    *uint  `json:"parent_id"`
+	SortOrder   int    `json:"sort_order"`
+}
+
+func (r *CreateCategoryRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Name == "" {
+		errors = append(errors, ValidationError{Field: "name", Message: "Name is required"})
+	}
+	if r.Slug == "" {
+		r.Slug = slugify(r.Name)
+	}
+	if r.Slug == "" {
+		errors = append(errors, ValidationError{Field: "slug", Message: "Slug is required"})
+	}
+	return errors
+}
+
+// UpdateCategoryRequest for updating category (admin)
+type UpdateCategoryRequest struct {
+	Name        *string `json:"name"`
+	Slug        *string `json:"slug"`
+	Description *string `json:"description"`
+	ParentID    *uint   `json:"parent_id"`
+	SortOrder   *int    `json:"sort_order"`
+	IsActive    *bool   `json:"is_active"`
+}
+
+// SetFeaturedRequest for toggling featured flag (admin)
+type SetFeaturedRequest struct {
+	IsFeatured bool `json:"is_featured"`
+}
+
+// CreateReviewRequest for posting a product review
+type CreateReviewRequest struct {
+	Rating  int    `json:"rating"`
+	Comment string `json:"comment"`
+}
+
+func (r *CreateReviewRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Rating < 1 || r.Rating > 5 {
+		errors = append(errors, ValidationError{Field: "rating", Message: "Rating must be between 1 and 5"})
+	}
+	if len(r.Comment) > 2000 {
+		errors = append(errors, ValidationError{Field: "comment", Message: "Comment must be at most 2000 characters"})
+	}
+	return errors
+}
+
+// ReplyReviewRequest for seller reply
+type ReplyReviewRequest struct {
+	Reply string `json:"reply"`
+}
+
+func (r *ReplyReviewRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Reply == "" {
+		errors = append(errors, ValidationError{Field: "reply", Message: "Reply is required"})
+	}
+	return errors
+}
+
+// ModerateReviewRequest for hiding or restoring a review (admin)
+type ModerateReviewRequest struct {
+	IsHidden bool   `json:"is_hidden"`
+	Reason   string `json:"reason"`
+}
+
 // CreateOrderRequest for creating order
 type CreateOrderRequest struct {
+	ProductID     uint   `json:"product_id"`
+	Quantity      int    `json:"quantity"`
+	VoucherCode   string `json:"voucher_code"`
+	ReservationID uint   `json:"reservation_id"`
+}
+
+// ReserveStockRequest for holding stock during checkout
+type ReserveStockRequest struct {
 	ProductID uint `json:"product_id"`
 	Quantity  int  `json:"quantity"`
 }
 
+func (r *ReserveStockRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.ProductID == 0 {
+		errors = append(errors, ValidationError{Field: "product_id", Message: "Product ID is required"})
+	}
+	if r.Quantity <= 0 {
+		r.Quantity = 1
+	}
+	if r.Quantity > 100 {
+		errors = append(errors, ValidationError{Field: "quantity", Message: "Quantity must be at most 100"})
+	}
+	return errors
+}
+
 func (r *CreateOrderRequest) Validate() []ValidationError {
 	var errors []ValidationError
 	if r.ProductID == 0 {
@@ -58,6 +200,7 @@ func (r *CreateOrderRequest) Validate() []ValidationError {
 	if r.Quantity <= 0 {
 		r.Quantity = 1
 	}
+	r.VoucherCode = strings.ToUpper(strings.TrimSpace(r.VoucherCode))
 	return errors
 }
 
@@ -69,51 +212,108 @@ type ValidationError struct {
 
 // ProductResponse for product details
 type ProductResponse struct {
+	ID            uint    `json:"id"`
+	SellerID      uint    `json:"seller_id"`
+	SellerName    string  `json:"seller_name,omitempty"`
+	Name          string  `json:"name"`
+	Description   string  `json:"description,omitempty"`
+	ProductType   string  `json:"product_type"`
+	CategoryID    *uint   `json:"category_id,omitempty"`
+	CategoryName  string  `json:"category_name,omitempty"`
+	Price         int64   `json:"price"`
+	Stock         *int    `json:"stock,omitempty"`
+	IsUnlimited   bool    `json:"is_unlimited"`
+	ThumbnailURL  string  `json:"thumbnail_url,omitempty"`
+	SoldCount     int     `json:"sold_count"`
+	RatingAverage float64 `json:"rating_average"`
+	RatingCount   int     `json:"rating_count"`
+	IsActive      bool    `json:"is_active"`
+	IsFeatured    bool    `json:"is_featured"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+// ReviewResponse for review details
+type ReviewResponse struct {
 	ID           uint   `json:"id"`
-	SellerID     uint   `json:"seller_id"`
-	SellerName   string `json:"seller_name,omitempty"`
-	Name         string `json:"name"`
-	Description  string `json:"description,omitempty"`
-	ProductType  string `json:"product_type"`
-	Price        int64  `json:"price"`
-	Stock        *int   `json:"stock,omitempty"`
-	IsUnlimited  bool   `json:"is_unlimited"`
-	ThumbnailURL string `json:"thumbnail_url,omitempty"`
-	SoldCount    int    `json:"sold_count"`
-	IsActive     bool   `json:"is_active"`
-	IsFeatured   bool   `json:"is_featured"`
+	ProductID    uint   `json:"product_id"`
+	ProductName  string `json:"product_name,omitempty"`
+	UserID       uint   `json:"user_id"`
+	UserName     string `json:"user_name,omitempty"`
+	Rating       int    `json:"rating"`
+	Comment      string `json:"comment,omitempty"`
+	SellerReply  string `json:"seller_reply,omitempty"`
+	RepliedAt    string `json:"replied_at,omitempty"`
+	IsHidden     bool   `json:"is_hidden"`
+	HiddenReason string `json:"hidden_reason,omitempty"`
 	CreatedAt    string `json:"created_at"`
 }
 
+// CategoryResponse for category details
+type CategoryResponse struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description,omitempty"`
+	ParentID    *uint  `json:"parent_id,omitempty"`
+	SortOrder   int    `json:"sort_order"`
+	IsActive    bool   `json:"is_active"`
+}
+
 // OrderResponse for order details
 type OrderResponse struct {
+	ID             uint   `json:"id"`
+	OrderCode      string `json:"order_code"`
+	ProductID      uint   `json:"product_id"`
+	ProductName    string `json:"product_name"`
+	SellerName     string `json:"seller_name,omitempty"`
+	BuyerName      string `json:"buyer_name,omitempty"`
+	Quantity       int    `json:"quantity"`
+	TotalPrice     int64  `json:"total_price"`
+	DiscountAmount int64  `json:"discount_amount"`
+	FinalPrice     int64  `json:"final_price"`
+	Status         string `json:"status"`
+	CreatedAt      string `json:"created_at"`
+	CompletedAt    string `json:"completed_at,omitempty"`
+}
+
+// ReservationResponse for a stock reservation
+type ReservationResponse struct {
 	ID          uint   `json:"id"`
-	OrderCode   string `json:"order_code"`
 	ProductID   uint   `json:"product_id"`
-	ProductName string `json:"product_name"`
-	SellerName  string `json:"seller_name,omitempty"`
+	ProductName string `json:"product_name,omitempty"`
 	Quantity    int    `json:"quantity"`
-	TotalPrice  int64  `json:"total_price"`
-	FinalPrice  int64  `json:"final_price"`
 	Status      string `json:"status"`
+	ExpiresAt   string `json:"expires_at"`
 	CreatedAt   string `json:"created_at"`
-	CompletedAt string `json:"completed_at,omitempty"`
+}
+
+// CheckoutPreviewResponse for checkout price preview
+type CheckoutPreviewResponse struct {
+	ProductID      uint   `json:"product_id"`
+	Quantity       int    `json:"quantity"`
+	UnitPrice      int64  `json:"unit_price"`
+	TotalPrice     int64  `json:"total_price"`
+	DiscountAmount int64  `json:"discount_amount"`
+	FinalPrice     int64  `json:"final_price"`
+	VoucherCode    string `json:"voucher_code,omitempty"`
 }
 
 // ToProductResponse converts entity to response
 func ToProductResponse(p *Product, sellerName string) ProductResponse {
 	resp := ProductResponse{
-		ID:          p.ID,
-		SellerID:    p.SellerID,
-		SellerName:  sellerName,
-		Name:        p.Name,
-		ProductType: p.ProductType,
-		Price:       p.Price,
-		IsUnlimited: p.IsUnlimited,
-		SoldCount:   p.SoldCount,
-		IsActive:    p.IsActive,
-		IsFeatured:  p.IsFeatured,
-		CreatedAt:   p.CreatedAt.Format(time.RFC3339),
+		ID:            p.ID,
+		SellerID:      p.SellerID,
+		SellerName:    sellerName,
+		Name:          p.Name,
+		ProductType:   p.ProductType,
+		Price:         p.Price,
+		IsUnlimited:   p.IsUnlimited,
+		SoldCount:     p.SoldCount,
+		RatingAverage: p.RatingAverage,
+		RatingCount:   p.RatingCount,
+		IsActive:      p.IsActive,
+		IsFeatured:    p.IsFeatured,
+		CreatedAt:     p.CreatedAt.Format(time.RFC3339),
 	}
 	if p.Description.Valid {
 		resp.Description = p.Description.String
@@ -125,25 +325,249 @@ func ToProductResponse(p *Product, sellerName string) ProductResponse {
 	if p.ThumbnailURL.Valid {
 		resp.ThumbnailURL = p.ThumbnailURL.String
 	}
+	if p.CategoryID.Valid {
+		categoryID := uint(p.CategoryID.Int64)
+		resp.CategoryID = &categoryID
+	}
 	return resp
 }
 
+// ToReservationResponse converts entity to response
+func ToReservationResponse(rs *StockReservation, productName string) ReservationResponse {
+	createdAt := rs.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	return ReservationResponse{
+		ID:          rs.ID,
+		ProductID:   rs.ProductID,
+		ProductName: productName,
+		Quantity:    rs.Quantity,
+		Status:      rs.Status,
+		ExpiresAt:   rs.ExpiresAt.Format(time.RFC3339),
+		CreatedAt:   createdAt.Format(time.RFC3339),
+	}
+}
+
+// ToReviewResponse converts entity to response
+func ToReviewResponse(r *Review, userName, productName string) ReviewResponse {
+	resp := ReviewResponse{
+		ID:          r.ID,
+		ProductID:   r.ProductID,
+		ProductName: productName,
+		UserID:      r.UserID,
+		UserName:    userName,
+		Rating:      r.Rating,
+		IsHidden:    r.IsHidden,
+		CreatedAt:   r.CreatedAt.Format(time.RFC3339),
+	}
+	if r.Comment.Valid {
+		resp.Comment = r.Comment.String
+	}
+	if r.SellerReply.Valid {
+		resp.SellerReply = r.SellerReply.String
+	}
+	if r.RepliedAt.Valid {
+		resp.RepliedAt = r.RepliedAt.Time.Format(time.RFC3339)
+	}
+	if r.HiddenReason.Valid {
+		resp.HiddenReason = r.HiddenReason.String
+	}
+	return resp
+}
+
+// ToCategoryResponse converts entity to response
+func ToCategoryResponse(c *Category) CategoryResponse {
+	resp := CategoryResponse{
+		ID:        c.ID,
+		Name:      c.Name,
+		Slug:      c.Slug,
+		SortOrder: c.SortOrder,
+		IsActive:  c.IsActive,
+	}
+	if c.Description.Valid {
+		resp.Description = c.Description.String
+	}
+	if c.ParentID.Valid {
+		parentID := uint(c.ParentID.Int64)
+		resp.ParentID = &parentID
+	}
+	return resp
+}
+
+// slugify builds a URL-friendly slug from a category name
+func slugify(name string) string {
+	var b strings.Builder
+	lastDash := true
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+			b.WriteRune(r)
+			lastDash = false
+		case !lastDash:
+			b.WriteRune('-')
+			lastDash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
 // ToOrderResponse converts entity to response
 func ToOrderResponse(o *Order, productName, sellerName string) OrderResponse {
 	resp := OrderResponse{
-		ID:          o.ID,
-		OrderCode:   o.OrderCode,
-		ProductID:   o.ProductID,
-		ProductName: productName,
-		SellerName:  sellerName,
-		Quantity:    o.Quantity,
-		TotalPrice:  o.TotalPrice,
-		FinalPrice:  o.FinalPrice,
-		Status:      o.Status,
-		CreatedAt:   o.CreatedAt.Format(time.RFC3339),
+		ID:             o.ID,
+		OrderCode:      o.OrderCode,
+		ProductID:      o.ProductID,
+		ProductName:    productName,
+		SellerName:     sellerName,
+		Quantity:       o.Quantity,
+		TotalPrice:     o.TotalPrice,
+		DiscountAmount: o.DiscountAmount,
+		FinalPrice:     o.FinalPrice,
+		Status:         o.Status,
+		CreatedAt:      o.CreatedAt.Format(time.RFC3339),
 	}
 	if o.CompletedAt.Valid {
 		resp.CompletedAt = o.CompletedAt.Time.Format(time.RFC3339)
 	}
 	return resp
 }
+
+// Analytics periods
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// periodFormats maps a period to its MySQL DATE_FORMAT layout
+var periodFormats = map[string]string{
+	PeriodDay:   "%Y-%m-%d",
+	PeriodWeek:  "%x-W%v",
+	PeriodMonth: "%Y-%m",
+}
+
+// SalesAnalyticsQuery holds dashboard query params; From/To are YYYY-MM-DD, To inclusive
+type SalesAnalyticsQuery struct {
+	From          string
+	To            string
+	Period        string
+	LowStockLimit int
+	TopBuyers     int
+
+	fromTime time.Time
+	toTime   time.Time
+}
+
+func (q *SalesAnalyticsQuery) Validate() []ValidationError {
+	var errors []ValidationError
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	q.toTime = today
+	q.fromTime = today.AddDate(0, 0, -29)
+
+	if q.To != "" {
+		t, err := time.ParseInLocation("2006-01-02", q.To, now.Location())
+		if err != nil {
+			errors = append(errors, ValidationError{Field: "to", Message: "Date must use YYYY-MM-DD format"})
+		} else {
+			q.toTime = t
+			if q.From == "" {
+				q.fromTime = t.AddDate(0, 0, -29)
+			}
+		}
+	}
+	if q.From != "" {
+		t, err := time.ParseInLocation("2006-01-02", q.From, now.Location())
+		if err != nil {
+			errors = append(errors, ValidationError{Field: "from", Message: "Date must use YYYY-MM-DD format"})
+		} else {
+			q.fromTime = t
+		}
+	}
+	if len(errors) == 0 {
+		if q.fromTime.After(q.toTime) {
+			errors = append(errors, ValidationError{Field: "from", Message: "Start date must not be after end date"})
+		} else if q.toTime.Sub(q.fromTime) > 366*24*time.Hour {
+			errors = append(errors, ValidationError{Field: "from", Message: "Date range must not exceed one year"})
+		}
+	}
+
+	if q.Period == "" {
+		q.Period = PeriodDay
+	}
+	if _, ok := periodFormats[q.Period]; !ok {
+		errors = append(errors, ValidationError{Field: "period", Message: "Period must be day, week or month"})
+	}
+	if q.LowStockLimit < 0 {
+		q.LowStockLimit = 5
+	}
+	if q.TopBuyers < 1 || q.TopBuyers > 50 {
+		q.TopBuyers = 10
+	}
+	return errors
+}
+
+// Range returns the half-open [from, to) interval covered by the query
+func (q *SalesAnalyticsQuery) Range() (time.Time, time.Time) {
+	return q.fromTime, q.toTime.AddDate(0, 0, 1)
+}
+
+// SellerAnalyticsResponse for the seller sales dashboard
+type SellerAnalyticsResponse struct {
+	From             string                 `json:"from"`
+	To               string                 `json:"to"`
+	Period           string                 `json:"period"`
+	Summary          SalesSummaryResponse   `json:"summary"`
+	RevenueByPeriod  []RevenuePointResponse `json:"revenue_by_period"`
+	ProductSales     []ProductSalesResponse `json:"product_sales"`
+	TopBuyers        []BuyerSalesResponse   `json:"top_buyers"`
+	StockOutWarnings []StockWarningResponse `json:"stock_out_warnings"`
+}
+
+// SalesSummaryResponse for aggregate sales figures
+type SalesSummaryResponse struct {
+	TotalOrders      int     `json:"total_orders"`
+	CompletedOrders  int     `json:"completed_orders"`
+	CancelledOrders  int     `json:"cancelled_orders"`
+	RefundedOrders   int     `json:"refunded_orders"`
+	UnitsSold        int     `json:"units_sold"`
+	GrossRevenue     int64   `json:"gross_revenue"`
+	DiscountTotal    int64   `json:"discount_total"`
+	NetRevenue       int64   `json:"net_revenue"`
+	RefundRate       float64 `json:"refund_rate"`
+	CancellationRate float64 `json:"cancellation_rate"`
+}
+
+// RevenuePointResponse for one revenue period bucket
+type RevenuePointResponse struct {
+	Period  string `json:"period"`
+	Orders  int    `json:"orders"`
+	Revenue int64  `json:"revenue"`
+}
+
+// ProductSalesResponse for per-product sales
+type ProductSalesResponse struct {
+	ProductID   uint   `json:"product_id"`
+	ProductName string `json:"product_name"`
+	UnitsSold   int    `json:"units_sold"`
+	Revenue     int64  `json:"revenue"`
+}
+
+// BuyerSalesResponse for a top buyer
+type BuyerSalesResponse struct {
+	BuyerID    uint   `json:"buyer_id"`
+	BuyerName  string `json:"buyer_name"`
+	Orders     int    `json:"orders"`
+	TotalSpent int64  `json:"total_spent"`
+}
+
+// StockWarningResponse for a product running out of stock
+type StockWarningResponse struct {
+	ProductID  uint   `json:"product_id"`
+	Name       string `json:"name"`
+	Stock      int64  `json:"stock"`
+	SoldCount  int    `json:"sold_count"`
+	OutOfStock bool   `json:"out_of_stock"`
+}
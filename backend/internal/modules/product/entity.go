@@ -7,24 +7,27 @@ import (
 
 // Product entity
 type Product struct {
-	ID           uint
-	SellerID     uint
-	Name         string
-	Description  sql.NullString
-	ProductType  string
-	Price        int64
-	Stock        sql.NullInt64
-	IsUnlimited  bool
-	ThumbnailURL sql.NullString
-	FileURL      sql.NullString
-	PreviewURL   sql.NullString
-	SoldCount    int
-	IsActive     bool
-	IsFeatured   bool
-	Metadata     sql.NullString
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	DeletedAt    sql.NullTime
+	ID            uint
+	SellerID      uint
+	Name          string
+	Description   sql.NullString
+	ProductType   string
+	CategoryID    sql.NullInt64
+	Price         int64
+	Stock         sql.NullInt64
+	IsUnlimited   bool
+	ThumbnailURL  sql.NullString
+	FileURL       sql.NullString
+	PreviewURL    sql.NullString
+	SoldCount     int
+	RatingAverage float64
+	RatingCount   int
+	IsActive      bool
+	IsFeatured    bool
+	Metadata      sql.NullString
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     sql.NullTime
 }
 
 // Order entity
@@ -39,6 +42,7 @@ type Order struct {
 	TotalPrice     int64
 	DiscountAmount int64
 	FinalPrice     int64
+	VoucherID      sql.NullInt64
 	Status         string
 	TransactionID  sql.NullInt64
 	QRCodeID       sql.NullInt64
@@ -51,10 +55,76 @@ type Order struct {
 	UpdatedAt      time.Time
 }
 
+// Category entity
+type Category struct {
+	ID          uint
+	Name        string
+	Slug        string
+	Description sql.NullString
+	ParentID    sql.NullInt64
+	SortOrder   int
+	IsActive    bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   sql.NullTime
+}
+
+// OrderItem entity
+type OrderItem struct {
+	ID          uint
+	OrderID     uint
+	ProductID   uint
+	ProductName string
+	Quantity    int
+	UnitPrice   int64
+	Subtotal    int64
+	CreatedAt   time.Time
+}
+
+// StockReservation holds stock for a buyer until checkout or expiry
+type StockReservation struct {
+	ID         uint
+	ProductID  uint
+	UserID     uint
+	Quantity   int
+	Status     string
+	OrderID    sql.NullInt64
+	ExpiresAt  time.Time
+	ReleasedAt sql.NullTime
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Review entity
+type Review struct {
+	ID           uint
+	ProductID    uint
+	UserID       uint
+	OrderID      uint
+	Rating       int
+	Comment      sql.NullString
+	SellerReply  sql.NullString
+	RepliedAt    sql.NullTime
+	IsHidden     bool
+	HiddenReason sql.NullString
+	HiddenBy     sql.NullInt64
+	HiddenAt     sql.NullTime
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// ReviewWithUser includes reviewer info
+type ReviewWithUser struct {
+	Review
+	UserName    string
+	ProductName string
+}
+
 // ProductWithSeller includes seller info
 type ProductWithSeller struct {
 	Product
-	SellerName string
+	SellerName   string
+	CategoryName sql.NullString
 }
 
 // OrderWithDetails includes product and user info
@@ -64,3 +134,38 @@ type OrderWithDetails struct {
 	BuyerName   string
 	SellerName  string
 }
+
+// SalesSummary aggregates a seller's orders over a date range
+type SalesSummary struct {
+	TotalOrders     int
+	CompletedOrders int
+	CancelledOrders int
+	RefundedOrders  int
+	UnitsSold       int
+	GrossRevenue    int64
+	DiscountTotal   int64
+	NetRevenue      int64
+}
+
+// RevenuePoint is the completed revenue of one period bucket
+type RevenuePoint struct {
+	Period  string
+	Orders  int
+	Revenue int64
+}
+
+// ProductSales is the units sold and revenue of one product
+type ProductSales struct {
+	ProductID   uint
+	ProductName string
+	UnitsSold   int
+	Revenue     int64
+}
+
+// BuyerSales is the spending of one buyer with a seller
+type BuyerSales struct {
+	BuyerID    uint
+	BuyerName  string
+	Orders     int
+	TotalSpent int64
+}
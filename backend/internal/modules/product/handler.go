@@ -65,7 +65,31 @@ func (h *Handler) GetActiveProducts(c *fiber.Ctx) error {
 		perPage = 20
 	}
 
-	products, total, err := h.service.GetActiveProducts(c.Context(), page, perPage)
+	filter := ProductFilter{
+		Search:      c.Query("q"),
+		ProductType: c.Query("type"),
+		Sort:        c.Query("sort"),
+		InStock:     c.QueryBool("in_stock"),
+		Featured:    c.QueryBool("featured"),
+	}
+	if sellerID, err := strconv.ParseUint(c.Query("seller_id"), 10, 64); err == nil {
+		filter.SellerID = uint(sellerID)
+	}
+	if categoryID, err := strconv.ParseUint(c.Query("category_id"), 10, 64); err == nil {
+		filter.CategoryID = uint(categoryID)
+	}
+	if minPrice, err := strconv.ParseInt(c.Query("min_price"), 10, 64); err == nil {
+		filter.MinPrice = &minPrice
+	}
+	if maxPrice, err := strconv.ParseInt(c.Query("max_price"), 10, 64); err == nil {
+		filter.MaxPrice = &maxPrice
+	}
+
+	if errors := filter.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	products, total, err := h.service.GetActiveProducts(c.Context(), filter, page, perPage)
 	if err != nil {
 		return handleError(c, err)
 	}
@@ -80,6 +104,21 @@ func (h *Handler) GetActiveProducts(c *fiber.Ctx) error {
 	})
 }
 
+// GetFeaturedProducts lists featured products
+func (h *Handler) GetFeaturedProducts(c *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	products, err := h.service.GetFeaturedProducts(c.Context(), limit)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Featured products retrieved", products)
+}
+
 // GetMyProducts lists products created by user
 func (h *Handler) GetMyProducts(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uint)
@@ -137,6 +176,97 @@ func (h *Handler) DeleteProduct(c *fiber.Ctx) error {
 	return response.Success(c, "Product deleted successfully", nil)
 }
 
+// SetFeatured marks a product as featured (admin)
+func (h *Handler) SetFeatured(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid product ID")
+	}
+
+	var req SetFeaturedRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := h.service.SetFeatured(c.Context(), uint(id), req.IsFeatured); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Product updated successfully", nil)
+}
+
+// GetCategories lists active categories
+func (h *Handler) GetCategories(c *fiber.Ctx) error {
+	categories, err := h.service.GetCategories(c.Context(), false)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Categories retrieved", categories)
+}
+
+// GetAllCategories lists all categories including inactive ones (admin)
+func (h *Handler) GetAllCategories(c *fiber.Ctx) error {
+	categories, err := h.service.GetCategories(c.Context(), true)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Categories retrieved", categories)
+}
+
+// CreateCategory creates a new category (admin)
+func (h *Handler) CreateCategory(c *fiber.Ctx) error {
+	var req CreateCategoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.CreateCategory(c.Context(), req)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Created(c, "Category created successfully", result)
+}
+
+// UpdateCategory updates a category (admin)
+func (h *Handler) UpdateCategory(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid category ID")
+	}
+
+	var req UpdateCategoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := h.service.UpdateCategory(c.Context(), uint(id), req); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Category updated successfully", nil)
+}
+
+// DeleteCategory deletes a category (admin)
+func (h *Handler) DeleteCategory(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid category ID")
+	}
+
+	if err := h.service.DeleteCategory(c.Context(), uint(id)); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Category deleted successfully", nil)
+}
+
 // CreateOrder creates a new order
 func (h *Handler) CreateOrder(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uint)
@@ -158,6 +288,27 @@ func (h *Handler) CreateOrder(c *fiber.Ctx) error {
 	return response.Created(c, "Order created successfully", result)
 }
 
+// PreviewOrder returns the checkout price for an order, including voucher discount
+func (h *Handler) PreviewOrder(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req CreateOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.PreviewOrder(c.Context(), req, userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Checkout preview", result)
+}
+
 // GetMyOrders lists user's orders
 func (h *Handler) GetMyOrders(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uint)
@@ -180,16 +331,261 @@ func (h *Handler) GetMyOrders(c *fiber.Ctx) error {
 	})
 }
 
+// GetProductReviews lists visible reviews of a product
+func (h *Handler) GetProductReviews(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid product ID")
+	}
+
+	return h.listReviews(c, uint(id), false)
+}
+
+// GetAllReviews lists reviews across products including hidden ones (admin)
+func (h *Handler) GetAllReviews(c *fiber.Ctx) error {
+	productID, _ := strconv.ParseUint(c.Query("product_id", "0"), 10, 64)
+	return h.listReviews(c, uint(productID), true)
+}
+
+func (h *Handler) listReviews(c *fiber.Ctx, productID uint, includeHidden bool) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	perPage, _ := strconv.Atoi(c.Query("per_page", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	reviews, total, err := h.service.GetReviews(c.Context(), productID, includeHidden, page, perPage)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	totalPages := (total + perPage - 1) / perPage
+
+	return response.SuccessWithMeta(c, "Reviews retrieved", reviews, &response.Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// CreateReview posts a review for a purchased product
+func (h *Handler) CreateReview(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid product ID")
+	}
+
+	var req CreateReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.CreateReview(c.Context(), uint(id), req, userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Created(c, "Review created successfully", result)
+}
+
+// UpdateReview edits the caller's own review
+func (h *Handler) UpdateReview(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid review ID")
+	}
+
+	var req CreateReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	if err := h.service.UpdateReview(c.Context(), uint(id), req, userID); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Review updated successfully", nil)
+}
+
+// ReplyReview lets the product seller reply to a review
+func (h *Handler) ReplyReview(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid review ID")
+	}
+
+	var req ReplyReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	if err := h.service.ReplyReview(c.Context(), uint(id), req, userID); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Reply saved successfully", nil)
+}
+
+// ModerateReview hides or restores a review (admin)
+func (h *Handler) ModerateReview(c *fiber.Ctx) error {
+	adminID := c.Locals("userID").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid review ID")
+	}
+
+	var req ModerateReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := h.service.ModerateReview(c.Context(), uint(id), req, adminID); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Review updated successfully", nil)
+}
+
+// GetMySales lists orders received by the seller
+func (h *Handler) GetMySales(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	perPage, _ := strconv.Atoi(c.Query("per_page", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	orders, total, err := h.service.GetMySales(c.Context(), userID, page, perPage)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	totalPages := (total + perPage - 1) / perPage
+
+	return response.SuccessWithMeta(c, "Sales retrieved", orders, &response.Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// GetSellerAnalytics returns the seller sales dashboard for a date range
+func (h *Handler) GetSellerAnalytics(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	lowStock, _ := strconv.Atoi(c.Query("low_stock", "5"))
+	topBuyers, _ := strconv.Atoi(c.Query("top_buyers", "10"))
+
+	q := SalesAnalyticsQuery{
+		From:          c.Query("from"),
+		To:            c.Query("to"),
+		Period:        c.Query("period", PeriodDay),
+		LowStockLimit: lowStock,
+		TopBuyers:     topBuyers,
+	}
+
+	if errors := q.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.GetSellerAnalytics(c.Context(), userID, q)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Sales analytics retrieved", result)
+}
+
+// ReserveStock holds product stock for the buyer during checkout
+func (h *Handler) ReserveStock(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req ReserveStockRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.ReserveStock(c.Context(), req, userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Created(c, "Stock reserved", result)
+}
+
+// GetMyReservations lists the buyer's active reservations
+func (h *Handler) GetMyReservations(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	result, err := h.service.GetMyReservations(c.Context(), userID)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Reservations retrieved", result)
+}
+
+// ReleaseReservation cancels a reservation and returns its stock
+func (h *Handler) ReleaseReservation(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid reservation ID")
+	}
+
+	if err := h.service.ReleaseReservation(c.Context(), uint(id), userID); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Reservation released", nil)
+}
+
 func handleError(c *fiber.Ctx, err error) error {
 	if appErr, ok := err.(*apperrors.AppError); ok {
 		switch appErr.Code {
-		case "NOT_FOUND", "PRODUCT_NOT_FOUND":
+		case "NOT_FOUND", "PRODUCT_NOT_FOUND", "CATEGORY_NOT_FOUND", "VOUCHER_NOT_FOUND",
+			"REVIEW_NOT_FOUND", "RESERVATION_NOT_FOUND":
 			return response.NotFound(c, appErr.Message)
-		case "FORBIDDEN":
+		case "CATEGORY_EXISTS", "REVIEW_EXISTS", "RESERVATION_EXISTS":
+			return response.Conflict(c, appErr.Message)
+		case "FORBIDDEN", "NOT_VERIFIED_BUYER":
 			return response.Forbidden(c, appErr.Message)
 		case "INSUFFICIENT_BALANCE":
 			return response.Error(c, fiber.StatusPaymentRequired, appErr.Message, appErr.Code)
-		case "PRODUCT_NOT_ACTIVE", "OUT_OF_STOCK", "CANNOT_BUY_OWN":
+		case "PRODUCT_NOT_ACTIVE", "OUT_OF_STOCK", "CANNOT_BUY_OWN", "INVALID_CATEGORY",
+			"VOUCHER_INVALID", "VOUCHER_NOT_STARTED", "VOUCHER_EXPIRED", "VOUCHER_NOT_APPLICABLE",
+			"VOUCHER_MIN_PURCHASE", "VOUCHER_EXHAUSTED", "VOUCHER_ALREADY_USED", "VOUCHER_UNFUNDED",
+			"RESERVATION_INACTIVE", "RESERVATION_EXPIRED", "RESERVATION_MISMATCH":
 			return response.BadRequest(c, appErr.Message)
 		default:
 			return response.InternalError(c, appErr.Message)
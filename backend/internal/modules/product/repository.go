@@ -3,6 +3,9 @@ package product
 import (
 	"context"
 	"database/sql"
+	"strings"
+	"time"
+	"unicode"
 )
 
 type Repository struct {
@@ -16,14 +19,14 @@ func NewRepository(db *sql.DB) *Repository {
 // Product operations
 func (r *Repository) CreateProduct(ctx context.Context, p *Product) error {
 	query := `
-		INSERT INTO products (seller_id, name, description, product_type, price, stock, 
+		INSERT INTO products (seller_id, name, description, product_type, category_id, price, stock, 
 			thumbnail_url, file_url, preview_url, sold_count, is_active, 
 			is_featured, metadata, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, FALSE, ?, NOW(), NOW())
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, FALSE, ?, NOW(), NOW())
 	`
 
 	result, err := r.db.ExecContext(ctx, query,
-		p.SellerID, p.Name, p.Description, p.ProductType, p.Price, p.Stock,
+		p.SellerID, p.Name, p.Description, p.ProductType, p.CategoryID, p.Price, p.Stock,
 		p.ThumbnailURL, p.FileURL, p.PreviewURL, p.IsActive, p.Metadata,
 	)
 	if err != nil {
@@ -41,16 +44,31 @@ func (r *Repository) CreateProduct(ctx context.Context, p *Product) error {
 
 func (r *Repository) GetProductByID(ctx context.Context, id uint) (*Product, error) {
 	query := `
-		SELECT id, seller_id, name, description, product_type, price, stock,
-			thumbnail_url, file_url, preview_url, sold_count, is_active, is_featured,
+		SELECT id, seller_id, name, description, product_type, category_id, price, stock,
+			thumbnail_url, file_url, preview_url, sold_count, rating_average, rating_count, is_active, is_featured,
 			metadata, created_at, updated_at
 		FROM products WHERE id = ? AND deleted_at IS NULL
 	`
+	return scanProduct(r.db.QueryRowContext(ctx, query, id))
+}
+
+// GetProductByIDForUpdate locks the product row so stock checks hold until commit
+func (r *Repository) GetProductByIDForUpdate(ctx context.Context, tx *sql.Tx, id uint) (*Product, error) {
+	query := `
+		SELECT id, seller_id, name, description, product_type, category_id, price, stock,
+			thumbnail_url, file_url, preview_url, sold_count, rating_average, rating_count, is_active, is_featured,
+			metadata, created_at, updated_at
+		FROM products WHERE id = ? AND deleted_at IS NULL
+		FOR UPDATE
+	`
+	return scanProduct(tx.QueryRowContext(ctx, query, id))
+}
 
+func scanProduct(row *sql.Row) (*Product, error) {
 	var p Product
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&p.ID, &p.SellerID, &p.Name, &p.Description, &p.ProductType, &p.Price, &p.Stock,
-		&p.ThumbnailURL, &p.FileURL, &p.PreviewURL, &p.SoldCount, &p.IsActive, &p.IsFeatured,
+	err := row.Scan(
+		&p.ID, &p.SellerID, &p.Name, &p.Description, &p.ProductType, &p.CategoryID, &p.Price, &p.Stock,
+		&p.ThumbnailURL, &p.FileURL, &p.PreviewURL, &p.SoldCount, &p.RatingAverage, &p.RatingCount, &p.IsActive, &p.IsFeatured,
 		&p.Metadata, &p.CreatedAt, &p.UpdatedAt,
 	)
 
@@ -67,25 +85,30 @@ func (r *Repository) GetProductByID(ctx context.Context, id uint) (*Product, err
 	return &p, nil
 }
 
-func (r *Repository) GetActiveProducts(ctx context.Context, limit, offset int) ([]*ProductWithSeller, int, error) {
+func (r *Repository) GetActiveProducts(ctx context.Context, filter ProductFilter, limit, offset int) ([]*ProductWithSeller, int, error) {
+	where, args := buildProductFilter(filter)
+
 	var total int
-	countQuery := `SELECT COUNT(*) FROM products WHERE is_active = TRUE AND deleted_at IS NULL`
-	if err := r.db.QueryRowContext(ctx, countQuery).Scan(&total); err != nil {
+	countQuery := `SELECT COUNT(*) FROM products p WHERE ` + where
+	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
+	orderBy, orderArgs := productOrderBy(filter)
 	query := `
-		SELECT p.id, p.seller_id, p.name, p.description, p.product_type, p.price, p.stock,
-			p.thumbnail_url, p.file_url, p.preview_url, p.sold_count, p.is_active, p.is_featured,
-			p.metadata, p.created_at, p.updated_at, u.full_name as seller_name
+		SELECT p.id, p.seller_id, p.name, p.description, p.product_type, p.category_id, p.price, p.stock,
+			p.thumbnail_url, p.file_url, p.preview_url, p.sold_count, p.rating_average, p.rating_count, p.is_active, p.is_featured,
+			p.metadata, p.created_at, p.updated_at, u.full_name as seller_name, c.name as category_name
 		FROM products p
 		INNER JOIN users u ON p.seller_id = u.id
-		WHERE p.is_active = TRUE AND p.deleted_at IS NULL
-		ORDER BY p.is_featured DESC, p.created_at DESC
+		LEFT JOIN product_categories c ON p.category_id = c.id
+		WHERE ` + where + `
+		ORDER BY ` + orderBy + `
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	queryArgs := append(append(args, orderArgs...), limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, queryArgs...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -95,9 +118,9 @@ func (r *Repository) GetActiveProducts(ctx context.Context, limit, offset int) (
 	for rows.Next() {
 		var p ProductWithSeller
 		if err := rows.Scan(
-			&p.ID, &p.SellerID, &p.Name, &p.Description, &p.ProductType, &p.Price, &p.Stock,
-			&p.ThumbnailURL, &p.FileURL, &p.PreviewURL, &p.SoldCount, &p.IsActive, &p.IsFeatured,
-			&p.Metadata, &p.CreatedAt, &p.UpdatedAt, &p.SellerName,
+			&p.ID, &p.SellerID, &p.Name, &p.Description, &p.ProductType, &p.CategoryID, &p.Price, &p.Stock,
+			&p.ThumbnailURL, &p.FileURL, &p.PreviewURL, &p.SoldCount, &p.RatingAverage, &p.RatingCount, &p.IsActive, &p.IsFeatured,
+			&p.Metadata, &p.CreatedAt, &p.UpdatedAt, &p.SellerName, &p.CategoryName,
 		); err != nil {
 			return nil, 0, err
 		}
@@ -108,6 +131,86 @@ func (r *Repository) GetActiveProducts(ctx context.Context, limit, offset int) (
 	return products, total, nil
 }
 
+// buildProductFilter builds the WHERE clause for catalog listing
+func buildProductFilter(f ProductFilter) (string, []interface{}) {
+	conditions := []string{"p.is_active = TRUE", "p.deleted_at IS NULL"}
+	var args []interface{}
+
+	if search := toBooleanSearch(f.Search); search != "" {
+		conditions = append(conditions, "MATCH(p.name, p.description) AGAINST (? IN BOOLEAN MODE)")
+		args = append(args, search)
+	}
+	if f.ProductType != "" {
+		conditions = append(conditions, "p.product_type = ?")
+		args = append(args, f.ProductType)
+	}
+	if f.SellerID != 0 {
+		conditions = append(conditions, "p.seller_id = ?")
+		args = append(args, f.SellerID)
+	}
+	if f.CategoryID != 0 {
+		// Include products of direct subcategories
+		conditions = append(conditions, "(p.category_id = ? OR p.category_id IN (SELECT id FROM product_categories WHERE parent_id = ?))")
+		args = append(args, f.CategoryID, f.CategoryID)
+	}
+	if f.MinPrice != nil {
+		conditions = append(conditions, "p.price >= ?")
+		args = append(args, *f.MinPrice)
+	}
+	if f.MaxPrice != nil {
+		conditions = append(conditions, "p.price <= ?")
+		args = append(args, *f.MaxPrice)
+	}
+	if f.InStock {
+		conditions = append(conditions, "(p.stock IS NULL OR p.stock > 0)")
+	}
+	if f.Featured {
+		conditions = append(conditions, "p.is_featured = TRUE")
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// productOrderBy maps a sort option to an ORDER BY clause
+func productOrderBy(f ProductFilter) (string, []interface{}) {
+	switch f.Sort {
+	case SortNewest:
+		return "p.created_at DESC, p.id DESC", nil
+	case SortPriceAsc:
+		return "p.price ASC, p.id DESC", nil
+	case SortPriceDesc:
+		return "p.price DESC, p.id DESC", nil
+	case SortBestSeller:
+		return "p.sold_count DESC, p.id DESC", nil
+	case SortRating:
+		return "p.rating_average DESC, p.rating_count DESC, p.id DESC", nil
+	}
+
+	// Default: relevance when searching, otherwise featured first
+	if search := toBooleanSearch(f.Search); search != "" {
+		return "MATCH(p.name, p.description) AGAINST (? IN BOOLEAN MODE) DESC, p.id DESC", []interface{}{search}
+	}
+	return "p.is_featured DESC, p.created_at DESC", nil
+}
+
+// toBooleanSearch converts free text into a MySQL boolean-mode query
+// where every word is required and matched as a prefix
+func toBooleanSearch(input string) string {
+	var terms []string
+	for _, word := range strings.Fields(input) {
+		word = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, word)
+		if word != "" {
+			terms = append(terms, "+"+word+"*")
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
 func (r *Repository) GetProductsBySellerID(ctx context.Context, sellerID uint, limit, offset int) ([]*Product, int, error) {
 	var total int
 	countQuery := `SELECT COUNT(*) FROM products WHERE seller_id = ? AND deleted_at IS NULL`
@@ -116,8 +219,8 @@ func (r *Repository) GetProductsBySellerID(ctx context.Context, sellerID uint, l
 	}
 
 	query := `
-		SELECT id, seller_id, name, description, product_type, price, stock,
-			thumbnail_url, file_url, preview_url, sold_count, is_active, is_featured,
+		SELECT id, seller_id, name, description, product_type, category_id, price, stock,
+			thumbnail_url, file_url, preview_url, sold_count, rating_average, rating_count, is_active, is_featured,
 			metadata, created_at, updated_at
 		FROM products 
 		WHERE seller_id = ? AND deleted_at IS NULL
@@ -135,8 +238,8 @@ func (r *Repository) GetProductsBySellerID(ctx context.Context, sellerID uint, l
 	for rows.Next() {
 		var p Product
 		if err := rows.Scan(
-			&p.ID, &p.SellerID, &p.Name, &p.Description, &p.ProductType, &p.Price, &p.Stock,
-			&p.ThumbnailURL, &p.FileURL, &p.PreviewURL, &p.SoldCount, &p.IsActive, &p.IsFeatured,
+			&p.ID, &p.SellerID, &p.Name, &p.Description, &p.ProductType, &p.CategoryID, &p.Price, &p.Stock,
+			&p.ThumbnailURL, &p.FileURL, &p.PreviewURL, &p.SoldCount, &p.RatingAverage, &p.RatingCount, &p.IsActive, &p.IsFeatured,
 			&p.Metadata, &p.CreatedAt, &p.UpdatedAt,
 		); err != nil {
 			return nil, 0, err
@@ -150,12 +253,12 @@ func (r *Repository) GetProductsBySellerID(ctx context.Context, sellerID uint, l
 
 func (r *Repository) UpdateProduct(ctx context.Context, p *Product) error {
 	query := `
-		UPDATE products SET name = ?, description = ?, price = ?, stock = ?,
+		UPDATE products SET name = ?, description = ?, category_id = ?, price = ?, stock = ?,
 			thumbnail_url = ?, file_url = ?, is_active = ?, updated_at = NOW()
 		WHERE id = ?
 	`
 	_, err := r.db.ExecContext(ctx, query,
-		p.Name, p.Description, p.Price, p.Stock,
+		p.Name, p.Description, p.CategoryID, p.Price, p.Stock,
 		p.ThumbnailURL, p.FileURL, p.IsActive, p.ID,
 	)
 	return err
@@ -167,6 +270,12 @@ func (r *Repository) DeleteProduct(ctx context.Context, id uint) error {
 	return err
 }
 
+func (r *Repository) SetFeatured(ctx context.Context, id uint, featured bool) error {
+	query := `UPDATE products SET is_featured = ?, updated_at = NOW() WHERE id = ? AND deleted_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, featured, id)
+	return err
+}
+
 func (r *Repository) DecrementStock(ctx context.Context, tx *sql.Tx, id uint, quantity int) error {
 	query := `UPDATE products SET stock = stock - ?, sold_count = sold_count + ? WHERE id = ? AND (stock IS NULL OR stock >= ?)`
 	result, err := tx.ExecContext(ctx, query, quantity, quantity, id, quantity)
@@ -180,14 +289,171 @@ func (r *Repository) DecrementStock(ctx context.Context, tx *sql.Tx, id uint, qu
 	return nil
 }
 
+// HoldStock takes quantity out of a limited product's stock without counting it as sold
+func (r *Repository) HoldStock(ctx context.Context, tx *sql.Tx, id uint, quantity int) error {
+	query := `UPDATE products SET stock = stock - ? WHERE id = ? AND stock IS NOT NULL AND stock >= ?`
+	result, err := tx.ExecContext(ctx, query, quantity, id, quantity)
+	if err != nil {
+		return err
+	}
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return sql.ErrNoRows // Stock not sufficient
+	}
+	return nil
+}
+
+// RestockProduct returns held units to a limited product's stock
+func (r *Repository) RestockProduct(ctx context.Context, tx *sql.Tx, id uint, quantity int) error {
+	query := `UPDATE products SET stock = stock + ? WHERE id = ? AND stock IS NOT NULL`
+	_, err := tx.ExecContext(ctx, query, quantity, id)
+	return err
+}
+
+func (r *Repository) AddSoldCount(ctx context.Context, tx *sql.Tx, id uint, quantity int) error {
+	query := `UPDATE products SET sold_count = sold_count + ? WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, quantity, id)
+	return err
+}
+
+// Category operations
+func (r *Repository) CreateCategory(ctx context.Context, c *Category) error {
+	query := `
+		INSERT INTO product_categories (name, slug, description, parent_id, sort_order, is_active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW(), NOW())
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		c.Name, c.Slug, c.Description, c.ParentID, c.SortOrder, c.IsActive,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	c.ID = uint(id)
+	return nil
+}
+
+func (r *Repository) GetCategoryByID(ctx context.Context, id uint) (*Category, error) {
+	query := `
+		SELECT id, name, slug, description, parent_id, sort_order, is_active, created_at, updated_at
+		FROM product_categories WHERE id = ? AND deleted_at IS NULL
+	`
+
+	var c Category
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&c.ID, &c.Name, &c.Slug, &c.Description, &c.ParentID, &c.SortOrder, &c.IsActive,
+		&c.CreatedAt, &c.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (r *Repository) GetCategoryBySlug(ctx context.Context, slug string) (*Category, error) {
+	query := `
+		SELECT id, name, slug, description, parent_id, sort_order, is_active, created_at, updated_at
+		FROM product_categories WHERE slug = ? AND deleted_at IS NULL
+	`
+
+	var c Category
+	err := r.db.QueryRowContext(ctx, query, slug).Scan(
+		&c.ID, &c.Name, &c.Slug, &c.Description, &c.ParentID, &c.SortOrder, &c.IsActive,
+		&c.CreatedAt, &c.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (r *Repository) GetCategories(ctx context.Context, includeInactive bool) ([]*Category, error) {
+	query := `
+		SELECT id, name, slug, description, parent_id, sort_order, is_active, created_at, updated_at
+		FROM product_categories
+		WHERE deleted_at IS NULL AND (is_active = TRUE OR ? = TRUE)
+		ORDER BY sort_order ASC, name ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*Category
+	for rows.Next() {
+		var c Category
+		if err := rows.Scan(
+			&c.ID, &c.Name, &c.Slug, &c.Description, &c.ParentID, &c.SortOrder, &c.IsActive,
+			&c.CreatedAt, &c.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		categories = append(categories, &c)
+	}
+
+	return categories, nil
+}
+
+func (r *Repository) UpdateCategory(ctx context.Context, c *Category) error {
+	query := `
+		UPDATE product_categories SET name = ?, slug = ?, description = ?, parent_id = ?,
+			sort_order = ?, is_active = ?, updated_at = NOW()
+		WHERE id = ?
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		c.Name, c.Slug, c.Description, c.ParentID, c.SortOrder, c.IsActive, c.ID,
+	)
+	return err
+}
+
+func (r *Repository) DeleteCategory(ctx context.Context, id uint) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Detach products and subcategories before soft-deleting
+	if _, err := tx.ExecContext(ctx, `UPDATE products SET category_id = NULL WHERE category_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE product_categories SET parent_id = NULL WHERE parent_id = ?`, id); err != nil {
+		return err
+	}
+	// Free the slug so it can be reused by a new category
+	if _, err := tx.ExecContext(ctx, `UPDATE product_categories SET slug = CONCAT(slug, '-deleted-', id), deleted_at = NOW() WHERE id = ?`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 // Order operations - simplified to work with existing orders table schema
 func (r *Repository) CreateOrder(ctx context.Context, tx *sql.Tx, o *Order) error {
 	// Note: Using existing orders table which has different columns
 	// We'll store product_id in notes as JSON for now
 	query := `
-		INSERT INTO orders (order_code, buyer_id, seller_id, total_amount, 
+		INSERT INTO orders (order_code, buyer_id, seller_id, total_amount, discount_amount, voucher_id,
 			status, payment_method, transaction_id, notes, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, 'WALLET', ?, ?, NOW(), NOW())
+		VALUES (?, ?, ?, ?, ?, ?, ?, 'WALLET', ?, ?, NOW(), NOW())
 	`
 
 	// Store product info in notes
@@ -197,7 +463,7 @@ func (r *Repository) CreateOrder(ctx context.Context, tx *sql.Tx, o *Order) erro
 	}
 
 	result, err := tx.ExecContext(ctx, query,
-		o.OrderCode, o.BuyerID, o.SellerID, o.FinalPrice,
+		o.OrderCode, o.BuyerID, o.SellerID, o.FinalPrice, o.DiscountAmount, o.VoucherID,
 		o.Status, o.TransactionID, notes,
 	)
 	if err != nil {
@@ -213,9 +479,31 @@ func (r *Repository) CreateOrder(ctx context.Context, tx *sql.Tx, o *Order) erro
 	return nil
 }
 
+func (r *Repository) CreateOrderItem(ctx context.Context, tx *sql.Tx, item *OrderItem) error {
+	query := `
+		INSERT INTO order_items (order_id, product_id, product_name, quantity, unit_price, subtotal, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW())
+	`
+
+	result, err := tx.ExecContext(ctx, query,
+		item.OrderID, item.ProductID, item.ProductName, item.Quantity, item.UnitPrice, item.Subtotal,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	item.ID = uint(id)
+	return nil
+}
+
 func (r *Repository) GetOrderByID(ctx context.Context, id uint) (*Order, error) {
 	query := `
-		SELECT id, order_code, buyer_id, seller_id, total_amount, status, 
+		SELECT id, order_code, buyer_id, seller_id, total_amount, discount_amount, voucher_id, status, 
 			transaction_id, completed_at, cancelled_at, cancel_reason, notes, created_at, updated_at
 		FROM orders WHERE id = ?
 	`
@@ -223,7 +511,7 @@ func (r *Repository) GetOrderByID(ctx context.Context, id uint) (*Order, error)
 	var o Order
 	var totalAmount int64
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&o.ID, &o.OrderCode, &o.BuyerID, &o.SellerID, &totalAmount, &o.Status,
+		&o.ID, &o.OrderCode, &o.BuyerID, &o.SellerID, &totalAmount, &o.DiscountAmount, &o.VoucherID, &o.Status,
 		&o.TransactionID, &o.CompletedAt, &o.CancelledAt, &o.CancelReason, &o.Notes, &o.CreatedAt, &o.UpdatedAt,
 	)
 
@@ -234,7 +522,7 @@ func (r *Repository) GetOrderByID(ctx context.Context, id uint) (*Order, error)
 		return nil, err
 	}
 
-	o.TotalPrice = totalAmount
+	o.TotalPrice = totalAmount + o.DiscountAmount
 	o.FinalPrice = totalAmount
 	return &o, nil
 }
@@ -247,13 +535,15 @@ func (r *Repository) GetOrdersByBuyerID(ctx context.Context, buyerID uint, limit
 	}
 
 	query := `
-		SELECT o.id, o.order_code, o.buyer_id, o.seller_id, o.total_amount, o.status,
+		SELECT o.id, o.order_code, o.buyer_id, o.seller_id, o.total_amount, o.discount_amount, o.voucher_id, o.status,
 			o.transaction_id, o.completed_at, o.cancelled_at, o.cancel_reason, o.notes, 
 			o.created_at, o.updated_at,
-			buyer.full_name as buyer_name, seller.full_name as seller_name
+			buyer.full_name as buyer_name, seller.full_name as seller_name,
+			oi.product_id, oi.product_name
 		FROM orders o
 		INNER JOIN users buyer ON o.buyer_id = buyer.id
 		INNER JOIN users seller ON o.seller_id = seller.id
+		LEFT JOIN order_items oi ON oi.id = (SELECT MIN(id) FROM order_items WHERE order_id = o.id)
 		WHERE o.buyer_id = ?
 		ORDER BY o.created_at DESC
 		LIMIT ? OFFSET ?
@@ -269,16 +559,23 @@ func (r *Repository) GetOrdersByBuyerID(ctx context.Context, buyerID uint, limit
 	for rows.Next() {
 		var o OrderWithDetails
 		var totalAmount int64
+		var productID sql.NullInt64
+		var productName sql.NullString
 		if err := rows.Scan(
-			&o.ID, &o.OrderCode, &o.BuyerID, &o.SellerID, &totalAmount, &o.Status,
+			&o.ID, &o.OrderCode, &o.BuyerID, &o.SellerID, &totalAmount, &o.DiscountAmount, &o.VoucherID, &o.Status,
 			&o.TransactionID, &o.CompletedAt, &o.CancelledAt, &o.CancelReason, &o.Notes,
 			&o.CreatedAt, &o.UpdatedAt, &o.BuyerName, &o.SellerName,
+			&productID, &productName,
 		); err != nil {
 			return nil, 0, err
 		}
-		o.TotalPrice = totalAmount
+		o.TotalPrice = totalAmount + o.DiscountAmount
 		o.FinalPrice = totalAmount
-		o.ProductName = "Product" // Simplified
+		o.ProductID = uint(productID.Int64)
+		o.ProductName = "Product"
+		if productName.Valid {
+			o.ProductName = productName.String
+		}
 		orders = append(orders, &o)
 	}
 
@@ -290,3 +587,524 @@ func (r *Repository) UpdateOrderStatus(ctx context.Context, id uint, status stri
 	_, err := r.db.ExecContext(ctx, query, status, id)
 	return err
 }
+
+// Review operations
+
+// GetCompletedOrderForProduct returns the latest completed order in which the buyer purchased the product
+func (r *Repository) GetCompletedOrderForProduct(ctx context.Context, buyerID, productID uint) (uint, error) {
+	query := `
+		SELECT o.id FROM orders o
+		INNER JOIN order_items oi ON oi.order_id = o.id
+		WHERE o.buyer_id = ? AND oi.product_id = ? AND o.status = 'COMPLETED'
+		ORDER BY o.created_at DESC
+		LIMIT 1
+	`
+
+	var orderID uint
+	err := r.db.QueryRowContext(ctx, query, buyerID, productID).Scan(&orderID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return orderID, err
+}
+
+func (r *Repository) CreateReview(ctx context.Context, rv *Review) error {
+	query := `
+		INSERT INTO product_reviews (product_id, user_id, order_id, rating, comment, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, NOW(), NOW())
+	`
+
+	result, err := r.db.ExecContext(ctx, query, rv.ProductID, rv.UserID, rv.OrderID, rv.Rating, rv.Comment)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	rv.ID = uint(id)
+	return r.RefreshProductRating(ctx, rv.ProductID)
+}
+
+func (r *Repository) GetReviewByID(ctx context.Context, id uint) (*Review, error) {
+	query := `
+		SELECT id, product_id, user_id, order_id, rating, comment, seller_reply, replied_at,
+			is_hidden, hidden_reason, hidden_by, hidden_at, created_at, updated_at
+		FROM product_reviews WHERE id = ?
+	`
+
+	var rv Review
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&rv.ID, &rv.ProductID, &rv.UserID, &rv.OrderID, &rv.Rating, &rv.Comment, &rv.SellerReply, &rv.RepliedAt,
+		&rv.IsHidden, &rv.HiddenReason, &rv.HiddenBy, &rv.HiddenAt, &rv.CreatedAt, &rv.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &rv, nil
+}
+
+func (r *Repository) GetReviewByProductAndUser(ctx context.Context, productID, userID uint) (*Review, error) {
+	query := `SELECT id FROM product_reviews WHERE product_id = ? AND user_id = ?`
+
+	var id uint
+	err := r.db.QueryRowContext(ctx, query, productID, userID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetReviewByID(ctx, id)
+}
+
+// GetReviews lists reviews for a product (productID 0 = all products);
+// hidden reviews are only included when includeHidden is set
+func (r *Repository) GetReviews(ctx context.Context, productID uint, includeHidden bool, limit, offset int) ([]*ReviewWithUser, int, error) {
+	where := "(? = 0 OR pr.product_id = ?) AND (pr.is_hidden = FALSE OR ? = TRUE)"
+	args := []interface{}{productID, productID, includeHidden}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM product_reviews pr WHERE ` + where
+	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT pr.id, pr.product_id, pr.user_id, pr.order_id, pr.rating, pr.comment, pr.seller_reply, pr.replied_at,
+			pr.is_hidden, pr.hidden_reason, pr.hidden_by, pr.hidden_at, pr.created_at, pr.updated_at,
+			u.full_name as user_name, p.name as product_name
+		FROM product_reviews pr
+		INNER JOIN users u ON pr.user_id = u.id
+		INNER JOIN products p ON pr.product_id = p.id
+		WHERE ` + where + `
+		ORDER BY pr.created_at DESC
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var reviews []*ReviewWithUser
+	for rows.Next() {
+		var rv ReviewWithUser
+		if err := rows.Scan(
+			&rv.ID, &rv.ProductID, &rv.UserID, &rv.OrderID, &rv.Rating, &rv.Comment, &rv.SellerReply, &rv.RepliedAt,
+			&rv.IsHidden, &rv.HiddenReason, &rv.HiddenBy, &rv.HiddenAt, &rv.CreatedAt, &rv.UpdatedAt,
+			&rv.UserName, &rv.ProductName,
+		); err != nil {
+			return nil, 0, err
+		}
+		reviews = append(reviews, &rv)
+	}
+
+	return reviews, total, nil
+}
+
+func (r *Repository) UpdateReview(ctx context.Context, rv *Review) error {
+	query := `UPDATE product_reviews SET rating = ?, comment = ?, updated_at = NOW() WHERE id = ?`
+	if _, err := r.db.ExecContext(ctx, query, rv.Rating, rv.Comment, rv.ID); err != nil {
+		return err
+	}
+	return r.RefreshProductRating(ctx, rv.ProductID)
+}
+
+func (r *Repository) ReplyReview(ctx context.Context, id uint, reply string) error {
+	query := `UPDATE product_reviews SET seller_reply = ?, replied_at = NOW(), updated_at = NOW() WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, reply, id)
+	return err
+}
+
+func (r *Repository) SetReviewHidden(ctx context.Context, rv *Review, hidden bool, reason string, adminID uint) error {
+	query := `
+		UPDATE product_reviews
+		SET is_hidden = ?, hidden_reason = ?, hidden_by = ?, hidden_at = IF(? = TRUE, NOW(), NULL), updated_at = NOW()
+		WHERE id = ?
+	`
+	hiddenReason := sql.NullString{String: reason, Valid: hidden && reason != ""}
+	hiddenBy := sql.NullInt64{Int64: int64(adminID), Valid: hidden}
+	if _, err := r.db.ExecContext(ctx, query, hidden, hiddenReason, hiddenBy, hidden, rv.ID); err != nil {
+		return err
+	}
+	return r.RefreshProductRating(ctx, rv.ProductID)
+}
+
+// RefreshProductRating recomputes the aggregate rating from visible reviews
+func (r *Repository) RefreshProductRating(ctx context.Context, productID uint) error {
+	query := `
+		UPDATE products p
+		SET rating_average = (
+				SELECT COALESCE(AVG(rating), 0) FROM product_reviews WHERE product_id = p.id AND is_hidden = FALSE
+			),
+			rating_count = (
+				SELECT COUNT(*) FROM product_reviews WHERE product_id = p.id AND is_hidden = FALSE
+			)
+		WHERE p.id = ?
+	`
+	_, err := r.db.ExecContext(ctx, query, productID)
+	return err
+}
+
+// Seller analytics
+
+// completedSale matches orders whose payment settled; orders without a linked
+// transaction are trusted on their own status
+const completedSale = "o.status = 'COMPLETED' AND (t.id IS NULL OR t.status = 'COMPLETED')"
+
+func (r *Repository) GetOrdersBySellerID(ctx context.Context, sellerID uint, limit, offset int) ([]*OrderWithDetails, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM orders WHERE seller_id = ?`
+	if err := r.db.QueryRowContext(ctx, countQuery, sellerID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT o.id, o.order_code, o.buyer_id, o.seller_id, o.total_amount, o.discount_amount, o.voucher_id, o.status,
+			o.transaction_id, o.completed_at, o.cancelled_at, o.cancel_reason, o.notes,
+			o.created_at, o.updated_at,
+			buyer.full_name as buyer_name, seller.full_name as seller_name,
+			oi.product_id, oi.product_name, oi.quantity
+		FROM orders o
+		INNER JOIN users buyer ON o.buyer_id = buyer.id
+		INNER JOIN users seller ON o.seller_id = seller.id
+		LEFT JOIN order_items oi ON oi.id = (SELECT MIN(id) FROM order_items WHERE order_id = o.id)
+		WHERE o.seller_id = ?
+		ORDER BY o.created_at DESC
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, sellerID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var orders []*OrderWithDetails
+	for rows.Next() {
+		var o OrderWithDetails
+		var totalAmount int64
+		var productID, quantity sql.NullInt64
+		var productName sql.NullString
+		if err := rows.Scan(
+			&o.ID, &o.OrderCode, &o.BuyerID, &o.SellerID, &totalAmount, &o.DiscountAmount, &o.VoucherID, &o.Status,
+			&o.TransactionID, &o.CompletedAt, &o.CancelledAt, &o.CancelReason, &o.Notes,
+			&o.CreatedAt, &o.UpdatedAt, &o.BuyerName, &o.SellerName,
+			&productID, &productName, &quantity,
+		); err != nil {
+			return nil, 0, err
+		}
+		o.TotalPrice = totalAmount + o.DiscountAmount
+		o.FinalPrice = totalAmount
+		o.ProductID = uint(productID.Int64)
+		o.Quantity = int(quantity.Int64)
+		o.ProductName = "Product"
+		if productName.Valid {
+			o.ProductName = productName.String
+		}
+		orders = append(orders, &o)
+	}
+
+	return orders, total, nil
+}
+
+func (r *Repository) GetSalesSummary(ctx context.Context, sellerID uint, from, to time.Time) (*SalesSummary, error) {
+	query := `
+		SELECT COUNT(*),
+			COALESCE(SUM(CASE WHEN ` + completedSale + ` THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN o.status = 'CANCELLED' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN o.status = 'REFUNDED' OR t.status = 'REFUNDED' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN ` + completedSale + ` THEN o.total_amount ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN ` + completedSale + ` THEN o.discount_amount ELSE 0 END), 0)
+		FROM orders o
+		LEFT JOIN transactions t ON o.transaction_id = t.id
+		WHERE o.seller_id = ? AND o.created_at >= ? AND o.created_at < ?
+	`
+
+	var s SalesSummary
+	err := r.db.QueryRowContext(ctx, query, sellerID, from, to).Scan(
+		&s.TotalOrders, &s.CompletedOrders, &s.CancelledOrders, &s.RefundedOrders,
+		&s.NetRevenue, &s.DiscountTotal,
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.GrossRevenue = s.NetRevenue + s.DiscountTotal
+
+	unitsQuery := `
+		SELECT COALESCE(SUM(oi.quantity), 0)
+		FROM orders o
+		INNER JOIN order_items oi ON oi.order_id = o.id
+		LEFT JOIN transactions t ON o.transaction_id = t.id
+		WHERE o.seller_id = ? AND o.created_at >= ? AND o.created_at < ? AND ` + completedSale
+
+	if err := r.db.QueryRowContext(ctx, unitsQuery, sellerID, from, to).Scan(&s.UnitsSold); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// GetRevenueByPeriod buckets completed revenue using a DATE_FORMAT layout
+func (r *Repository) GetRevenueByPeriod(ctx context.Context, sellerID uint, from, to time.Time, dateFormat string) ([]*RevenuePoint, error) {
+	query := `
+		SELECT DATE_FORMAT(o.created_at, ?) as period_key, COUNT(*), COALESCE(SUM(o.total_amount), 0)
+		FROM orders o
+		LEFT JOIN transactions t ON o.transaction_id = t.id
+		WHERE o.seller_id = ? AND o.created_at >= ? AND o.created_at < ? AND ` + completedSale + `
+		GROUP BY period_key
+		ORDER BY period_key
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, dateFormat, sellerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []*RevenuePoint
+	for rows.Next() {
+		var p RevenuePoint
+		if err := rows.Scan(&p.Period, &p.Orders, &p.Revenue); err != nil {
+			return nil, err
+		}
+		points = append(points, &p)
+	}
+
+	return points, nil
+}
+
+func (r *Repository) GetProductSales(ctx context.Context, sellerID uint, from, to time.Time) ([]*ProductSales, error) {
+	query := `
+		SELECT oi.product_id, MAX(oi.product_name), COALESCE(SUM(oi.quantity), 0), COALESCE(SUM(o.total_amount), 0)
+		FROM orders o
+		INNER JOIN order_items oi ON oi.order_id = o.id
+		LEFT JOIN transactions t ON o.transaction_id = t.id
+		WHERE o.seller_id = ? AND o.created_at >= ? AND o.created_at < ? AND ` + completedSale + `
+		GROUP BY oi.product_id
+		ORDER BY SUM(oi.quantity) DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, sellerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sales []*ProductSales
+	for rows.Next() {
+		var ps ProductSales
+		if err := rows.Scan(&ps.ProductID, &ps.ProductName, &ps.UnitsSold, &ps.Revenue); err != nil {
+			return nil, err
+		}
+		sales = append(sales, &ps)
+	}
+
+	return sales, nil
+}
+
+func (r *Repository) GetTopBuyers(ctx context.Context, sellerID uint, from, to time.Time, limit int) ([]*BuyerSales, error) {
+	query := `
+		SELECT o.buyer_id, u.full_name, COUNT(*), COALESCE(SUM(o.total_amount), 0) as total_spent
+		FROM orders o
+		INNER JOIN users u ON o.buyer_id = u.id
+		LEFT JOIN transactions t ON o.transaction_id = t.id
+		WHERE o.seller_id = ? AND o.created_at >= ? AND o.created_at < ? AND ` + completedSale + `
+		GROUP BY o.buyer_id, u.full_name
+		ORDER BY total_spent DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, sellerID, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buyers []*BuyerSales
+	for rows.Next() {
+		var b BuyerSales
+		if err := rows.Scan(&b.BuyerID, &b.BuyerName, &b.Orders, &b.TotalSpent); err != nil {
+			return nil, err
+		}
+		buyers = append(buyers, &b)
+	}
+
+	return buyers, nil
+}
+
+// GetLowStockProducts lists a seller's active limited-stock products at or below threshold
+func (r *Repository) GetLowStockProducts(ctx context.Context, sellerID uint, threshold int) ([]*Product, error) {
+	query := `
+		SELECT id, seller_id, name, price, stock, sold_count
+		FROM products
+		WHERE seller_id = ? AND is_active = TRUE AND is_unlimited = FALSE
+			AND stock IS NOT NULL AND stock <= ? AND deleted_at IS NULL
+		ORDER BY stock ASC, name ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, sellerID, threshold)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*Product
+	for rows.Next() {
+		var p Product
+		if err := rows.Scan(&p.ID, &p.SellerID, &p.Name, &p.Price, &p.Stock, &p.SoldCount); err != nil {
+			return nil, err
+		}
+		products = append(products, &p)
+	}
+
+	return products, nil
+}
+
+// Stock reservation operations
+
+func (r *Repository) CreateReservation(ctx context.Context, tx *sql.Tx, rs *StockReservation) error {
+	query := `
+		INSERT INTO stock_reservations (product_id, user_id, quantity, status, expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, NOW(), NOW())
+	`
+
+	result, err := tx.ExecContext(ctx, query, rs.ProductID, rs.UserID, rs.Quantity, rs.Status, rs.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	rs.ID = uint(id)
+	return nil
+}
+
+// GetReservationByIDForUpdate locks a reservation row
+func (r *Repository) GetReservationByIDForUpdate(ctx context.Context, tx *sql.Tx, id uint) (*StockReservation, error) {
+	query := `
+		SELECT id, product_id, user_id, quantity, status, order_id, expires_at, released_at, created_at, updated_at
+		FROM stock_reservations WHERE id = ?
+		FOR UPDATE
+	`
+
+	var rs StockReservation
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&rs.ID, &rs.ProductID, &rs.UserID, &rs.Quantity, &rs.Status, &rs.OrderID,
+		&rs.ExpiresAt, &rs.ReleasedAt, &rs.CreatedAt, &rs.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &rs, nil
+}
+
+// HasActiveReservation reports whether the user already holds stock of the product
+func (r *Repository) HasActiveReservation(ctx context.Context, tx *sql.Tx, productID, userID uint) (bool, error) {
+	query := `
+		SELECT COUNT(*) FROM stock_reservations
+		WHERE product_id = ? AND user_id = ? AND status = 'ACTIVE' AND expires_at > NOW()
+	`
+
+	var count int
+	if err := tx.QueryRowContext(ctx, query, productID, userID).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetActiveReservationsByUserID lists a user's unexpired reservations with product names
+func (r *Repository) GetActiveReservationsByUserID(ctx context.Context, userID uint) ([]*StockReservation, []string, error) {
+	query := `
+		SELECT sr.id, sr.product_id, sr.user_id, sr.quantity, sr.status, sr.order_id, sr.expires_at,
+			sr.released_at, sr.created_at, sr.updated_at, p.name
+		FROM stock_reservations sr
+		INNER JOIN products p ON sr.product_id = p.id
+		WHERE sr.user_id = ? AND sr.status = 'ACTIVE' AND sr.expires_at > NOW()
+		ORDER BY sr.expires_at ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var reservations []*StockReservation
+	var names []string
+	for rows.Next() {
+		var rs StockReservation
+		var name string
+		if err := rows.Scan(
+			&rs.ID, &rs.ProductID, &rs.UserID, &rs.Quantity, &rs.Status, &rs.OrderID, &rs.ExpiresAt,
+			&rs.ReleasedAt, &rs.CreatedAt, &rs.UpdatedAt, &name,
+		); err != nil {
+			return nil, nil, err
+		}
+		reservations = append(reservations, &rs)
+		names = append(names, name)
+	}
+
+	return reservations, names, nil
+}
+
+// GetExpiredReservationsForUpdate locks a batch of lapsed active reservations,
+// skipping rows another sweeper or checkout already holds
+func (r *Repository) GetExpiredReservationsForUpdate(ctx context.Context, tx *sql.Tx, limit int) ([]*StockReservation, error) {
+	query := `
+		SELECT id, product_id, user_id, quantity, status, order_id, expires_at, released_at, created_at, updated_at
+		FROM stock_reservations
+		WHERE status = 'ACTIVE' AND expires_at <= NOW()
+		ORDER BY id
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reservations []*StockReservation
+	for rows.Next() {
+		var rs StockReservation
+		if err := rows.Scan(
+			&rs.ID, &rs.ProductID, &rs.UserID, &rs.Quantity, &rs.Status, &rs.OrderID,
+			&rs.ExpiresAt, &rs.ReleasedAt, &rs.CreatedAt, &rs.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, &rs)
+	}
+
+	return reservations, rows.Err()
+}
+
+func (r *Repository) UpdateReservationStatus(ctx context.Context, tx *sql.Tx, id uint, status string, orderID sql.NullInt64) error {
+	query := `
+		UPDATE stock_reservations
+		SET status = ?, order_id = ?, released_at = IF(? IN ('RELEASED', 'EXPIRED'), NOW(), released_at), updated_at = NOW()
+		WHERE id = ?
+	`
+	_, err := tx.ExecContext(ctx, query, status, orderID, status, id)
+	return err
+}
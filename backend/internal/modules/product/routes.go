@@ -10,19 +10,51 @@ func RegisterRoutes(app fiber.Router, handler *Handler, jwtManager *middleware.J
 	// Products - public listing
 	products := app.Group("/products")
 	products.Get("", handler.GetActiveProducts)
+	products.Get("/featured", handler.GetFeaturedProducts)
+
+	// Seller views - registered before /:id so they are not shadowed by it
+	products.Get("/my", middleware.JWTMiddleware(jwtManager), middleware.RequireDosen(), handler.GetMyProducts)
+	products.Get("/my/analytics", middleware.JWTMiddleware(jwtManager), middleware.RequireDosen(), handler.GetSellerAnalytics)
+
 	products.Get("/:id", handler.GetProductByID)
+	products.Get("/:id/reviews", handler.GetProductReviews)
+
+	// Categories - public listing
+	app.Get("/categories", handler.GetCategories)
 
 	// Protected product routes
 	productsAuth := products.Group("", middleware.JWTMiddleware(jwtManager))
 
 	// Dosen only - create/update/delete products
 	productsAuth.Post("", middleware.RequireDosen(), handler.CreateProduct)
-	productsAuth.Get("/my", middleware.RequireDosen(), handler.GetMyProducts)
 	productsAuth.Put("/:id", middleware.RequireDosen(), handler.UpdateProduct)
 	productsAuth.Delete("/:id", middleware.RequireDosen(), handler.DeleteProduct)
 
+	// Reviews - verified buyers review, sellers reply
+	productsAuth.Post("/:id/reviews", handler.CreateReview)
+	reviews := app.Group("/reviews", middleware.JWTMiddleware(jwtManager))
+	reviews.Put("/:id", handler.UpdateReview)
+	reviews.Post("/:id/reply", middleware.RequireDosen(), handler.ReplyReview)
+
 	// Orders - authenticated only
 	orders := app.Group("/orders", middleware.JWTMiddleware(jwtManager))
 	orders.Get("", handler.GetMyOrders)
 	orders.Post("", handler.CreateOrder)
+	orders.Post("/preview", handler.PreviewOrder)
+	orders.Get("/sales", middleware.RequireDosen(), handler.GetMySales)
+
+	// Stock reservations - hold stock during checkout
+	orders.Get("/reservations", handler.GetMyReservations)
+	orders.Post("/reservations", handler.ReserveStock)
+	orders.Delete("/reservations/:id", handler.ReleaseReservation)
+
+	// Admin - catalog management
+	admin := app.Group("/admin", middleware.JWTMiddleware(jwtManager), middleware.RequireAdmin())
+	admin.Get("/categories", handler.GetAllCategories)
+	admin.Post("/categories", handler.CreateCategory)
+	admin.Put("/categories/:id", handler.UpdateCategory)
+	admin.Delete("/categories/:id", handler.DeleteCategory)
+	admin.Put("/products/:id/featured", handler.SetFeatured)
+	admin.Get("/reviews", handler.GetAllReviews)
+	admin.Put("/reviews/:id/visibility", handler.ModerateReview)
 }
@@ -3,7 +3,11 @@ package product
 import (
 	"context"
 	"database/sql"
+	"log"
+	"time"
 
+	"walletpoint/internal/config"
+	"walletpoint/internal/modules/voucher"
 	"walletpoint/internal/modules/wallet"
 	"walletpoint/internal/shared/constants"
 	apperrors "walletpoint/internal/shared/errors"
@@ -11,16 +15,20 @@ import (
 )
 
 type Service struct {
-	repo       *Repository
-	walletRepo *wallet.Repository
-	db         *sql.DB
+	repo        *Repository
+	walletRepo  *wallet.Repository
+	voucherRepo *voucher.Repository
+	db          *sql.DB
+	config      config.MarketConfig
 }
 
-func NewService(repo *Repository, walletRepo *wallet.Repository, db *sql.DB) *Service {
+func NewService(repo *Repository, walletRepo *wallet.Repository, voucherRepo *voucher.Repository, db *sql.DB, cfg config.MarketConfig) *Service {
 	return &Service{
-		repo:       repo,
-		walletRepo: walletRepo,
-		db:         db,
+		repo:        repo,
+		walletRepo:  walletRepo,
+		voucherRepo: voucherRepo,
+		db:          db,
+		config:      cfg,
 	}
 }
 
@@ -38,6 +46,12 @@ func (s *Service) CreateProduct(ctx context.Context, req CreateProductRequest, s
 	if req.Stock != nil {
 		p.Stock = sql.NullInt64{Int64: int64(*req.Stock), Valid: true}
 	}
+	if req.CategoryID != nil {
+		if err := s.ensureCategoryExists(ctx, *req.CategoryID); err != nil {
+			return nil, err
+		}
+		p.CategoryID = sql.NullInt64{Int64: int64(*req.CategoryID), Valid: true}
+	}
 	if req.ThumbnailURL != "" {
 		p.ThumbnailURL = sql.NullString{String: req.ThumbnailURL, Valid: true}
 	}
@@ -66,9 +80,9 @@ func (s *Service) GetProductByID(ctx context.Context, id uint) (*ProductResponse
 	return &resp, nil
 }
 
-func (s *Service) GetActiveProducts(ctx context.Context, page, perPage int) ([]*ProductResponse, int, error) {
+func (s *Service) GetActiveProducts(ctx context.Context, filter ProductFilter, page, perPage int) ([]*ProductResponse, int, error) {
 	offset := (page - 1) * perPage
-	products, total, err := s.repo.GetActiveProducts(ctx, perPage, offset)
+	products, total, err := s.repo.GetActiveProducts(ctx, filter, perPage, offset)
 	if err != nil {
 		return nil, 0, apperrors.Wrap(err, "DB_ERROR", "Failed to get products")
 	}
@@ -76,12 +90,36 @@ func (s *Service) GetActiveProducts(ctx context.Context, page, perPage int) ([]*
 	var responses []*ProductResponse
 	for _, p := range products {
 		resp := ToProductResponse(&p.Product, p.SellerName)
+		if p.CategoryName.Valid {
+			resp.CategoryName = p.CategoryName.String
+		}
 		responses = append(responses, &resp)
 	}
 
 	return responses, total, nil
 }
 
+func (s *Service) GetFeaturedProducts(ctx context.Context, limit int) ([]*ProductResponse, error) {
+	responses, _, err := s.GetActiveProducts(ctx, ProductFilter{Featured: true, Sort: SortBestSeller}, 1, limit)
+	return responses, err
+}
+
+// SetFeatured marks or unmarks a product as featured (admin)
+func (s *Service) SetFeatured(ctx context.Context, id uint, featured bool) error {
+	p, err := s.repo.GetProductByID(ctx, id)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to get product")
+	}
+	if p == nil {
+		return apperrors.New("PRODUCT_NOT_FOUND", "Product not found")
+	}
+
+	if err := s.repo.SetFeatured(ctx, id, featured); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to update product")
+	}
+	return nil
+}
+
 func (s *Service) GetMyProducts(ctx context.Context, sellerID uint, page, perPage int) ([]*ProductResponse, int, error) {
 	offset := (page - 1) * perPage
 	products, total, err := s.repo.GetProductsBySellerID(ctx, sellerID, perPage, offset)
@@ -116,6 +154,16 @@ func (s *Service) UpdateProduct(ctx context.Context, id uint, req UpdateProductR
 	if req.Description != nil {
 		p.Description = sql.NullString{String: *req.Description, Valid: true}
 	}
+	if req.CategoryID != nil {
+		if *req.CategoryID == 0 {
+			p.CategoryID = sql.NullInt64{}
+		} else {
+			if err := s.ensureCategoryExists(ctx, *req.CategoryID); err != nil {
+				return err
+			}
+			p.CategoryID = sql.NullInt64{Int64: int64(*req.CategoryID), Valid: true}
+		}
+	}
 	if req.Price != nil {
 		p.Price = *req.Price
 	}
@@ -153,10 +201,175 @@ func (s *Service) DeleteProduct(ctx context.Context, id uint, userID uint) error
 	return s.repo.DeleteProduct(ctx, id)
 }
 
+// Category operations
+func (s *Service) GetCategories(ctx context.Context, includeInactive bool) ([]*CategoryResponse, error) {
+	categories, err := s.repo.GetCategories(ctx, includeInactive)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get categories")
+	}
+
+	var responses []*CategoryResponse
+	for _, c := range categories {
+		resp := ToCategoryResponse(c)
+		responses = append(responses, &resp)
+	}
+
+	return responses, nil
+}
+
+func (s *Service) CreateCategory(ctx context.Context, req CreateCategoryRequest) (*CategoryResponse, error) {
+	existing, err := s.repo.GetCategoryBySlug(ctx, req.Slug)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to check category")
+	}
+	if existing != nil {
+		return nil, apperrors.New("CATEGORY_EXISTS", "Category slug already exists")
+	}
+
+	c := &Category{
+		Name:        req.Name,
+		Slug:        req.Slug,
+		Description: sql.NullString{String: req.Description, Valid: req.Description != ""},
+		SortOrder:   req.SortOrder,
+		IsActive:    true,
+	}
+
+	if req.ParentID != nil {
+		if err := s.ensureCategoryExists(ctx, *req.ParentID); err != nil {
+			return nil, err
+		}
+		c.ParentID = sql.NullInt64{Int64: int64(*req.ParentID), Valid: true}
+	}
+
+	if err := s.repo.CreateCategory(ctx, c); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to create category")
+	}
+
+	resp := ToCategoryResponse(c)
+	return &resp, nil
+}
+
+func (s *Service) UpdateCategory(ctx context.Context, id uint, req UpdateCategoryRequest) error {
+	c, err := s.repo.GetCategoryByID(ctx, id)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to get category")
+	}
+	if c == nil {
+		return apperrors.New("CATEGORY_NOT_FOUND", "Category not found")
+	}
+
+	if req.Name != nil {
+		c.Name = *req.Name
+	}
+	if req.Slug != nil && *req.Slug != c.Slug {
+		existing, err := s.repo.GetCategoryBySlug(ctx, *req.Slug)
+		if err != nil {
+			return apperrors.Wrap(err, "DB_ERROR", "Failed to check category")
+		}
+		if existing != nil {
+			return apperrors.New("CATEGORY_EXISTS", "Category slug already exists")
+		}
+		c.Slug = *req.Slug
+	}
+	if req.Description != nil {
+		c.Description = sql.NullString{String: *req.Description, Valid: *req.Description != ""}
+	}
+	if req.ParentID != nil {
+		switch {
+		case *req.ParentID == 0:
+			c.ParentID = sql.NullInt64{}
+		case *req.ParentID == id:
+			return apperrors.New("INVALID_CATEGORY", "Category cannot be its own parent")
+		default:
+			if err := s.ensureNotDescendant(ctx, id, *req.ParentID); err != nil {
+				return err
+			}
+			c.ParentID = sql.NullInt64{Int64: int64(*req.ParentID), Valid: true}
+		}
+	}
+	if req.SortOrder != nil {
+		c.SortOrder = *req.SortOrder
+	}
+	if req.IsActive != nil {
+		c.IsActive = *req.IsActive
+	}
+
+	if err := s.repo.UpdateCategory(ctx, c); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to update category")
+	}
+	return nil
+}
+
+func (s *Service) DeleteCategory(ctx context.Context, id uint) error {
+	if err := s.ensureCategoryExists(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteCategory(ctx, id); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to delete category")
+	}
+	return nil
+}
+
+// ensureNotDescendant checks that parentID exists and that id is none of its
+// ancestors, since moving a category under its own subtree makes a cycle
+func (s *Service) ensureNotDescendant(ctx context.Context, id, parentID uint) error {
+	seen := map[uint]bool{}
+	for next := parentID; next != 0 && !seen[next]; {
+		seen[next] = true
+		c, err := s.repo.GetCategoryByID(ctx, next)
+		if err != nil {
+			return apperrors.Wrap(err, "DB_ERROR", "Failed to get category")
+		}
+		if c == nil {
+			if next == parentID {
+				return apperrors.New("CATEGORY_NOT_FOUND", "Category not found")
+			}
+			break
+		}
+		if c.ParentID.Valid && c.ParentID.Int64 == int64(id) {
+			return apperrors.New("INVALID_CATEGORY", "Category cannot be moved under its own subcategory")
+		}
+		next = uint(c.ParentID.Int64)
+	}
+	return nil
+}
+
+func (s *Service) ensureCategoryExists(ctx context.Context, id uint) error {
+	c, err := s.repo.GetCategoryByID(ctx, id)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to get category")
+	}
+	if c == nil {
+		return apperrors.New("CATEGORY_NOT_FOUND", "Category not found")
+	}
+	return nil
+}
+
 // Order operations
 func (s *Service) CreateOrder(ctx context.Context, req CreateOrderRequest, buyerID uint) (*OrderResponse, error) {
-	// Get product
-	product, err := s.repo.GetProductByID(ctx, req.ProductID)
+	// Start transaction
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	// Lock the reservation before the product, matching the sweeper's lock order
+	var reservation *StockReservation
+	if req.ReservationID != 0 {
+		reservation, err = s.repo.GetReservationByIDForUpdate(ctx, tx, req.ReservationID)
+		if err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get reservation")
+		}
+		if err := checkReservation(reservation, buyerID, req.ProductID); err != nil {
+			return nil, err
+		}
+		req.Quantity = reservation.Quantity
+	}
+
+	// Lock product row so the stock check holds until commit
+	product, err := s.repo.GetProductByIDForUpdate(ctx, tx, req.ProductID)
 	if err != nil {
 		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get product")
 	}
@@ -172,19 +385,34 @@ func (s *Service) CreateOrder(ctx context.Context, req CreateOrderRequest, buyer
 		return nil, apperrors.New("CANNOT_BUY_OWN", "Cannot buy your own product")
 	}
 
-	// Check stock
-	if !product.IsUnlimited && product.Stock.Valid && product.Stock.Int64 < int64(req.Quantity) {
+	// Check stock (reserved units were already taken out of stock)
+	if reservation == nil && !product.IsUnlimited && product.Stock.Int64 < int64(req.Quantity) {
 		return nil, apperrors.New("OUT_OF_STOCK", "Product is out of stock")
 	}
 
 	totalPrice := product.Price * int64(req.Quantity)
 
-	// Start transaction
-	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
-	if err != nil {
-		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	// Apply voucher (row is locked until commit so usage caps hold under concurrency)
+	var appliedVoucher *voucher.Voucher
+	discountAmount := int64(0)
+	if req.VoucherCode != "" {
+		appliedVoucher, err = s.voucherRepo.GetByCodeForUpdate(ctx, tx, req.VoucherCode)
+		if err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get voucher")
+		}
+		if appliedVoucher == nil {
+			return nil, apperrors.New("VOUCHER_NOT_FOUND", "Voucher not found")
+		}
+		used, err := s.voucherRepo.CountUserUsages(ctx, tx, appliedVoucher.ID, buyerID)
+		if err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to check voucher usage")
+		}
+		discountAmount, err = voucher.Evaluate(appliedVoucher, product.SellerID, product.ID, totalPrice, used, time.Now())
+		if err != nil {
+			return nil, err
+		}
 	}
-	defer tx.Rollback()
+	finalPrice := totalPrice - discountAmount
 
 	// Lock buyer wallet
 	buyerWallet, err := s.walletRepo.GetByUserIDForUpdate(ctx, tx, buyerID)
@@ -197,7 +425,7 @@ func (s *Service) CreateOrder(ctx context.Context, req CreateOrderRequest, buyer
 	if buyerWallet.IsFrozen {
 		return nil, apperrors.ErrWalletFrozen
 	}
-	if buyerWallet.Balance < totalPrice {
+	if buyerWallet.Balance < finalPrice {
 		return nil, apperrors.ErrInsufficientBalance
 	}
 
@@ -210,6 +438,26 @@ func (s *Service) CreateOrder(ctx context.Context, req CreateOrderRequest, buyer
 		return nil, apperrors.New("SELLER_WALLET_NOT_FOUND", "Seller wallet not found")
 	}
 
+	// A voucher the seller did not create is paid for by its creator, so an
+	// admin promotion never comes out of the sellers' pockets
+	var sponsorWallet *wallet.Wallet
+	if appliedVoucher != nil && discountAmount > 0 && appliedVoucher.CreatorID != product.SellerID {
+		sponsorWallet, err = s.walletRepo.GetByUserIDForUpdate(ctx, tx, appliedVoucher.CreatorID)
+		if err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to lock voucher sponsor wallet")
+		}
+		available := int64(0)
+		if sponsorWallet != nil && !sponsorWallet.IsFrozen {
+			available = sponsorWallet.Balance
+			if sponsorWallet.ID == buyerWallet.ID {
+				available -= finalPrice
+			}
+		}
+		if available < discountAmount {
+			return nil, apperrors.New("VOUCHER_UNFUNDED", "Voucher is not available right now")
+		}
+	}
+
 	// Generate order code
 	orderCode := utils.GenerateTransactionCode("ORD")
 	txCode := utils.GenerateTransactionCode("TRX")
@@ -222,9 +470,9 @@ func (s *Service) CreateOrder(ctx context.Context, req CreateOrderRequest, buyer
 		Status:          constants.TxStatusCompleted,
 		FromWalletID:    sql.NullInt64{Int64: int64(buyerWallet.ID), Valid: true},
 		ToWalletID:      sql.NullInt64{Int64: int64(sellerWallet.ID), Valid: true},
-		Amount:          totalPrice,
+		Amount:          finalPrice,
 		FeeAmount:       0,
-		NetAmount:       totalPrice,
+		NetAmount:       finalPrice,
 		Description:     sql.NullString{String: "Purchase: " + product.Name, Valid: true},
 	}
 
@@ -241,31 +489,97 @@ func (s *Service) CreateOrder(ctx context.Context, req CreateOrderRequest, buyer
 		Quantity:       req.Quantity,
 		UnitPrice:      product.Price,
 		TotalPrice:     totalPrice,
-		DiscountAmount: 0,
-		FinalPrice:     totalPrice,
+		DiscountAmount: discountAmount,
+		FinalPrice:     finalPrice,
 		Status:         constants.OrderStatusCompleted,
 		TransactionID:  sql.NullInt64{Int64: int64(transaction.ID), Valid: true},
 	}
+	if appliedVoucher != nil {
+		order.VoucherID = sql.NullInt64{Int64: int64(appliedVoucher.ID), Valid: true}
+	}
 
 	if err := s.repo.CreateOrder(ctx, tx, order); err != nil {
 		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to create order")
 	}
 
+	item := &OrderItem{
+		OrderID:     order.ID,
+		ProductID:   product.ID,
+		ProductName: product.Name,
+		Quantity:    req.Quantity,
+		UnitPrice:   product.Price,
+		Subtotal:    totalPrice,
+	}
+	if err := s.repo.CreateOrderItem(ctx, tx, item); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to create order item")
+	}
+
+	// Record voucher usage
+	if appliedVoucher != nil {
+		usage := &voucher.VoucherUsage{
+			VoucherID:      appliedVoucher.ID,
+			UserID:         buyerID,
+			OrderID:        order.ID,
+			DiscountAmount: discountAmount,
+		}
+		if err := s.voucherRepo.RecordUsage(ctx, tx, usage); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to record voucher usage")
+		}
+	}
+
 	// Debit buyer
-	if err := s.walletRepo.UpdateBalanceWithStats(ctx, tx, buyerWallet.ID, totalPrice, false); err != nil {
+	if err := s.walletRepo.UpdateBalanceWithStats(ctx, tx, buyerWallet.ID, finalPrice, false); err != nil {
 		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to debit buyer")
 	}
 
-	// Credit seller
-	if err := s.walletRepo.UpdateBalanceWithStats(ctx, tx, sellerWallet.ID, totalPrice, true); err != nil {
+	// Credit seller; the seller absorbs the discount of their own vouchers
+	if err := s.walletRepo.UpdateBalanceWithStats(ctx, tx, sellerWallet.ID, finalPrice, true); err != nil {
 		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to credit seller")
 	}
 
-	// Decrement stock
-	if !product.IsUnlimited {
+	// The sponsor of any other voucher makes up the discount
+	if sponsorWallet != nil {
+		sponsorTx := &wallet.Transaction{
+			TransactionCode: utils.GenerateTransactionCode("TRX"),
+			IdempotencyKey:  orderCode + "-VOUCHER",
+			TransactionType: constants.TxTypePurchase,
+			Status:          constants.TxStatusCompleted,
+			FromWalletID:    sql.NullInt64{Int64: int64(sponsorWallet.ID), Valid: true},
+			ToWalletID:      sql.NullInt64{Int64: int64(sellerWallet.ID), Valid: true},
+			Amount:          discountAmount,
+			FeeAmount:       0,
+			NetAmount:       discountAmount,
+			Description:     sql.NullString{String: "Voucher " + appliedVoucher.Code + ": " + product.Name, Valid: true},
+		}
+		if err := s.walletRepo.CreateTransaction(ctx, tx, sponsorTx); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to create voucher transaction")
+		}
+		if err := s.walletRepo.UpdateBalanceWithStats(ctx, tx, sponsorWallet.ID, discountAmount, false); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to debit voucher sponsor")
+		}
+		if err := s.walletRepo.UpdateBalanceWithStats(ctx, tx, sellerWallet.ID, discountAmount, true); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to credit seller")
+		}
+	}
+
+	// Decrement stock, or settle the reservation that already holds it
+	if reservation != nil {
+		orderID := sql.NullInt64{Int64: int64(order.ID), Valid: true}
+		if err := s.repo.UpdateReservationStatus(ctx, tx, reservation.ID, constants.ReservationStatusConsumed, orderID); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to consume reservation")
+		}
+		if err := s.repo.AddSoldCount(ctx, tx, product.ID, req.Quantity); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to update sold count")
+		}
+	} else if !product.IsUnlimited {
 		if err := s.repo.DecrementStock(ctx, tx, product.ID, req.Quantity); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, apperrors.New("OUT_OF_STOCK", "Product is out of stock")
+			}
 			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to decrement stock")
 		}
+	} else if err := s.repo.AddSoldCount(ctx, tx, product.ID, req.Quantity); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to update sold count")
 	}
 
 	// Commit
@@ -277,6 +591,52 @@ func (s *Service) CreateOrder(ctx context.Context, req CreateOrderRequest, buyer
 	return &resp, nil
 }
 
+// PreviewOrder computes the checkout price, including any voucher discount, without placing the order
+func (s *Service) PreviewOrder(ctx context.Context, req CreateOrderRequest, buyerID uint) (*CheckoutPreviewResponse, error) {
+	product, err := s.repo.GetProductByID(ctx, req.ProductID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get product")
+	}
+	if product == nil {
+		return nil, apperrors.New("PRODUCT_NOT_FOUND", "Product not found")
+	}
+	if !product.IsActive {
+		return nil, apperrors.New("PRODUCT_NOT_ACTIVE", "Product is not available")
+	}
+
+	totalPrice := product.Price * int64(req.Quantity)
+	preview := &CheckoutPreviewResponse{
+		ProductID:  product.ID,
+		Quantity:   req.Quantity,
+		UnitPrice:  product.Price,
+		TotalPrice: totalPrice,
+		FinalPrice: totalPrice,
+	}
+
+	if req.VoucherCode != "" {
+		v, err := s.voucherRepo.GetByCode(ctx, req.VoucherCode)
+		if err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get voucher")
+		}
+		if v == nil {
+			return nil, apperrors.New("VOUCHER_NOT_FOUND", "Voucher not found")
+		}
+		used, err := s.voucherRepo.CountUserUsages(ctx, nil, v.ID, buyerID)
+		if err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to check voucher usage")
+		}
+		discount, err := voucher.Evaluate(v, product.SellerID, product.ID, totalPrice, used, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		preview.DiscountAmount = discount
+		preview.FinalPrice = totalPrice - discount
+		preview.VoucherCode = v.Code
+	}
+
+	return preview, nil
+}
+
 func (s *Service) GetMyOrders(ctx context.Context, buyerID uint, page, perPage int) ([]*OrderResponse, int, error) {
 	offset := (page - 1) * perPage
 	orders, total, err := s.repo.GetOrdersByBuyerID(ctx, buyerID, perPage, offset)
@@ -292,3 +652,428 @@ func (s *Service) GetMyOrders(ctx context.Context, buyerID uint, page, perPage i
 
 	return responses, total, nil
 }
+
+// Review operations
+
+func (s *Service) CreateReview(ctx context.Context, productID uint, req CreateReviewRequest, userID uint) (*ReviewResponse, error) {
+	product, err := s.repo.GetProductByID(ctx, productID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get product")
+	}
+	if product == nil {
+		return nil, apperrors.New("PRODUCT_NOT_FOUND", "Product not found")
+	}
+
+	orderID, err := s.repo.GetCompletedOrderForProduct(ctx, userID, productID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to check purchase")
+	}
+	if orderID == 0 {
+		return nil, apperrors.New("NOT_VERIFIED_BUYER", "Only buyers with a completed order can review this product")
+	}
+
+	existing, err := s.repo.GetReviewByProductAndUser(ctx, productID, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to check review")
+	}
+	if existing != nil {
+		return nil, apperrors.New("REVIEW_EXISTS", "You have already reviewed this product")
+	}
+
+	review := &Review{
+		ProductID: productID,
+		UserID:    userID,
+		OrderID:   orderID,
+		Rating:    req.Rating,
+		Comment:   sql.NullString{String: req.Comment, Valid: req.Comment != ""},
+	}
+
+	if err := s.repo.CreateReview(ctx, review); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to create review")
+	}
+
+	review, err = s.repo.GetReviewByID(ctx, review.ID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get review")
+	}
+
+	resp := ToReviewResponse(review, "", product.Name)
+	return &resp, nil
+}
+
+func (s *Service) UpdateReview(ctx context.Context, id uint, req CreateReviewRequest, userID uint) error {
+	review, err := s.getReview(ctx, id)
+	if err != nil {
+		return err
+	}
+	if review.UserID != userID {
+		return apperrors.ErrForbidden
+	}
+
+	review.Rating = req.Rating
+	review.Comment = sql.NullString{String: req.Comment, Valid: req.Comment != ""}
+
+	if err := s.repo.UpdateReview(ctx, review); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to update review")
+	}
+	return nil
+}
+
+func (s *Service) ReplyReview(ctx context.Context, id uint, req ReplyReviewRequest, sellerID uint) error {
+	review, err := s.getReview(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	product, err := s.repo.GetProductByID(ctx, review.ProductID)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to get product")
+	}
+	if product == nil || product.SellerID != sellerID {
+		return apperrors.ErrForbidden
+	}
+
+	if err := s.repo.ReplyReview(ctx, id, req.Reply); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to reply review")
+	}
+	return nil
+}
+
+func (s *Service) ModerateReview(ctx context.Context, id uint, req ModerateReviewRequest, adminID uint) error {
+	review, err := s.getReview(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.SetReviewHidden(ctx, review, req.IsHidden, req.Reason, adminID); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to moderate review")
+	}
+	return nil
+}
+
+// GetReviews lists reviews; productID 0 lists reviews across all products
+func (s *Service) GetReviews(ctx context.Context, productID uint, includeHidden bool, page, perPage int) ([]*ReviewResponse, int, error) {
+	offset := (page - 1) * perPage
+	reviews, total, err := s.repo.GetReviews(ctx, productID, includeHidden, perPage, offset)
+	if err != nil {
+		return nil, 0, apperrors.Wrap(err, "DB_ERROR", "Failed to get reviews")
+	}
+
+	var responses []*ReviewResponse
+	for _, r := range reviews {
+		resp := ToReviewResponse(&r.Review, r.UserName, r.ProductName)
+		responses = append(responses, &resp)
+	}
+
+	return responses, total, nil
+}
+
+func (s *Service) getReview(ctx context.Context, id uint) (*Review, error) {
+	review, err := s.repo.GetReviewByID(ctx, id)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get review")
+	}
+	if review == nil {
+		return nil, apperrors.New("REVIEW_NOT_FOUND", "Review not found")
+	}
+	return review, nil
+}
+
+// Seller analytics
+
+func (s *Service) GetMySales(ctx context.Context, sellerID uint, page, perPage int) ([]*OrderResponse, int, error) {
+	offset := (page - 1) * perPage
+	orders, total, err := s.repo.GetOrdersBySellerID(ctx, sellerID, perPage, offset)
+	if err != nil {
+		return nil, 0, apperrors.Wrap(err, "DB_ERROR", "Failed to get sales")
+	}
+
+	var responses []*OrderResponse
+	for _, o := range orders {
+		resp := ToOrderResponse(&o.Order, o.ProductName, o.SellerName)
+		resp.BuyerName = o.BuyerName
+		responses = append(responses, &resp)
+	}
+
+	return responses, total, nil
+}
+
+func (s *Service) GetSellerAnalytics(ctx context.Context, sellerID uint, q SalesAnalyticsQuery) (*SellerAnalyticsResponse, error) {
+	from, to := q.Range()
+
+	summary, err := s.repo.GetSalesSummary(ctx, sellerID, from, to)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get sales summary")
+	}
+
+	points, err := s.repo.GetRevenueByPeriod(ctx, sellerID, from, to, periodFormats[q.Period])
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get revenue")
+	}
+
+	productSales, err := s.repo.GetProductSales(ctx, sellerID, from, to)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get product sales")
+	}
+
+	buyers, err := s.repo.GetTopBuyers(ctx, sellerID, from, to, q.TopBuyers)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get top buyers")
+	}
+
+	lowStock, err := s.repo.GetLowStockProducts(ctx, sellerID, q.LowStockLimit)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get stock warnings")
+	}
+
+	resp := &SellerAnalyticsResponse{
+		From:   from.Format("2006-01-02"),
+		To:     to.AddDate(0, 0, -1).Format("2006-01-02"),
+		Period: q.Period,
+		Summary: SalesSummaryResponse{
+			TotalOrders:     summary.TotalOrders,
+			CompletedOrders: summary.CompletedOrders,
+			CancelledOrders: summary.CancelledOrders,
+			RefundedOrders:  summary.RefundedOrders,
+			UnitsSold:       summary.UnitsSold,
+			GrossRevenue:    summary.GrossRevenue,
+			DiscountTotal:   summary.DiscountTotal,
+			NetRevenue:      summary.NetRevenue,
+		},
+		RevenueByPeriod:  []RevenuePointResponse{},
+		ProductSales:     []ProductSalesResponse{},
+		TopBuyers:        []BuyerSalesResponse{},
+		StockOutWarnings: []StockWarningResponse{},
+	}
+	if summary.TotalOrders > 0 {
+		resp.Summary.RefundRate = float64(summary.RefundedOrders) / float64(summary.TotalOrders)
+		resp.Summary.CancellationRate = float64(summary.CancelledOrders) / float64(summary.TotalOrders)
+	}
+
+	for _, p := range points {
+		resp.RevenueByPeriod = append(resp.RevenueByPeriod, RevenuePointResponse{
+			Period:  p.Period,
+			Orders:  p.Orders,
+			Revenue: p.Revenue,
+		})
+	}
+	for _, ps := range productSales {
+		resp.ProductSales = append(resp.ProductSales, ProductSalesResponse{
+			ProductID:   ps.ProductID,
+			ProductName: ps.ProductName,
+			UnitsSold:   ps.UnitsSold,
+			Revenue:     ps.Revenue,
+		})
+	}
+	for _, b := range buyers {
+		resp.TopBuyers = append(resp.TopBuyers, BuyerSalesResponse{
+			BuyerID:    b.BuyerID,
+			BuyerName:  b.BuyerName,
+			Orders:     b.Orders,
+			TotalSpent: b.TotalSpent,
+		})
+	}
+	for _, p := range lowStock {
+		resp.StockOutWarnings = append(resp.StockOutWarnings, StockWarningResponse{
+			ProductID:  p.ID,
+			Name:       p.Name,
+			Stock:      p.Stock.Int64,
+			SoldCount:  p.SoldCount,
+			OutOfStock: p.Stock.Int64 <= 0,
+		})
+	}
+
+	return resp, nil
+}
+
+// Stock reservations
+
+// ReserveStock holds stock for the buyer until checkout or until the reservation expires
+func (s *Service) ReserveStock(ctx context.Context, req ReserveStockRequest, userID uint) (*ReservationResponse, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	product, err := s.repo.GetProductByIDForUpdate(ctx, tx, req.ProductID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get product")
+	}
+	if product == nil {
+		return nil, apperrors.New("PRODUCT_NOT_FOUND", "Product not found")
+	}
+	if !product.IsActive {
+		return nil, apperrors.New("PRODUCT_NOT_ACTIVE", "Product is not available")
+	}
+	if product.SellerID == userID {
+		return nil, apperrors.New("CANNOT_BUY_OWN", "Cannot buy your own product")
+	}
+
+	exists, err := s.repo.HasActiveReservation(ctx, tx, product.ID, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to check reservation")
+	}
+	if exists {
+		return nil, apperrors.New("RESERVATION_EXISTS", "You already have an active reservation for this product")
+	}
+
+	if !product.IsUnlimited {
+		if err := s.repo.HoldStock(ctx, tx, product.ID, req.Quantity); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, apperrors.New("OUT_OF_STOCK", "Product is out of stock")
+			}
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to hold stock")
+		}
+	}
+
+	reservation := &StockReservation{
+		ProductID: product.ID,
+		UserID:    userID,
+		Quantity:  req.Quantity,
+		Status:    constants.ReservationStatusActive,
+		ExpiresAt: time.Now().Add(s.config.ReservationTTL),
+	}
+	if err := s.repo.CreateReservation(ctx, tx, reservation); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to create reservation")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to commit")
+	}
+
+	resp := ToReservationResponse(reservation, product.Name)
+	return &resp, nil
+}
+
+// ReleaseReservation returns the held stock of a reservation the buyer no longer needs
+func (s *Service) ReleaseReservation(ctx context.Context, id uint, userID uint) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	reservation, err := s.repo.GetReservationByIDForUpdate(ctx, tx, id)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to get reservation")
+	}
+	if reservation == nil {
+		return apperrors.New("RESERVATION_NOT_FOUND", "Reservation not found")
+	}
+	if reservation.UserID != userID {
+		return apperrors.ErrForbidden
+	}
+	if reservation.Status != constants.ReservationStatusActive {
+		return apperrors.New("RESERVATION_INACTIVE", "Reservation is no longer active")
+	}
+
+	if err := s.releaseReservation(ctx, tx, reservation, constants.ReservationStatusReleased); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to commit")
+	}
+	return nil
+}
+
+func (s *Service) GetMyReservations(ctx context.Context, userID uint) ([]*ReservationResponse, error) {
+	reservations, names, err := s.repo.GetActiveReservationsByUserID(ctx, userID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get reservations")
+	}
+
+	responses := []*ReservationResponse{}
+	for i, rs := range reservations {
+		resp := ToReservationResponse(rs, names[i])
+		responses = append(responses, &resp)
+	}
+
+	return responses, nil
+}
+
+// ReleaseExpiredReservations returns the stock of lapsed reservations and reports how many were released
+func (s *Service) ReleaseExpiredReservations(ctx context.Context) (int, error) {
+	const batchSize = 200
+
+	released := 0
+	for {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return released, apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+		}
+
+		reservations, err := s.repo.GetExpiredReservationsForUpdate(ctx, tx, batchSize)
+		if err != nil {
+			tx.Rollback()
+			return released, apperrors.Wrap(err, "DB_ERROR", "Failed to get expired reservations")
+		}
+
+		for _, rs := range reservations {
+			if err := s.releaseReservation(ctx, tx, rs, constants.ReservationStatusExpired); err != nil {
+				tx.Rollback()
+				return released, err
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return released, apperrors.Wrap(err, "DB_ERROR", "Failed to commit")
+		}
+
+		released += len(reservations)
+		if len(reservations) < batchSize {
+			return released, nil
+		}
+	}
+}
+
+// RunReservationSweeper releases expired reservations every interval until ctx is cancelled
+func (s *Service) RunReservationSweeper(ctx context.Context) {
+	ticker := time.NewTicker(s.config.ReservationSweep)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			released, err := s.ReleaseExpiredReservations(ctx)
+			if err != nil {
+				log.Printf("Reservation sweeper: %v", err)
+			} else if released > 0 {
+				log.Printf("Reservation sweeper: released %d expired reservations", released)
+			}
+		}
+	}
+}
+
+func (s *Service) releaseReservation(ctx context.Context, tx *sql.Tx, rs *StockReservation, status string) error {
+	if err := s.repo.RestockProduct(ctx, tx, rs.ProductID, rs.Quantity); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to restock product")
+	}
+	if err := s.repo.UpdateReservationStatus(ctx, tx, rs.ID, status, sql.NullInt64{}); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to release reservation")
+	}
+	return nil
+}
+
+// checkReservation verifies a locked reservation can pay for the given checkout
+func checkReservation(rs *StockReservation, buyerID, productID uint) error {
+	if rs == nil {
+		return apperrors.New("RESERVATION_NOT_FOUND", "Reservation not found")
+	}
+	if rs.UserID != buyerID {
+		return apperrors.ErrForbidden
+	}
+	if rs.ProductID != productID {
+		return apperrors.New("RESERVATION_MISMATCH", "Reservation does not belong to this product")
+	}
+	if rs.Status != constants.ReservationStatusActive {
+		return apperrors.New("RESERVATION_INACTIVE", "Reservation is no longer active")
+	}
+	if !rs.ExpiresAt.After(time.Now()) {
+		return apperrors.New("RESERVATION_EXPIRED", "Reservation has expired")
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"walletpoint/internal/config"
+	"walletpoint/internal/modules/voucher"
+	"walletpoint/internal/modules/wallet"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// The stock tests place real orders, so they only run against a disposable
+// database with all migrations applied:
+//
+//	TEST_DATABASE_DSN='root:secret@tcp(localhost:3306)/walletpoint_test?parseTime=true&loc=Local' go test ./internal/modules/product/
+const testDSNEnv = "TEST_DATABASE_DSN"
+
+const (
+	raceStock    = 10
+	raceAttempts = 40
+	racePrice    = 100
+)
+
+func TestConcurrentOrdersNeverOversell(t *testing.T) {
+	testStockRace(t, false)
+}
+
+func TestConcurrentReservationsNeverOversell(t *testing.T) {
+	testStockRace(t, true)
+}
+
+// testStockRace fires raceAttempts simultaneous purchases of one unit at a
+// product holding raceStock units, then checks no unit was sold twice and
+// none went missing
+func testStockRace(t *testing.T, reserve bool) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	sellerID, buyerIDs := createRaceUsers(t, db, raceAttempts)
+	productID := createRaceProduct(t, db, sellerID, raceStock)
+
+	repo := NewRepository(db)
+	service := NewService(repo, wallet.NewRepository(db), voucher.NewRepository(db), db, config.MarketConfig{ReservationTTL: time.Minute})
+
+	var (
+		mu    sync.Mutex
+		sold  int
+		wg    sync.WaitGroup
+		start = make(chan struct{})
+	)
+	for _, buyerID := range buyerIDs {
+		wg.Add(1)
+		go func(buyerID uint) {
+			defer wg.Done()
+			<-start
+			if err := racePurchase(ctx, service, productID, buyerID, reserve); err != nil {
+				return
+			}
+			mu.Lock()
+			sold++
+			mu.Unlock()
+		}(buyerID)
+	}
+
+	// Sample stock while the purchases run, so a transient negative value
+	// is caught and not just the final one
+	done := make(chan struct{})
+	lowest := make(chan int64, 1)
+	go func() {
+		min := int64(raceStock)
+		for {
+			select {
+			case <-done:
+				lowest <- min
+				return
+			default:
+			}
+			var stock int64
+			if err := db.QueryRow("SELECT stock FROM products WHERE id = ?", productID).Scan(&stock); err == nil && stock < min {
+				min = stock
+			}
+		}
+	}()
+
+	close(start)
+	wg.Wait()
+	close(done)
+
+	if min := <-lowest; min < 0 {
+		t.Errorf("stock went down to %d during the race", min)
+	}
+
+	after, err := repo.GetProductByID(ctx, productID)
+	if err != nil || after == nil {
+		t.Fatalf("reload product: %v", err)
+	}
+	if after.Stock.Int64 < 0 {
+		t.Errorf("stock = %d, want >= 0", after.Stock.Int64)
+	}
+	if sold == 0 {
+		t.Errorf("no purchase succeeded")
+	}
+	if got := int64(sold) + after.Stock.Int64; got != raceStock {
+		t.Errorf("sold %d + remaining %d = %d, want starting stock %d", sold, after.Stock.Int64, got, raceStock)
+	}
+	if after.SoldCount != sold {
+		t.Errorf("sold_count = %d, want %d", after.SoldCount, sold)
+	}
+
+	var units int
+	if err := db.QueryRow("SELECT COALESCE(SUM(quantity), 0) FROM order_items WHERE product_id = ?", productID).Scan(&units); err != nil {
+		t.Fatalf("count sold units: %v", err)
+	}
+	if units != sold {
+		t.Errorf("%d units in order items, want %d", units, sold)
+	}
+}
+
+// racePurchase places one order, optionally through a reservation; a
+// reservation whose checkout fails is released so its stock comes back
+func racePurchase(ctx context.Context, service *Service, productID, buyerID uint, reserve bool) error {
+	req := CreateOrderRequest{ProductID: productID, Quantity: 1}
+	if !reserve {
+		_, err := service.CreateOrder(ctx, req, buyerID)
+		return err
+	}
+
+	reservation, err := service.ReserveStock(ctx, ReserveStockRequest{ProductID: productID, Quantity: 1}, buyerID)
+	if err != nil {
+		return err
+	}
+	req.ReservationID = reservation.ID
+	if _, err := service.CreateOrder(ctx, req, buyerID); err != nil {
+		service.ReleaseReservation(ctx, reservation.ID, buyerID)
+		return err
+	}
+	return nil
+}
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", testDSNEnv)
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	db.SetMaxOpenConns(25)
+	if err := db.Ping(); err != nil {
+		t.Fatalf("ping database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// createRaceUsers adds a seller and funded buyers, removing them and
+// everything they own when the test ends
+func createRaceUsers(t *testing.T, db *sql.DB, buyers int) (uint, []uint) {
+	t.Helper()
+	prefix := fmt.Sprintf("race%d", time.Now().UnixNano())
+
+	var ids []uint
+	t.Cleanup(func() {
+		for _, id := range ids {
+			db.Exec("DELETE FROM transactions WHERE id IN (SELECT transaction_id FROM orders WHERE buyer_id = ?)", id)
+			db.Exec("DELETE FROM users WHERE id = ?", id)
+		}
+	})
+
+	for i := 0; i <= buyers; i++ {
+		username := fmt.Sprintf("%s_%d", prefix, i)
+		result, err := db.Exec(`
+			INSERT INTO users (username, email, password_hash, full_name, is_active)
+			VALUES (?, ?, '-', ?, TRUE)
+		`, username, username+"@test.local", username)
+		if err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		id, _ := result.LastInsertId()
+		ids = append(ids, uint(id))
+
+		// The insert trigger creates the wallet; fund it for the purchase
+		_, err = db.Exec(`
+			INSERT INTO wallets (user_id, balance) VALUES (?, ?)
+			ON DUPLICATE KEY UPDATE balance = VALUES(balance), is_frozen = FALSE
+		`, id, racePrice*10)
+		if err != nil {
+			t.Fatalf("fund wallet: %v", err)
+		}
+	}
+	return ids[0], ids[1:]
+}
+
+func createRaceProduct(t *testing.T, db *sql.DB, sellerID uint, stock int) uint {
+	t.Helper()
+	result, err := db.Exec(`
+		INSERT INTO products (seller_id, name, product_type, price, stock, sold_count, is_active)
+		VALUES (?, 'Stock race', 'OTHER', ?, ?, 0, TRUE)
+	`, sellerID, racePrice, stock)
+	if err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	return uint(id)
+}
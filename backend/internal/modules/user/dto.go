@@ -0,0 +1,279 @@
+package user
+
+import (
+	"strings"
+	"time"
+
+	"walletpoint/internal/modules/wallet"
+	"walletpoint/internal/shared/constants"
+)
+
+// Account statuses for filtering the user list
+const (
+	StatusActive   = "ACTIVE"
+	StatusInactive = "INACTIVE"
+	StatusDeleted  = "DELETED"
+)
+
+// UpdateUserRequest for editing a user's profile; omitted fields are kept
+type UpdateUserRequest struct {
+	FullName  *string `json:"full_name"`
+	Email     *string `json:"email"`
+	NimNip    *string `json:"nim_nip"`
+	Phone     *string `json:"phone"`
+	AvatarURL *string `json:"avatar_url"`
+}
+
+func (r *UpdateUserRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.FullName != nil && strings.TrimSpace(*r.FullName) == "" {
+		errors = append(errors, ValidationError{Field: "full_name", Message: "Full name must not be empty"})
+	}
+	if r.Email != nil && !strings.Contains(*r.Email, "@") {
+		errors = append(errors, ValidationError{Field: "email", Message: "Email is invalid"})
+	}
+	if r.NimNip != nil && len(strings.TrimSpace(*r.NimNip)) > 20 {
+		errors = append(errors, ValidationError{Field: "nim_nip", Message: "NIM/NIP must be at most 20 characters"})
+	}
+	return errors
+}
+
+// StatusChangeRequest for deactivating, deleting or restoring an account
+type StatusChangeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// UpdateRolesRequest replaces every role of a user
+type UpdateRolesRequest struct {
+	Roles []RoleInput `json:"roles"`
+}
+
+// RoleInput is one role to grant, optionally until a point in time
+type RoleInput struct {
+	Role      string  `json:"role"`
+	ExpiresAt *string `json:"expires_at"` // RFC3339; omitted for a permanent role
+}
+
+func (r *UpdateRolesRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if len(r.Roles) == 0 {
+		errors = append(errors, ValidationError{Field: "roles", Message: "At least one role is required"})
+	}
+	seen := map[string]bool{}
+	for _, role := range r.Roles {
+		if !validRoles[role.Role] {
+			errors = append(errors, ValidationError{Field: "roles", Message: "Role must be admin, dosen or mahasiswa"})
+			continue
+		}
+		if seen[role.Role] {
+			errors = append(errors, ValidationError{Field: "roles", Message: "Role " + role.Role + " is listed twice"})
+		}
+		seen[role.Role] = true
+		if role.ExpiresAt != nil {
+			t, err := time.Parse(time.RFC3339, *role.ExpiresAt)
+			if err != nil {
+				errors = append(errors, ValidationError{Field: "expires_at", Message: "Expiry must be an RFC3339 timestamp"})
+			} else if !t.After(time.Now()) {
+				errors = append(errors, ValidationError{Field: "expires_at", Message: "Expiry must be in the future"})
+			}
+		}
+	}
+	return errors
+}
+
+var validRoles = map[string]bool{constants.RoleAdmin: true, constants.RoleDosen: true, constants.RoleMahasiswa: true}
+
+// ValidationError for validation
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Actor identifies the administrator making a change, for the audit trail
+type Actor struct {
+	UserID    uint
+	IPAddress string
+	UserAgent string
+}
+
+// UserResponse for the admin user list
+type UserResponse struct {
+	ID              uint     `json:"id"`
+	Username        string   `json:"username"`
+	Email           string   `json:"email"`
+	FullName        string   `json:"full_name"`
+	NimNip          string   `json:"nim_nip,omitempty"`
+	Phone           string   `json:"phone,omitempty"`
+	AvatarURL       string   `json:"avatar_url,omitempty"`
+	Roles           []string `json:"roles"`
+	Status          string   `json:"status"`
+	EmailVerifiedAt string   `json:"email_verified_at,omitempty"`
+	LastLoginAt     string   `json:"last_login_at,omitempty"`
+	CreatedAt       string   `json:"created_at"`
+	DeletedAt       string   `json:"deleted_at,omitempty"`
+}
+
+// UserDetailResponse adds role history, wallet and session details
+type UserDetailResponse struct {
+	UserResponse
+	RoleAssignments []*RoleAssignmentResponse `json:"role_assignments"`
+	Wallet          *wallet.BalanceResponse   `json:"wallet,omitempty"`
+	ActiveSessions  int                       `json:"active_sessions"`
+}
+
+// RoleAssignmentResponse is one role row of a user
+type RoleAssignmentResponse struct {
+	Role       string `json:"role"`
+	AssignedBy *uint  `json:"assigned_by,omitempty"`
+	AssignedAt string `json:"assigned_at"`
+	ExpiresAt  string `json:"expires_at,omitempty"`
+	Expired    bool   `json:"expired"`
+}
+
+// ToUserResponse converts entity to response
+func ToUserResponse(u *User) UserResponse {
+	resp := UserResponse{
+		ID:        u.ID,
+		Username:  u.Username,
+		Email:     u.Email,
+		FullName:  u.FullName,
+		Roles:     u.Roles,
+		Status:    userStatus(u),
+		CreatedAt: u.CreatedAt.Format(time.RFC3339),
+	}
+	if resp.Roles == nil {
+		resp.Roles = []string{}
+	}
+	if u.NimNip.Valid {
+		resp.NimNip = u.NimNip.String
+	}
+	if u.Phone.Valid {
+		resp.Phone = u.Phone.String
+	}
+	if u.AvatarURL.Valid {
+		resp.AvatarURL = u.AvatarURL.String
+	}
+	if u.EmailVerifiedAt.Valid {
+		resp.EmailVerifiedAt = u.EmailVerifiedAt.Time.Format(time.RFC3339)
+	}
+	if u.LastLoginAt.Valid {
+		resp.LastLoginAt = u.LastLoginAt.Time.Format(time.RFC3339)
+	}
+	if u.DeletedAt.Valid {
+		resp.DeletedAt = u.DeletedAt.Time.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// ToRoleAssignmentResponse converts entity to response
+func ToRoleAssignmentResponse(a *RoleAssignment, now time.Time) *RoleAssignmentResponse {
+	resp := &RoleAssignmentResponse{
+		Role:       a.RoleName,
+		AssignedAt: a.AssignedAt.Format(time.RFC3339),
+		Expired:    a.Expired(now),
+	}
+	if a.AssignedBy.Valid {
+		by := uint(a.AssignedBy.Int64)
+		resp.AssignedBy = &by
+	}
+	if a.ExpiresAt.Valid {
+		resp.ExpiresAt = a.ExpiresAt.Time.Format(time.RFC3339)
+	}
+	return resp
+}
+
+func userStatus(u *User) string {
+	switch {
+	case u.DeletedAt.Valid:
+		return StatusDeleted
+	case !u.IsActive:
+		return StatusInactive
+	}
+	return StatusActive
+}
+
+// How an import treats rows whose username already exists
+const (
+	ExistingSkip   = "SKIP"
+	ExistingUpdate = "UPDATE"
+)
+
+// How an import hands out credentials to new accounts
+const (
+	CredentialPassword = "PASSWORD" // a random initial password
+	CredentialInvite   = "INVITE"   // a single-use token the student redeems to set a password
+)
+
+// Import row statuses
+const (
+	ImportCreated   = "CREATED"
+	ImportUpdated   = "UPDATED"
+	ImportUnchanged = "UNCHANGED"
+	ImportSkipped   = "SKIPPED"
+	ImportInvalid   = "INVALID"
+	ImportFailed    = "FAILED"
+)
+
+// ImportOptions controls a roster import
+type ImportOptions struct {
+	DryRun      bool   `query:"dry_run"`
+	OnExisting  string `query:"on_existing"` // SKIP (default) or UPDATE
+	Credentials string `query:"credentials"` // PASSWORD (default) or INVITE
+	DefaultRole string `query:"default_role"`
+}
+
+func (o *ImportOptions) Validate() []ValidationError {
+	var errors []ValidationError
+	o.OnExisting = strings.ToUpper(strings.TrimSpace(o.OnExisting))
+	if o.OnExisting == "" {
+		o.OnExisting = ExistingSkip
+	}
+	if o.OnExisting != ExistingSkip && o.OnExisting != ExistingUpdate {
+		errors = append(errors, ValidationError{Field: "on_existing", Message: "On existing must be SKIP or UPDATE"})
+	}
+
+	o.Credentials = strings.ToUpper(strings.TrimSpace(o.Credentials))
+	if o.Credentials == "" {
+		o.Credentials = CredentialPassword
+	}
+	if o.Credentials != CredentialPassword && o.Credentials != CredentialInvite {
+		errors = append(errors, ValidationError{Field: "credentials", Message: "Credentials must be PASSWORD or INVITE"})
+	}
+
+	o.DefaultRole = strings.ToLower(strings.TrimSpace(o.DefaultRole))
+	if o.DefaultRole == "" {
+		o.DefaultRole = constants.RoleMahasiswa
+	}
+	if !validRoles[o.DefaultRole] {
+		errors = append(errors, ValidationError{Field: "default_role", Message: "Default role must be admin, dosen or mahasiswa"})
+	}
+	return errors
+}
+
+// ImportResult reports what happened to every row of a roster
+type ImportResult struct {
+	DryRun  bool         `json:"dry_run"`
+	Created int          `json:"created"`
+	Updated int          `json:"updated"`
+	Skipped int          `json:"skipped"`
+	Failed  int          `json:"failed"`
+	Rows    []*ImportRow `json:"rows"`
+}
+
+// ImportRow is the outcome for one roster line. Passwords and invitation
+// tokens are only ever returned here, so the report must be kept safe
+type ImportRow struct {
+	Row             int    `json:"row"`
+	Username        string `json:"username"`
+	Email           string `json:"email"`
+	FullName        string `json:"full_name"`
+	NimNip          string `json:"nim_nip,omitempty"`
+	Role            string `json:"role"`
+	Phone           string `json:"-"`
+	Status          string `json:"status"`
+	Message         string `json:"message,omitempty"`
+	UserID          uint   `json:"user_id,omitempty"`
+	Password        string `json:"password,omitempty"`
+	InviteToken     string `json:"invite_token,omitempty"`
+	InviteExpiresAt string `json:"invite_expires_at,omitempty"`
+}
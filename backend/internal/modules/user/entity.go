@@ -0,0 +1,38 @@
+package user
+
+import (
+	"database/sql"
+	"time"
+)
+
+// User entity as seen by administrators, including deactivated and deleted accounts
+type User struct {
+	ID              uint
+	Username        string
+	Email           string
+	FullName        string
+	NimNip          sql.NullString
+	Phone           sql.NullString
+	AvatarURL       sql.NullString
+	IsActive        bool
+	EmailVerifiedAt sql.NullTime
+	LastLoginAt     sql.NullTime
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       sql.NullTime
+	Roles           []string // currently effective roles
+}
+
+// RoleAssignment is one role held by a user
+type RoleAssignment struct {
+	RoleID     uint
+	RoleName   string
+	AssignedBy sql.NullInt64
+	AssignedAt time.Time
+	ExpiresAt  sql.NullTime
+}
+
+// Expired reports whether the assignment no longer grants its role
+func (a *RoleAssignment) Expired(now time.Time) bool {
+	return a.ExpiresAt.Valid && !a.ExpiresAt.Time.After(now)
+}
@@ -0,0 +1,281 @@
+package user
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+
+	apperrors "walletpoint/internal/shared/errors"
+	"walletpoint/internal/shared/response"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// List searches users
+func (h *Handler) List(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	perPage, _ := strconv.Atoi(c.Query("per_page", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	filter := ListFilter{
+		Search: strings.TrimSpace(c.Query("q")),
+		Role:   c.Query("role"),
+		Status: strings.ToUpper(c.Query("status")),
+	}
+
+	users, total, err := h.service.List(c.Context(), filter, page, perPage)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	totalPages := (total + perPage - 1) / perPage
+
+	return response.SuccessWithMeta(c, "Users retrieved", users, &response.Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// Import provisions users from a CSV roster, sent as a multipart "file" or as
+// the raw request body
+func (h *Handler) Import(c *fiber.Ctx) error {
+	var opts ImportOptions
+	if err := c.QueryParser(&opts); err != nil {
+		return response.BadRequest(c, "Invalid query parameters")
+	}
+
+	if errors := opts.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	var body io.Reader
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return response.BadRequest(c, "CSV file is required")
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return response.BadRequest(c, "Failed to read CSV file")
+		}
+		defer f.Close()
+		body = f
+	} else {
+		body = bytes.NewReader(c.Body())
+	}
+
+	result, err := h.service.ImportCSV(c.Context(), body, opts, actor(c))
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	message := "Users imported"
+	if opts.DryRun {
+		message = "Roster validated, nothing was saved"
+	}
+	return response.Success(c, message, result)
+}
+
+// GetByID returns a user with their wallet
+func (h *Handler) GetByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	result, err := h.service.GetByID(c.Context(), uint(id))
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "User retrieved", result)
+}
+
+// Update edits a user's profile
+func (h *Handler) Update(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	var req UpdateUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.Update(c.Context(), uint(id), req, actor(c))
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "User updated successfully", result)
+}
+
+// Activate re-enables an account
+func (h *Handler) Activate(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	result, err := h.service.Activate(c.Context(), uint(id), actor(c))
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "User activated", result)
+}
+
+// Deactivate disables an account and revokes its sessions
+func (h *Handler) Deactivate(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	var req StatusChangeRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "Invalid request body")
+		}
+	}
+
+	result, err := h.service.Deactivate(c.Context(), uint(id), req, actor(c))
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "User deactivated", result)
+}
+
+// Delete soft-deletes an account
+func (h *Handler) Delete(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	var req StatusChangeRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "Invalid request body")
+		}
+	}
+
+	if err := h.service.Delete(c.Context(), uint(id), req, actor(c)); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "User deleted", nil)
+}
+
+// Restore undoes a soft delete
+func (h *Handler) Restore(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	result, err := h.service.Restore(c.Context(), uint(id), actor(c))
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "User restored", result)
+}
+
+// ResetTwoFactor removes a user's two-factor enrollment
+func (h *Handler) ResetTwoFactor(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	if err := h.service.ResetTwoFactor(c.Context(), uint(id), actor(c)); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Two-factor authentication reset", nil)
+}
+
+// UpdateRoles replaces a user's roles
+func (h *Handler) UpdateRoles(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	var req UpdateRolesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.UpdateRoles(c.Context(), uint(id), req, actor(c))
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Roles updated", result)
+}
+
+func actor(c *fiber.Ctx) Actor {
+	return Actor{
+		UserID:    c.Locals("userID").(uint),
+		IPAddress: c.IP(),
+		UserAgent: c.Get("User-Agent"),
+	}
+}
+
+func handleError(c *fiber.Ctx, err error) error {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		switch appErr.Code {
+		case "NOT_FOUND", "USER_NOT_FOUND":
+			return response.NotFound(c, appErr.Message)
+		case "USER_EXISTS", "USER_DELETED", "LAST_ADMIN":
+			return response.Conflict(c, appErr.Message)
+		case "CANNOT_MODIFY_SELF":
+			return response.Forbidden(c, appErr.Message)
+		case "INVALID_ROLE", "INVALID_CSV":
+			return response.BadRequest(c, appErr.Message)
+		default:
+			return response.InternalError(c, appErr.Message)
+		}
+	}
+	return response.InternalError(c, "Internal server error")
+}
+
+func toResponseErrors(errors []ValidationError) []response.ValidationError {
+	result := make([]response.ValidationError, len(errors))
+	for i, e := range errors {
+		result[i] = response.ValidationError{
+			Field:   e.Field,
+			Message: e.Message,
+		}
+	}
+	return result
+}
@@ -0,0 +1,395 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"walletpoint/internal/modules/audit"
+	"walletpoint/internal/modules/auth"
+	"walletpoint/internal/shared/constants"
+	apperrors "walletpoint/internal/shared/errors"
+	"walletpoint/pkg/utils"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	maxImportRows      = 2000
+	initialPasswordLen = 12
+)
+
+// rosterColumns maps accepted header names to roster fields
+var rosterColumns = map[string]string{
+	"username":  "username",
+	"email":     "email",
+	"full_name": "full_name",
+	"fullname":  "full_name",
+	"name":      "full_name",
+	"nim_nip":   "nim_nip",
+	"nim":       "nim_nip",
+	"nip":       "nim_nip",
+	"role":      "role",
+	"phone":     "phone",
+}
+
+// ImportCSV provisions users from a roster with a header row naming at least
+// username, email and full_name; nim_nip, role and phone are optional. Each
+// row is created in its own transaction, so one bad line never blocks the
+// rest; a row that fails to save is reported as FAILED rather than aborting
+// the import, so the credentials of the rows already saved are never lost.
+// An error is only returned when no row has been saved yet
+func (s *Service) ImportCSV(ctx context.Context, r io.Reader, opts ImportOptions, actor Actor) (*ImportResult, error) {
+	rows, err := parseRosterCSV(r, opts.DefaultRole)
+	if err != nil {
+		return nil, err
+	}
+
+	roleIDs, err := s.repo.GetRoleIDs(ctx)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get roles")
+	}
+
+	result := &ImportResult{DryRun: opts.DryRun, Rows: rows}
+	seen := map[string]int{}
+	for _, row := range rows {
+		if msg := validateRosterRow(row); msg != "" {
+			row.Status, row.Message = ImportInvalid, msg
+		} else if msg := checkRosterDuplicate(seen, row); msg != "" {
+			row.Status, row.Message = ImportInvalid, msg
+		} else if err := s.importRow(ctx, row, roleIDs[row.Role], opts, actor); err != nil {
+			log.Printf("Roster import row %d (%s): %v", row.Row, row.Username, err)
+			row.Status, row.Message = ImportFailed, "Could not be saved; import this row again"
+			row.Password, row.InviteToken, row.InviteExpiresAt = "", "", ""
+		}
+
+		switch row.Status {
+		case ImportCreated:
+			result.Created++
+		case ImportUpdated:
+			result.Updated++
+		case ImportUnchanged, ImportSkipped:
+			result.Skipped++
+		default:
+			result.Failed++
+		}
+	}
+
+	if !opts.DryRun && result.Created+result.Updated > 0 {
+		entry := &audit.Log{
+			UserID:         sql.NullInt64{Int64: int64(actor.UserID), Valid: actor.UserID != 0},
+			TargetType:     "users",
+			Action:         "USER_IMPORT",
+			ActionCategory: constants.AuditCategoryUser,
+			NewValues: audit.Values(map[string]interface{}{
+				"created": result.Created, "updated": result.Updated,
+				"skipped": result.Skipped, "failed": result.Failed,
+				"on_existing": opts.OnExisting, "credentials": opts.Credentials,
+			}),
+			IPAddress:   nullString(actor.IPAddress),
+			UserAgent:   nullString(actor.UserAgent),
+			Description: fmt.Sprintf("Roster import: %d created, %d updated", result.Created, result.Updated),
+			RiskLevel:   constants.RiskLevelMedium,
+		}
+		if err := s.auditRepo.Create(ctx, entry); err != nil {
+			log.Printf("Failed to write audit log for roster import: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+// importRow decides what to do with one valid row and, unless this is a dry
+// run, does it. Only database failures are returned; row problems are
+// recorded on the row
+func (s *Service) importRow(ctx context.Context, row *ImportRow, roleID uint, opts ImportOptions, actor Actor) error {
+	conflicts, err := s.repo.FindConflicts(ctx, row.Username, row.Email, row.NimNip)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to look up users")
+	}
+
+	var existing *User
+	for _, u := range conflicts {
+		if u.Username == row.Username {
+			existing = u
+		}
+	}
+	for _, u := range conflicts {
+		if u == existing {
+			continue
+		}
+		field := "NIM/NIP"
+		if strings.EqualFold(u.Email, row.Email) {
+			field = "Email"
+		}
+		row.Status = ImportFailed
+		row.Message = fmt.Sprintf("%s is already used by %s", field, u.Username)
+		return nil
+	}
+
+	if existing == nil {
+		return s.createFromRow(ctx, row, roleID, opts, actor)
+	}
+
+	row.UserID = existing.ID
+	switch {
+	case existing.DeletedAt.Valid:
+		row.Status, row.Message = ImportSkipped, "Account is deleted; restore it first"
+		return nil
+	case opts.OnExisting == ExistingSkip:
+		row.Status, row.Message = ImportSkipped, "User already exists"
+		return nil
+	}
+	return s.updateFromRow(ctx, row, existing, roleID, opts, actor)
+}
+
+func (s *Service) createFromRow(ctx context.Context, row *ImportRow, roleID uint, opts ImportOptions, actor Actor) error {
+	row.Status = ImportCreated
+	if opts.DryRun {
+		return nil
+	}
+
+	// Invited accounts get a password nobody knows until the invitation is used
+	secret, err := utils.GeneratePassword(initialPasswordLen)
+	if opts.Credentials == CredentialInvite {
+		secret, err = utils.GenerateSecureToken(32)
+	}
+	if err != nil {
+		return apperrors.Wrap(err, "TOKEN_ERROR", "Failed to generate credentials")
+	}
+	passwordHash, err := utils.HashPassword(secret)
+	if err != nil {
+		return apperrors.Wrap(err, "HASH_ERROR", "Failed to hash password")
+	}
+
+	u := &User{
+		Username: row.Username,
+		Email:    row.Email,
+		FullName: row.FullName,
+		NimNip:   nullString(row.NimNip),
+		Phone:    nullString(row.Phone),
+		IsActive: true,
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if err := s.repo.CreateUser(ctx, tx, u, passwordHash); err != nil {
+		return rowFailure(row, err)
+	}
+	if _, err := s.repo.AddRole(ctx, tx, u.ID, roleID, actor.UserID); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to assign role")
+	}
+
+	if opts.Credentials == CredentialInvite {
+		invite, err := utils.GenerateSecureToken(32)
+		if err != nil {
+			return apperrors.Wrap(err, "TOKEN_ERROR", "Failed to generate invitation")
+		}
+		token := &auth.UserToken{
+			UserID:    u.ID,
+			Purpose:   auth.TokenPurposeInvite,
+			TokenHash: utils.HashToken(invite),
+			CreatedBy: sql.NullInt64{Int64: int64(actor.UserID), Valid: actor.UserID != 0},
+			ExpiresAt: time.Now().Add(s.account.InviteTTL),
+		}
+		if err := s.authRepo.CreateUserToken(ctx, tx, token); err != nil {
+			return apperrors.Wrap(err, "DB_ERROR", "Failed to create invitation")
+		}
+		row.InviteToken = invite
+		row.InviteExpiresAt = token.ExpiresAt.Format(time.RFC3339)
+	} else {
+		row.Password = secret
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to commit transaction")
+	}
+
+	row.UserID = u.ID
+	return nil
+}
+
+// updateFromRow refreshes the profile of an existing user and grants the row's
+// role if they lack it. The email, other roles and the password are left
+// alone: a changed email would let whoever controls the new address reset the
+// password, so that goes through the user editor instead
+func (s *Service) updateFromRow(ctx context.Context, row *ImportRow, existing *User, roleID uint, opts ImportOptions, actor Actor) error {
+	updated := *existing
+	updated.FullName = row.FullName
+	if row.NimNip != "" {
+		updated.NimNip = nullString(row.NimNip)
+	}
+	if row.Phone != "" {
+		updated.Phone = nullString(row.Phone)
+	}
+	if !strings.EqualFold(row.Email, existing.Email) {
+		row.Message = "Email not changed; the account keeps " + existing.Email
+	}
+
+	profileChanged := updated.FullName != existing.FullName ||
+		updated.NimNip != existing.NimNip || updated.Phone != existing.Phone
+	roleMissing := !hasRole(existing, row.Role)
+	if !profileChanged && !roleMissing {
+		row.Status = ImportUnchanged
+		return nil
+	}
+
+	row.Status = ImportUpdated
+	if opts.DryRun {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if profileChanged {
+		if err := s.repo.UpdateProfile(ctx, tx, &updated); err != nil {
+			return rowFailure(row, err)
+		}
+	}
+	if roleMissing {
+		if _, err := s.repo.AddRole(ctx, tx, existing.ID, roleID, actor.UserID); err != nil {
+			return apperrors.Wrap(err, "DB_ERROR", "Failed to assign role")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to commit transaction")
+	}
+	return nil
+}
+
+// rowFailure records a unique-key clash with a concurrent change on the row;
+// anything else aborts the import
+func rowFailure(row *ImportRow, err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		row.Status = ImportFailed
+		row.Message = "Username, email or NIM/NIP is already in use"
+		row.Password, row.InviteToken, row.InviteExpiresAt = "", "", ""
+		return nil
+	}
+	return apperrors.Wrap(err, "DB_ERROR", "Failed to save user")
+}
+
+func validateRosterRow(row *ImportRow) string {
+	switch {
+	case row.Username == "":
+		return "Username is required"
+	case len(row.Username) > 50 || strings.ContainsAny(row.Username, " \t"):
+		return "Username must be at most 50 characters without spaces"
+	case !strings.Contains(row.Email, "@") || len(row.Email) > 100:
+		return "Email is invalid"
+	case row.FullName == "" || len(row.FullName) > 100:
+		return "Full name is required and must be at most 100 characters"
+	case len(row.NimNip) > 20:
+		return "NIM/NIP must be at most 20 characters"
+	case !validRoles[row.Role]:
+		return "Role must be admin, dosen or mahasiswa"
+	}
+	return ""
+}
+
+// checkRosterDuplicate rejects a row that repeats an earlier row's username,
+// email or NIM/NIP
+func checkRosterDuplicate(seen map[string]int, row *ImportRow) string {
+	keys := []struct{ key, field string }{
+		{"username:" + row.Username, "Username"},
+		{"email:" + row.Email, "Email"},
+	}
+	if row.NimNip != "" {
+		keys = append(keys, struct{ key, field string }{"nim:" + row.NimNip, "NIM/NIP"})
+	}
+	for _, k := range keys {
+		if first, ok := seen[k.key]; ok {
+			return fmt.Sprintf("%s repeats row %d", k.field, first)
+		}
+	}
+	for _, k := range keys {
+		seen[k.key] = row.Row
+	}
+	return ""
+}
+
+func parseRosterCSV(r io.Reader, defaultRole string) ([]*ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, apperrors.New("INVALID_CSV", "CSV file is empty")
+	}
+	if err != nil {
+		return nil, apperrors.New("INVALID_CSV", fmt.Sprintf("Line 1: %v", err))
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if field, ok := rosterColumns[name]; ok {
+			columns[field] = i
+		}
+	}
+	for _, required := range []string{"username", "email", "full_name"} {
+		if _, ok := columns[required]; !ok {
+			return nil, apperrors.New("INVALID_CSV", "Header must name the username, email and full_name columns")
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []*ImportRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, apperrors.New("INVALID_CSV", fmt.Sprintf("Line %d: %v", line, err))
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		row := &ImportRow{
+			Row:      line,
+			Username: field(record, "username"),
+			Email:    strings.ToLower(field(record, "email")),
+			FullName: field(record, "full_name"),
+			NimNip:   field(record, "nim_nip"),
+			Role:     strings.ToLower(field(record, "role")),
+			Phone:    field(record, "phone"),
+		}
+		if row.Role == "" {
+			row.Role = defaultRole
+		}
+		rows = append(rows, row)
+		if len(rows) > maxImportRows {
+			return nil, apperrors.New("INVALID_CSV", fmt.Sprintf("At most %d users can be imported at once", maxImportRows))
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil, apperrors.New("INVALID_CSV", "CSV file has no users")
+	}
+	return rows, nil
+}
@@ -0,0 +1,317 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const userColumns = `
+	u.id, u.username, u.email, u.full_name, u.nim_nip, u.phone, u.avatar_url, u.is_active,
+	u.email_verified_at, u.last_login_at, u.created_at, u.updated_at, u.deleted_at,
+	(SELECT GROUP_CONCAT(ro.name ORDER BY ro.id)
+		FROM user_roles ur
+		INNER JOIN roles ro ON ur.role_id = ro.id
+		WHERE ur.user_id = u.id AND (ur.expires_at IS NULL OR ur.expires_at > NOW()))
+`
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (*User, error) {
+	var u User
+	var roles sql.NullString
+	err := row.Scan(
+		&u.ID, &u.Username, &u.Email, &u.FullName, &u.NimNip, &u.Phone, &u.AvatarURL, &u.IsActive,
+		&u.EmailVerifiedAt, &u.LastLoginAt, &u.CreatedAt, &u.UpdatedAt, &u.DeletedAt,
+		&roles,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if roles.Valid && roles.String != "" {
+		u.Roles = strings.Split(roles.String, ",")
+	}
+	return &u, nil
+}
+
+// ListFilter narrows the admin user list
+type ListFilter struct {
+	Search string // matches name, username, email or NIM/NIP
+	Role   string
+	Status string // ACTIVE, INACTIVE, DELETED or empty for every non-deleted user
+}
+
+func (f ListFilter) where() (string, []interface{}) {
+	conditions := []string{}
+	var args []interface{}
+
+	switch f.Status {
+	case StatusActive:
+		conditions = append(conditions, "u.deleted_at IS NULL", "u.is_active = TRUE")
+	case StatusInactive:
+		conditions = append(conditions, "u.deleted_at IS NULL", "u.is_active = FALSE")
+	case StatusDeleted:
+		conditions = append(conditions, "u.deleted_at IS NOT NULL")
+	default:
+		conditions = append(conditions, "u.deleted_at IS NULL")
+	}
+
+	if f.Search != "" {
+		like := "%" + f.Search + "%"
+		conditions = append(conditions, "(u.full_name LIKE ? OR u.username LIKE ? OR u.email LIKE ? OR u.nim_nip LIKE ?)")
+		args = append(args, like, like, like, like)
+	}
+
+	if f.Role != "" {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM user_roles ur
+			INNER JOIN roles ro ON ur.role_id = ro.id
+			WHERE ur.user_id = u.id AND ro.name = ? AND (ur.expires_at IS NULL OR ur.expires_at > NOW())
+		)`)
+		args = append(args, f.Role)
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (r *Repository) List(ctx context.Context, f ListFilter, limit, offset int) ([]*User, int, error) {
+	where, args := f.where()
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM users u` + where
+	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users u` + where + ` ORDER BY u.full_name ASC, u.id ASC LIMIT ? OFFSET ?`
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+	return users, total, rows.Err()
+}
+
+// GetByID returns a user whether or not they are deleted
+func (r *Repository) GetByID(ctx context.Context, id uint) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users u WHERE u.id = ?`
+	return scanUser(r.db.QueryRowContext(ctx, query, id))
+}
+
+// FindConflicts returns every account, deleted or not, that already uses the
+// username, email or NIM/NIP
+func (r *Repository) FindConflicts(ctx context.Context, username, email, nimNip string) ([]*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users u WHERE u.username = ? OR u.email = ?`
+	args := []interface{}{username, email}
+	if nimNip != "" {
+		query += ` OR u.nim_nip = ?`
+		args = append(args, nimNip)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (r *Repository) CreateUser(ctx context.Context, ex execer, u *User, passwordHash string) error {
+	query := `
+		INSERT INTO users (username, email, password_hash, full_name, nim_nip, phone, is_active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
+	`
+
+	result, err := ex.ExecContext(ctx, query,
+		u.Username, u.Email, passwordHash, u.FullName, u.NimNip, u.Phone, u.IsActive,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	u.ID = uint(id)
+	return nil
+}
+
+// UpdateProfile saves profile fields. A changed email address is no longer
+// verified; MySQL assigns left to right, so the IF still sees the old address
+func (r *Repository) UpdateProfile(ctx context.Context, ex execer, u *User) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = IF(email = ?, email_verified_at, NULL),
+			full_name = ?, email = ?, nim_nip = ?, phone = ?, avatar_url = ?, updated_at = NOW()
+		WHERE id = ?
+	`
+	_, err := ex.ExecContext(ctx, query, u.Email, u.FullName, u.Email, u.NimNip, u.Phone, u.AvatarURL, u.ID)
+	return err
+}
+
+// AddRole grants a permanent role; it reports false when the user already had it
+func (r *Repository) AddRole(ctx context.Context, ex execer, userID, roleID, assignedBy uint) (bool, error) {
+	query := `
+		INSERT IGNORE INTO user_roles (user_id, role_id, assigned_by, assigned_at)
+		VALUES (?, ?, ?, NOW())
+	`
+	result, err := ex.ExecContext(ctx, query, userID, roleID, sql.NullInt64{Int64: int64(assignedBy), Valid: assignedBy != 0})
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *Repository) SetActive(ctx context.Context, id uint, active bool) error {
+	query := `UPDATE users SET is_active = ?, updated_at = NOW() WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, active, id)
+	return err
+}
+
+func (r *Repository) SoftDelete(ctx context.Context, id uint) error {
+	query := `UPDATE users SET deleted_at = NOW(), updated_at = NOW() WHERE id = ? AND deleted_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *Repository) Restore(ctx context.Context, id uint) error {
+	query := `UPDATE users SET deleted_at = NULL, updated_at = NOW() WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// GetRoleAssignments lists every role row of a user, including expired ones
+func (r *Repository) GetRoleAssignments(ctx context.Context, userID uint) ([]*RoleAssignment, error) {
+	query := `
+		SELECT ur.role_id, ro.name, ur.assigned_by, ur.assigned_at, ur.expires_at
+		FROM user_roles ur
+		INNER JOIN roles ro ON ur.role_id = ro.id
+		WHERE ur.user_id = ?
+		ORDER BY ro.id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []*RoleAssignment
+	for rows.Next() {
+		var a RoleAssignment
+		if err := rows.Scan(&a.RoleID, &a.RoleName, &a.AssignedBy, &a.AssignedAt, &a.ExpiresAt); err != nil {
+			return nil, err
+		}
+		roles = append(roles, &a)
+	}
+	return roles, rows.Err()
+}
+
+// GetRoleIDs maps role names to their ids
+func (r *Repository) GetRoleIDs(ctx context.Context) (map[string]uint, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, name FROM roles`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := map[string]uint{}
+	for rows.Next() {
+		var id uint
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		ids[name] = id
+	}
+	return ids, rows.Err()
+}
+
+// ReplaceRoles sets the user's roles to exactly roleIDs. Roles the user keeps
+// retain their original assigned_at; only the expiry is updated
+func (r *Repository) ReplaceRoles(ctx context.Context, tx *sql.Tx, userID uint, roles map[uint]sql.NullTime, assignedBy uint) error {
+	ids := make([]interface{}, 0, len(roles)+1)
+	ids = append(ids, userID)
+	for roleID := range roles {
+		ids = append(ids, roleID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(roles)), ", ")
+
+	deleteQuery := `DELETE FROM user_roles WHERE user_id = ? AND role_id NOT IN (` + placeholders + `)`
+	if _, err := tx.ExecContext(ctx, deleteQuery, ids...); err != nil {
+		return err
+	}
+
+	upsertQuery := `
+		INSERT INTO user_roles (user_id, role_id, assigned_by, assigned_at, expires_at)
+		VALUES (?, ?, ?, NOW(), ?)
+		ON DUPLICATE KEY UPDATE expires_at = VALUES(expires_at)
+	`
+	for roleID, expiresAt := range roles {
+		if _, err := tx.ExecContext(ctx, upsertQuery, userID, roleID, assignedBy, expiresAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountActiveAdmins counts usable admin accounts, so the last one cannot be locked out
+func (r *Repository) CountActiveAdmins(ctx context.Context) (int, error) {
+	query := `
+		SELECT COUNT(DISTINCT u.id)
+		FROM users u
+		INNER JOIN user_roles ur ON ur.user_id = u.id
+		INNER JOIN roles ro ON ur.role_id = ro.id
+		WHERE ro.name = 'admin' AND u.is_active = TRUE AND u.deleted_at IS NULL
+			AND (ur.expires_at IS NULL OR ur.expires_at > NOW())
+	`
+	var count int
+	err := r.db.QueryRowContext(ctx, query).Scan(&count)
+	return count, err
+}
+
+// CountActiveSessions counts unexpired, unrevoked sessions of a user
+func (r *Repository) CountActiveSessions(ctx context.Context, userID uint) (int, error) {
+	query := `SELECT COUNT(*) FROM sessions WHERE user_id = ? AND is_active = TRUE AND expires_at > NOW()`
+	var count int
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&count)
+	return count, err
+}
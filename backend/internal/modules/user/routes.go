@@ -0,0 +1,21 @@
+package user
+
+import (
+	"walletpoint/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterRoutes(app fiber.Router, handler *Handler, jwtManager *middleware.JWTManager) {
+	admin := app.Group("/admin/users", middleware.JWTMiddleware(jwtManager), middleware.RequireAdmin())
+	admin.Get("", handler.List)
+	admin.Post("/import", middleware.RequireStepUp(), handler.Import)
+	admin.Get("/:id", handler.GetByID)
+	admin.Put("/:id", handler.Update)
+	admin.Post("/:id/activate", handler.Activate)
+	admin.Post("/:id/deactivate", handler.Deactivate)
+	admin.Delete("/:id", handler.Delete)
+	admin.Post("/:id/restore", handler.Restore)
+	admin.Put("/:id/roles", middleware.RequireStepUp(), handler.UpdateRoles)
+	admin.Post("/:id/2fa/reset", middleware.RequireStepUp(), handler.ResetTwoFactor)
+}
@@ -0,0 +1,451 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"walletpoint/internal/config"
+	"walletpoint/internal/middleware"
+	"walletpoint/internal/modules/audit"
+	"walletpoint/internal/modules/auth"
+	"walletpoint/internal/modules/wallet"
+	"walletpoint/internal/shared/constants"
+	apperrors "walletpoint/internal/shared/errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type Service struct {
+	repo       *Repository
+	authRepo   *auth.Repository
+	walletRepo *wallet.Repository
+	auditRepo  *audit.Repository
+	jwtManager *middleware.JWTManager
+	db         *sql.DB
+	account    config.AccountConfig
+}
+
+func NewService(repo *Repository, authRepo *auth.Repository, walletRepo *wallet.Repository, auditRepo *audit.Repository, jwtManager *middleware.JWTManager, db *sql.DB, accountCfg config.AccountConfig) *Service {
+	return &Service{
+		repo:       repo,
+		authRepo:   authRepo,
+		walletRepo: walletRepo,
+		auditRepo:  auditRepo,
+		jwtManager: jwtManager,
+		db:         db,
+		account:    accountCfg,
+	}
+}
+
+// List searches users by name, NIM/NIP, role or status
+func (s *Service) List(ctx context.Context, f ListFilter, page, perPage int) ([]UserResponse, int, error) {
+	offset := (page - 1) * perPage
+	users, total, err := s.repo.List(ctx, f, perPage, offset)
+	if err != nil {
+		return nil, 0, apperrors.Wrap(err, "DB_ERROR", "Failed to get users")
+	}
+
+	result := make([]UserResponse, len(users))
+	for i, u := range users {
+		result[i] = ToUserResponse(u)
+	}
+	return result, total, nil
+}
+
+// GetByID returns a user with their roles, wallet and session count
+func (s *Service) GetByID(ctx context.Context, id uint) (*UserDetailResponse, error) {
+	u, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.detail(ctx, u)
+}
+
+// Update edits a user's profile
+func (s *Service) Update(ctx context.Context, id uint, req UpdateUserRequest, actor Actor) (*UserDetailResponse, error) {
+	u, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u.DeletedAt.Valid {
+		return nil, apperrors.New("USER_DELETED", "Restore the account before editing it")
+	}
+
+	old := profileValues(u)
+	if req.FullName != nil {
+		u.FullName = strings.TrimSpace(*req.FullName)
+	}
+	if req.Email != nil {
+		u.Email = strings.ToLower(strings.TrimSpace(*req.Email))
+	}
+	if req.NimNip != nil {
+		u.NimNip = nullString(*req.NimNip)
+	}
+	if req.Phone != nil {
+		u.Phone = nullString(*req.Phone)
+	}
+	if req.AvatarURL != nil {
+		u.AvatarURL = nullString(*req.AvatarURL)
+	}
+
+	if err := s.repo.UpdateProfile(ctx, s.db, u); err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return nil, apperrors.New("USER_EXISTS", "Email or NIM/NIP is already used by another account")
+		}
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to update user")
+	}
+
+	// Links sent to the old address must not verify the new one
+	if u.Email != old["email"] {
+		if err := s.authRepo.ExpireUserTokens(ctx, u.ID, auth.TokenPurposeEmailVerify); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to expire verification links")
+		}
+	}
+
+	s.audit(ctx, actor, u, "USER_UPDATE", constants.RiskLevelLow, old, profileValues(u),
+		fmt.Sprintf("Profile of %s updated", u.Username))
+
+	return s.GetByID(ctx, id)
+}
+
+// Activate re-enables a deactivated account
+func (s *Service) Activate(ctx context.Context, id uint, actor Actor) (*UserDetailResponse, error) {
+	u, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u.DeletedAt.Valid {
+		return nil, apperrors.New("USER_DELETED", "Restore the account before activating it")
+	}
+
+	if !u.IsActive {
+		if err := s.repo.SetActive(ctx, id, true); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to activate user")
+		}
+		s.jwtManager.ForgetSessions(id)
+		s.audit(ctx, actor, u, "USER_ACTIVATE", constants.RiskLevelLow,
+			map[string]interface{}{"is_active": false}, map[string]interface{}{"is_active": true},
+			fmt.Sprintf("Account %s activated", u.Username))
+	}
+
+	return s.GetByID(ctx, id)
+}
+
+// Deactivate disables an account and signs it out everywhere
+func (s *Service) Deactivate(ctx context.Context, id uint, req StatusChangeRequest, actor Actor) (*UserDetailResponse, error) {
+	u, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCanDisable(ctx, u, actor); err != nil {
+		return nil, err
+	}
+
+	if u.IsActive {
+		if err := s.repo.SetActive(ctx, id, false); err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to deactivate user")
+		}
+		s.audit(ctx, actor, u, "USER_DEACTIVATE", constants.RiskLevelMedium,
+			map[string]interface{}{"is_active": true}, map[string]interface{}{"is_active": false, "reason": req.Reason},
+			withReason(fmt.Sprintf("Account %s deactivated", u.Username), req.Reason))
+	}
+
+	if err := s.revokeSessions(ctx, id, "account_deactivated"); err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(ctx, id)
+}
+
+// revokeSessions signs the user out everywhere, including access tokens
+// already handed out
+func (s *Service) revokeSessions(ctx context.Context, id uint, reason string) error {
+	if err := s.authRepo.RevokeAllUserSessions(ctx, s.db, id, reason); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to revoke sessions")
+	}
+	s.jwtManager.ForgetSessions(id)
+	return nil
+}
+
+// Delete soft-deletes an account and signs it out everywhere
+func (s *Service) Delete(ctx context.Context, id uint, req StatusChangeRequest, actor Actor) error {
+	u, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if u.DeletedAt.Valid {
+		return nil
+	}
+	if err := s.checkCanDisable(ctx, u, actor); err != nil {
+		return err
+	}
+
+	if err := s.repo.SoftDelete(ctx, id); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to delete user")
+	}
+	if err := s.revokeSessions(ctx, id, "account_deleted"); err != nil {
+		return err
+	}
+
+	s.audit(ctx, actor, u, "USER_DELETE", constants.RiskLevelMedium,
+		nil, map[string]interface{}{"reason": req.Reason},
+		withReason(fmt.Sprintf("Account %s deleted", u.Username), req.Reason))
+	return nil
+}
+
+// Restore undoes a soft delete. The account keeps its previous active flag
+func (s *Service) Restore(ctx context.Context, id uint, actor Actor) (*UserDetailResponse, error) {
+	u, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.DeletedAt.Valid {
+		if err := s.repo.Restore(ctx, id); err != nil {
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+				return nil, apperrors.New("USER_EXISTS", "Another account now uses this username, email or NIM/NIP")
+			}
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to restore user")
+		}
+		s.audit(ctx, actor, u, "USER_RESTORE", constants.RiskLevelLow, nil, nil,
+			fmt.Sprintf("Account %s restored", u.Username))
+	}
+
+	return s.GetByID(ctx, id)
+}
+
+// ResetTwoFactor removes a user's authenticator and recovery codes, for when
+// they lost both. The user is signed out; admins enroll again at next login
+func (s *Service) ResetTwoFactor(ctx context.Context, id uint, actor Actor) error {
+	u, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if id == actor.UserID {
+		return apperrors.New("CANNOT_MODIFY_SELF", "You cannot reset your own two-factor authentication")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if err := s.authRepo.DeleteTwoFactor(ctx, tx, id); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to reset two-factor")
+	}
+	if err := tx.Commit(); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to commit transaction")
+	}
+	if err := s.revokeSessions(ctx, id, "two_factor_reset"); err != nil {
+		return err
+	}
+
+	s.audit(ctx, actor, u, "USER_2FA_RESET", constants.RiskLevelHigh, nil, nil,
+		fmt.Sprintf("Two-factor authentication of %s reset", u.Username))
+	return nil
+}
+
+// UpdateRoles replaces a user's roles. Existing sessions are revoked so the
+// new roles take effect on the next login
+func (s *Service) UpdateRoles(ctx context.Context, id uint, req UpdateRolesRequest, actor Actor) (*UserDetailResponse, error) {
+	u, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u.DeletedAt.Valid {
+		return nil, apperrors.New("USER_DELETED", "Restore the account before changing its roles")
+	}
+
+	roleIDs, err := s.repo.GetRoleIDs(ctx)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get roles")
+	}
+
+	roles := make(map[uint]sql.NullTime, len(req.Roles))
+	newNames := make([]string, 0, len(req.Roles))
+	keepsAdmin := false
+	for _, r := range req.Roles {
+		roleID, ok := roleIDs[r.Role]
+		if !ok {
+			return nil, apperrors.New("INVALID_ROLE", "Invalid role specified")
+		}
+		var expiresAt sql.NullTime
+		if r.ExpiresAt != nil {
+			t, _ := time.Parse(time.RFC3339, *r.ExpiresAt)
+			expiresAt = sql.NullTime{Time: t, Valid: true}
+		}
+		if r.Role == constants.RoleAdmin && !expiresAt.Valid {
+			keepsAdmin = true
+		}
+		roles[roleID] = expiresAt
+		newNames = append(newNames, r.Role)
+	}
+
+	if hasRole(u, constants.RoleAdmin) && !keepsAdmin {
+		if u.ID == actor.UserID {
+			return nil, apperrors.New("CANNOT_MODIFY_SELF", "You cannot remove or limit your own admin role")
+		}
+		if err := s.checkNotLastAdmin(ctx, u); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if err := s.repo.ReplaceRoles(ctx, tx, id, roles, actor.UserID); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to update roles")
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to commit transaction")
+	}
+
+	if err := s.revokeSessions(ctx, id, "roles_changed"); err != nil {
+		return nil, err
+	}
+
+	// Granting or revoking admin is the change most worth reviewing
+	risk := constants.RiskLevelMedium
+	if hasRole(u, constants.RoleAdmin) != containsString(newNames, constants.RoleAdmin) {
+		risk = constants.RiskLevelHigh
+	}
+	s.audit(ctx, actor, u, "USER_ROLES_CHANGE", risk,
+		map[string]interface{}{"roles": u.Roles}, map[string]interface{}{"roles": req.Roles},
+		fmt.Sprintf("Roles of %s changed to %s", u.Username, strings.Join(newNames, ", ")))
+
+	return s.GetByID(ctx, id)
+}
+
+func (s *Service) get(ctx context.Context, id uint) (*User, error) {
+	u, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get user")
+	}
+	if u == nil {
+		return nil, apperrors.New("USER_NOT_FOUND", "User not found")
+	}
+	return u, nil
+}
+
+func (s *Service) detail(ctx context.Context, u *User) (*UserDetailResponse, error) {
+	resp := &UserDetailResponse{UserResponse: ToUserResponse(u)}
+
+	assignments, err := s.repo.GetRoleAssignments(ctx, u.ID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get roles")
+	}
+	now := time.Now()
+	resp.RoleAssignments = make([]*RoleAssignmentResponse, len(assignments))
+	for i, a := range assignments {
+		resp.RoleAssignments[i] = ToRoleAssignmentResponse(a, now)
+	}
+
+	w, err := s.walletRepo.GetByUserID(ctx, u.ID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get wallet")
+	}
+	if w != nil {
+		resp.Wallet = &wallet.BalanceResponse{
+			UserID:         u.ID,
+			Balance:        w.Balance,
+			LockedBalance:  w.LockedBalance,
+			LifetimeEarned: w.LifetimeEarned,
+			LifetimeSpent:  w.LifetimeSpent,
+			IsFrozen:       w.IsFrozen,
+		}
+	}
+
+	resp.ActiveSessions, err = s.repo.CountActiveSessions(ctx, u.ID)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get sessions")
+	}
+
+	return resp, nil
+}
+
+// checkCanDisable stops admins from locking themselves or the last admin out
+func (s *Service) checkCanDisable(ctx context.Context, u *User, actor Actor) error {
+	if u.ID == actor.UserID {
+		return apperrors.New("CANNOT_MODIFY_SELF", "You cannot deactivate or delete your own account")
+	}
+	if hasRole(u, constants.RoleAdmin) && u.IsActive && !u.DeletedAt.Valid {
+		return s.checkNotLastAdmin(ctx, u)
+	}
+	return nil
+}
+
+func (s *Service) checkNotLastAdmin(ctx context.Context, u *User) error {
+	admins, err := s.repo.CountActiveAdmins(ctx)
+	if err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to count admins")
+	}
+	if admins <= 1 {
+		return apperrors.New("LAST_ADMIN", "At least one active admin account must remain")
+	}
+	return nil
+}
+
+func (s *Service) audit(ctx context.Context, actor Actor, u *User, action, risk string, oldValues, newValues interface{}, description string) {
+	entry := &audit.Log{
+		UserID:         sql.NullInt64{Int64: int64(actor.UserID), Valid: actor.UserID != 0},
+		TargetType:     "users",
+		TargetID:       u.ID,
+		Action:         action,
+		ActionCategory: constants.AuditCategoryUser,
+		OldValues:      audit.Values(oldValues),
+		NewValues:      audit.Values(newValues),
+		IPAddress:      nullString(actor.IPAddress),
+		UserAgent:      nullString(actor.UserAgent),
+		Description:    description,
+		RiskLevel:      risk,
+	}
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		log.Printf("Failed to write audit log for user %d: %v", u.ID, err)
+	}
+}
+
+func profileValues(u *User) map[string]interface{} {
+	return map[string]interface{}{
+		"full_name":  u.FullName,
+		"email":      u.Email,
+		"nim_nip":    u.NimNip.String,
+		"phone":      u.Phone.String,
+		"avatar_url": u.AvatarURL.String,
+	}
+}
+
+func hasRole(u *User, role string) bool {
+	return containsString(u.Roles, role)
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func withReason(description, reason string) string {
+	if reason == "" {
+		return description
+	}
+	return description + ": " + reason
+}
+
+func nullString(s string) sql.NullString {
+	s = strings.TrimSpace(s)
+	return sql.NullString{String: s, Valid: s != ""}
+}
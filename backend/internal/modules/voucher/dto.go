@@ -0,0 +1,156 @@
+package voucher
+
+import (
+	"strings"
+	"time"
+
+	"walletpoint/internal/shared/constants"
+)
+
+// CreateVoucherRequest for creating voucher
+type CreateVoucherRequest struct {
+	Code          string     `json:"code"`
+	Description   string     `json:"description"`
+	Scope         string     `json:"scope"` // GLOBAL (admin only), SELLER, PRODUCT
+	SellerID      *uint      `json:"seller_id"`
+	ProductID     *uint      `json:"product_id"`
+	DiscountType  string     `json:"discount_type"` // PERCENTAGE or FIXED
+	DiscountValue int64      `json:"discount_value"`
+	MaxDiscount   *int64     `json:"max_discount"`
+	MinPurchase   int64      `json:"min_purchase"`
+	UsageLimit    *int       `json:"usage_limit"`
+	UsagePerUser  int        `json:"usage_per_user"`
+	StartsAt      *time.Time `json:"starts_at"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+}
+
+func (r *CreateVoucherRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	r.Code = strings.ToUpper(strings.TrimSpace(r.Code))
+	if r.Code == "" {
+		errors = append(errors, ValidationError{Field: "code", Message: "Code is required"})
+	}
+	if r.Scope == "" {
+		r.Scope = constants.VoucherScopeSeller
+	}
+	validScopes := map[string]bool{constants.VoucherScopeGlobal: true, constants.VoucherScopeSeller: true, constants.VoucherScopeProduct: true}
+	if !validScopes[r.Scope] {
+		errors = append(errors, ValidationError{Field: "scope", Message: "Scope must be GLOBAL, SELLER or PRODUCT"})
+	}
+	if r.Scope == constants.VoucherScopeProduct && r.ProductID == nil {
+		errors = append(errors, ValidationError{Field: "product_id", Message: "Product ID is required for PRODUCT scope"})
+	}
+	switch r.DiscountType {
+	case constants.VoucherTypePercentage:
+		if r.DiscountValue <= 0 || r.DiscountValue > 100 {
+			errors = append(errors, ValidationError{Field: "discount_value", Message: "Percentage must be between 1 and 100"})
+		}
+	case constants.VoucherTypeFixed:
+		if r.DiscountValue <= 0 {
+			errors = append(errors, ValidationError{Field: "discount_value", Message: "Discount value must be positive"})
+		}
+	default:
+		errors = append(errors, ValidationError{Field: "discount_type", Message: "Discount type must be PERCENTAGE or FIXED"})
+	}
+	if r.MaxDiscount != nil && *r.MaxDiscount <= 0 {
+		errors = append(errors, ValidationError{Field: "max_discount", Message: "Max discount must be positive"})
+	}
+	if r.MinPurchase < 0 {
+		errors = append(errors, ValidationError{Field: "min_purchase", Message: "Minimum purchase cannot be negative"})
+	}
+	if r.UsageLimit != nil && *r.UsageLimit <= 0 {
+		errors = append(errors, ValidationError{Field: "usage_limit", Message: "Usage limit must be positive"})
+	}
+	if r.UsagePerUser <= 0 {
+		r.UsagePerUser = 1
+	}
+	if r.StartsAt != nil && r.ExpiresAt != nil && !r.ExpiresAt.After(*r.StartsAt) {
+		errors = append(errors, ValidationError{Field: "expires_at", Message: "Expiry must be after start time"})
+	}
+	return errors
+}
+
+// UpdateVoucherRequest for updating voucher
+type UpdateVoucherRequest struct {
+	Description  *string    `json:"description"`
+	MaxDiscount  *int64     `json:"max_discount"`
+	MinPurchase  *int64     `json:"min_purchase"`
+	UsageLimit   *int       `json:"usage_limit"`
+	UsagePerUser *int       `json:"usage_per_user"`
+	StartsAt     *time.Time `json:"starts_at"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	IsActive     *bool      `json:"is_active"`
+}
+
+// ValidationError for validation
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// VoucherResponse for voucher details
+type VoucherResponse struct {
+	ID            uint    `json:"id"`
+	Code          string  `json:"code"`
+	Description   string  `json:"description,omitempty"`
+	CreatorID     uint    `json:"creator_id"`
+	Scope         string  `json:"scope"`
+	SellerID      *uint   `json:"seller_id,omitempty"`
+	ProductID     *uint   `json:"product_id,omitempty"`
+	DiscountType  string  `json:"discount_type"`
+	DiscountValue int64   `json:"discount_value"`
+	MaxDiscount   *int64  `json:"max_discount,omitempty"`
+	MinPurchase   int64   `json:"min_purchase"`
+	UsageLimit    *int64  `json:"usage_limit,omitempty"`
+	UsagePerUser  int     `json:"usage_per_user"`
+	UsedCount     int     `json:"used_count"`
+	StartsAt      *string `json:"starts_at,omitempty"`
+	ExpiresAt     *string `json:"expires_at,omitempty"`
+	IsActive      bool    `json:"is_active"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+// ToVoucherResponse converts entity to response
+func ToVoucherResponse(v *Voucher) VoucherResponse {
+	resp := VoucherResponse{
+		ID:            v.ID,
+		Code:          v.Code,
+		CreatorID:     v.CreatorID,
+		Scope:         v.Scope,
+		DiscountType:  v.DiscountType,
+		DiscountValue: v.DiscountValue,
+		MinPurchase:   v.MinPurchase,
+		UsagePerUser:  v.UsagePerUser,
+		UsedCount:     v.UsedCount,
+		IsActive:      v.IsActive,
+		CreatedAt:     v.CreatedAt.Format(time.RFC3339),
+	}
+
+	if v.Description.Valid {
+		resp.Description = v.Description.String
+	}
+	if v.SellerID.Valid {
+		sellerID := uint(v.SellerID.Int64)
+		resp.SellerID = &sellerID
+	}
+	if v.ProductID.Valid {
+		productID := uint(v.ProductID.Int64)
+		resp.ProductID = &productID
+	}
+	if v.MaxDiscount.Valid {
+		resp.MaxDiscount = &v.MaxDiscount.Int64
+	}
+	if v.UsageLimit.Valid {
+		resp.UsageLimit = &v.UsageLimit.Int64
+	}
+	if v.StartsAt.Valid {
+		t := v.StartsAt.Time.Format(time.RFC3339)
+		resp.StartsAt = &t
+	}
+	if v.ExpiresAt.Valid {
+		t := v.ExpiresAt.Time.Format(time.RFC3339)
+		resp.ExpiresAt = &t
+	}
+
+	return resp
+}
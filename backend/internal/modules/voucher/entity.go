@@ -0,0 +1,40 @@
+package voucher
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Voucher entity
+type Voucher struct {
+	ID            uint
+	Code          string
+	Description   sql.NullString
+	CreatorID     uint
+	Scope         string // GLOBAL, SELLER, PRODUCT
+	SellerID      sql.NullInt64
+	ProductID     sql.NullInt64
+	DiscountType  string // PERCENTAGE or FIXED
+	DiscountValue int64
+	MaxDiscount   sql.NullInt64
+	MinPurchase   int64
+	UsageLimit    sql.NullInt64
+	UsagePerUser  int
+	UsedCount     int
+	StartsAt      sql.NullTime
+	ExpiresAt     sql.NullTime
+	IsActive      bool
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     sql.NullTime
+}
+
+// VoucherUsage entity
+type VoucherUsage struct {
+	ID             uint
+	VoucherID      uint
+	UserID         uint
+	OrderID        uint
+	DiscountAmount int64
+	CreatedAt      time.Time
+}
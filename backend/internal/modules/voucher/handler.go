@@ -0,0 +1,162 @@
+package voucher
+
+import (
+	"strconv"
+
+	apperrors "walletpoint/internal/shared/errors"
+	"walletpoint/internal/shared/response"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Create creates a new voucher
+func (h *Handler) Create(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	role := c.Locals("role").(string)
+
+	var req CreateVoucherRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.Create(c.Context(), req, userID, role)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Created(c, "Voucher created successfully", result)
+}
+
+// GetMyVouchers lists vouchers created by user
+func (h *Handler) GetMyVouchers(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	perPage, _ := strconv.Atoi(c.Query("per_page", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	vouchers, total, err := h.service.GetMyVouchers(c.Context(), userID, page, perPage)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	totalPages := (total + perPage - 1) / perPage
+
+	return response.SuccessWithMeta(c, "Vouchers retrieved", vouchers, &response.Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// GetAll lists all vouchers (admin)
+func (h *Handler) GetAll(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	perPage, _ := strconv.Atoi(c.Query("per_page", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	vouchers, total, err := h.service.GetAll(c.Context(), page, perPage)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	totalPages := (total + perPage - 1) / perPage
+
+	return response.SuccessWithMeta(c, "Vouchers retrieved", vouchers, &response.Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// Update updates a voucher
+func (h *Handler) Update(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	role := c.Locals("role").(string)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid voucher ID")
+	}
+
+	var req UpdateVoucherRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := h.service.Update(c.Context(), uint(id), req, userID, role); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Voucher updated successfully", nil)
+}
+
+// Delete deletes a voucher
+func (h *Handler) Delete(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	role := c.Locals("role").(string)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid voucher ID")
+	}
+
+	if err := h.service.Delete(c.Context(), uint(id), userID, role); err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Voucher deleted successfully", nil)
+}
+
+func handleError(c *fiber.Ctx, err error) error {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		switch appErr.Code {
+		case "NOT_FOUND", "VOUCHER_NOT_FOUND", "PRODUCT_NOT_FOUND":
+			return response.NotFound(c, appErr.Message)
+		case "FORBIDDEN":
+			return response.Forbidden(c, appErr.Message)
+		case "VOUCHER_EXISTS":
+			return response.Conflict(c, appErr.Message)
+		case "INVALID_VOUCHER":
+			return response.BadRequest(c, appErr.Message)
+		default:
+			return response.InternalError(c, appErr.Message)
+		}
+	}
+	return response.InternalError(c, "Internal server error")
+}
+
+func toResponseErrors(errors []ValidationError) []response.ValidationError {
+	result := make([]response.ValidationError, len(errors))
+	for i, e := range errors {
+		result[i] = response.ValidationError{
+			Field:   e.Field,
+			Message: e.Message,
+		}
+	}
+	return result
+}
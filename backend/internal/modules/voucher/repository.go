@@ -0,0 +1,231 @@
+package voucher
+
+import (
+	"context"
+	"database/sql"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(ctx context.Context, v *Voucher) error {
+	query := `
+		INSERT INTO vouchers (code, description, creator_id, scope, seller_id, product_id,
+			discount_type, discount_value, max_discount, min_purchase, usage_limit,
+			usage_per_user, used_count, starts_at, expires_at, is_active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?, NOW(), NOW())
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		v.Code, v.Description, v.CreatorID, v.Scope, v.SellerID, v.ProductID,
+		v.DiscountType, v.DiscountValue, v.MaxDiscount, v.MinPurchase, v.UsageLimit,
+		v.UsagePerUser, v.StartsAt, v.ExpiresAt, v.IsActive,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	v.ID = uint(id)
+	return nil
+}
+
+func (r *Repository) GetByID(ctx context.Context, id uint) (*Voucher, error) {
+	query := `
+		SELECT id, code, description, creator_id, scope, seller_id, product_id,
+			discount_type, discount_value, max_discount, min_purchase, usage_limit,
+			usage_per_user, used_count, starts_at, expires_at, is_active, created_at, updated_at
+		FROM vouchers WHERE id = ? AND deleted_at IS NULL
+	`
+
+	return scanVoucher(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *Repository) GetByCode(ctx context.Context, code string) (*Voucher, error) {
+	query := `
+		SELECT id, code, description, creator_id, scope, seller_id, product_id,
+			discount_type, discount_value, max_discount, min_purchase, usage_limit,
+			usage_per_user, used_count, starts_at, expires_at, is_active, created_at, updated_at
+		FROM vouchers WHERE code = ? AND deleted_at IS NULL
+	`
+
+	return scanVoucher(r.db.QueryRowContext(ctx, query, code))
+}
+
+// GetByCodeForUpdate locks the voucher row so usage caps are checked atomically
+func (r *Repository) GetByCodeForUpdate(ctx context.Context, tx *sql.Tx, code string) (*Voucher, error) {
+	query := `
+		SELECT id, code, description, creator_id, scope, seller_id, product_id,
+			discount_type, discount_value, max_discount, min_purchase, usage_limit,
+			usage_per_user, used_count, starts_at, expires_at, is_active, created_at, updated_at
+		FROM vouchers WHERE code = ? AND deleted_at IS NULL FOR UPDATE
+	`
+
+	return scanVoucher(tx.QueryRowContext(ctx, query, code))
+}
+
+func (r *Repository) GetByCreatorID(ctx context.Context, creatorID uint, limit, offset int) ([]*Voucher, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM vouchers WHERE creator_id = ? AND deleted_at IS NULL`
+	if err := r.db.QueryRowContext(ctx, countQuery, creatorID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, code, description, creator_id, scope, seller_id, product_id,
+			discount_type, discount_value, max_discount, min_purchase, usage_limit,
+			usage_per_user, used_count, starts_at, expires_at, is_active, created_at, updated_at
+		FROM vouchers
+		WHERE creator_id = ? AND deleted_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT ? OFFSET ?
+	`
+
+	vouchers, err := r.queryVouchers(ctx, query, creatorID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return vouchers, total, nil
+}
+
+func (r *Repository) GetAll(ctx context.Context, limit, offset int) ([]*Voucher, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM vouchers WHERE deleted_at IS NULL`
+	if err := r.db.QueryRowContext(ctx, countQuery).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, code, description, creator_id, scope, seller_id, product_id,
+			discount_type, discount_value, max_discount, min_purchase, usage_limit,
+			usage_per_user, used_count, starts_at, expires_at, is_active, created_at, updated_at
+		FROM vouchers
+		WHERE deleted_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT ? OFFSET ?
+	`
+
+	vouchers, err := r.queryVouchers(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return vouchers, total, nil
+}
+
+func (r *Repository) Update(ctx context.Context, v *Voucher) error {
+	query := `
+		UPDATE vouchers SET description = ?, max_discount = ?, min_purchase = ?, usage_limit = ?,
+			usage_per_user = ?, starts_at = ?, expires_at = ?, is_active = ?, updated_at = NOW()
+		WHERE id = ?
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		v.Description, v.MaxDiscount, v.MinPurchase, v.UsageLimit,
+		v.UsagePerUser, v.StartsAt, v.ExpiresAt, v.IsActive, v.ID,
+	)
+	return err
+}
+
+func (r *Repository) Delete(ctx context.Context, id uint) error {
+	query := `UPDATE vouchers SET is_active = FALSE, deleted_at = NOW() WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// CountUserUsages counts how many times a user has redeemed a voucher
+func (r *Repository) CountUserUsages(ctx context.Context, tx *sql.Tx, voucherID, userID uint) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM voucher_usages WHERE voucher_id = ? AND user_id = ?`
+	var err error
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, voucherID, userID).Scan(&count)
+	} else {
+		err = r.db.QueryRowContext(ctx, query, voucherID, userID).Scan(&count)
+	}
+	return count, err
+}
+
+// RecordUsage stores a redemption and increments the voucher usage counter
+func (r *Repository) RecordUsage(ctx context.Context, tx *sql.Tx, usage *VoucherUsage) error {
+	query := `
+		INSERT INTO voucher_usages (voucher_id, user_id, order_id, discount_amount, created_at)
+		VALUES (?, ?, ?, ?, NOW())
+	`
+
+	result, err := tx.ExecContext(ctx, query, usage.VoucherID, usage.UserID, usage.OrderID, usage.DiscountAmount)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	usage.ID = uint(id)
+
+	updateQuery := `UPDATE vouchers SET used_count = used_count + 1, updated_at = NOW() WHERE id = ?`
+	_, err = tx.ExecContext(ctx, updateQuery, usage.VoucherID)
+	return err
+}
+
+// GetProductSellerID returns the seller of a product, or 0 if it does not exist
+func (r *Repository) GetProductSellerID(ctx context.Context, productID uint) (uint, error) {
+	var sellerID uint
+	query := `SELECT seller_id FROM products WHERE id = ? AND deleted_at IS NULL`
+	err := r.db.QueryRowContext(ctx, query, productID).Scan(&sellerID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return sellerID, err
+}
+
+func (r *Repository) queryVouchers(ctx context.Context, query string, args ...interface{}) ([]*Voucher, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vouchers []*Voucher
+	for rows.Next() {
+		var v Voucher
+		if err := rows.Scan(
+			&v.ID, &v.Code, &v.Description, &v.CreatorID, &v.Scope, &v.SellerID, &v.ProductID,
+			&v.DiscountType, &v.DiscountValue, &v.MaxDiscount, &v.MinPurchase, &v.UsageLimit,
+			&v.UsagePerUser, &v.UsedCount, &v.StartsAt, &v.ExpiresAt, &v.IsActive, &v.CreatedAt, &v.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		vouchers = append(vouchers, &v)
+	}
+
+	return vouchers, nil
+}
+
+func scanVoucher(row *sql.Row) (*Voucher, error) {
+	var v Voucher
+	err := row.Scan(
+		&v.ID, &v.Code, &v.Description, &v.CreatorID, &v.Scope, &v.SellerID, &v.ProductID,
+		&v.DiscountType, &v.DiscountValue, &v.MaxDiscount, &v.MinPurchase, &v.UsageLimit,
+		&v.UsagePerUser, &v.UsedCount, &v.StartsAt, &v.ExpiresAt, &v.IsActive, &v.CreatedAt, &v.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
@@ -0,0 +1,20 @@
+package voucher
+
+import (
+	"walletpoint/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterRoutes(app fiber.Router, handler *Handler, jwtManager *middleware.JWTManager) {
+	// Sellers (dosen) and admins manage vouchers
+	vouchers := app.Group("/vouchers", middleware.JWTMiddleware(jwtManager), middleware.RequireRole("dosen", "admin"))
+	vouchers.Get("/my", handler.GetMyVouchers)
+	vouchers.Post("", handler.Create)
+	vouchers.Put("/:id", handler.Update)
+	vouchers.Delete("/:id", handler.Delete)
+
+	// Admin - all vouchers
+	admin := app.Group("/admin/vouchers", middleware.JWTMiddleware(jwtManager), middleware.RequireAdmin())
+	admin.Get("", handler.GetAll)
+}
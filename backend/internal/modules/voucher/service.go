@@ -0,0 +1,245 @@
+package voucher
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"walletpoint/internal/shared/constants"
+	apperrors "walletpoint/internal/shared/errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) Create(ctx context.Context, req CreateVoucherRequest, creatorID uint, role string) (*VoucherResponse, error) {
+	existing, err := s.repo.GetByCode(ctx, req.Code)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to check voucher")
+	}
+	if existing != nil {
+		return nil, apperrors.New("VOUCHER_EXISTS", "Voucher code already exists")
+	}
+
+	v := &Voucher{
+		Code:          req.Code,
+		Description:   sql.NullString{String: req.Description, Valid: req.Description != ""},
+		CreatorID:     creatorID,
+		Scope:         req.Scope,
+		DiscountType:  req.DiscountType,
+		DiscountValue: req.DiscountValue,
+		MinPurchase:   req.MinPurchase,
+		UsagePerUser:  req.UsagePerUser,
+		IsActive:      true,
+	}
+
+	// Sellers may only discount their own catalog. Admin vouchers are paid
+	// for from the admin's wallet at checkout, not by the seller
+	sellerID := uint(0)
+	if req.SellerID != nil {
+		sellerID = *req.SellerID
+	}
+	if role != constants.RoleAdmin {
+		if req.Scope == constants.VoucherScopeGlobal {
+			return nil, apperrors.New("FORBIDDEN", "Only admins can create global vouchers")
+		}
+		sellerID = creatorID
+	}
+
+	switch req.Scope {
+	case constants.VoucherScopeSeller:
+		if sellerID == 0 {
+			return nil, apperrors.New("INVALID_VOUCHER", "Seller ID is required for SELLER scope")
+		}
+		v.SellerID = sql.NullInt64{Int64: int64(sellerID), Valid: true}
+	case constants.VoucherScopeProduct:
+		productSellerID, err := s.repo.GetProductSellerID(ctx, *req.ProductID)
+		if err != nil {
+			return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get product")
+		}
+		if productSellerID == 0 {
+			return nil, apperrors.New("PRODUCT_NOT_FOUND", "Product not found")
+		}
+		if role != constants.RoleAdmin && productSellerID != creatorID {
+			return nil, apperrors.ErrForbidden
+		}
+		v.SellerID = sql.NullInt64{Int64: int64(productSellerID), Valid: true}
+		v.ProductID = sql.NullInt64{Int64: int64(*req.ProductID), Valid: true}
+	}
+
+	if req.MaxDiscount != nil {
+		v.MaxDiscount = sql.NullInt64{Int64: *req.MaxDiscount, Valid: true}
+	}
+	if req.UsageLimit != nil {
+		v.UsageLimit = sql.NullInt64{Int64: int64(*req.UsageLimit), Valid: true}
+	}
+	if req.StartsAt != nil {
+		v.StartsAt = sql.NullTime{Time: *req.StartsAt, Valid: true}
+	}
+	if req.ExpiresAt != nil {
+		v.ExpiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	if err := s.repo.Create(ctx, v); err != nil {
+		// Deleted vouchers keep their code, so it cannot be reused
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return nil, apperrors.New("VOUCHER_EXISTS", "Voucher code already exists or belonged to a deleted voucher")
+		}
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to create voucher")
+	}
+
+	v.CreatedAt = time.Now()
+	resp := ToVoucherResponse(v)
+	return &resp, nil
+}
+
+func (s *Service) GetMyVouchers(ctx context.Context, creatorID uint, page, perPage int) ([]*VoucherResponse, int, error) {
+	offset := (page - 1) * perPage
+	vouchers, total, err := s.repo.GetByCreatorID(ctx, creatorID, perPage, offset)
+	if err != nil {
+		return nil, 0, apperrors.Wrap(err, "DB_ERROR", "Failed to get vouchers")
+	}
+
+	return toVoucherResponses(vouchers), total, nil
+}
+
+func (s *Service) GetAll(ctx context.Context, page, perPage int) ([]*VoucherResponse, int, error) {
+	offset := (page - 1) * perPage
+	vouchers, total, err := s.repo.GetAll(ctx, perPage, offset)
+	if err != nil {
+		return nil, 0, apperrors.Wrap(err, "DB_ERROR", "Failed to get vouchers")
+	}
+
+	return toVoucherResponses(vouchers), total, nil
+}
+
+func (s *Service) Update(ctx context.Context, id uint, req UpdateVoucherRequest, userID uint, role string) error {
+	v, err := s.getOwned(ctx, id, userID, role)
+	if err != nil {
+		return err
+	}
+
+	if req.Description != nil {
+		v.Description = sql.NullString{String: *req.Description, Valid: *req.Description != ""}
+	}
+	if req.MaxDiscount != nil {
+		v.MaxDiscount = sql.NullInt64{Int64: *req.MaxDiscount, Valid: *req.MaxDiscount > 0}
+	}
+	if req.MinPurchase != nil {
+		v.MinPurchase = *req.MinPurchase
+	}
+	if req.UsageLimit != nil {
+		v.UsageLimit = sql.NullInt64{Int64: int64(*req.UsageLimit), Valid: *req.UsageLimit > 0}
+	}
+	if req.UsagePerUser != nil && *req.UsagePerUser > 0 {
+		v.UsagePerUser = *req.UsagePerUser
+	}
+	if req.StartsAt != nil {
+		v.StartsAt = sql.NullTime{Time: *req.StartsAt, Valid: true}
+	}
+	if req.ExpiresAt != nil {
+		v.ExpiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+	if req.IsActive != nil {
+		v.IsActive = *req.IsActive
+	}
+
+	if err := s.repo.Update(ctx, v); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to update voucher")
+	}
+	return nil
+}
+
+func (s *Service) Delete(ctx context.Context, id uint, userID uint, role string) error {
+	if _, err := s.getOwned(ctx, id, userID, role); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to delete voucher")
+	}
+	return nil
+}
+
+func (s *Service) getOwned(ctx context.Context, id uint, userID uint, role string) (*Voucher, error) {
+	v, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, apperrors.Wrap(err, "DB_ERROR", "Failed to get voucher")
+	}
+	if v == nil {
+		return nil, apperrors.New("VOUCHER_NOT_FOUND", "Voucher not found")
+	}
+	if role != constants.RoleAdmin && v.CreatorID != userID {
+		return nil, apperrors.ErrForbidden
+	}
+	return v, nil
+}
+
+// Evaluate checks whether a voucher can be applied to a purchase and
+// returns the discount it grants. usedByUser is the number of times the
+// buyer has already redeemed the voucher.
+func Evaluate(v *Voucher, sellerID, productID uint, amount int64, usedByUser int, now time.Time) (int64, error) {
+	if v == nil || !v.IsActive {
+		return 0, apperrors.New("VOUCHER_INVALID", "Voucher is not valid")
+	}
+	if v.StartsAt.Valid && now.Before(v.StartsAt.Time) {
+		return 0, apperrors.New("VOUCHER_NOT_STARTED", "Voucher is not yet active")
+	}
+	if v.ExpiresAt.Valid && !now.Before(v.ExpiresAt.Time) {
+		return 0, apperrors.New("VOUCHER_EXPIRED", "Voucher has expired")
+	}
+
+	switch v.Scope {
+	case constants.VoucherScopeSeller:
+		if !v.SellerID.Valid || uint(v.SellerID.Int64) != sellerID {
+			return 0, apperrors.New("VOUCHER_NOT_APPLICABLE", "Voucher does not apply to this product")
+		}
+	case constants.VoucherScopeProduct:
+		if !v.ProductID.Valid || uint(v.ProductID.Int64) != productID {
+			return 0, apperrors.New("VOUCHER_NOT_APPLICABLE", "Voucher does not apply to this product")
+		}
+	}
+
+	if amount < v.MinPurchase {
+		return 0, apperrors.New("VOUCHER_MIN_PURCHASE", "Purchase amount is below the voucher minimum")
+	}
+	if v.UsageLimit.Valid && int64(v.UsedCount) >= v.UsageLimit.Int64 {
+		return 0, apperrors.New("VOUCHER_EXHAUSTED", "Voucher usage limit reached")
+	}
+	if usedByUser >= v.UsagePerUser {
+		return 0, apperrors.New("VOUCHER_ALREADY_USED", "You have already used this voucher")
+	}
+
+	var discount int64
+	if v.DiscountType == constants.VoucherTypePercentage {
+		discount = amount * v.DiscountValue / 100
+		if v.MaxDiscount.Valid && discount > v.MaxDiscount.Int64 {
+			discount = v.MaxDiscount.Int64
+		}
+	} else {
+		discount = v.DiscountValue
+	}
+
+	if discount > amount {
+		discount = amount
+	}
+	return discount, nil
+}
+
+func toVoucherResponses(vouchers []*Voucher) []*VoucherResponse {
+	var responses []*VoucherResponse
+	for _, v := range vouchers {
+		resp := ToVoucherResponse(v)
+		responses = append(responses, &resp)
+	}
+	return responses
+}
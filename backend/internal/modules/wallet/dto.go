@@ -60,6 +60,26 @@ func (r *AdjustBalanceRequest) Validate() []ValidationError {
 	return errors
 }
 
+// Actor identifies the administrator freezing a wallet, for the audit trail
+type Actor struct {
+	UserID    uint
+	IPAddress string
+	UserAgent string
+}
+
+// FreezeWalletRequest for admin wallet freeze
+type FreezeWalletRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (r *FreezeWalletRequest) Validate() []ValidationError {
+	var errors []ValidationError
+	if r.Reason == "" {
+		errors = append(errors, ValidationError{Field: "reason", Message: "Reason is required for a freeze"})
+	}
+	return errors
+}
+
 // TransactionResponse for transaction details
 type TransactionResponse struct {
 	ID              uint   `json:"id"`
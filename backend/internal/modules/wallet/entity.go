@@ -69,5 +69,5 @@ type WalletWithUser struct {
 	Username string
 	FullName string
 	Email    string
-	RoleName string
+	RoleName string // all unexpired roles, comma separated, earliest first
 }
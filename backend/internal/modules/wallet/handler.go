@@ -3,6 +3,7 @@ package wallet
 import (
 	"strconv"
 
+	"walletpoint/internal/middleware"
 	apperrors "walletpoint/internal/shared/errors"
 	"walletpoint/internal/shared/response"
 
@@ -113,6 +114,11 @@ func (h *Handler) Transfer(c *fiber.Ctx) error {
 		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
 	}
 
+	// Large transfers need a recent step-up verification
+	if h.service.TransferNeedsStepUp(req.Amount) && !middleware.HasStepUp(c) {
+		return middleware.StepUpRequired(c)
+	}
+
 	result, err := h.service.Transfer(c.Context(), userID, req)
 	if err != nil {
 		return handleError(c, err)
@@ -148,6 +154,53 @@ func (h *Handler) AdjustBalance(c *fiber.Ctx) error {
 	return response.Success(c, "Balance adjusted successfully", result)
 }
 
+// Freeze handles admin wallet freeze
+func (h *Handler) Freeze(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	var req FreezeWalletRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if errors := req.Validate(); len(errors) > 0 {
+		return response.ErrorWithDetails(c, fiber.StatusBadRequest, "Validation failed", "VALIDATION_ERROR", toResponseErrors(errors))
+	}
+
+	result, err := h.service.Freeze(c.Context(), actor(c), uint(userID), req)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Wallet frozen successfully", result)
+}
+
+// Unfreeze handles admin wallet unfreeze
+func (h *Handler) Unfreeze(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("userId"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	result, err := h.service.Unfreeze(c.Context(), actor(c), uint(userID))
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	return response.Success(c, "Wallet unfrozen successfully", result)
+}
+
+func actor(c *fiber.Ctx) Actor {
+	return Actor{
+		UserID:    c.Locals("userID").(uint),
+		IPAddress: c.IP(),
+		UserAgent: c.Get("User-Agent"),
+	}
+}
+
 func handleError(c *fiber.Ctx, err error) error {
 	if appErr, ok := err.(*apperrors.AppError); ok {
 		switch appErr.Code {
@@ -18,6 +18,7 @@ type RepositoryInterface interface {
 	GetTransactionsByWalletID(ctx context.Context, walletID uint, limit, offset int) ([]*Transaction, int, error)
 	GetUserIDByWalletID(ctx context.Context, walletID uint) (uint, error)
 	GetWalletWithUser(ctx context.Context, userID uint) (*WalletWithUser, error)
+	SetFrozen(ctx context.Context, walletID uint, frozen bool, reason string, adminID uint) error
 }
 
 type Repository struct {
@@ -97,6 +98,27 @@ func (r *Repository) UpdateBalanceWithStats(ctx context.Context, tx *sql.Tx, wal
 	return err
 }
 
+// LockBalance moves points from the spendable balance into locked_balance
+func (r *Repository) LockBalance(ctx context.Context, tx *sql.Tx, walletID uint, amount int64) error {
+	query := `UPDATE wallets SET balance = balance - ?, locked_balance = locked_balance + ?, updated_at = NOW() WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, amount, amount, walletID)
+	return err
+}
+
+// UnlockBalance returns locked points to the spendable balance
+func (r *Repository) UnlockBalance(ctx context.Context, tx *sql.Tx, walletID uint, amount int64) error {
+	query := `UPDATE wallets SET balance = balance + ?, locked_balance = locked_balance - ?, updated_at = NOW() WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, amount, amount, walletID)
+	return err
+}
+
+// SpendLockedBalance pays out of locked_balance, counting it as spent
+func (r *Repository) SpendLockedBalance(ctx context.Context, tx *sql.Tx, walletID uint, amount int64) error {
+	query := `UPDATE wallets SET locked_balance = locked_balance - ?, lifetime_spent = lifetime_spent + ?, updated_at = NOW() WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, amount, amount, walletID)
+	return err
+}
+
 func (r *Repository) CreateLedgerEntry(ctx context.Context, tx *sql.Tx, entry *WalletLedger) error {
 	query := `
 		INSERT INTO wallet_ledgers (wallet_id, transaction_id, entry_type, amount, balance_before, balance_after, 
@@ -189,14 +211,15 @@ func (r *Repository) CreateTransaction(ctx context.Context, tx *sql.Tx, transact
 	query := `
 		INSERT INTO transactions (transaction_code, idempotency_key, transaction_type, status, 
 			from_wallet_id, to_wallet_id, amount, fee_amount, net_amount, description, 
-			processed_at, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
+			order_id, mission_log_id, metadata, processed_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
 	`
 
 	result, err := tx.ExecContext(ctx, query,
 		transaction.TransactionCode, transaction.IdempotencyKey, transaction.TransactionType,
 		transaction.Status, transaction.FromWalletID, transaction.ToWalletID, transaction.Amount,
-		transaction.FeeAmount, transaction.NetAmount, transaction.Description, transaction.ProcessedAt,
+		transaction.FeeAmount, transaction.NetAmount, transaction.Description,
+		transaction.OrderID, transaction.MissionLogID, transaction.Metadata, transaction.ProcessedAt,
 	)
 	if err != nil {
 		return err
@@ -268,11 +291,13 @@ func (r *Repository) GetWalletWithUser(ctx context.Context, userID uint) (*Walle
 	query := `
 		SELECT w.id, w.user_id, w.balance, w.locked_balance, w.lifetime_earned, w.lifetime_spent,
 			   w.is_frozen, w.frozen_reason, w.frozen_at, w.frozen_by, w.created_at, w.updated_at,
-			   u.username, u.full_name, u.email, r.name as role_name
+			   u.username, u.full_name, u.email,
+			   COALESCE((SELECT GROUP_CONCAT(ro.name ORDER BY ur.assigned_at ASC, ur.id ASC)
+				   FROM user_roles ur
+				   INNER JOIN roles ro ON ur.role_id = ro.id
+				   WHERE ur.user_id = u.id AND (ur.expires_at IS NULL OR ur.expires_at > NOW())), '') as role_names
 		FROM wallets w
 		INNER JOIN users u ON w.user_id = u.id
-		INNER JOIN user_roles ur ON u.id = ur.user_id
-		INNER JOIN roles r ON ur.role_id = r.id
 		WHERE w.user_id = ?
 	`
 
@@ -292,3 +317,22 @@ func (r *Repository) GetWalletWithUser(ctx context.Context, userID uint) (*Walle
 
 	return &ww, nil
 }
+
+// SetFrozen freezes or unfreezes a wallet; unfreezing clears who froze it and why
+func (r *Repository) SetFrozen(ctx context.Context, walletID uint, frozen bool, reason string, adminID uint) error {
+	if !frozen {
+		query := `
+			UPDATE wallets SET is_frozen = FALSE, frozen_reason = NULL, frozen_at = NULL, frozen_by = NULL, updated_at = NOW()
+			WHERE id = ?
+		`
+		_, err := r.db.ExecContext(ctx, query, walletID)
+		return err
+	}
+
+	query := `
+		UPDATE wallets SET is_frozen = TRUE, frozen_reason = ?, frozen_at = NOW(), frozen_by = ?, updated_at = NOW()
+		WHERE id = ?
+	`
+	_, err := r.db.ExecContext(ctx, query, reason, adminID, walletID)
+	return err
+}
@@ -21,7 +21,9 @@ func RegisterRoutes(app fiber.Router, handler *Handler, jwtManager *middleware.J
 		handler.Transfer,
 	)
 
-	// Admin routes
+	// Admin routes; each needs a recent step-up verification
 	admin := app.Group("/admin/wallets", middleware.JWTMiddleware(jwtManager), middleware.RequireAdmin())
-	admin.Post("/adjust", handler.AdjustBalance)
+	admin.Post("/adjust", middleware.RequireStepUp(), handler.AdjustBalance)
+	admin.Post("/:userId/freeze", middleware.RequireStepUp(), handler.Freeze)
+	admin.Post("/:userId/unfreeze", middleware.RequireStepUp(), handler.Unfreeze)
 }
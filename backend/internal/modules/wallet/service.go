@@ -3,8 +3,13 @@ package wallet
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 
+	"walletpoint/internal/config"
+	"walletpoint/internal/modules/audit"
 	"walletpoint/internal/shared/constants"
 	apperrors "walletpoint/internal/shared/errors"
 	"walletpoint/pkg/utils"
@@ -16,20 +21,32 @@ type ServiceInterface interface {
 	GetLedger(ctx context.Context, userID uint, page, perPage int) ([]*LedgerEntryResponse, int, error)
 	Transfer(ctx context.Context, fromUserID uint, req TransferRequest) (*TransferResponse, error)
 	AdjustBalance(ctx context.Context, adminID uint, req AdjustBalanceRequest) (*TransactionResponse, error)
+	Freeze(ctx context.Context, actor Actor, userID uint, req FreezeWalletRequest) (*BalanceResponse, error)
+	Unfreeze(ctx context.Context, actor Actor, userID uint) (*BalanceResponse, error)
 }
 
 type Service struct {
-	repo *Repository
-	db   *sql.DB
+	repo      *Repository
+	auditRepo *audit.Repository
+	db        *sql.DB
+	twoFactor config.TwoFactorConfig
 }
 
-func NewService(repo *Repository, db *sql.DB) *Service {
+func NewService(repo *Repository, auditRepo *audit.Repository, db *sql.DB, twoFactorCfg config.TwoFactorConfig) *Service {
 	return &Service{
-		repo: repo,
-		db:   db,
+		repo:      repo,
+		auditRepo: auditRepo,
+		db:        db,
+		twoFactor: twoFactorCfg,
 	}
 }
 
+// TransferNeedsStepUp reports whether a transfer is large enough to need a
+// recent step-up verification
+func (s *Service) TransferNeedsStepUp(amount int64) bool {
+	return amount > s.twoFactor.TransferThreshold
+}
+
 func (s *Service) GetBalance(ctx context.Context, userID uint) (*BalanceResponse, error) {
 	wallet, err := s.repo.GetByUserID(ctx, userID)
 	if err != nil {
@@ -326,3 +343,66 @@ func (s *Service) AdjustBalance(ctx context.Context, adminID uint, req AdjustBal
 	resp := ToTransactionResponse(transaction, entryType, "")
 	return &resp, nil
 }
+
+// Freeze stops a wallet from sending points until an admin unfreezes it
+func (s *Service) Freeze(ctx context.Context, actor Actor, userID uint, req FreezeWalletRequest) (*BalanceResponse, error) {
+	if err := s.setFrozen(ctx, actor, userID, true, req.Reason); err != nil {
+		return nil, err
+	}
+	return s.GetBalance(ctx, userID)
+}
+
+// Unfreeze lifts a freeze
+func (s *Service) Unfreeze(ctx context.Context, actor Actor, userID uint) (*BalanceResponse, error) {
+	if err := s.setFrozen(ctx, actor, userID, false, ""); err != nil {
+		return nil, err
+	}
+	return s.GetBalance(ctx, userID)
+}
+
+func (s *Service) setFrozen(ctx context.Context, actor Actor, userID uint, frozen bool, reason string) error {
+	wallet, err := s.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		return apperrors.Wrap(err, "INTERNAL_ERROR", "Failed to get wallet")
+	}
+	if wallet == nil {
+		return apperrors.ErrWalletNotFound
+	}
+	if err := s.repo.SetFrozen(ctx, wallet.ID, frozen, reason, actor.UserID); err != nil {
+		return apperrors.Wrap(err, "DB_ERROR", "Failed to update wallet")
+	}
+
+	action, description := "WALLET_UNFREEZE", fmt.Sprintf("Wallet of user %d unfrozen", userID)
+	if frozen {
+		action, description = "WALLET_FREEZE", fmt.Sprintf("Wallet of user %d frozen: %s", userID, reason)
+	}
+	entry := &audit.Log{
+		UserID:         sql.NullInt64{Int64: int64(actor.UserID), Valid: actor.UserID != 0},
+		TargetType:     "wallets",
+		TargetID:       wallet.ID,
+		Action:         action,
+		ActionCategory: constants.AuditCategoryWallet,
+		OldValues:      audit.Values(frozenValues(wallet.IsFrozen, wallet.FrozenReason.String)),
+		NewValues:      audit.Values(frozenValues(frozen, reason)),
+		IPAddress:      nullString(actor.IPAddress),
+		UserAgent:      nullString(actor.UserAgent),
+		Description:    description,
+		RiskLevel:      constants.RiskLevelHigh,
+	}
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		log.Printf("Failed to write audit log for wallet %d: %v", wallet.ID, err)
+	}
+	return nil
+}
+
+func frozenValues(frozen bool, reason string) map[string]interface{} {
+	return map[string]interface{}{
+		"is_frozen":     frozen,
+		"frozen_reason": reason,
+	}
+}
+
+func nullString(s string) sql.NullString {
+	s = strings.TrimSpace(s)
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -67,12 +67,45 @@ const (
 	MissionStatusExpired    = "EXPIRED"
 )
 
+// Mission Budget Sources
+const (
+	BudgetSourceNone   = "NONE"
+	BudgetSourceWallet = "WALLET"
+	BudgetSourcePool   = "POOL"
+)
+
+// Class Group Kinds
+const (
+	GroupKindClass  = "CLASS"
+	GroupKindCohort = "COHORT"
+	GroupKindList   = "LIST"
+)
+
+// Notification Types
+const (
+	NotificationMissionDeadline = "MISSION_DEADLINE"
+	NotificationMissionExpired  = "MISSION_EXPIRED"
+	NotificationBadgeAwarded    = "BADGE_AWARDED"
+)
+
+// Ledger reference for points moved in or out of a mission budget
+const LedgerRefMissionBudget = "MISSION_BUDGET"
+
 // Order Status
 const (
 	OrderStatusPending   = "PENDING"
 	OrderStatusPaid      = "PAID"
 	OrderStatusCompleted = "COMPLETED"
 	OrderStatusCancelled = "CANCELLED"
+	OrderStatusRefunded  = "REFUNDED"
+)
+
+// Stock Reservation Status
+const (
+	ReservationStatusActive   = "ACTIVE"
+	ReservationStatusConsumed = "CONSUMED"
+	ReservationStatusReleased = "RELEASED"
+	ReservationStatusExpired  = "EXPIRED"
 )
 
 // Audit Categories
@@ -93,3 +126,16 @@ const (
 	RiskLevelHigh     = "HIGH"
 	RiskLevelCritical = "CRITICAL"
 )
+
+// Voucher Discount Types
+const (
+	VoucherTypePercentage = "PERCENTAGE"
+	VoucherTypeFixed      = "FIXED"
+)
+
+// Voucher Scopes
+const (
+	VoucherScopeGlobal  = "GLOBAL"
+	VoucherScopeSeller  = "SELLER"
+	VoucherScopeProduct = "PRODUCT"
+)
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/mail"
+	"net/smtp"
+)
+
+// SMTPMailer delivers through an SMTP server, upgrading to TLS when the
+// server offers STARTTLS
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	// The envelope sender is the bare address of the From header
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.Host, m.Port)
+	return smtp.SendMail(addr, auth, from.Address, []string{headerValue(msg.To)}, compose(m.From, msg))
+}
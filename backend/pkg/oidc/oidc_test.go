@@ -0,0 +1,327 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID = "walletpoint"
+	testRedirect = "http://localhost:8080/sso/callback"
+	testNonce    = "nonce-1"
+)
+
+// testIdP is an in-process provider. Its token endpoint answers a single
+// pending code with whatever ID token the test queued
+type testIdP struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu         sync.Mutex
+	keys       map[string]ed25519.PrivateKey
+	jwksHits   int
+	challenge  string
+	idToken    string
+	lastSecret string
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	idp := &testIdP{t: t, keys: map[string]ed25519.PrivateKey{}}
+	idp.addKey("k1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+		idp.jwksHits++
+		var keys []map[string]string
+		for kid, key := range idp.keys {
+			keys = append(keys, map[string]string{
+				"kty": "OKP",
+				"crv": "Ed25519",
+				"kid": kid,
+				"use": "sig",
+				"x":   base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+		_, idp.lastSecret, _ = r.BasicAuth()
+		if r.Form.Get("code") != "code-1" || r.Form.Get("client_id") != testClientID ||
+			r.Form.Get("redirect_uri") != testRedirect || Challenge(r.Form.Get("code_verifier")) != idp.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "at",
+			"token_type":   "Bearer",
+			"id_token":     idp.idToken,
+		})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *testIdP) addKey(kid string) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		idp.t.Fatalf("generate key: %v", err)
+	}
+	idp.mu.Lock()
+	idp.keys[kid] = key
+	idp.mu.Unlock()
+}
+
+func (idp *testIdP) provider(secret string) *Provider {
+	return NewProvider(Config{
+		IssuerURL:    idp.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: secret,
+		RedirectURL:  testRedirect,
+		Scopes:       []string{"openid", "email"},
+	})
+}
+
+// claims returns valid ID token claims for tweaking
+func (idp *testIdP) claims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"aud":            testClientID,
+		"sub":            "user-1",
+		"email":          "user1@campus.ac.id",
+		"email_verified": true,
+		"nim":            "2201001",
+		"nonce":          testNonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+func (idp *testIdP) sign(kid string, claims jwt.MapClaims) string {
+	idp.mu.Lock()
+	key := idp.keys[kid]
+	idp.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = kid
+	raw, err := token.SignedString(key)
+	if err != nil {
+		idp.t.Fatalf("sign: %v", err)
+	}
+	return raw
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	idp := newTestIdP(t)
+	p := idp.provider("")
+
+	raw, err := p.AuthCodeURL(context.Background(), "state-1", testNonce, "verifier-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	for _, want := range []string{
+		idp.server.URL + "/authorize?",
+		"client_id=" + testClientID,
+		"state=state-1",
+		"nonce=" + testNonce,
+		"code_challenge=" + Challenge("verifier-1"),
+		"code_challenge_method=S256",
+	} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("URL %q is missing %q", raw, want)
+		}
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	// RFC 7636 appendix B
+	got := Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("Challenge = %q, want %q", got, want)
+	}
+}
+
+func TestExchangeAndVerify(t *testing.T) {
+	idp := newTestIdP(t)
+	p := idp.provider("s3cret")
+	ctx := context.Background()
+
+	verifier, err := NewVerifier()
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	idp.challenge = Challenge(verifier)
+	idp.idToken = idp.sign("k1", idp.claims())
+
+	token, err := p.Exchange(ctx, "code-1", verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if idp.lastSecret != "s3cret" {
+		t.Errorf("client secret = %q, want it sent with basic auth", idp.lastSecret)
+	}
+
+	claims, err := p.VerifyIDToken(ctx, token.IDToken, testNonce)
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "user1@campus.ac.id" || !claims.EmailVerified {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if claims.Issuer != idp.server.URL {
+		t.Errorf("Issuer = %q, want %q", claims.Issuer, idp.server.URL)
+	}
+	if got := claims.String("nim"); got != "2201001" {
+		t.Errorf("nim = %q, want 2201001", got)
+	}
+}
+
+func TestExchangeVerifierMismatch(t *testing.T) {
+	idp := newTestIdP(t)
+	p := idp.provider("")
+
+	idp.challenge = Challenge("the-real-verifier")
+	idp.idToken = idp.sign("k1", idp.claims())
+
+	if _, err := p.Exchange(context.Background(), "code-1", "another-verifier"); err == nil {
+		t.Fatal("Exchange accepted a wrong PKCE verifier")
+	}
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	idp := newTestIdP(t)
+	p := idp.provider("")
+
+	hs256 := jwt.NewWithClaims(jwt.SigningMethodHS256, idp.claims())
+	hs256.Header["kid"] = "k1"
+	hs256Raw, err := hs256.SignedString([]byte("shared"))
+	if err != nil {
+		t.Fatalf("sign HS256: %v", err)
+	}
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, idp.claims())
+	none.Header["kid"] = "k1"
+	noneRaw, err := none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("sign none: %v", err)
+	}
+
+	// A valid signature over different claims
+	signed := strings.Split(idp.sign("k1", idp.claims()), ".")
+	forged := strings.Split(idp.sign("k1", with(idp.claims(), "sub", "admin")), ".")
+	tampered := signed[0] + "." + forged[1] + "." + signed[2]
+
+	tests := []struct {
+		name  string
+		raw   string
+		nonce string
+	}{
+		{"nonce mismatch", idp.sign("k1", idp.claims()), "other-nonce"},
+		{"missing nonce", idp.sign("k1", without(idp.claims(), "nonce")), testNonce},
+		{"wrong audience", idp.sign("k1", with(idp.claims(), "aud", "another-client")), testNonce},
+		{"wrong issuer", idp.sign("k1", with(idp.claims(), "iss", "https://evil.example")), testNonce},
+		{"expired", idp.sign("k1", with(idp.claims(), "exp", time.Now().Add(-2*time.Minute).Unix())), testNonce},
+		{"no expiry", idp.sign("k1", without(idp.claims(), "exp")), testNonce},
+		{"missing subject", idp.sign("k1", without(idp.claims(), "sub")), testNonce},
+		{"HS256", hs256Raw, testNonce},
+		{"alg none", noneRaw, testNonce},
+		{"tampered payload", tampered, testNonce},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.VerifyIDToken(context.Background(), tt.raw, tt.nonce); err == nil {
+				t.Fatal("VerifyIDToken accepted the token")
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenUnknownKeyRefresh(t *testing.T) {
+	idp := newTestIdP(t)
+	p := idp.provider("")
+	ctx := context.Background()
+
+	if _, err := p.VerifyIDToken(ctx, idp.sign("k1", idp.claims()), testNonce); err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+
+	// The provider rotates; right after a fetch the new kid is not looked up
+	// again, so a flood of unknown kids cannot hammer the provider
+	idp.addKey("k2")
+	rotated := idp.sign("k2", idp.claims())
+	if _, err := p.VerifyIDToken(ctx, rotated, testNonce); err == nil {
+		t.Fatal("VerifyIDToken refetched keys within the refresh interval")
+	}
+	if idp.jwksHits != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1", idp.jwksHits)
+	}
+
+	// Once the interval has passed the unknown kid triggers a refetch
+	p.mu.Lock()
+	p.keysFetched = time.Now().Add(-keysRefreshInterval - time.Second)
+	p.mu.Unlock()
+	if _, err := p.VerifyIDToken(ctx, rotated, testNonce); err != nil {
+		t.Fatalf("VerifyIDToken after rotation: %v", err)
+	}
+	if idp.jwksHits != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", idp.jwksHits)
+	}
+
+	// A kid the provider never published stays unknown
+	p.mu.Lock()
+	p.keysFetched = time.Time{}
+	p.mu.Unlock()
+	idp.addKey("k3")
+	forged := idp.sign("k3", idp.claims())
+	idp.mu.Lock()
+	delete(idp.keys, "k3")
+	idp.mu.Unlock()
+	if _, err := p.VerifyIDToken(ctx, forged, testNonce); err == nil {
+		t.Fatal("VerifyIDToken accepted an unpublished key")
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	idp := newTestIdP(t)
+	p := NewProvider(Config{IssuerURL: idp.server.URL + "/other", ClientID: testClientID})
+
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "v"); err == nil {
+		t.Fatal("discovery accepted a document for another issuer")
+	}
+}
+
+func with(claims jwt.MapClaims, name string, value interface{}) jwt.MapClaims {
+	claims[name] = value
+	return claims
+}
+
+func without(claims jwt.MapClaims, name string) jwt.MapClaims {
+	delete(claims, name)
+	return claims
+}
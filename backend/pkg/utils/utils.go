@@ -1,9 +1,14 @@
 package utils
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/hmac"
+	crand "crypto/rand"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/rand"
 	"time"
@@ -61,6 +66,86 @@ func VerifyQRSignature(code string, amount int64, creatorID uint, signature, sec
 	return VerifyHMAC(data, signature, secret)
 }
 
+// GenerateSecureToken returns n random bytes encoded for use in URLs
+func GenerateSecureToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// passwordAlphabet leaves out characters that are easy to misread on paper
+const passwordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz23456789"
+
+// GeneratePassword returns a random password of the given length
+func GeneratePassword(length int) (string, error) {
+	// Bytes at or above limit are discarded so every character is equally likely
+	limit := 256 - 256%len(passwordAlphabet)
+	password := make([]byte, 0, length)
+	buf := make([]byte, length)
+	for len(password) < length {
+		if _, err := crand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, c := range buf {
+			if int(c) < limit && len(password) < length {
+				password = append(password, passwordAlphabet[int(c)%len(passwordAlphabet)])
+			}
+		}
+	}
+	return string(password), nil
+}
+
+// HashToken returns the SHA-256 hex digest stored in place of a bearer token
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// EncryptString seals plain with AES-256-GCM under a key derived from secret
+func EncryptString(plain, secret string) (string, error) {
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := crand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptString opens a value produced by EncryptString
+func DecryptString(encrypted, secret string) (string, error) {
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func newGCM(secret string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
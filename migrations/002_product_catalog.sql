@@ -0,0 +1,45 @@
+-- ========================================================
+-- MIGRATION: PRODUCT CATALOG (CATEGORIES & SEARCH)
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- 18. TABLE: product_categories
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS product_categories;
+CREATE TABLE product_categories (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(120) NOT NULL UNIQUE,
+    description VARCHAR(255) NULL,
+    parent_id BIGINT UNSIGNED NULL,
+    sort_order INT DEFAULT 0,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL,
+    FOREIGN KEY (parent_id) REFERENCES product_categories(id) ON DELETE SET NULL,
+    INDEX idx_slug (slug),
+    INDEX idx_parent_id (parent_id),
+    INDEX idx_is_active (is_active),
+    INDEX idx_deleted_at (deleted_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+-- products: category, full-text search & sorting indexes
+-- --------------------------------------------------------
+ALTER TABLE products
+    ADD COLUMN category_id BIGINT UNSIGNED NULL AFTER product_type,
+    ADD CONSTRAINT fk_products_category FOREIGN KEY (category_id) REFERENCES product_categories(id) ON DELETE SET NULL,
+    ADD INDEX idx_category_id (category_id),
+    ADD INDEX idx_is_featured (is_featured),
+    ADD INDEX idx_sold_count (sold_count),
+    ADD INDEX idx_created_at (created_at),
+    ADD FULLTEXT INDEX ft_name_description (name, description);
+
+-- Sample categories
+INSERT INTO product_categories (name, slug, description, sort_order) VALUES
+('Pemrograman', 'pemrograman', 'Materi dan kursus pemrograman', 1),
+('Basis Data', 'basis-data', 'Materi dan kursus basis data', 2),
+('Umum', 'umum', 'Produk umum lainnya', 99);
@@ -0,0 +1,67 @@
+-- ========================================================
+-- MIGRATION: VOUCHERS & PROMO CODES
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- 19. TABLE: vouchers
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS vouchers;
+CREATE TABLE vouchers (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(255) NULL,
+    creator_id BIGINT UNSIGNED NOT NULL,
+    scope ENUM('GLOBAL', 'SELLER', 'PRODUCT') NOT NULL DEFAULT 'SELLER',
+    seller_id BIGINT UNSIGNED NULL,
+    product_id BIGINT UNSIGNED NULL,
+    discount_type ENUM('PERCENTAGE', 'FIXED') NOT NULL,
+    discount_value BIGINT NOT NULL,
+    max_discount BIGINT NULL COMMENT 'Batas potongan untuk voucher persentase',
+    min_purchase BIGINT NOT NULL DEFAULT 0,
+    usage_limit INT NULL COMMENT 'NULL = tanpa batas',
+    usage_per_user INT NOT NULL DEFAULT 1,
+    used_count INT NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP NULL,
+    expires_at TIMESTAMP NULL,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL,
+    FOREIGN KEY (creator_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (seller_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    INDEX idx_code (code),
+    INDEX idx_creator_id (creator_id),
+    INDEX idx_seller_id (seller_id),
+    INDEX idx_product_id (product_id),
+    INDEX idx_is_active (is_active),
+    INDEX idx_deleted_at (deleted_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+-- 20. TABLE: voucher_usages
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS voucher_usages;
+CREATE TABLE voucher_usages (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    voucher_id BIGINT UNSIGNED NOT NULL,
+    user_id BIGINT UNSIGNED NOT NULL,
+    order_id BIGINT UNSIGNED NOT NULL,
+    discount_amount BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (voucher_id) REFERENCES vouchers(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_voucher_order (voucher_id, order_id),
+    INDEX idx_voucher_user (voucher_id, user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+-- orders: discount & voucher reference
+-- --------------------------------------------------------
+ALTER TABLE orders
+    ADD COLUMN discount_amount BIGINT NOT NULL DEFAULT 0 AFTER total_amount,
+    ADD COLUMN voucher_id BIGINT UNSIGNED NULL AFTER discount_amount,
+    ADD CONSTRAINT fk_orders_voucher FOREIGN KEY (voucher_id) REFERENCES vouchers(id) ON DELETE SET NULL;
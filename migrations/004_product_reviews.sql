@@ -0,0 +1,41 @@
+-- ========================================================
+-- MIGRATION: PRODUCT REVIEWS & RATINGS
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- 21. TABLE: product_reviews
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS product_reviews;
+CREATE TABLE product_reviews (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    product_id BIGINT UNSIGNED NOT NULL,
+    user_id BIGINT UNSIGNED NOT NULL,
+    order_id BIGINT UNSIGNED NOT NULL,
+    rating TINYINT UNSIGNED NOT NULL,
+    comment TEXT NULL,
+    seller_reply TEXT NULL,
+    replied_at TIMESTAMP NULL,
+    is_hidden BOOLEAN DEFAULT FALSE,
+    hidden_reason VARCHAR(255) NULL,
+    hidden_by BIGINT UNSIGNED NULL,
+    hidden_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    FOREIGN KEY (hidden_by) REFERENCES users(id) ON DELETE SET NULL,
+    UNIQUE KEY uk_product_user (product_id, user_id),
+    INDEX idx_product_id (product_id),
+    INDEX idx_user_id (user_id),
+    INDEX idx_is_hidden (is_hidden),
+    CONSTRAINT chk_rating CHECK (rating BETWEEN 1 AND 5)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+-- products: rating sort index
+-- --------------------------------------------------------
+ALTER TABLE products
+    ADD INDEX idx_rating (rating_average, rating_count);
@@ -0,0 +1,18 @@
+-- ========================================================
+-- MIGRATION: SELLER SALES ANALYTICS
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- transactions: marketplace purchases are recorded as PURCHASE
+-- --------------------------------------------------------
+ALTER TABLE transactions
+    MODIFY COLUMN transaction_type ENUM('QR_PAYMENT', 'TOPUP', 'MISSION_REWARD', 'TRANSFER', 'SYNC', 'ADJUSTMENT', 'PURCHASE') NOT NULL;
+
+-- --------------------------------------------------------
+-- orders: date-range reporting indexes
+-- --------------------------------------------------------
+ALTER TABLE orders
+    ADD INDEX idx_seller_created (seller_id, created_at),
+    ADD INDEX idx_seller_buyer (seller_id, buyer_id);
@@ -0,0 +1,37 @@
+-- ========================================================
+-- MIGRATION: STOCK RESERVATIONS
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- 22. TABLE: stock_reservations
+-- Holds stock for a buyer during checkout; held units are
+-- taken out of products.stock and returned on release/expiry
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS stock_reservations;
+CREATE TABLE stock_reservations (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    product_id BIGINT UNSIGNED NOT NULL,
+    user_id BIGINT UNSIGNED NOT NULL,
+    quantity INT NOT NULL,
+    status ENUM('ACTIVE', 'CONSUMED', 'RELEASED', 'EXPIRED') NOT NULL DEFAULT 'ACTIVE',
+    order_id BIGINT UNSIGNED NULL,
+    expires_at TIMESTAMP NOT NULL,
+    released_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE SET NULL,
+    INDEX idx_product_status (product_id, status),
+    INDEX idx_user_status (user_id, status),
+    INDEX idx_status_expires (status, expires_at),
+    CONSTRAINT chk_reservation_quantity CHECK (quantity > 0)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+-- products: stock can never go negative
+-- --------------------------------------------------------
+ALTER TABLE products
+    ADD CONSTRAINT chk_products_stock CHECK (stock IS NULL OR stock >= 0);
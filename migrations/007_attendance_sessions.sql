@@ -0,0 +1,30 @@
+-- ========================================================
+-- MIGRATION: QR ATTENDANCE SESSIONS
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- 23. TABLE: attendance_sessions
+-- A check-in window of an ATTENDANCE mission; the QR shown by the
+-- lecturer rotates every rotation_seconds and is signed with secret
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS attendance_sessions;
+CREATE TABLE attendance_sessions (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    mission_id BIGINT UNSIGNED NOT NULL,
+    created_by BIGINT UNSIGNED NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    rotation_seconds INT NOT NULL DEFAULT 30,
+    latitude DECIMAL(10,7) NULL,
+    longitude DECIMAL(10,7) NULL,
+    radius_meters INT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    closed_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (mission_id) REFERENCES missions(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_mission_id (mission_id),
+    INDEX idx_ends_at (ends_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
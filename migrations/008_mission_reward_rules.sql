@@ -0,0 +1,12 @@
+-- ========================================================
+-- MIGRATION: MISSION REWARD RULES
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- missions: per-mission reward rules (proportional/tiered,
+-- early bonus, late penalty); NULL keeps the fixed reward
+-- --------------------------------------------------------
+ALTER TABLE missions
+    ADD COLUMN reward_rules JSON NULL AFTER reward_points;
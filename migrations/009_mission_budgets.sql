@@ -0,0 +1,48 @@
+-- ========================================================
+-- MIGRATION: MISSION BUDGETS & REWARD POOLS
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- 24. TABLE: reward_pools
+-- Admin-funded points that lecturers can draw mission budgets from.
+-- balance is available to new missions, reserved is held by open ones.
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS reward_pools;
+CREATE TABLE reward_pools (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(255) NULL,
+    balance BIGINT NOT NULL DEFAULT 0,
+    reserved BIGINT NOT NULL DEFAULT 0,
+    total_funded BIGINT NOT NULL DEFAULT 0,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_by BIGINT UNSIGNED NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_is_active (is_active),
+    CONSTRAINT chk_reward_pools_balance CHECK (balance >= 0 AND reserved >= 0)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+-- missions: reserved reward budget. WALLET budgets sit in the
+-- creator's locked_balance, POOL budgets in reward_pools.reserved.
+-- NONE marks missions created before budgets existed.
+-- --------------------------------------------------------
+ALTER TABLE missions
+    ADD COLUMN budget_source ENUM('NONE', 'WALLET', 'POOL') NOT NULL DEFAULT 'NONE' AFTER reward_rules,
+    ADD COLUMN reward_pool_id BIGINT UNSIGNED NULL AFTER budget_source,
+    ADD COLUMN budget_amount BIGINT NOT NULL DEFAULT 0 AFTER reward_pool_id,
+    ADD COLUMN budget_spent BIGINT NOT NULL DEFAULT 0 AFTER budget_amount,
+    ADD COLUMN budget_released_at TIMESTAMP NULL AFTER budget_spent,
+    ADD CONSTRAINT fk_missions_reward_pool FOREIGN KEY (reward_pool_id) REFERENCES reward_pools(id) ON DELETE SET NULL,
+    ADD CONSTRAINT chk_missions_budget CHECK (budget_spent <= budget_amount OR budget_source = 'NONE');
+
+-- --------------------------------------------------------
+-- transactions: rewards are looked up by the graded submission
+-- --------------------------------------------------------
+ALTER TABLE transactions
+    ADD INDEX idx_mission_log (mission_log_id),
+    ADD CONSTRAINT fk_transactions_mission_log FOREIGN KEY (mission_log_id) REFERENCES mission_logs(id) ON DELETE SET NULL;
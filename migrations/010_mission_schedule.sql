@@ -0,0 +1,37 @@
+-- ========================================================
+-- MIGRATION: MISSION SCHEDULE & NOTIFICATIONS
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- 25. TABLE: notifications
+-- In-app notifications; the unique key lets background jobs
+-- send each reminder at most once per user and subject.
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS notifications;
+CREATE TABLE notifications (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    body VARCHAR(500) NOT NULL,
+    reference_type VARCHAR(50) NOT NULL,
+    reference_id VARCHAR(100) NOT NULL,
+    read_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_user_type_reference (user_id, type, reference_type, reference_id),
+    INDEX idx_user_read (user_id, read_at),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+-- missions / mission_logs: scheduler lookups
+-- --------------------------------------------------------
+ALTER TABLE missions
+    ADD INDEX idx_active_end_date (is_active, end_date),
+    ADD INDEX idx_deadline (deadline);
+
+ALTER TABLE mission_logs
+    ADD INDEX idx_status_mission (status, mission_id);
@@ -0,0 +1,42 @@
+-- ========================================================
+-- MIGRATION: MISSION FILE SUBMISSIONS
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- 26. TABLE: mission_submissions
+-- Every hand-in of a manually graded mission is kept as a
+-- numbered version; mission_logs.answers mirrors the latest.
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS mission_submissions;
+CREATE TABLE mission_submissions (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    mission_log_id BIGINT UNSIGNED NOT NULL,
+    version INT NOT NULL,
+    answers JSON NULL,
+    note TEXT NULL,
+    submitted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (mission_log_id) REFERENCES mission_logs(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_log_version (mission_log_id, version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+-- 27. TABLE: submission_attachments
+-- Uploaded files (stored on disk under storage_key) and links
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS submission_attachments;
+CREATE TABLE submission_attachments (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    submission_id BIGINT UNSIGNED NOT NULL,
+    kind ENUM('FILE', 'LINK') NOT NULL,
+    file_name VARCHAR(255) NULL,
+    content_type VARCHAR(100) NULL,
+    size_bytes BIGINT NULL,
+    storage_key VARCHAR(255) NULL,
+    checksum CHAR(64) NULL,
+    url VARCHAR(2048) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (submission_id) REFERENCES mission_submissions(id) ON DELETE CASCADE,
+    INDEX idx_submission_id (submission_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
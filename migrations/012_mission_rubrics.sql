@@ -0,0 +1,18 @@
+-- ========================================================
+-- MIGRATION: MISSION GRADING RUBRICS
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- missions: weighted grading criteria; NULL grades with a
+-- single 0-100 score
+-- --------------------------------------------------------
+ALTER TABLE missions
+    ADD COLUMN rubric JSON NULL AFTER reward_rules;
+
+-- --------------------------------------------------------
+-- mission_logs: points awarded per rubric criterion
+-- --------------------------------------------------------
+ALTER TABLE mission_logs
+    ADD COLUMN rubric_scores JSON NULL AFTER score;
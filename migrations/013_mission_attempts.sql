@@ -0,0 +1,25 @@
+-- ========================================================
+-- MIGRATION: REPEATABLE MISSION ATTEMPTS
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- missions: attempt policy for repeatable missions. NULL
+-- max_attempts and reward_cap mean unlimited.
+-- --------------------------------------------------------
+ALTER TABLE missions
+    ADD COLUMN max_attempts INT NULL AFTER is_repeatable,
+    ADD COLUMN attempt_cooldown_minutes INT NOT NULL DEFAULT 0 AFTER max_attempts,
+    ADD COLUMN reward_cap BIGINT NULL AFTER attempt_cooldown_minutes,
+    ADD COLUMN reward_cap_period ENUM('DAY', 'WEEK', 'MONTH') NULL AFTER reward_cap;
+
+-- --------------------------------------------------------
+-- mission_logs: one row per attempt instead of one per
+-- participant; existing rows become attempt 1
+-- --------------------------------------------------------
+ALTER TABLE mission_logs
+    ADD COLUMN attempt_number INT NOT NULL DEFAULT 1 AFTER user_id,
+    ADD UNIQUE KEY uk_mission_user_attempt (mission_id, user_id, attempt_number),
+    DROP INDEX uk_mission_user,
+    ADD INDEX idx_user_mission_completed (user_id, mission_id, completed_at);
@@ -0,0 +1,54 @@
+-- ========================================================
+-- MIGRATION: CLASS GROUPS & MISSION TARGETING
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- 28. TABLE: class_groups
+-- Classes, cohorts or ad-hoc enrollment lists owned by a lecturer
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS class_groups;
+CREATE TABLE class_groups (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    code VARCHAR(30) NULL,
+    kind ENUM('CLASS', 'COHORT', 'LIST') NOT NULL DEFAULT 'CLASS',
+    description VARCHAR(255) NULL,
+    owner_id BIGINT UNSIGNED NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL,
+    FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_owner_id (owner_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+-- 29. TABLE: class_group_members
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS class_group_members;
+CREATE TABLE class_group_members (
+    group_id BIGINT UNSIGNED NOT NULL,
+    user_id BIGINT UNSIGNED NOT NULL,
+    added_by BIGINT UNSIGNED NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, user_id),
+    FOREIGN KEY (group_id) REFERENCES class_groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (added_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+-- 30. TABLE: mission_target_groups
+-- A mission with no rows here is open to every student
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS mission_target_groups;
+CREATE TABLE mission_target_groups (
+    mission_id BIGINT UNSIGNED NOT NULL,
+    group_id BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (mission_id, group_id),
+    FOREIGN KEY (mission_id) REFERENCES missions(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_id) REFERENCES class_groups(id) ON DELETE CASCADE,
+    INDEX idx_group_id (group_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,61 @@
+-- ========================================================
+-- MIGRATION: ACHIEVEMENT BADGES & LEADERBOARDS
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- 31. TABLE: badges
+-- Achievement rules. MISSIONS_COMPLETED counts completed
+-- missions (optionally of one mission_type), POINTS_EARNED
+-- sums mission rewards, STREAK_DAYS is the longest run of
+-- consecutive days with a completed mission.
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS badges;
+CREATE TABLE badges (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(255) NULL,
+    icon VARCHAR(255) NULL,
+    rule_type ENUM('MISSIONS_COMPLETED', 'POINTS_EARNED', 'STREAK_DAYS') NOT NULL,
+    mission_type ENUM('QUIZ', 'ASSIGNMENT', 'ATTENDANCE', 'PROJECT', 'OTHER') NULL,
+    threshold BIGINT NOT NULL,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_is_active (is_active),
+    CONSTRAINT chk_badges_threshold CHECK (threshold > 0)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+-- 32. TABLE: user_badges
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS user_badges;
+CREATE TABLE user_badges (
+    user_id BIGINT UNSIGNED NOT NULL,
+    badge_id BIGINT UNSIGNED NOT NULL,
+    awarded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, badge_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (badge_id) REFERENCES badges(id) ON DELETE CASCADE,
+    INDEX idx_badge_id (badge_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT INTO badges (code, name, description, rule_type, mission_type, threshold) VALUES
+('FIRST_MISSION', 'First Steps', 'Complete your first mission', 'MISSIONS_COMPLETED', NULL, 1),
+('MISSIONS_25', 'Mission Regular', 'Complete 25 missions', 'MISSIONS_COMPLETED', NULL, 25),
+('QUIZ_10', 'Quiz Whiz', 'Pass 10 quizzes', 'MISSIONS_COMPLETED', 'QUIZ', 10),
+('ATTENDANCE_20', 'Always There', 'Check in to 20 classes', 'MISSIONS_COMPLETED', 'ATTENDANCE', 20),
+('POINTS_1000', 'Point Collector', 'Earn 1,000 points from missions', 'POINTS_EARNED', NULL, 1000),
+('STREAK_7', 'On a Roll', 'Complete a mission 7 days in a row', 'STREAK_DAYS', NULL, 7),
+('STREAK_30', 'Unstoppable', 'Complete a mission 30 days in a row', 'STREAK_DAYS', NULL, 30);
+
+-- --------------------------------------------------------
+-- Leaderboard lookups by period
+-- --------------------------------------------------------
+ALTER TABLE mission_logs
+    ADD INDEX idx_status_completed (status, completed_at);
+
+ALTER TABLE wallet_ledgers
+    ADD INDEX idx_reference_created (reference_type, created_at);
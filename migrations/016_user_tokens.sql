@@ -0,0 +1,26 @@
+-- ========================================================
+-- MIGRATION: ACCOUNT TOKENS (INVITATIONS)
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- 33. TABLE: user_tokens
+-- Single-use account tokens. Only the SHA-256 hash of the
+-- token is stored; the plaintext is handed out once.
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS user_tokens;
+CREATE TABLE user_tokens (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    purpose ENUM('INVITE') NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_by BIGINT UNSIGNED NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_user_purpose (user_id, purpose),
+    INDEX idx_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
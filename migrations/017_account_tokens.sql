@@ -0,0 +1,8 @@
+-- ========================================================
+-- MIGRATION: PASSWORD RESET & EMAIL VERIFICATION TOKENS
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+ALTER TABLE user_tokens
+    MODIFY COLUMN purpose ENUM('INVITE', 'PASSWORD_RESET', 'EMAIL_VERIFY') NOT NULL;
@@ -0,0 +1,19 @@
+-- ========================================================
+-- MIGRATION: REFRESH TOKEN FAMILIES
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- Every login starts a family; each refresh rotates to a new
+-- session in the same family. Presenting a rotated refresh
+-- token again revokes the whole family.
+ALTER TABLE sessions
+    ADD COLUMN family_id CHAR(36) NULL AFTER user_id,
+    ADD COLUMN replaced_by BIGINT UNSIGNED NULL AFTER revoked_reason;
+
+UPDATE sessions SET family_id = UUID() WHERE family_id IS NULL;
+
+ALTER TABLE sessions
+    MODIFY COLUMN family_id CHAR(36) NOT NULL,
+    ADD INDEX idx_family_id (family_id),
+    ADD INDEX idx_user_active (user_id, is_active);
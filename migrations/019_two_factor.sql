@@ -0,0 +1,48 @@
+-- ========================================================
+-- MIGRATION: TWO-FACTOR AUTHENTICATION (TOTP)
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- 34. TABLE: user_two_factor
+-- One TOTP secret per user, encrypted at rest. enabled_at
+-- stays NULL until the user confirms a first code, so an
+-- abandoned enrollment never locks anyone out.
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS user_two_factor;
+CREATE TABLE user_two_factor (
+    user_id BIGINT UNSIGNED PRIMARY KEY,
+    secret_encrypted VARCHAR(255) NOT NULL,
+    enabled_at TIMESTAMP NULL,
+    last_used_step BIGINT NULL COMMENT 'Time step of the last accepted code; older or equal steps are replays',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+-- 35. TABLE: two_factor_recovery_codes
+-- Single-use codes for when the authenticator is lost.
+-- Only SHA-256 hashes are stored.
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS two_factor_recovery_codes;
+CREATE TABLE two_factor_recovery_codes (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_user_code (user_id, code_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Password logins of users with two-factor hand out a short-lived
+-- challenge instead of tokens
+ALTER TABLE user_tokens
+    MODIFY COLUMN purpose ENUM('INVITE', 'PASSWORD_RESET', 'EMAIL_VERIFY', 'TWO_FACTOR_LOGIN') NOT NULL;
+
+-- A step-up verification unlocks sensitive actions for the
+-- session's device until this time
+ALTER TABLE sessions
+    ADD COLUMN step_up_until TIMESTAMP NULL AFTER replaced_by;
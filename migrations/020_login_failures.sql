@@ -0,0 +1,31 @@
+-- ========================================================
+-- MIGRATION: LOGIN BRUTE-FORCE PROTECTION
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- 36. TABLE: login_failures
+-- Failed logins counted per username and per client IP.
+-- The count restarts once failures stop for a while;
+-- lockout_count survives so repeat lockouts last longer,
+-- until the next successful login.
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS login_failures;
+CREATE TABLE login_failures (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    scope ENUM('USERNAME', 'IP') NOT NULL,
+    subject VARCHAR(100) NOT NULL COMMENT 'Lower-cased username or IP address',
+    failed_count INT UNSIGNED NOT NULL DEFAULT 0,
+    first_failed_at TIMESTAMP NULL,
+    last_failed_at TIMESTAMP NULL,
+    locked_until TIMESTAMP NULL,
+    lockout_count INT UNSIGNED NOT NULL DEFAULT 0,
+    last_ip VARCHAR(45),
+    last_user_agent VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_scope_subject (scope, subject),
+    INDEX idx_locked_until (locked_until),
+    INDEX idx_last_failed_at (last_failed_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,15 @@
+-- ========================================================
+-- MIGRATION: MULTI-ROLE LOGIN
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- The role a signed-in device acts as. Switching roles updates
+-- the whole family; NULL for sessions started before this
+ALTER TABLE sessions
+    ADD COLUMN active_role VARCHAR(50) NULL AFTER step_up_until;
+
+-- Two-factor login challenges remember the role the password
+-- login asked for
+ALTER TABLE user_tokens
+    ADD COLUMN role VARCHAR(50) NULL AFTER purpose;
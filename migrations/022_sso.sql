@@ -0,0 +1,43 @@
+-- ========================================================
+-- MIGRATION: SINGLE SIGN-ON (OPENID CONNECT)
+-- Database: MySQL 8.0+
+-- Charset: utf8mb4
+-- ========================================================
+
+-- --------------------------------------------------------
+-- 37. TABLE: sso_states
+-- One row per started SSO login. The state is stored as a
+-- SHA-256 hash; the PKCE verifier and nonce never leave the
+-- server until the code is exchanged.
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS sso_states;
+CREATE TABLE sso_states (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    state_hash CHAR(64) NOT NULL UNIQUE,
+    code_verifier VARCHAR(128) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    role VARCHAR(50) NULL COMMENT 'Role the login asked to sign in as',
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+-- 38. TABLE: user_identities
+-- Campus identities linked to users, so a user keeps their
+-- account when their email or NIM/NIP changes at the IdP.
+-- --------------------------------------------------------
+DROP TABLE IF EXISTS user_identities;
+CREATE TABLE user_identities (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    issuer VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(100),
+    linked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_issuer_subject (issuer, subject),
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;